@@ -0,0 +1,336 @@
+package bibtex
+
+import (
+	"fmt"
+	goscan "go/scanner"
+	gotok "go/token"
+	"sort"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/asts"
+	"github.com/jschaf/bibtex/token"
+)
+
+// predefinedAbbrevs are the abbreviations defined by the standard bibtex
+// styles, like plain.bst.
+var predefinedAbbrevs = map[string]string{
+	"jan": "January",
+	"feb": "February",
+	"mar": "March",
+	"apr": "April",
+	"may": "May",
+	"jun": "June",
+	"jul": "July",
+	"aug": "August",
+	"sep": "September",
+	"oct": "October",
+	"nov": "November",
+	"dec": "December",
+}
+
+// AbbrevResolver expands abbreviations, defined with @string, and flattens
+// concatenation expressions. For example, AbbrevResolver resolves the value of
+// the publisher tag in:
+//
+//	@string{acm = "ACM"}
+//	@book{key, publisher = acm # " Press"}
+//
+// into the single text "ACM Press".
+//
+// Abbreviation names are case-insensitive. An abbreviation may be used before
+// it's defined. If an abbreviation is defined more than once in a file, the
+// last definition wins, like in bibtex. Across the files of a package, the
+// definition of the first file in the order of parser.ParsePackage wins. The
+// month abbreviations jan through dec are predefined.
+type AbbrevResolver struct {
+	fset *gotok.FileSet
+}
+
+// NewAbbrevResolver creates a resolver that expands abbreviations in an
+// ast.File, an ast.Package or a single ast.Decl. The file set fset is used to
+// report the positions of undefined abbreviations; it may be nil.
+func NewAbbrevResolver(fset *gotok.FileSet) *AbbrevResolver {
+	return &AbbrevResolver{fset: fset}
+}
+
+// abbrevState is the state of a single AbbrevResolver.Resolve call.
+type abbrevState struct {
+	fset    *gotok.FileSet
+	abbrevs map[string]*ast.AbbrevDecl // by lowercase name
+	done    map[*ast.AbbrevDecl]bool   // true if expanded, false if expanding
+	errs    goscan.ErrorList
+}
+
+// Resolve expands the abbreviations in root using the abbreviation scope the
+// parser built for root. If root has no abbreviation scope, Resolve adds the
+// abbreviations in root to a new one. Resolve returns a scanner.ErrorList
+// with an error for each undefined abbreviation.
+//
+// A single ast.Decl has no abbreviation scope, so Resolve expands the
+// abbreviations the parser resolved for the identifiers of the declaration,
+// the declarations of Ident.Obj, which includes the abbreviations defined in
+// the enclosing file or package.
+func (r *AbbrevResolver) Resolve(root ast.Node) error {
+	s := &abbrevState{
+		fset:    r.fset,
+		abbrevs: make(map[string]*ast.AbbrevDecl),
+		done:    make(map[*ast.AbbrevDecl]bool),
+	}
+	var decls []ast.Decl
+	switch n := root.(type) {
+	case *ast.Package:
+		names := make([]string, 0, len(n.Files))
+		for name := range n.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			decls = append(decls, n.Files[name].Entries...)
+		}
+		if n.Abbrevs == nil {
+			n.Abbrevs = ast.NewScope(nil)
+			s.collect(n.Abbrevs, decls)
+		}
+		s.useScope(n.Abbrevs)
+	case *ast.File:
+		decls = n.Entries
+		if n.Abbrevs == nil {
+			n.Abbrevs = ast.NewScope(nil)
+			s.collect(n.Abbrevs, decls)
+		}
+		s.useScope(n.Abbrevs)
+	case ast.Decl:
+		decls = []ast.Decl{n}
+		s.collectRefs(n)
+	default:
+		return fmt.Errorf("abbrev resolver: unsupported node %T", root)
+	}
+
+	for _, decl := range decls {
+		switch d := decl.(type) {
+		case *ast.AbbrevDecl:
+			s.expandAbbrev(d)
+		case *ast.BibDecl:
+			for _, tag := range d.Tags {
+				tag.Value = s.expand(tag.Value)
+			}
+		case *ast.PreambleDecl:
+			d.Text = s.expand(d.Text)
+		}
+	}
+	s.errs.Sort()
+	return s.errs.Err()
+}
+
+// collect adds the abbreviations in decls to scope, like the parser does for
+// a file.
+func (s *abbrevState) collect(scope *ast.Scope, decls []ast.Decl) {
+	for _, decl := range decls {
+		d, ok := decl.(*ast.AbbrevDecl)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(d.Tag.Name)
+		obj := ast.NewObj(ast.Abbrev, name)
+		obj.Decl = d
+		scope.Objects[name] = obj // the last definition wins
+	}
+}
+
+// useScope adds the abbreviations declared in scope.
+func (s *abbrevState) useScope(scope *ast.Scope) {
+	for name, obj := range scope.Objects {
+		if d, ok := obj.Decl.(*ast.AbbrevDecl); ok && obj.Kind == ast.Abbrev {
+			s.abbrevs[name] = d
+		}
+	}
+}
+
+// collectRefs adds the abbreviations referenced by the identifiers of decl,
+// and the abbreviations they reference in turn, using the objects resolved by
+// the parser.
+func (s *abbrevState) collectRefs(decl ast.Decl) {
+	switch d := decl.(type) {
+	case *ast.AbbrevDecl:
+		s.abbrevs[strings.ToLower(d.Tag.Name)] = d
+		s.collectExprRefs(d.Tag.Value)
+	case *ast.BibDecl:
+		for _, tag := range d.Tags {
+			s.collectExprRefs(tag.Value)
+		}
+	case *ast.PreambleDecl:
+		s.collectExprRefs(d.Text)
+	}
+}
+
+func (s *abbrevState) collectExprRefs(x ast.Expr) {
+	switch x := x.(type) {
+	case *ast.ConcatExpr:
+		s.collectExprRefs(x.X)
+		s.collectExprRefs(x.Y)
+	case *ast.Ident:
+		if x.Obj == nil || x.Obj.Kind != ast.Abbrev {
+			return
+		}
+		d, ok := x.Obj.Decl.(*ast.AbbrevDecl)
+		if !ok {
+			return
+		}
+		name := strings.ToLower(d.Tag.Name)
+		if _, seen := s.abbrevs[name]; !seen {
+			s.abbrevs[name] = d
+			s.collectExprRefs(d.Tag.Value)
+		}
+	}
+}
+
+// expandAbbrev expands the value of the abbreviation d in place and returns
+// the expanded value.
+func (s *abbrevState) expandAbbrev(d *ast.AbbrevDecl) ast.Expr {
+	done, seen := s.done[d]
+	switch {
+	case done:
+		return d.Tag.Value
+	case seen:
+		s.error(d.Tag.NamePos, fmt.Sprintf("abbreviation %q refers to itself", d.Tag.Name))
+		return d.Tag.Value
+	}
+	s.done[d] = false
+	d.Tag.Value = s.expand(d.Tag.Value)
+	s.done[d] = true
+	return d.Tag.Value
+}
+
+// expand returns x with all abbreviations replaced by their value and all
+// concatenations flattened into a single text node.
+func (s *abbrevState) expand(x ast.Expr) ast.Expr {
+	switch x := x.(type) {
+	case *ast.Ident:
+		return s.lookup(x)
+	case *ast.ConcatExpr:
+		var parts []ast.Expr
+		s.flatten(x, &parts)
+		return joinTexts(parts)
+	default:
+		return x
+	}
+}
+
+// flatten appends the expanded operands of the concatenation x to parts.
+func (s *abbrevState) flatten(x ast.Expr, parts *[]ast.Expr) {
+	if c, ok := x.(*ast.ConcatExpr); ok {
+		s.flatten(c.X, parts)
+		s.flatten(c.Y, parts)
+		return
+	}
+	*parts = append(*parts, s.expand(x))
+}
+
+// lookup returns a copy of the value of the abbreviation named by ident,
+// preferring the declaration the parser resolved for ident. If the
+// abbreviation is undefined, lookup reports an error and returns ident.
+func (s *abbrevState) lookup(ident *ast.Ident) ast.Expr {
+	name := strings.ToLower(ident.Name)
+	if ident.Obj != nil && ident.Obj.Kind == ast.Abbrev {
+		if d, ok := ident.Obj.Decl.(*ast.AbbrevDecl); ok {
+			return asts.CloneExpr(s.expandAbbrev(d))
+		}
+	}
+	if d, ok := s.abbrevs[name]; ok {
+		return asts.CloneExpr(s.expandAbbrev(d))
+	}
+	if v, ok := predefinedAbbrevs[name]; ok {
+		return &ast.ParsedText{
+			Opener: ident.NamePos,
+			Delim:  ast.BraceDelimiter,
+			Values: []ast.Expr{&ast.Text{ValuePos: ident.NamePos, Value: v}},
+			Closer: ident.End(),
+		}
+	}
+	s.error(ident.NamePos, fmt.Sprintf("undefined abbreviation %q", ident.Name))
+	return ident
+}
+
+func (s *abbrevState) error(pos gotok.Pos, msg string) {
+	var position gotok.Position
+	if s.fset != nil {
+		position = s.fset.Position(pos)
+	}
+	s.errs.Add(position, msg)
+}
+
+// joinTexts joins the text of the concatenated parts into a single text node.
+// The result is an ast.ParsedText unless all parts are unparsed, in which
+// case it's an ast.UnparsedText. If a part can't be joined, like an undefined
+// abbreviation, joinTexts returns the concatenation of the parts.
+func joinTexts(parts []ast.Expr) ast.Expr {
+	unparsed := false
+	for _, part := range parts {
+		if _, ok := part.(*ast.UnparsedText); ok {
+			unparsed = true
+		}
+	}
+	if unparsed {
+		if x, ok := joinUnparsedTexts(parts); ok {
+			return x
+		}
+		return concat(parts)
+	}
+
+	txt := &ast.ParsedText{
+		Opener: parts[0].Pos(),
+		Delim:  ast.BraceDelimiter,
+		Closer: parts[len(parts)-1].End(),
+	}
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *ast.ParsedText:
+			txt.Values = append(txt.Values, p.Values...)
+		case *ast.Number:
+			txt.Values = append(txt.Values, &ast.Text{ValuePos: p.ValuePos, Value: p.Value})
+		default:
+			return concat(parts)
+		}
+	}
+	return txt
+}
+
+// joinUnparsedTexts joins parts into a single ast.UnparsedText. Parsed text
+// may only contain plain text, like a predefined abbreviation.
+func joinUnparsedTexts(parts []ast.Expr) (ast.Expr, bool) {
+	sb := &strings.Builder{}
+	for _, part := range parts {
+		switch p := part.(type) {
+		case *ast.UnparsedText:
+			sb.WriteString(p.Value)
+		case *ast.Number:
+			sb.WriteString(p.Value)
+		case *ast.ParsedText:
+			for _, v := range p.Values {
+				t, ok := v.(*ast.Text)
+				if !ok {
+					return nil, false
+				}
+				sb.WriteString(t.Value)
+			}
+		default:
+			return nil, false
+		}
+	}
+	return &ast.UnparsedText{
+		ValuePos: parts[0].Pos(),
+		Type:     token.BraceString,
+		Value:    sb.String(),
+	}, true
+}
+
+// concat returns the right-associative concatenation of parts, like the
+// parser produces.
+func concat(parts []ast.Expr) ast.Expr {
+	x := parts[len(parts)-1]
+	for i := len(parts) - 2; i >= 0; i-- {
+		x = &ast.ConcatExpr{X: parts[i], Y: x}
+	}
+	return x
+}
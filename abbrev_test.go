@@ -0,0 +1,238 @@
+package bibtex
+
+import (
+	"fmt"
+	gotok "go/token"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/asts"
+	"github.com/jschaf/bibtex/parser"
+)
+
+func TestAbbrevResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want ast.Expr // value of the first tag of the last declaration
+	}{
+		{
+			name: "abbrev",
+			src:  `@string{acm = "ACM"} @book{key, publisher = acm}`,
+			want: asts.QuotedText(0, "ACM"),
+		},
+		{
+			name: "concat",
+			src:  `@string{acm = "ACM"} @book{key, publisher = acm # " Press"}`,
+			want: asts.BraceText(0, "ACM", " ", "Press"),
+		},
+		{
+			name: "concat without abbrevs",
+			src:  `@book{key, title = "Foo" # { Bar} # 2004}`,
+			want: asts.BraceText(0, "Foo", " ", "Bar", "2004"),
+		},
+		{
+			name: "case insensitive",
+			src:  `@STRING{ACM = {ACM}} @book{key, publisher = Acm}`,
+			want: asts.BraceText(0, "ACM"),
+		},
+		{
+			name: "month",
+			src:  `@book{key, month = jan # "~1"}`,
+			want: asts.BraceText(0, "January", "~", "1"),
+		},
+		{
+			name: "use before definition",
+			src:  `@book{key, publisher = acm} @string{acm = "ACM"} @misc{other, publisher = acm}`,
+			want: asts.QuotedText(0, "ACM"),
+		},
+		{
+			name: "last definition wins",
+			src:  `@string{acm = "ACM"} @string{acm = "IEEE"} @book{key, publisher = acm}`,
+			want: asts.QuotedText(0, "IEEE"),
+		},
+		{
+			name: "abbrev referring to abbrev",
+			src:  `@string{acm = "ACM"} @string{acmp = acm # " Press"} @book{key, publisher = acmp}`,
+			want: asts.BraceText(0, "ACM", " ", "Press"),
+		},
+		{
+			name: "preamble",
+			src:  `@string{foo = "foo"} @preamble{foo # " bar"}`,
+			want: asts.BraceText(0, "foo", " ", "bar"),
+		},
+	}
+	for _, tt := range tests {
+		// Resolving only the last declaration uses the abbreviations of the
+		// file that the parser resolved.
+		for _, root := range []string{"file", "decl"} {
+			t.Run(tt.name+"/"+root, func(t *testing.T) {
+				fset := gotok.NewFileSet()
+				f, err := parser.ParseFile(fset, "", tt.src, parser.ParseStrings)
+				if err != nil {
+					t.Fatal(err)
+				}
+				last := f.Entries[len(f.Entries)-1]
+				var node ast.Node = f
+				if root == "decl" {
+					node = last
+				}
+				if err := NewAbbrevResolver(fset).Resolve(node); err != nil {
+					t.Fatal(err)
+				}
+				var got ast.Expr
+				switch d := last.(type) {
+				case *ast.BibDecl:
+					got = d.Tags[0].Value
+				case *ast.PreambleDecl:
+					got = d.Text
+				}
+				if diff := cmp.Diff(asts.ExprString(tt.want), asts.ExprString(got)); diff != "" {
+					t.Errorf("AbbrevResolver.Resolve() mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestAbbrevResolver_Resolve_declInPackage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"abbrevs.bib": {Data: []byte(`@string{acm = "ACM"} @string{acmp = acm # " Press"}`)},
+		"books.bib":   {Data: []byte("@book{key, publisher = acmp}\n@book{other, publisher = ieee}")},
+	}
+	fset := gotok.NewFileSet()
+	pkg, err := parser.ParsePackageFS(fset, fsys, []string{"abbrevs.bib", "books.bib"}, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := pkg.Files["books.bib"]
+	if err := NewAbbrevResolver(fset).Resolve(books.Entries[0]); err != nil {
+		t.Fatal(err)
+	}
+	got := books.Entries[0].(*ast.BibDecl).Tags[0].Value
+	want := asts.BraceText(0, "ACM", " ", "Press")
+	if diff := cmp.Diff(asts.ExprString(want), asts.ExprString(got)); diff != "" {
+		t.Errorf("AbbrevResolver.Resolve() mismatch (-want +got):\n%s", diff)
+	}
+
+	err = NewAbbrevResolver(fset).Resolve(books.Entries[1])
+	if diff := cmp.Diff(`books.bib:2:26: undefined abbreviation "ieee"`, fmt.Sprint(err)); diff != "" {
+		t.Errorf("AbbrevResolver.Resolve() error mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAbbrevResolver_Resolve_unparsed(t *testing.T) {
+	src := `@string{acm = "ACM"} @book{key, publisher = acm # { Press} # " " # jan}`
+	fset := gotok.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewAbbrevResolver(fset).Resolve(f); err != nil {
+		t.Fatal(err)
+	}
+	got := f.Entries[1].(*ast.BibDecl).Tags[0].Value
+	want := asts.UnparsedBraceText("ACM Press January")
+	if diff := cmp.Diff(asts.ExprString(want), asts.ExprString(got)); diff != "" {
+		t.Errorf("AbbrevResolver.Resolve() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestAbbrevResolver_Resolve_scope(t *testing.T) {
+	src := `@string{ACM = "ACM"} @book{key, publisher = acm} @book{other, publisher = acm}`
+	fset := gotok.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewAbbrevResolver(fset).Resolve(f); err != nil {
+		t.Fatal(err)
+	}
+	obj := f.Abbrevs.Lookup("acm")
+	if obj == nil || obj.Kind != ast.Abbrev || obj.Decl != f.Entries[0] {
+		t.Errorf("expected abbrev object for acm in scope; got %v", obj)
+	}
+	// Each use gets its own copy so that resolvers can modify it in place.
+	x := f.Entries[1].(*ast.BibDecl).Tags[0].Value
+	y := f.Entries[2].(*ast.BibDecl).Tags[0].Value
+	if x == y {
+		t.Error("expected separate copies of the abbrev value")
+	}
+}
+
+func TestAbbrevResolver_Resolve_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "undefined",
+			src:  "@book{key,\n  publisher = acm # \" Press\"}",
+			want: `2:15: undefined abbreviation "acm"`,
+		},
+		{
+			name: "multiple undefined",
+			src:  "@book{key, publisher = foo}\n@book{key2, publisher = bar}",
+			want: `1:24: undefined abbreviation "foo" (and 1 more errors)`,
+		},
+		{
+			name: "self reference",
+			src:  `@string{foo = "a" # foo}`,
+			want: `1:9: abbreviation "foo" refers to itself`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := gotok.NewFileSet()
+			f, err := parser.ParseFile(fset, "", tt.src, parser.ParseStrings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = NewAbbrevResolver(fset).Resolve(f)
+			if err == nil {
+				t.Fatal("expected error but had none")
+			}
+			if diff := cmp.Diff(tt.want, err.Error()); diff != "" {
+				t.Errorf("AbbrevResolver.Resolve() error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAbbrevResolver_Resolve_packageOrder(t *testing.T) {
+	// The parser declares the abbreviation of the first file in path order, so
+	// resolving the package and resolving the declaration agree.
+	fsys := fstest.MapFS{
+		"b.bib": {Data: []byte(`@string{pub = "B"}`)},
+		"a.bib": {Data: []byte(`@string{pub = "A"}`)},
+		"c.bib": {Data: []byte(`@book{key, publisher = pub}`)},
+	}
+	paths := []string{"a.bib", "b.bib", "c.bib"}
+	for _, root := range []string{"package", "decl"} {
+		t.Run(root, func(t *testing.T) {
+			fset := gotok.NewFileSet()
+			pkg, err := parser.ParsePackageFS(fset, fsys, paths, parser.ParseStrings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decl := pkg.Files["c.bib"].Entries[0].(*ast.BibDecl)
+			var node ast.Node = pkg
+			if root == "decl" {
+				node = decl
+			}
+			if err := NewAbbrevResolver(fset).Resolve(node); err != nil {
+				t.Fatal(err)
+			}
+			want := asts.QuotedText(0, "A")
+			if diff := cmp.Diff(asts.ExprString(want), asts.ExprString(decl.Tags[0].Value)); diff != "" {
+				t.Errorf("AbbrevResolver.Resolve() mismatch (-want +got):\n%s", diff)
+			}
+			if obj := pkg.Abbrevs.Lookup("pub"); obj == nil || obj.Decl != pkg.Files["a.bib"].Entries[0] {
+				t.Errorf("Abbrevs.Lookup(%q) = %v; want the declaration of a.bib", "pub", obj)
+			}
+		})
+	}
+}
@@ -27,6 +27,7 @@ const (
 	KindUnparsedText
 	KindParsedText
 	KindText
+	KindTextAccent
 	KindTextComma
 	KindTextEscaped
 	KindTextHyphen
@@ -40,6 +41,7 @@ const (
 	KindBadDecl
 	KindAbbrevDecl
 	KindBibDecl
+	KindCommentDecl
 	KindPreambleDecl
 	KindFile
 	KindPackage
@@ -56,6 +58,7 @@ var kindNames = [...]string{
 	KindUnparsedText:    "UnparsedText",
 	KindParsedText:      "ParsedText",
 	KindText:            "Text",
+	KindTextAccent:      "TextAccent",
 	KindTextComma:       "TextComma",
 	KindTextEscaped:     "TextEscaped",
 	KindTextHyphen:      "TextHyphen",
@@ -69,6 +72,7 @@ var kindNames = [...]string{
 	KindBadDecl:         "BadDecl",
 	KindAbbrevDecl:      "AbbrevDecl",
 	KindBibDecl:         "BibDecl",
+	KindCommentDecl:     "CommentDecl",
 	KindPreambleDecl:    "PreambleDecl",
 	KindFile:            "File",
 	KindPackage:         "Package",
@@ -169,13 +173,15 @@ type (
 	// editor fields of a bibtex declaration.
 	Authors []*Author
 
-	// An Author node represents a single bibtex author.
+	// An Author node represents a single bibtex author. Each part is a
+	// ParsedText at depth 0 with the words of the part, or a Text after
+	// rendering, like with bibtex.RenderParsedTextResolver.
 	Author struct {
-		From, To gotok.Pos
-		First    Expr // given name
-		Prefix   Expr // often called the 'von' part
-		Last     Expr // family name
-		Suffix   Expr // often called the 'jr' part
+		From, To gotok.Pos // position range of the name in the field
+		First    Expr      // given name
+		Prefix   Expr      // often called the 'von' part
+		Last     Expr      // family name
+		Suffix   Expr      // often called the 'jr' part
 	}
 
 	// An UnparsedText is a bibtex string as it appears in the source. Only
@@ -206,6 +212,7 @@ type (
 		ValuePos gotok.Pos    // literal position
 		Accent   token.Accent // the accent character, like "^" for circumflex
 		Text     *Text        // the text to accent; must be a single rune
+		Raw      string       // accent as it appeared in the source, like \'{o}; or ""
 	}
 
 	// A TextComma node is a string of exactly one comma. Useful because a comma
@@ -216,7 +223,7 @@ type (
 
 	// A TextEscaped node is a string of exactly 1 escaped character. The only
 	// escapable characters are:
-	//     '\\', '$', '&', '%', '{', '}'
+	//     '\\', '$', '&', '%', '#', '{', '}', '_'
 	// In all other cases, a backslash is interpreted as the start of a TeX macro.
 	TextEscaped struct {
 		ValuePos gotok.Pos // literal position
@@ -303,38 +310,38 @@ func (Authors) exprNode()        {}
 func (x *Author) Pos() gotok.Pos { return x.From }
 func (x *Author) End() gotok.Pos { return x.To }
 func (x *Author) Kind() NodeKind { return KindAuthor }
+
+// IsEmpty returns true if all parts of the author are empty.
 func (x *Author) IsEmpty() bool {
-	if s, ok := x.First.(*Text); !ok || s.Value != "" {
-		return false
-	}
-	if s, ok := x.Prefix.(*Text); !ok || s.Value != "" {
-		return false
-	}
-	if s, ok := x.Last.(*Text); !ok || s.Value != "" {
-		return false
-	}
-	if s, ok := x.Suffix.(*Text); !ok || s.Value != "" {
-		return false
-	}
-	return true
+	return isEmptyPart(x.First) && isEmptyPart(x.Prefix) && isEmptyPart(x.Last) && isEmptyPart(x.Suffix)
 }
 
 // IsOthers returns true if this author was created from the "and others"
 // suffix in from authors.
 func (x *Author) IsOthers() bool {
-	if s, ok := x.First.(*Text); !ok || s.Value != "" {
-		return false
-	}
-	if s, ok := x.Prefix.(*Text); !ok || s.Value != "" {
-		return false
-	}
-	if s, ok := x.Last.(*Text); !ok || s.Value != "others" {
+	if !isEmptyPart(x.First) || !isEmptyPart(x.Prefix) || !isEmptyPart(x.Suffix) {
 		return false
 	}
-	if s, ok := x.Suffix.(*Text); !ok || s.Value != "" {
-		return false
+	last := x.Last
+	if t, ok := last.(*ParsedText); ok && len(t.Values) == 1 {
+		last = t.Values[0]
 	}
-	return true
+	s, ok := last.(*Text)
+	return ok && s.Value == "others"
+}
+
+// isEmptyPart returns true if the author name part x is nil, empty text or
+// parsed text without values.
+func isEmptyPart(x Expr) bool {
+	switch t := x.(type) {
+	case nil:
+		return true
+	case *Text:
+		return t.Value == ""
+	case *ParsedText:
+		return len(t.Values) == 0
+	}
+	return false
 }
 func (x *Author) exprNode() {}
 
@@ -360,7 +367,7 @@ func (*Text) exprNode()        {}
 
 func (x *TextAccent) Pos() gotok.Pos { return x.ValuePos }
 func (x *TextAccent) End() gotok.Pos { return x.Text.End() }
-func (x *TextAccent) Kind() NodeKind { return KindText }
+func (x *TextAccent) Kind() NodeKind { return KindTextAccent }
 func (*TextAccent) exprNode()        {}
 
 func (x *TextComma) Pos() gotok.Pos { return x.ValuePos }
@@ -430,7 +437,9 @@ type (
 		NamePos gotok.Pos        // identifier position
 		Name    string           // identifier name, normalized with lowercase
 		RawName string           // identifier name as it appeared in the source
+		Assign  gotok.Pos        // position of "="
 		Value   Expr             // denoted expression
+		Comma   gotok.Pos        // position of the trailing ","; or NoPos
 	}
 )
 
@@ -458,31 +467,55 @@ type (
 	// An AbbrevDecl node represents a bibtex abbreviation, like:
 	//   @STRING { foo = "bar" }
 	AbbrevDecl struct {
-		Doc    *TexCommentGroup // associated documentation; or nil
-		Entry  gotok.Pos        // position of the "@STRING" token
-		Tag    *TagStmt
-		RBrace gotok.Pos // position of the closing right brace token: "}".
+		Doc     *TexCommentGroup // associated documentation; or nil
+		Entry   gotok.Pos        // position of the "@STRING" token
+		RawType string           // command as it appeared in the source without '@', e.g. "String"
+		Delim   token.Token      // opening delimiter, token.LBrace or token.LParen
+		LBrace  gotok.Pos        // position of the opening delimiter
+		Tag     *TagStmt
+		RBrace  gotok.Pos // position of the closing right brace token: "}".
 	}
 
 	// An BibDecl node represents a bibtex entry, like:
 	//   @article { author = "bar" }
 	BibDecl struct {
 		Type      string           // type of entry, e.g. "article"
+		RawType   string           // type of entry as it appeared in the source, e.g. "ARTICLE"
 		Doc       *TexCommentGroup // associated documentation; or nil
 		Entry     gotok.Pos        // position of the start token, e.g. "@article"
+		Delim     token.Token      // opening delimiter, token.LBrace or token.LParen
+		LBrace    gotok.Pos        // position of the opening delimiter
 		Key       *Ident           // the first key in the declaration
 		ExtraKeys []*Ident         // any other keys in the declaration, usually nil
 		Tags      []*TagStmt       // all tags in the declaration
 		RBrace    gotok.Pos        // position of the closing right brace token: "}".
 	}
 
+	// A CommentDecl node represents a bibtex comment, either delimited:
+	//   @COMMENT { foo }
+	// or extending to the end of the line:
+	//   @comment foo
+	CommentDecl struct {
+		Doc     *TexCommentGroup // associated documentation; or nil
+		Entry   gotok.Pos        // position of the "@COMMENT" token
+		RawType string           // command as it appeared in the source without '@', e.g. "Comment"
+		Delim   token.Token      // opening delimiter, token.LBrace or token.LParen; token.Illegal if not delimited
+		LBrace  gotok.Pos        // position of the opening delimiter; or NoPos
+		TextPos gotok.Pos        // position of the comment text; or NoPos if not delimited and blank
+		Text    string           // comment text excluding delimiters
+		RBrace  gotok.Pos        // position of the closing delimiter; or NoPos
+	}
+
 	// An PreambleDecl node represents a bibtex preamble, like:
 	//   @PREAMBLE { "foo" }
 	PreambleDecl struct {
-		Doc    *TexCommentGroup // associated documentation; or nil
-		Entry  gotok.Pos        // position of the "@PREAMBLE" token
-		Text   Expr             // The content of the preamble node
-		RBrace gotok.Pos        // position of the closing right brace token: "}"
+		Doc     *TexCommentGroup // associated documentation; or nil
+		Entry   gotok.Pos        // position of the "@PREAMBLE" token
+		RawType string           // command as it appeared in the source without '@', e.g. "Preamble"
+		Delim   token.Token      // opening delimiter, token.LBrace or token.LParen
+		LBrace  gotok.Pos        // position of the opening delimiter
+		Text    Expr             // The content of the preamble node
+		RBrace  gotok.Pos        // position of the closing right brace token: "}"
 	}
 )
 
@@ -501,6 +534,19 @@ func (e *BibDecl) End() gotok.Pos { return e.RBrace }
 func (e *BibDecl) Kind() NodeKind { return KindBibDecl }
 func (*BibDecl) declNode()        {}
 
+func (e *CommentDecl) Pos() gotok.Pos { return e.Entry }
+func (e *CommentDecl) End() gotok.Pos {
+	if e.RBrace.IsValid() {
+		return e.RBrace
+	}
+	if !e.TextPos.IsValid() {
+		return gotok.Pos(int(e.Entry) + len(e.RawType) + 1)
+	}
+	return gotok.Pos(int(e.TextPos) + len(e.Text))
+}
+func (e *CommentDecl) Kind() NodeKind { return KindCommentDecl }
+func (*CommentDecl) declNode()        {}
+
 func (e *PreambleDecl) Pos() gotok.Pos { return e.Entry }
 func (e *PreambleDecl) End() gotok.Pos { return e.RBrace }
 func (e *PreambleDecl) Kind() NodeKind { return KindPreambleDecl }
@@ -528,12 +574,15 @@ func (*PreambleDecl) declNode()        {}
 // and Comment comments directly associated with nodes, the remaining comments
 // are "free-floating".
 type File struct {
-	Name       string
-	Doc        *TexCommentGroup   // associated documentation; or nil
-	Entries    []Decl             // top-level entries; or nil
-	Scope      *Scope             // package scope (this file only)
-	Unresolved []*Ident           // unresolved identifiers in this file
-	Comments   []*TexCommentGroup // list of all comments in the source file
+	Name           string
+	Doc            *TexCommentGroup   // associated documentation; or nil
+	Entries        []Decl             // top-level entries; or nil
+	Scope          *Scope             // entries declared in this file
+	Abbrevs        *Scope             // abbreviations declared in this file
+	Unresolved     []*Ident           // unresolved abbreviations in this file
+	UnresolvedKeys []*Ident           // unresolved crossref keys in this file; not part of the AST
+	Comments       []*TexCommentGroup // list of all comments in the source file
+	Src            []byte             // source text of the file; used by the printer to keep the layout
 }
 
 func (f *File) Pos() gotok.Pos { return gotok.Pos(1) }
@@ -548,8 +597,9 @@ func (f *File) Kind() NodeKind { return KindFile }
 // A Package node represents a set of source files collectively representing
 // a single, unified bibliography.
 type Package struct {
-	Scope   *Scope             // package scope across all files
-	Objects map[string]*Object // map of package id -> package object
+	Scope   *Scope             // entries declared in all files
+	Abbrevs *Scope             // abbreviations declared in all files
+	Objects map[string]*Object // entries declared in all files by key; same map as Scope.Objects
 	Files   map[string]*File   // Bibtex source files by filename
 }
 
@@ -83,6 +83,10 @@ func (obj *Object) Pos() token.Pos {
 		if d.Key != nil && d.Key.Name == name {
 			return d.Key.Pos()
 		}
+	case *AbbrevDecl:
+		if d.Tag != nil && d.Tag.Name == name {
+			return d.Tag.NamePos
+		}
 	case *Scope:
 		// predeclared object - nothing to do for now
 	}
@@ -301,3 +301,91 @@ func WithBibTags(key string, val ast.Expr, rest ...interface{}) func(decl *ast.B
 		}
 	}
 }
+
+// CloneExpr returns a deep copy of x. Identifiers in the copy refer to the same
+// ast.Object as the original.
+func CloneExpr(x ast.Expr) ast.Expr {
+	switch v := x.(type) {
+	case nil:
+		return nil
+	case *ast.BadExpr:
+		c := *v
+		return &c
+	case *ast.Ident:
+		c := *v
+		return &c
+	case *ast.Number:
+		c := *v
+		return &c
+	case ast.Authors:
+		c := make(ast.Authors, len(v))
+		for i, a := range v {
+			c[i] = CloneExpr(a).(*ast.Author)
+		}
+		return c
+	case *ast.Author:
+		c := *v
+		c.First = CloneExpr(v.First)
+		c.Prefix = CloneExpr(v.Prefix)
+		c.Last = CloneExpr(v.Last)
+		c.Suffix = CloneExpr(v.Suffix)
+		return &c
+	case *ast.UnparsedText:
+		c := *v
+		return &c
+	case *ast.ParsedText:
+		c := *v
+		c.Values = cloneExprs(v.Values)
+		return &c
+	case *ast.Text:
+		c := *v
+		return &c
+	case *ast.TextAccent:
+		c := *v
+		if v.Text != nil {
+			t := *v.Text
+			c.Text = &t
+		}
+		return &c
+	case *ast.TextComma:
+		c := *v
+		return &c
+	case *ast.TextEscaped:
+		c := *v
+		return &c
+	case *ast.TextHyphen:
+		c := *v
+		return &c
+	case *ast.TextMath:
+		c := *v
+		return &c
+	case *ast.TextNBSP:
+		c := *v
+		return &c
+	case *ast.TextSpace:
+		c := *v
+		return &c
+	case *ast.TextMacro:
+		c := *v
+		c.Values = cloneExprs(v.Values)
+		return &c
+	case *ast.ConcatExpr:
+		c := *v
+		c.X = CloneExpr(v.X)
+		c.Y = CloneExpr(v.Y)
+		return &c
+	default:
+		panic(fmt.Sprintf("unsupported type for CloneExpr: %T", x))
+	}
+}
+
+func cloneExprs(xs []ast.Expr) []ast.Expr {
+	if xs == nil {
+		return nil
+	}
+	c := make([]ast.Expr, len(xs))
+	for i, x := range xs {
+		c[i] = CloneExpr(x)
+	}
+	return c
+}
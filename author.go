@@ -1,279 +1,316 @@
 package bibtex
 
 import (
-	"fmt"
+	gotok "go/token"
 	"strings"
 	"unicode"
-	"unicode/utf8"
 
 	"github.com/jschaf/bibtex/ast"
-	"github.com/jschaf/bibtex/render"
 )
 
-const authorSep = "and"
+// AuthorError is an error in the names of an author or editor field.
+type AuthorError struct {
+	Pos gotok.Pos // the position of the error in the field
+	Msg string
+}
+
+func (e *AuthorError) Error() string {
+	return e.Msg
+}
 
 // ExtractAuthors extracts the authors from the parsed text of a bibtex field,
 // usually from the author or editor field of bibtex entry.
+//
+// ExtractAuthors follows the name rules of bibtex, as described in "Tame the
+// BeaST" and implemented by btparse. Names are separated by the word "and"
+// at brace depth 0, so "{Barnes and Noble}" is a single name. Each name has
+// one of the forms:
+//
+//	First von Last
+//	von Last, First
+//	von Last, Jr, First
+//
+// Words are separated by whitespace, ties and hyphens at brace depth 0. The
+// von part is the longest run of words, starting and ending with a
+// lowercase word, that leaves at least one word for the last name. The case
+// of a word is the case of its first letter at brace depth 0. Brace groups
+// have no case, except for special characters, like {\'E} or {\ae}, which
+// have the case of their letter. Without a comma and a von part, the last
+// name is the last word and any words joined to it with hyphens, like
+// "Jean-Paul Sartre".
+//
+// Each part of an author is an *ast.ParsedText at depth 0 with the
+// expressions of its words and the separators between them, so the parts
+// keep their positions, accents and brace groups. From and To are the
+// positions of the name in the field. RenderParsedTextResolver renders the
+// parts into text.
+//
+// ExtractAuthors returns an *AuthorError for an empty name or a name with
+// more than two commas.
 func ExtractAuthors(txt *ast.ParsedText) (ast.Authors, error) {
-	// Pop tokens until we get the author separator then hand off the tokens
-	// to extractAuthor to get the Author.
-	authors := make([]*ast.Author, 0, 4)
-	aExprs := make([]ast.Expr, 0, 8)
-	for _, v := range txt.Values {
-		switch t := v.(type) {
-		case *ast.Text:
-			if t.Value == authorSep {
-				a := extractAuthor(aExprs)
-				if a.IsEmpty() {
-					return nil, fmt.Errorf("found an empty author")
-				}
-				authors = append(authors, a)
-				aExprs = aExprs[:0]
-				continue
-			}
+	authors := make(ast.Authors, 0, 4)
+	start := 0
+	toks := splitNameTokens(txt.Values)
+	pos := txt.Pos()
+	for i := 0; i <= len(toks); i++ {
+		if i < len(toks) && !isAuthorSep(toks, i) {
+			continue
 		}
-		aExprs = append(aExprs, v)
-	}
-	final := extractAuthor(aExprs)
-	if final.IsEmpty() {
-		return nil, fmt.Errorf("found an empty author")
+		if i == start {
+			return nil, &AuthorError{Pos: pos, Msg: "found an empty author"}
+		}
+		a, err := extractAuthor(toks[start:i])
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, a)
+		if i < len(toks) {
+			pos = toks[i].pos
+		}
+		start = i + 1
 	}
-	authors = append(authors, final)
 	return authors, nil
 }
 
-func trimSpaces(xs []ast.Expr) []ast.Expr {
-	lo, hi := 0, len(xs)
-
-	for i := 0; i < len(xs); i++ {
-		if _, ok := xs[i].(*ast.TextSpace); !ok {
-			break
-		}
-		lo++
-	}
-
-	for i := len(xs) - 1; i >= 0; i-- {
-		if _, ok := xs[i].(*ast.TextSpace); !ok {
-			break
-		}
-		hi--
-	}
-	if hi <= lo {
-		return xs[lo:lo]
-	}
-	return xs[lo:hi]
+// nameToken is a word or a comma of a list of names.
+type nameToken struct {
+	pos    gotok.Pos
+	comma  bool       // a comma at brace depth 0
+	sep    byte       // the separator before a word: ' ', '-' or '~', or 0
+	seps   []ast.Expr // the separator expressions before a word
+	values []ast.Expr // the expressions of a word
 }
 
-func extractAuthor(xs []ast.Expr) *ast.Author {
-	xs = trimSpaces(xs)
-	commas := findCommas(xs)
-	if len(commas) == 0 {
-		return resolveAuthor0(xs)
-	} else {
-		return resolveAuthorN(xs, commas)
+func (t *nameToken) end() gotok.Pos {
+	if t.comma {
+		return t.pos + 1
 	}
+	return t.values[len(t.values)-1].End()
 }
 
-type resolveAction int
-
-const (
-	resolveContinue resolveAction = iota
-	resolveNextPart
-)
-
-func parseFirstName(idx int, xs []ast.Expr) (string, resolveAction) {
-	x := xs[idx]
-	if _, ok := x.(*ast.TextSpace); ok && idx < len(xs)-1 {
-		if t, ok := xs[idx+1].(*ast.Text); ok && !hasUpperPrefix(t.Value) {
-			// lowercase after space means we're out of the first name
-			return "", resolveNextPart
+// splitNameTokens splits the expressions of a name field into words and
+// commas at brace depth 0. A text is split at hyphens, like "Jean-Paul".
+func splitNameTokens(xs []ast.Expr) []*nameToken {
+	var toks []*nameToken
+	var word *nameToken
+	var sep byte
+	var seps []ast.Expr
+	separate := func(s byte, x ast.Expr) {
+		word = nil
+		seps = append(seps, x)
+		if s == '-' || s == '~' || sep == 0 && len(toks) > 0 && !toks[len(toks)-1].comma {
+			sep = s
 		}
 	}
-
-	if _, ok := x.(*ast.Text); !ok {
-		return parseDefault(idx, xs), resolveContinue
+	add := func(x ast.Expr) {
+		if word == nil {
+			word = &nameToken{pos: x.Pos(), sep: sep, seps: seps}
+			toks = append(toks, word)
+			sep, seps = 0, nil
+		}
+		word.values = append(word.values, x)
 	}
-
-	t := x.(*ast.Text)
-
-	return t.Value, resolveContinue
-}
-
-func hasUpperPrefix(s string) bool {
-	r, _ := utf8.DecodeRuneInString(s)
-	return unicode.IsUpper(r)
+	for _, x := range xs {
+		switch x := x.(type) {
+		case *ast.TextSpace:
+			separate(' ', x)
+		case *ast.TextNBSP:
+			separate('~', x)
+		case *ast.TextHyphen:
+			separate('-', x)
+		case *ast.TextComma:
+			word, sep, seps = nil, 0, nil
+			toks = append(toks, &nameToken{pos: x.ValuePos, comma: true})
+		case *ast.Text:
+			if !strings.Contains(x.Value, "-") {
+				add(x)
+				continue
+			}
+			off := 0
+			for _, s := range strings.Split(x.Value, "-") {
+				if s != "" {
+					add(&ast.Text{ValuePos: x.ValuePos + gotok.Pos(off), Value: s})
+				}
+				off += len(s) + 1
+				if off <= len(x.Value) {
+					separate('-', &ast.TextHyphen{ValuePos: x.ValuePos + gotok.Pos(off-1)})
+				}
+			}
+		default:
+			add(x)
+		}
+	}
+	return toks
 }
 
-func parsePrefixName(idx int, xs []ast.Expr) (string, resolveAction) {
-	x := xs[idx]
-	if _, ok := x.(*ast.Text); !ok {
-		return parseDefault(idx, xs), resolveContinue
+// isAuthorSep returns true if toks[i] is the word "and" surrounded by
+// whitespace.
+func isAuthorSep(toks []*nameToken, i int) bool {
+	tok := toks[i]
+	if tok.comma || tok.sep != ' ' || len(tok.values) != 1 {
+		return false
 	}
-
-	t := x.(*ast.Text)
-	if hasUpperPrefix(t.Value) {
-		return "", resolveNextPart // Only lowercase goes in prefix
+	if t, ok := tok.values[0].(*ast.Text); !ok || !strings.EqualFold(t.Value, "and") {
+		return false
 	}
-	return t.Value, resolveContinue
-}
-
-func parseLastName(idx int, xs []ast.Expr) (string, resolveAction) {
-	return parseDefault(idx, xs), resolveContinue
+	return i+1 < len(toks) && !toks[i+1].comma && toks[i+1].sep == ' '
 }
 
-// TODO: replace with single resolver
-func parseDefault(idx int, xs []ast.Expr) string {
-	x := xs[idx]
-	switch t := x.(type) {
-	case *ast.ParsedText:
-		sb := strings.Builder{}
-		sb.Grow(16)
-		for i := range t.Values {
-			d := parseDefault(i, t.Values)
-			sb.WriteString(d)
+// extractAuthor splits the tokens of a single name into the parts of an
+// author.
+func extractAuthor(toks []*nameToken) (*ast.Author, error) {
+	var words []*nameToken
+	var commas []int // the index in words after each comma
+	for _, tok := range toks {
+		if !tok.comma {
+			words = append(words, tok)
+			continue
 		}
-		return sb.String()
-	case *ast.TextComma:
-		return ","
-	case *ast.TextEscaped:
-		return `\` + t.Value
-	case *ast.TextHyphen:
-		return "-"
-	case *ast.TextNBSP, *ast.TextSpace:
-		return " "
-	case *ast.TextMath:
-		return "$" + t.Value + "$"
-	case *ast.Text:
-		return t.Value
-	case *ast.TextAccent:
-		r, err := render.RenderAccent(t.Accent, t.Text.Value)
-		if err != nil {
-			panic("cannot render accent")
+		if len(commas) == 2 {
+			return nil, &AuthorError{Pos: tok.pos, Msg: "too many commas in name"}
 		}
-		return string(r)
-	default:
-		panic("unhandled ast.Expr value")
+		commas = append(commas, len(words))
 	}
-}
-
-// resolveAuthor0 resolves an author for an entry with no commas, like
-// "First von Last".
-func resolveAuthor0(xs []ast.Expr) *ast.Author {
-	first := strings.Builder{}
-	first.Grow(16)
-	idx := 0
-	for ; idx < len(xs); idx++ {
-		if idx == len(xs)-1 {
-			// If we're on the last part, it belongs to the last name.
-			break
-		}
-		val, action := parseFirstName(idx, xs)
-		if action == resolveNextPart {
-			break
-		}
-		first.WriteString(val)
+	if len(words) == 0 {
+		return nil, &AuthorError{Pos: toks[0].pos, Msg: "found an empty author"}
 	}
 
-	prefix := strings.Builder{}
-	for ; idx < len(xs); idx++ {
-		if idx == len(xs)-1 {
-			// If we're on the last part, it belongs to the last name.
-			break
+	// vonEnd returns the end of the von part given its start and the end of
+	// the last name. The last name has at least one word.
+	vonEnd := func(vonStart, lastEnd int) int {
+		end := lastEnd - 1
+		for end > vonStart && !isVonWord(words[end-1]) {
+			end--
 		}
-		val, action := parsePrefixName(idx, xs)
-		if action == resolveNextPart {
-			break
-		}
-		prefix.WriteString(val)
+		return end
 	}
-
-	last := strings.Builder{}
-	last.Grow(16)
-	for ; idx < len(xs); idx++ {
-		val, action := parseLastName(idx, xs)
-		if action == resolveNextPart {
-			break
+	var first, von, last, jr []*nameToken
+	n := len(words)
+	switch len(commas) {
+	case 0:
+		vonStart := 0
+		for vonStart < n-1 && !isVonWord(words[vonStart]) {
+			vonStart++
 		}
-		last.WriteString(val)
+		end := vonStart
+		if vonStart == n-1 {
+			// No von part: the last name is the last word and any words
+			// joined to it with hyphens.
+			for vonStart > 0 && words[vonStart].sep == '-' {
+				vonStart--
+			}
+			end = vonStart
+		} else {
+			end = vonEnd(vonStart, n)
+		}
+		first, von, last = words[:vonStart], words[vonStart:end], words[end:]
+	case 1:
+		end := vonEnd(0, commas[0])
+		von, last, first = words[:max(end, 0)], words[max(end, 0):commas[0]], words[commas[0]:]
+	case 2:
+		end := vonEnd(0, commas[0])
+		von, last = words[:max(end, 0)], words[max(end, 0):commas[0]]
+		jr, first = words[commas[0]:commas[1]], words[commas[1]:]
 	}
 
 	return &ast.Author{
-		First:  &ast.Text{Value: strings.TrimSpace(first.String())},
-		Prefix: &ast.Text{Value: strings.TrimSpace(prefix.String())},
-		Last:   &ast.Text{Value: strings.TrimSpace(last.String())},
-		Suffix: &ast.Text{Value: ""},
-	}
+		From:   toks[0].pos,
+		To:     toks[len(toks)-1].end(),
+		First:  namePart(first),
+		Prefix: namePart(von),
+		Last:   namePart(last),
+		Suffix: namePart(jr),
+	}, nil
 }
 
-// resolveAuthorN resolves an author entry that contains 1 or more commas.
-//
-//	1 comma:  last, first             => Author{first, "",  last, ""}
-//	1 comma:  von last, first         => Author(first, von, last, "")
-//	2 commas: last, suffix, first     => Author{first, "",  last, suffix}
-//	2 commas: von last, suffix, first => Author{first, von, last, suffix}
-func resolveAuthorN(xs []ast.Expr, commas []int) *ast.Author {
-	part1 := xs[:commas[0]]
-	idx1 := 0
-	prefix := strings.Builder{}
-	for ; idx1 < len(part1); idx1++ {
-		if idx1 == len(part1)-1 {
-			// If we're on the last part, it belongs to the last name.
-			break
-		}
-		val, action := parsePrefixName(idx1, part1)
-		if action == resolveNextPart {
-			break
+// namePart joins the words of a name part with the separators between them
+// into parsed text at depth 0.
+func namePart(words []*nameToken) *ast.ParsedText {
+	part := &ast.ParsedText{Delim: ast.BraceDelimiter}
+	for i, w := range words {
+		if i > 0 {
+			part.Values = append(part.Values, w.seps...)
 		}
-		prefix.WriteString(val)
+		part.Values = append(part.Values, w.values...)
+	}
+	if len(words) > 0 {
+		part.Opener = words[0].pos
+		part.Closer = words[len(words)-1].end()
 	}
+	return part
+}
 
-	last := strings.Builder{}
-	last.Grow(16)
-	for ; idx1 < len(part1); idx1++ {
-		val, action := parseLastName(idx1, part1)
-		if action == resolveNextPart {
-			break
+// isVonWord returns true if the first letter of the word at brace depth 0 is
+// lowercase.
+func isVonWord(w *nameToken) bool {
+	for _, x := range w.values {
+		if lower, ok := letterCase(x, true); ok {
+			return lower
 		}
-		last.WriteString(val)
 	}
+	return false
+}
 
-	part2 := xs[commas[0]+1:]
-
-	suffix := strings.Builder{}
-	suffix.Grow(16)
-	if len(commas) > 1 {
-		val := parseDefault(0, xs[commas[0]+1:])
-		suffix.WriteString(val)
-		part2 = xs[commas[1]+1:]
-	}
+// specialLetters are the control sequences of special characters that
+// bibtex treats as letters. The value is true for lowercase letters.
+var specialLetters = map[string]bool{
+	"i": true, "j": true, "oe": true, "OE": false, "ae": true, "AE": false,
+	"aa": true, "AA": false, "o": true, "O": false, "l": true, "L": false,
+	"ss": true,
+}
 
-	idx2 := 0
-	first := strings.Builder{}
-	first.Grow(16)
-	for ; idx2 < len(part2); idx2++ {
-		val, action := parseFirstName(idx2, part2)
-		if action == resolveNextPart {
-			break
+// letterCase returns whether the first letter of x is lowercase. Ok is false
+// if x has no letters. At depth 0, brace groups have no case unless they're
+// special characters, which start with a control sequence, like {\'E}.
+func letterCase(x ast.Expr, depth0 bool) (lower, ok bool) {
+	switch x := x.(type) {
+	case *ast.Text:
+		for _, r := range x.Value {
+			if unicode.IsUpper(r) {
+				return false, true
+			}
+			if unicode.IsLower(r) {
+				return true, true
+			}
+		}
+	case *ast.TextAccent:
+		return letterCase(x.Text, false)
+	case *ast.TextMacro:
+		if lower, ok := specialLetters[x.Name]; ok {
+			return lower, true
+		}
+		if depth0 {
+			// Like bibtex, a control sequence outside of braces has the
+			// case of its name, like \emph.
+			return letterCase(&ast.Text{Value: x.Name}, false)
+		}
+		for _, v := range x.Values {
+			if lower, ok := letterCase(v, false); ok {
+				return lower, true
+			}
+		}
+	case *ast.ParsedText:
+		if depth0 && !isSpecialChar(x) {
+			return false, false
+		}
+		for _, v := range x.Values {
+			if lower, ok := letterCase(v, false); ok {
+				return lower, true
+			}
 		}
-		first.WriteString(val)
-	}
-
-	return &ast.Author{
-		First:  &ast.Text{Value: strings.TrimSpace(first.String())},
-		Prefix: &ast.Text{Value: strings.TrimSpace(prefix.String())},
-		Last:   &ast.Text{Value: strings.TrimSpace(last.String())},
-		Suffix: &ast.Text{Value: strings.TrimSpace(suffix.String())},
 	}
+	return false, false
 }
 
-// findCommas returns the offsets of all commas in xs. Only searches
-// 1 level deep.
-func findCommas(xs []ast.Expr) []int {
-	idxs := make([]int, 0, 2)
-	for i, x := range xs {
-		if _, ok := x.(*ast.TextComma); ok {
-			idxs = append(idxs, i)
-		}
+// isSpecialChar returns true if the brace group starts with a control
+// sequence, like {\'E} or {\ss}.
+func isSpecialChar(x *ast.ParsedText) bool {
+	if x.Depth != 1 || len(x.Values) == 0 {
+		return false
+	}
+	switch x.Values[0].(type) {
+	case *ast.TextAccent, *ast.TextMacro, *ast.TextEscaped:
+		return true
 	}
-	return idxs
+	return false
 }
@@ -1,13 +1,43 @@
 package bibtex
 
 import (
+	"errors"
+	gotok "go/token"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jschaf/bibtex/ast"
 	"github.com/jschaf/bibtex/parser"
+	"github.com/jschaf/bibtex/printer"
 )
 
+// authorOpts ignores positions to compare rendered authors with newAuthor.
+var authorOpts = cmp.Options{
+	cmpopts.IgnoreFields(ast.Text{}, "ValuePos"),
+	cmpopts.IgnoreFields(ast.Author{}, "From", "To"),
+}
+
+// extractRendered extracts the authors of the text and renders each name
+// part into text.
+func extractRendered(t *testing.T, authors string) (ast.Authors, error) {
+	t.Helper()
+	x, err := parser.ParseExpr("{" + authors + "}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ExtractAuthors(x.(*ast.ParsedText))
+	if err != nil {
+		return nil, err
+	}
+	tag := &ast.TagStmt{Name: "author", Value: got}
+	if err := NewRenderParsedTextResolver().Resolve(tag); err != nil {
+		t.Fatal(err)
+	}
+	return got, nil
+}
+
 func TestResolveAuthors_single(t *testing.T) {
 	tests := []struct {
 		authors string
@@ -29,6 +59,7 @@ func TestResolveAuthors_single(t *testing.T) {
 		{"Fran{\\cc}oise Chollet", newAuthor("Françoise", "Chollet")},
 		{"Fran{\\c c}oise Chollet", newAuthor("Françoise", "Chollet")},
 		{"Fran{\\c    c}oise Chollet", newAuthor("Françoise", "Chollet")},
+		{"S{\\o}ren Kierkegaard", newAuthor("Søren", "Kierkegaard")},
 		{
 			"Charles Louis Xavier Joseph de la Vallee Poussin",
 			newAuthor("Charles Louis Xavier Joseph", "de la", "Vallee Poussin"),
@@ -36,12 +67,8 @@ func TestResolveAuthors_single(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.authors, func(t *testing.T) {
-			a, err := parser.ParseExpr("{" + tt.authors + "}")
-			if err != nil {
-				t.Fatal(err)
-			}
-			got, _ := ExtractAuthors(a.(*ast.ParsedText))
-			if diff := cmp.Diff(newAuthors(tt.want), got); diff != "" {
+			got, _ := extractRendered(t, tt.authors)
+			if diff := cmp.Diff(newAuthors(tt.want), got, authorOpts); diff != "" {
 				t.Errorf("ExtractAuthors() mismatch (-want +got):\n%s", diff)
 			}
 		})
@@ -61,16 +88,191 @@ func TestResolveAuthors_multiple(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.authors, func(t *testing.T) {
-			a, err := parser.ParseExpr("{" + tt.authors + "}")
-			if err != nil {
+			got, err := extractRendered(t, tt.authors)
+			if err != nil && !tt.wantErr {
 				t.Fatal(err)
+			} else if diff := cmp.Diff(tt.want, got, authorOpts); diff != "" {
+				t.Errorf("ExtractAuthors() mismatch (-want +got):\n%s", diff)
 			}
-			got, err := ExtractAuthors(a.(*ast.ParsedText))
-			if err != nil && !tt.wantErr {
+		})
+	}
+}
+
+// TestExtractAuthors_tameTheBeaST tests the name examples of "Tame the
+// BeaST", which match the output of bibtex and btparse.
+func TestExtractAuthors_tameTheBeaST(t *testing.T) {
+	tests := []struct {
+		authors string
+		want    ast.Authors
+	}{
+		{"jean de la fontaine", newAuthors(newAuthor("", "jean de la", "fontaine"))},
+		{"Jean de la fontaine", newAuthors(newAuthor("Jean", "de la", "fontaine"))},
+		{"Jean {de} la fontaine", newAuthors(newAuthor("Jean de", "la", "fontaine"))},
+		{"jean {de} {la} fontaine", newAuthors(newAuthor("", "jean", "de la fontaine"))},
+		{"Jean {de} {la} fontaine", newAuthors(newAuthor("Jean de la", "", "fontaine"))},
+		{"Jean De La Fontaine", newAuthors(newAuthor("Jean De La", "", "Fontaine"))},
+		{"jean De la Fontaine", newAuthors(newAuthor("", "jean De la", "Fontaine"))},
+		{"Jean de La Fontaine", newAuthors(newAuthor("Jean", "de", "La Fontaine"))},
+		{"de La Fontaine, Jean", newAuthors(newAuthor("Jean", "de", "La Fontaine"))},
+		{"De La Fontaine, Jean", newAuthors(newAuthor("Jean", "", "De La Fontaine"))},
+		{"De la Fontaine, Jean", newAuthors(newAuthor("Jean", "De la", "Fontaine"))},
+		{"de La Fontaine, Jr., Jean", newAuthors(newAuthor("Jean", "de", "La Fontaine", "Jr."))},
+		{"Ford, Jr., Henry", newAuthors(newAuthor("Henry", "", "Ford", "Jr."))},
+		{"Ford, Jr, Henry", newAuthors(newAuthor("Henry", "", "Ford", "Jr"))},
+		{
+			"de la Vall{\\'e}e Poussin, Charles Louis Xavier Joseph",
+			newAuthors(newAuthor("Charles Louis Xavier Joseph", "de la", "Vallée Poussin")),
+		},
+		// Hyphens separate words, and hyphenated words stay together in the
+		// last name.
+		{"Jean-Paul Sartre", newAuthors(newAuthor("Jean-Paul", "Sartre"))},
+		{"Sartre, Jean-Paul", newAuthors(newAuthor("Jean-Paul", "", "Sartre"))},
+		{"Louis-Albert Ngo-Ngo", newAuthors(newAuthor("Louis-Albert", "Ngo-Ngo"))},
+		{"Jean-paul Sartre", newAuthors(newAuthor("Jean", "paul", "Sartre"))},
+		// Ties separate words but don't join last names.
+		{"Per Brinch~Hansen", newAuthors(newAuthor("Per Brinch", "Hansen"))},
+		{"Brinch~Hansen, Per", newAuthors(newAuthor("Per", "", "Brinch Hansen"))},
+		// Brace groups have no case and are never split.
+		{"{Barnes and Noble, Inc.}", newAuthors(newAuthor("Barnes and Noble, Inc."))},
+		{"{Barnes and Noble, Inc.} and Anand", newAuthors(newAuthor("Barnes and Noble, Inc."), newAuthor("Anand"))},
+		{"Jan {van der} Berg", newAuthors(newAuthor("Jan van der", "Berg"))},
+		{"{van der} Berg, Jan", newAuthors(newAuthor("Jan", "", "van der Berg"))},
+		// Special characters have the case of their letter.
+		{"{\\'E}mile Zola", newAuthors(newAuthor("Émile", "Zola"))},
+		{"{\\'e}mile zola", newAuthors(newAuthor("", "émile", "zola"))},
+		{"Jean {\\'e}t{\\'e} Fontaine", newAuthors(newAuthor("Jean", "été", "Fontaine"))},
+		{"Jean {\\AE}ble Fontaine", newAuthors(newAuthor("Jean Æble", "Fontaine"))},
+		{"Jean {\\ae}ble Fontaine", newAuthors(newAuthor("Jean", "æble", "Fontaine"))},
+		// Macros don't panic.
+		{"\\textsc{Smith}, John", newAuthors(newAuthor("John", "", "Smith"))},
+		// The separator "and" is case-insensitive and needs whitespace.
+		{"Doe, Jane AND Roe, Rick", newAuthors(newAuthor("Jane", "Doe"), newAuthor("Rick", "Roe"))},
+		{"Sandy Andrews", newAuthors(newAuthor("Sandy", "Andrews"))},
+		{"Gaspard Monge and Amp{\\`e}re, Andr{\\'e}-Marie and others", newAuthors(
+			newAuthor("Gaspard", "Monge"),
+			newAuthor("André-Marie", "Ampère"),
+			newAuthor("others"),
+		)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.authors, func(t *testing.T) {
+			got, err := extractRendered(t, tt.authors)
+			if err != nil {
 				t.Fatal(err)
-			} else if diff := cmp.Diff(tt.want, got); diff != "" {
+			}
+			if diff := cmp.Diff(tt.want, got, authorOpts); diff != "" {
 				t.Errorf("ExtractAuthors() mismatch (-want +got):\n%s", diff)
 			}
 		})
 	}
 }
+
+func TestExtractAuthors_positions(t *testing.T) {
+	const authors = "Donald E. Knuth and de la Vall{\\'e}e~Poussin, Jr., {C}harles-Jean"
+	x, err := parser.ParseExpr("{" + authors + "}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := x.(*ast.ParsedText)
+	got, err := ExtractAuthors(txt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// slice returns the source text of a position range.
+	slice := func(from, to gotok.Pos) string {
+		off := int(from-txt.Opener) - 1
+		return authors[off : off+int(to-from)]
+	}
+	wantNames := []string{
+		"Donald E. Knuth",
+		"de la Vall{\\'e}e~Poussin, Jr., {C}harles-Jean",
+	}
+	var gotNames []string
+	for _, a := range got {
+		gotNames = append(gotNames, slice(a.From, a.To))
+	}
+	if diff := cmp.Diff(wantNames, gotNames); diff != "" {
+		t.Errorf("ExtractAuthors() name positions mismatch (-want +got):\n%s", diff)
+	}
+
+	// Each part keeps its expressions, so printing a part gives its source.
+	wantParts := [][4]string{
+		{"Donald E.", "", "Knuth", ""},
+		{"{C}harles-Jean", "de la", "Vall{\\'e}e~Poussin", "Jr."},
+	}
+	var gotParts [][4]string
+	for _, a := range got {
+		var parts [4]string
+		for i, part := range []ast.Expr{a.First, a.Prefix, a.Last, a.Suffix} {
+			p := part.(*ast.ParsedText)
+			if len(p.Values) > 0 {
+				parts[i] = slice(p.Opener, p.Closer)
+			}
+		}
+		gotParts = append(gotParts, parts)
+	}
+	if diff := cmp.Diff(wantParts, gotParts); diff != "" {
+		t.Errorf("ExtractAuthors() part positions mismatch (-want +got):\n%s", diff)
+	}
+
+	sb := &strings.Builder{}
+	if err := printer.Fprint(sb, nil, got); err != nil {
+		t.Fatal(err)
+	}
+	want := "{Knuth, Donald E. and de la Vall{\\'e}e~Poussin, Jr., {C}harles-Jean}"
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("printer.Fprint() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestExtractAuthors_errors(t *testing.T) {
+	tests := []struct {
+		authors string
+		wantMsg string
+		wantOff int // offset of the error in authors
+	}{
+		{"", "found an empty author", -1},
+		{"Doe and and Roe", "found an empty author", 4},
+		{"Doe, Jr., Jane, Extra", "too many commas in name", 14},
+		{"Roe and Doe, Jr., Jane, Extra", "too many commas in name", 22},
+	}
+	for _, tt := range tests {
+		t.Run(tt.authors, func(t *testing.T) {
+			x, err := parser.ParseExpr("{" + tt.authors + "}")
+			if err != nil {
+				t.Fatal(err)
+			}
+			txt := x.(*ast.ParsedText)
+			_, err = ExtractAuthors(txt)
+			var authErr *AuthorError
+			if !errors.As(err, &authErr) {
+				t.Fatalf("ExtractAuthors() got error %v; want *AuthorError", err)
+			}
+			if authErr.Msg != tt.wantMsg {
+				t.Errorf("ExtractAuthors() error message = %q; want %q", authErr.Msg, tt.wantMsg)
+			}
+			// The opening brace is one byte before the authors.
+			if got := int(authErr.Pos-txt.Opener) - 1; got != tt.wantOff {
+				t.Errorf("ExtractAuthors() error offset = %d; want %d", got, tt.wantOff)
+			}
+		})
+	}
+}
+
+func TestAuthorResolver_Resolve_errors(t *testing.T) {
+	src := "@book{key,\n  author = {Doe and and Roe},\n  editor = {A, B, C, D}}"
+	fset := gotok.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewAuthorResolver(FieldAuthor, FieldEditor).WithFileSet(fset).Resolve(f)
+	if err == nil {
+		t.Fatal("expected error but had none")
+	}
+	want := `2:17: author: found an empty author (and 1 more errors)`
+	if diff := cmp.Diff(want, err.Error()); diff != "" {
+		t.Errorf("AuthorResolver.Resolve() error mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -2,8 +2,10 @@ package bibtex
 
 import (
 	"fmt"
+	goscan "go/scanner"
 	gotok "go/token"
 	"io"
+	"sort"
 
 	"github.com/jschaf/bibtex/ast"
 	"github.com/jschaf/bibtex/parser"
@@ -64,13 +66,15 @@ const (
 	FieldSeries       Field = "series"
 	FieldTitle        Field = "title"
 	FieldType         Field = "type"
+	FieldURL          Field = "url"
 	FieldVolume       Field = "volume"
 	FieldYear         Field = "year"
 )
 
 // Biber contains methods for parsing, resolving, and rendering bibtex.
 type Biber struct {
-	usePresets bool
+	fset       *gotok.FileSet // positions of all parsed files
+	usePresets bool           // prepend the preset resolvers; see WithPresets
 	parserMode parser.Mode
 	resolvers  []Resolver
 	// Renderers for each node. The renderer for ast.Node n is contained at:
@@ -102,6 +106,22 @@ func WithResolvers(rs ...Resolver) Option {
 	}
 }
 
+// WithPresets prepends the preset resolvers to the list of resolvers. The
+// presets resolve entries into plain text and structured authors:
+//
+//  1. AbbrevResolver expands @string abbreviations and concatenations.
+//  2. AuthorResolver parses the author and editor tags into ast.Authors.
+//  3. SimplifyEscapedTextResolver replaces escaped text, like `\&`, with the
+//     escaped character.
+//  4. RenderParsedTextResolver replaces parsed text with the rendered text.
+//
+// Resolvers added with WithResolvers run after the presets.
+func WithPresets() Option {
+	return func(b *Biber) {
+		b.usePresets = true
+	}
+}
+
 // WithRenderer sets the renderer for the node kind, replacing the previous
 // renderer.
 func WithRenderer(kind ast.NodeKind, r render.NodeRendererFunc) Option {
@@ -112,64 +132,132 @@ func WithRenderer(kind ast.NodeKind, r render.NodeRendererFunc) Option {
 
 func New(opts ...Option) *Biber {
 	b := &Biber{
-		// TODO: add mode to constant propagate abbrevs and concat expressions
+		fset:       gotok.NewFileSet(),
 		parserMode: parser.ParseStrings,
 		renderers:  render.Defaults(),
 	}
 	for _, opt := range opts {
 		opt(b)
 	}
+	if b.usePresets {
+		b.resolvers = append(b.presetResolvers(), b.resolvers...)
+	}
 	return b
 }
 
+// presetResolvers returns the resolvers used by WithPresets.
+func (b *Biber) presetResolvers() []Resolver {
+	return []Resolver{
+		NewAbbrevResolver(b.fset),
+		NewAuthorResolver(FieldAuthor, FieldEditor).WithFileSet(b.fset),
+		ResolverFunc(SimplifyEscapedTextResolver),
+		NewRenderParsedTextResolver(),
+	}
+}
+
+// FileSet returns the file set for the positions of all files parsed by b.
+// Resolvers that report positioned errors, like AbbrevResolver, need it.
+func (b *Biber) FileSet() *gotok.FileSet {
+	return b.fset
+}
+
 func (b *Biber) Parse(r io.Reader) (*ast.File, error) {
-	f, err := parser.ParseFile(gotok.NewFileSet(), "", r, b.parserMode)
+	f, err := parser.ParseFile(b.fset, "", r, b.parserMode)
 	if err != nil {
 		return nil, err
 	}
 	return f, nil
 }
 
+// Read parses and resolves all entries from r using the preset resolvers.
+// The tag values of the entries are ast.Text nodes, except for the author and
+// editor tags, which are ast.Authors.
+func Read(r io.Reader) ([]Entry, error) {
+	b := New(WithPresets())
+	f, err := b.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return b.Resolve(f)
+}
+
+// ReadFile is like Read but reads the file named by filename. Errors contain
+// the filename.
+func ReadFile(filename string) ([]Entry, error) {
+	b := New(WithPresets())
+	f, err := parser.ParseFile(b.fset, filename, nil, b.parserMode)
+	if err != nil {
+		return nil, err
+	}
+	return b.Resolve(f)
+}
+
 // Resolve resolves all bibtex entries from an AST. The AST is a faithful
 // representation of source code. By default, resolving the AST means replacing
 // all abbreviation expressions with the value, inlining concatenation
 // expressions, simplifying tag values by replacing TeX quote macros with
 // Unicode graphemes, and stripping Tex macros.
 //
-// The exact resolve steps are configurable using bibtex.WithResolvers.
+// The exact resolve steps are configurable using bibtex.WithResolvers. Resolve
+// returns a scanner.ErrorList with an error for each entry without a key. The
+// entries of a package are in source order, with the files sorted by name.
 func (b *Biber) Resolve(node ast.Node) ([]Entry, error) {
 	for i, resolver := range b.resolvers {
 		if err := resolver.Resolve(node); err != nil {
 			return nil, fmt.Errorf("run resolvers[%d]: %w", i, err)
 		}
 	}
+	var decls []*ast.BibDecl
 	switch n := node.(type) {
 	case *ast.Package:
-		entries := make([]Entry, 0, len(n.Files)*4)
-		for _, file := range n.Files {
-			for _, decl := range file.Entries {
-				if decl, ok := decl.(*ast.BibDecl); ok {
-					entries = append(entries, b.resolveEntry(decl))
-				}
-			}
+		for _, name := range sortedFileNames(n) {
+			decls = appendBibDecls(decls, n.Files[name].Entries)
 		}
-		return entries, nil
 
 	case *ast.File:
-		entries := make([]Entry, 0, len(n.Entries))
-		for _, decl := range n.Entries {
-			if decl, ok := decl.(*ast.BibDecl); ok {
-				entries = append(entries, b.resolveEntry(decl))
-			}
-		}
-		return entries, nil
+		decls = appendBibDecls(decls, n.Entries)
 
 	case *ast.BibDecl:
-		return []Entry{b.resolveEntry(n)}, nil
+		decls = []*ast.BibDecl{n}
 
 	default:
 		return nil, fmt.Errorf("bibtex.Resolve - node %T cannot be resolved into entries", node)
 	}
+
+	// The parser accepts an entry without a key, like @misc{title = {Foo}},
+	// but an Entry needs one.
+	var errs goscan.ErrorList
+	entries := make([]Entry, 0, len(decls))
+	for _, decl := range decls {
+		if decl.Key == nil {
+			errs.Add(b.fset.Position(decl.Pos()), "entry has no key")
+			continue
+		}
+		entries = append(entries, b.resolveEntry(decl))
+	}
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// sortedFileNames returns the names of the files of pkg in sorted order.
+func sortedFileNames(pkg *ast.Package) []string {
+	names := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func appendBibDecls(decls []*ast.BibDecl, entries []ast.Decl) []*ast.BibDecl {
+	for _, decl := range entries {
+		if decl, ok := decl.(*ast.BibDecl); ok {
+			decls = append(decls, decl)
+		}
+	}
+	return decls
 }
 
 func (b *Biber) resolveEntry(decl *ast.BibDecl) Entry {
@@ -2,6 +2,8 @@ package bibtex
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
@@ -15,6 +17,7 @@ import (
 func TestNew_resolve(t *testing.T) {
 	cmpOpts := cmp.Options{
 		cmpopts.IgnoreFields(ast.Text{}, "ValuePos"),
+		cmpopts.IgnoreFields(ast.Author{}, "From", "To"),
 	}
 	tests := []struct {
 		name string
@@ -87,6 +90,17 @@ func TestNew_resolve(t *testing.T) {
 			src:  `@article{cite_key, url = "https://example.com/foo--bar/~baz/#" }`,
 			want: Entry{Type: EntryArticle, Key: "cite_key", Tags: map[Field]ast.Expr{"url": asts.Text("https://example.com/foo--bar/~baz/#")}},
 		},
+		{
+			name: "jabref comments",
+			src: `
+				% Encoding: UTF-8
+				@Comment{jabref-meta: databaseType:bibtex;}
+				@book{citekey, title={Foo} }
+				@Comment{jabref-meta: grouping:
+				0 AllEntriesGroup:;
+				}`,
+			want: Entry{Type: EntryBook, Key: "citekey", Tags: map[Field]ast.Expr{"title": asts.Text("Foo")}},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -115,6 +129,115 @@ func TestNew_resolve(t *testing.T) {
 	}
 }
 
+func TestNew_resolveKeepsComments(t *testing.T) {
+	src := "@comment{jabref-meta: databaseType:bibtex;}\n@book{key, title={Foo}}\n@comment rest of line\n"
+	bib := New(
+		WithResolvers(
+			NewAuthorResolver("author"),
+			ResolverFunc(SimplifyEscapedTextResolver),
+			NewRenderParsedTextResolver(),
+		))
+	file, err := bib.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bib.Resolve(file); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	err = ast.Walk(file, func(n ast.Node, isEntering bool) (ast.WalkStatus, error) {
+		if c, ok := n.(*ast.CommentDecl); ok && isEntering {
+			got = append(got, c.Text)
+		}
+		return ast.WalkContinue, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"jabref-meta: databaseType:bibtex;", "rest of line"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("comments mismatch (-want +got):\n%s", diff)
+	}
+	if err := bib.Render(&strings.Builder{}, file.Entries[0]); err != nil {
+		t.Errorf("render comment: %v", err)
+	}
+}
+
+func TestRead(t *testing.T) {
+	src := `
+		@string{acm = "ACM"}
+		@book{knuth84,
+			title = {The {TeX}book},
+			author = {Knuth, Donald E.},
+			editor = "Doe, Jane",
+			publisher = acm # { Press \& Co},
+			month = jan,
+		}`
+	entries, err := Read(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly 1 entry, got %d entries", len(entries))
+	}
+	entry := entries[0]
+	want := map[Field]string{
+		FieldTitle:     "The TeXbook",
+		FieldPublisher: "ACM Press & Co",
+		FieldMonth:     "January",
+	}
+	for field, wantVal := range want {
+		txt, ok := entry.Tags[field].(*ast.Text)
+		if !ok {
+			t.Errorf("tag %s: want *ast.Text; got %T", field, entry.Tags[field])
+			continue
+		}
+		if txt.Value != wantVal {
+			t.Errorf("tag %s: got %q; want %q", field, txt.Value, wantVal)
+		}
+	}
+	for _, field := range []Field{FieldAuthor, FieldEditor} {
+		authors, ok := entry.Tags[field].(ast.Authors)
+		if !ok || len(authors) != 1 {
+			t.Errorf("tag %s: want 1 ast.Authors; got %T", field, entry.Tags[field])
+		}
+	}
+}
+
+func TestRead_noKey(t *testing.T) {
+	src := "@misc{}\n@misc{key, title = {Foo}}\n@misc{title = {Bar}}"
+	_, err := Read(strings.NewReader(src))
+	want := "1:1: entry has no key (and 1 more errors)"
+	if err == nil || err.Error() != want {
+		t.Errorf("Read() error = %v; want %s", err, want)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "refs.bib")
+	if err := os.WriteFile(name, []byte("@misc{foo, title = {Foo}}\n@misc{bar, title = \"Bar\" # baz}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ReadFile(name)
+	if err == nil {
+		t.Fatal("expected error for undefined abbreviation")
+	}
+	if want := name + `:2:28: undefined abbreviation "baz"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("ReadFile() error = %v; want %s", err, want)
+	}
+
+	if err := os.WriteFile(name, []byte("@misc{foo, title = {Foo}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Title() != "Foo" {
+		t.Errorf("ReadFile() = %v; want entry with title Foo", entries)
+	}
+}
+
 func ExampleNew_renderToString() {
 	input := `
     @book{greub2012linear,
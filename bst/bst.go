@@ -0,0 +1,267 @@
+// Package bst interprets BibTeX style files, the .bst files used by the
+// bibtex program, to produce .bbl output.
+//
+// Parse reads a style into a Style. An Interpreter runs the commands of the
+// style, like READ, SORT and ITERATE, on the entries of a parsed bibtex
+// package:
+//
+//	style, err := bst.ParseFile("plain.bst")
+//	pkg, err := parser.ParsePackage(fset, []string{"refs.bib"}, 0)
+//	err = bst.NewInterpreter(style).Run(w, pkg, []string{"knuth1984"})
+//
+// The interpreter implements the stack machine and all built-in functions of
+// BibTeX 0.99, like format.name$, purify$ and change.case$.
+//
+// FormatName and FormatAuthor format names with format.name$ patterns
+// outside of a style:
+//
+//	s, err := bst.FormatAuthor(author, "{f.~}{vv~}{ll}{, jj}") // "D.~E. Knuth"
+package bst
+
+import (
+	gotok "go/token"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jschaf/bibtex/scanner"
+)
+
+// Style is a parsed BibTeX style file.
+type Style struct {
+	commands []*command
+}
+
+// command is a top-level command of a style, like FUNCTION {name} {body}.
+type command struct {
+	pos  gotok.Position
+	name string  // lowercase command name, like "function"
+	args []*item // the brace-delimited arguments
+}
+
+// itemKind is the kind of an item of a function body.
+type itemKind int
+
+const (
+	itemIdent  itemKind = iota // a function name, like add.period$
+	itemQuote                  // a quoted function name, like 'skip$
+	itemString                 // a string literal, like "and"
+	itemInt                    // an integer literal, like #1
+	itemBlock                  // a brace-delimited list of items
+)
+
+// item is a token or a brace-delimited block of a style.
+type item struct {
+	pos   gotok.Position
+	kind  itemKind
+	text  string // the name, string value or integer for non-blocks
+	items []*item
+}
+
+// argCounts is the number of brace-delimited arguments of each command.
+var argCounts = map[string]int{
+	"entry":    3,
+	"execute":  1,
+	"function": 2,
+	"integers": 1,
+	"iterate":  1,
+	"macro":    2,
+	"read":     0,
+	"reverse":  1,
+	"sort":     0,
+	"strings":  1,
+}
+
+// Parse parses a BibTeX style.
+func Parse(r io.Reader) (*Style, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return parse("", src)
+}
+
+// ParseFile parses the BibTeX style file filename.
+func ParseFile(filename string) (*Style, error) {
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parse(filename, src)
+}
+
+func parse(filename string, src []byte) (*Style, error) {
+	p := &styleParser{src: src, pos: gotok.Position{Filename: filename, Line: 1, Column: 1}}
+	s := &Style{}
+	for {
+		p.skipSpace()
+		if p.off >= len(p.src) {
+			break
+		}
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		s.commands = append(s.commands, cmd)
+	}
+	return s, nil
+}
+
+// styleParser parses the commands of a style.
+type styleParser struct {
+	src []byte
+	off int
+	pos gotok.Position
+}
+
+func (p *styleParser) errorf(pos gotok.Position, msg string) error {
+	return scanner.ErrorList{&scanner.Error{Pos: pos, Msg: msg}}
+}
+
+func (p *styleParser) peek() byte {
+	if p.off >= len(p.src) {
+		return 0
+	}
+	return p.src[p.off]
+}
+
+func (p *styleParser) advance() {
+	if p.src[p.off] == '\n' {
+		p.pos.Line++
+		p.pos.Column = 0
+	}
+	p.off++
+	p.pos.Offset = p.off
+	p.pos.Column++
+}
+
+// skipSpace skips whitespace and comments, which start with % and extend to
+// the end of the line.
+func (p *styleParser) skipSpace() {
+	for p.off < len(p.src) {
+		switch ch := p.peek(); {
+		case ch == '%':
+			for p.off < len(p.src) && p.peek() != '\n' {
+				p.advance()
+			}
+		case isSpace(ch):
+			p.advance()
+		default:
+			return
+		}
+	}
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f'
+}
+
+// isTokenEnd returns true if ch ends an identifier or integer.
+func isTokenEnd(ch byte) bool {
+	return isSpace(ch) || ch == '{' || ch == '}' || ch == '%' || ch == '"' || ch == '#' || ch == 0
+}
+
+func (p *styleParser) word() string {
+	start := p.off
+	for p.off < len(p.src) && !isTokenEnd(p.peek()) {
+		p.advance()
+	}
+	return string(p.src[start:p.off])
+}
+
+func (p *styleParser) parseCommand() (*command, error) {
+	pos := p.pos
+	name := strings.ToLower(p.word())
+	n, ok := argCounts[name]
+	if !ok {
+		if name == "" {
+			return nil, p.errorf(pos, "expected a command, got "+string(p.peek()))
+		}
+		return nil, p.errorf(pos, "unknown command "+name)
+	}
+	cmd := &command{pos: pos, name: name}
+	for i := 0; i < n; i++ {
+		p.skipSpace()
+		if p.peek() != '{' {
+			return nil, p.errorf(p.pos, "expected { for argument of "+strings.ToUpper(name))
+		}
+		block, err := p.parseBlock()
+		if err != nil {
+			return nil, err
+		}
+		cmd.args = append(cmd.args, block)
+	}
+	return cmd, nil
+}
+
+// parseBlock parses a brace-delimited block of items.
+func (p *styleParser) parseBlock() (*item, error) {
+	block := &item{pos: p.pos, kind: itemBlock}
+	p.advance() // {
+	for {
+		p.skipSpace()
+		pos := p.pos
+		switch ch := p.peek(); ch {
+		case 0:
+			return nil, p.errorf(block.pos, "unterminated {")
+		case '}':
+			p.advance()
+			return block, nil
+		case '{':
+			b, err := p.parseBlock()
+			if err != nil {
+				return nil, err
+			}
+			block.items = append(block.items, b)
+		case '"':
+			p.advance()
+			start := p.off
+			for p.off < len(p.src) && p.peek() != '"' {
+				if p.peek() == '\n' {
+					return nil, p.errorf(pos, "unterminated string")
+				}
+				p.advance()
+			}
+			if p.off >= len(p.src) {
+				return nil, p.errorf(pos, "unterminated string")
+			}
+			s := string(p.src[start:p.off])
+			p.advance()
+			block.items = append(block.items, &item{pos: pos, kind: itemString, text: s})
+		case '#':
+			p.advance()
+			s := p.word()
+			if !isInt(s) {
+				return nil, p.errorf(pos, "invalid integer #"+s)
+			}
+			block.items = append(block.items, &item{pos: pos, kind: itemInt, text: s})
+		case '\'':
+			p.advance()
+			s := p.word()
+			if s == "" {
+				return nil, p.errorf(pos, "expected a function name after '")
+			}
+			block.items = append(block.items, &item{pos: pos, kind: itemQuote, text: strings.ToLower(s)})
+		default:
+			s := p.word()
+			if s == "" {
+				return nil, p.errorf(pos, "unexpected "+string(ch))
+			}
+			block.items = append(block.items, &item{pos: pos, kind: itemIdent, text: strings.ToLower(s)})
+		}
+	}
+}
+
+// isInt returns true if s is a decimal integer with an optional sign.
+func isInt(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
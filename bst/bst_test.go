@@ -0,0 +1,459 @@
+package bst
+
+import (
+	gotok "go/token"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/asts"
+	"github.com/jschaf/bibtex/parser"
+)
+
+const testBib = `
+@string{cj = "The Computer Journal"}
+@preamble{"\newcommand{\noop}[1]{}"}
+@article{knuth84,
+  title = {Literate {Programming}},
+  author = {Donald E. Knuth},
+  journal = cj,
+  year = 1984,
+}
+@book{sartre,
+  title = {L'{\^E}tre et le n{\'e}ant},
+  author = {Sartre, Jean-Paul and de la Vall{\'e}e Poussin, Charles Louis Xavier Joseph and Ford, Jr., Henry},
+  year = 1943,
+}
+@inproceedings{paper,
+  title = {A Paper},
+  author = {Doe, Jane},
+  crossref = {proc},
+}
+@inproceedings{paper2,
+  title = {Another Paper},
+  author = {Roe, Rick},
+  crossref = {proc},
+}
+@proceedings{proc,
+  title = {Proceedings},
+  year = 2020,
+}
+`
+
+// testStyle is a small style in the manner of plain.bst.
+const testStyle = `
+% A small style for tests.
+ENTRY { author title journal year } { } { label }
+INTEGERS { nameptr namesleft numnames }
+STRINGS { s t }
+
+MACRO {jan} {"January"}
+
+FUNCTION {format.names}
+{ 's :=
+  #1 'nameptr :=
+  s num.names$ 'numnames :=
+  numnames 'namesleft :=
+  { namesleft #0 > }
+  { s nameptr "{ff~}{vv~}{ll}{, jj}" format.name$ 't :=
+    nameptr #1 >
+      { namesleft #1 >
+          { ", " * t * }
+          { " and " * t * }
+        if$
+      }
+       't
+    if$
+    nameptr #1 + 'nameptr :=
+    namesleft #1 - 'namesleft :=
+  }
+  while$
+}
+
+FUNCTION {output.entry}
+{ newline$
+  "\bibitem{" cite$ * "}" * write$ newline$
+  author empty$
+    { "" }
+    { author format.names add.period$ }
+  if$
+  write$ newline$
+  "\newblock " title "t" change.case$ * add.period$ write$
+  journal missing$
+    'skip$
+    { newline$ "\newblock {\em " journal * "}, " * year * "." * write$ }
+  if$
+  newline$
+}
+
+FUNCTION {article} { output.entry }
+FUNCTION {book} { output.entry }
+FUNCTION {inproceedings} { output.entry }
+FUNCTION {default.type} { output.entry }
+
+READ
+
+FUNCTION {presort}
+{ author empty$
+    { title }
+    { author #1 "{vv{ } }{ll{ }}{ f{ }}{ jj{ }}" format.name$ }
+  if$
+  purify$ "l" change.case$ 'sort.key$ :=
+}
+
+ITERATE {presort}
+SORT
+
+FUNCTION {begin.bib}
+{ preamble$ empty$
+    'skip$
+    { preamble$ write$ newline$ }
+  if$
+  "\begin{thebibliography}{9}" write$ newline$
+}
+
+EXECUTE {begin.bib}
+ITERATE {call.type$}
+
+FUNCTION {end.bib}
+{ newline$
+  "\end{thebibliography}" write$ newline$
+}
+
+EXECUTE {end.bib}
+`
+
+func TestInterpreter_Run(t *testing.T) {
+	style, err := Parse(strings.NewReader(testStyle))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"test.bib": {Data: []byte(testBib)}}
+	pkg, err := parser.ParsePackageFS(gotok.NewFileSet(), fsys, []string{"test.bib"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := &strings.Builder{}
+	sb := &strings.Builder{}
+	in := NewInterpreter(style, WithLog(log))
+	if err := in.Run(sb, pkg, []string{"knuth84", "sartre", "paper", "paper2", "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `\newcommand{\noop}[1]{}
+\begin{thebibliography}{9}
+
+\bibitem{paper}
+Jane Doe.
+\newblock A paper.
+
+\bibitem{knuth84}
+Donald~E. Knuth.
+\newblock Literate {Programming}.
+\newblock {\em The Computer Journal}, 1984.
+
+\bibitem{proc}
+
+\newblock Proceedings.
+
+\bibitem{paper2}
+Rick Roe.
+\newblock Another paper.
+
+\bibitem{sartre}
+Jean-Paul Sartre, Charles Louis Xavier~Joseph de~la Vall{\'e}e~Poussin and
+  Henry Ford, Jr.
+\newblock L'{\^e}tre et le n{\'e}ant.
+
+\end{thebibliography}
+`
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("Run() mismatch (-want +got):\n%s", diff)
+	}
+	wantLog := "Warning--I didn't find a database entry for \"missing\"\n"
+	if diff := cmp.Diff(wantLog, log.String()); diff != "" {
+		t.Errorf("Run() log mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestInterpreter_Run_errors(t *testing.T) {
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"EXECUTE {nope}", "bst: 1:1: unknown function nope"},
+		{"FUNCTION {f} { g }", "bst: 1:16: unknown function g"},
+		{"FUNCTION {f} { pop$ } EXECUTE {f}", "bst: 1:16: pop$: pop from an empty stack"},
+		{"FUNCTION {f} { #1 \"a\" + } EXECUTE {f}", "bst: 1:23: +: \"a\" is not an integer"},
+		{"INTEGERS {x} STRINGS {x}", "bst: 1:23: x is already defined"},
+		{"ITERATE {skip$}", "bst: 1:1: ITERATE must come after READ"},
+		{"FUNCTION {g} { pop$ } FUNCTION {f} { g } EXECUTE {f}", "bst: 1:16: pop$: pop from an empty stack"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			style, err := Parse(strings.NewReader(tt.style))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = NewInterpreter(style).Run(&strings.Builder{}, nil, nil)
+			if err == nil {
+				t.Fatalf("Run() got nil error, want %q", tt.want)
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("Run() error mismatch:\n got: %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_errors(t *testing.T) {
+	tests := []struct {
+		style string
+		want  string
+	}{
+		{"FOO {bar}", "1:1: unknown command foo"},
+		{"FUNCTION {f}", "1:13: expected { for argument of FUNCTION"},
+		{"FUNCTION {f} {\n  \"abc\n}", "2:3: unterminated string"},
+		{"EXECUTE {f", "1:9: unterminated {"},
+		{"FUNCTION {f} { #x }", "1:16: invalid integer #x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.style))
+			if err == nil {
+				t.Fatalf("Parse() got nil error, want %q", tt.want)
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("Parse() error mismatch:\n got: %s\nwant: %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatName(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{"Donald E. Knuth", "{ff~}{vv~}{ll}{, jj}", "Donald~E. Knuth"},
+		{"Donald E. Knuth", "{f.~}{vv~}{ll}{, jj}", "D.~E. Knuth"},
+		{"Knuth, Donald Ervin", "{vv~}{ll}{, f.}", "Knuth, D.~E."},
+		{"Jean-Paul Sartre", "{f.~}{ll}", "J.-P. Sartre"},
+		{"Sartre, Jean-Paul", "{ll}{, ff}", "Sartre, Jean-Paul"},
+		{"Ludwig van Beethoven", "{ff~}{vv~}{ll}", "Ludwig van Beethoven"},
+		{"Charles de Gaulle", "{ff~}{vv~}{ll}", "Charles de~Gaulle"},
+		{"de la Vallée Poussin, Charles", "{vv}|{ll}|{ff}", "de~la|Vallée~Poussin|Charles"},
+		{"Ford, Jr., Henry", "{ff~}{ll}{, jj}", "Henry Ford, Jr."},
+		{"{\\\"O}zt{\\\"u}rk, {\\O}ystein", "{f.~}{ll}", "{\\O}.~{\\\"O}zt{\\\"u}rk"},
+		{"{Barnes and Noble, Inc.}", "{ll}", "{Barnes and Noble, Inc.}"},
+		{"John Paul Jones", "{f{}}{ll}", "JPJones"},
+		{"John Paul Jones", "{f{-}}", "J-P"},
+		{"Brinch Hansen, Per", "{ll}", "Brinch~Hansen"},
+		{"Alfred North Whitehead Smith", "{ff}", "Alfred North~Whitehead"},
+		{"Knuth", "{ff~}{ll}", "Knuth"},
+		{"Louis-Albert {\\relax Ch}ristophe", "{f.~}{ll}", "L.-A. {\\relax Ch}ristophe"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name+" "+tt.format, func(t *testing.T) {
+			got, err := FormatName(tt.name, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("FormatName(%q, %q) = %q; want %q", tt.name, tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatAuthor(t *testing.T) {
+	tests := []struct {
+		author *ast.Author
+		format string
+		want   string
+	}{
+		{
+			&ast.Author{First: asts.Text("Donald E."), Prefix: asts.Text(""), Last: asts.Text("Knuth"), Suffix: asts.Text("")},
+			"{ff~}{vv~}{ll}{, jj}",
+			"Donald~E. Knuth",
+		},
+		{
+			&ast.Author{First: asts.Text("Jean-Paul"), Last: asts.Text("Sartre")},
+			"{f.~}{vv~}{ll}",
+			"J.-P. Sartre",
+		},
+		{
+			&ast.Author{First: asts.Text("Ludwig"), Prefix: asts.Text("van"), Last: asts.Text("Beethoven")},
+			"{vv~}{ll}{, f.}",
+			"van Beethoven, L.",
+		},
+		{
+			// The last name isn't split into von and last parts again.
+			&ast.Author{First: asts.Text("Per"), Last: asts.Text("Brinch Hansen")},
+			"{ll}, {f.}",
+			"Brinch~Hansen, P.",
+		},
+		{
+			&ast.Author{First: asts.Text("Henry"), Last: asts.Text("Ford"), Suffix: asts.Text("Jr.")},
+			"{f.~}{ll}{, jj}",
+			"H.~Ford, Jr.",
+		},
+		{
+			&ast.Author{
+				First: asts.BraceText(0, asts.BraceText(1, asts.Macro(`\"`, "O")), "rjan"),
+				Last:  asts.Text("Smith"),
+			},
+			"{f.~}{ll}",
+			`{\"{O}}.~Smith`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			got, err := FormatAuthor(tt.author, tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("FormatAuthor(%q) = %q; want %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitNames(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+	}{
+		{"", nil},
+		{"Knuth", []string{"Knuth"}},
+		{"Doe, Jane and Roe, Rick AND Poe", []string{"Doe, Jane", "Roe, Rick", "Poe"}},
+		{"{Barnes and Noble} and Anand", []string{"{Barnes and Noble}", "Anand"}},
+		{"Sandy Andrews", []string{"Sandy Andrews"}},
+	}
+	for _, tt := range tests {
+		got := splitNames(tt.s)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("splitNames(%q) mismatch (-want +got):\n%s", tt.s, diff)
+		}
+	}
+}
+
+func TestChangeCase(t *testing.T) {
+	tests := []struct {
+		s    string
+		mode byte
+		want string
+	}{
+		{"The {TeX} Book: A Guide", 't', "The {TeX} book: A guide"},
+		{"The {TeX} Book: A Guide", 'l', "the {TeX} book: a guide"},
+		{"The {TeX} Book: A Guide", 'u', "THE {TeX} BOOK: A GUIDE"},
+		{"{\\OE}uvres {\\'E}crites", 'l', "{\\oe}uvres {\\'e}crites"},
+		{"{\\ss} and {\\ae}", 'u', "{SS} AND {\\AE}"},
+		{"{\\'E}tudes", 't', "{\\'E}tudes"},
+		{"Un {\\'E}t{\\'E}", 't', "Un {\\'e}t{\\'e}"},
+	}
+	for _, tt := range tests {
+		if got := changeCase(tt.s, tt.mode); got != tt.want {
+			t.Errorf("changeCase(%q, %q) = %q; want %q", tt.s, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestPurify(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"Knuth, Donald E.", "Knuth Donald E"},
+		{"Jean-Paul~Sartre", "Jean Paul Sartre"},
+		{"{\\\"O}zt{\\\"u}rk", "Ozturk"},
+		{"{\\ss}e {\\aa}ngstr{\\\"o}m", "sse angstrom"},
+		{"{The {TeX}book}", "The TeXbook"},
+	}
+	for _, tt := range tests {
+		if got := purify(tt.s); got != tt.want {
+			t.Errorf("purify(%q) = %q; want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTextPrefix(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+		len  int
+	}{
+		{"Knuth", 3, "Knu", 5},
+		{"{\\\"O}zt{\\\"u}rk", 3, "{\\\"O}zt", 6},
+		{"{Barnes and Noble}", 6, "{Barnes}", 16},
+		{"ab", 5, "ab", 2},
+	}
+	for _, tt := range tests {
+		if got := textPrefix(tt.s, tt.n); got != tt.want {
+			t.Errorf("textPrefix(%q, %d) = %q; want %q", tt.s, tt.n, got, tt.want)
+		}
+		if got := textLength(tt.s); got != tt.len {
+			t.Errorf("textLength(%q) = %d; want %d", tt.s, got, tt.len)
+		}
+	}
+}
+
+func TestSubstring(t *testing.T) {
+	tests := []struct {
+		s             string
+		start, length int
+		want          string
+	}{
+		{"Knuth", 1, 3, "Knu"},
+		{"Knuth", 3, 10, "uth"},
+		{"Knuth", -1, 2, "th"},
+		{"Knuth", -2, 10, "Knut"},
+		{"Knuth", 0, 1, ""},
+		{"Knuth", 6, 1, ""},
+	}
+	for _, tt := range tests {
+		if got := substring(tt.s, tt.start, tt.length); got != tt.want {
+			t.Errorf("substring(%q, %d, %d) = %q; want %q", tt.s, tt.start, tt.length, got, tt.want)
+		}
+	}
+}
+
+func TestAddPeriod(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"", ""},
+		{"Knuth", "Knuth."},
+		{"Knuth.", "Knuth."},
+		{"{Why?}", "{Why?}"},
+		{"{\\em Title}", "{\\em Title}."},
+	}
+	for _, tt := range tests {
+		if got := addPeriod(tt.s); got != tt.want {
+			t.Errorf("addPeriod(%q) = %q; want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"Knuth", 778 + 556 + 556 + 389 + 556},
+		{"{\\ss}", 500},
+		{"{\\\"o}", 500},
+		{"{\\AE}", 903},
+	}
+	for _, tt := range tests {
+		if got := width(tt.s); got != tt.want {
+			t.Errorf("width(%q) = %d; want %d", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,532 @@
+package bst
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// builtins are the built-in functions of BibTeX 0.99 by name.
+var builtins = map[string]func(m *machine) error{
+	">":            compareInts(func(a, b int) bool { return a > b }),
+	"<":            compareInts(func(a, b int) bool { return a < b }),
+	"=":            builtinEquals,
+	"+":            arithmetic(func(a, b int) int { return a + b }),
+	"-":            arithmetic(func(a, b int) int { return a - b }),
+	"*":            builtinConcat,
+	":=":           builtinAssign,
+	"add.period$":  builtinAddPeriod,
+	"call.type$":   builtinCallType,
+	"change.case$": builtinChangeCase,
+	"chr.to.int$":  builtinChrToInt,
+	"cite$":        builtinCite,
+	"duplicate$":   builtinDuplicate,
+	"empty$":       builtinEmpty,
+	"format.name$": builtinFormatName,
+	"if$":          builtinIf,
+	"int.to.chr$":  builtinIntToChr,
+	"int.to.str$":  builtinIntToStr,
+	"missing$":     builtinMissing,
+	"newline$":     builtinNewline,
+	"num.names$":   builtinNumNames,
+	"pop$":         builtinPop,
+	"preamble$":    builtinPreamble,
+	"purify$":      stringFunc(purify),
+	"quote$":       func(m *machine) error { m.push(`"`); return nil },
+	"skip$":        func(m *machine) error { return nil },
+	"stack$":       builtinStack,
+	"substring$":   builtinSubstring,
+	"swap$":        builtinSwap,
+	"text.length$": builtinTextLength,
+	"text.prefix$": builtinTextPrefix,
+	"top$":         builtinTop,
+	"type$":        builtinType,
+	"warning$":     builtinWarning,
+	"while$":       builtinWhile,
+	"width$":       builtinWidth,
+	"write$":       builtinWrite,
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// compareInts pops two integers and pushes 1 if cmp(second, top) is true and
+// 0 otherwise.
+func compareInts(cmp func(a, b int) bool) func(m *machine) error {
+	return func(m *machine) error {
+		b, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		a, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		m.push(boolInt(cmp(a, b)))
+		return nil
+	}
+}
+
+func arithmetic(op func(a, b int) int) func(m *machine) error {
+	return func(m *machine) error {
+		b, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		a, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		m.push(op(a, b))
+		return nil
+	}
+}
+
+// stringFunc pops a string and pushes fn of it.
+func stringFunc(fn func(s string) string) func(m *machine) error {
+	return func(m *machine) error {
+		s, err := m.popString()
+		if err != nil {
+			return err
+		}
+		m.push(fn(s))
+		return nil
+	}
+}
+
+func builtinEquals(m *machine) error {
+	b, err := m.pop()
+	if err != nil {
+		return err
+	}
+	a, err := m.pop()
+	if err != nil {
+		return err
+	}
+	switch a := a.(type) {
+	case int:
+		if b, ok := b.(int); ok {
+			m.push(boolInt(a == b))
+			return nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			m.push(boolInt(a == b))
+			return nil
+		}
+	}
+	return fmt.Errorf("can't compare %s and %s", describe(a), describe(b))
+}
+
+func builtinConcat(m *machine) error {
+	b, err := m.popString()
+	if err != nil {
+		return err
+	}
+	a, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.push(a + b)
+	return nil
+}
+
+func builtinAssign(m *machine) error {
+	s, err := m.popFunc()
+	if err != nil {
+		return err
+	}
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	switch s.kind {
+	case symGlobalInt, symEntryInt:
+		n, ok := v.(int)
+		if !ok {
+			return fmt.Errorf("can't assign %s to the integer %s", describe(v), s.name)
+		}
+		if s.kind == symGlobalInt {
+			s.intVal = n
+		} else if m.cur == nil {
+			return fmt.Errorf("entry variable %s assigned outside of an entry", s.name)
+		} else {
+			m.cur.ints[s.index] = n
+		}
+	case symGlobalStr, symEntryStr:
+		str, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("can't assign %s to the string %s", describe(v), s.name)
+		}
+		if s.kind == symGlobalStr {
+			s.strVal = str
+		} else if m.cur == nil {
+			return fmt.Errorf("entry variable %s assigned outside of an entry", s.name)
+		} else {
+			m.cur.strs[s.index] = str
+		}
+	default:
+		return fmt.Errorf("can't assign to %s", s.name)
+	}
+	return nil
+}
+
+func builtinAddPeriod(m *machine) error {
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.push(addPeriod(s))
+	return nil
+}
+
+// addPeriod adds a period to s unless s is empty or its last non-brace
+// character is a period, question mark or exclamation mark.
+func addPeriod(s string) string {
+	t := strings.TrimRight(s, "}")
+	if t == "" || strings.ContainsAny(t[len(t)-1:], ".?!") {
+		return s
+	}
+	return s + "."
+}
+
+func builtinCallType(m *machine) error {
+	if m.cur == nil {
+		return fmt.Errorf("used outside of an entry")
+	}
+	if s, ok := m.syms[m.cur.typ]; ok && s.kind == symFunction {
+		return m.call(s)
+	}
+	s, ok := m.syms["default.type"]
+	if !ok {
+		m.warn("no function for entry type %s of %s", m.cur.typ, m.cur.key)
+		return nil
+	}
+	return m.call(s)
+}
+
+func builtinChangeCase(m *machine) error {
+	spec, err := m.popString()
+	if err != nil {
+		return err
+	}
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	switch spec {
+	case "t", "T", "l", "L", "u", "U":
+		m.push(changeCase(s, spec[0]|0x20))
+	default:
+		m.warn("%q is an illegal case-conversion string", spec)
+		m.push(s)
+	}
+	return nil
+}
+
+func builtinChrToInt(m *machine) error {
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	if len(s) != 1 {
+		return fmt.Errorf("%q isn't a single character", s)
+	}
+	m.push(int(s[0]))
+	return nil
+}
+
+func builtinCite(m *machine) error {
+	if m.cur == nil {
+		return fmt.Errorf("used outside of an entry")
+	}
+	m.push(m.cur.key)
+	return nil
+}
+
+func builtinDuplicate(m *machine) error {
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	m.push(v)
+	m.push(v)
+	return nil
+}
+
+func builtinEmpty(m *machine) error {
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case missing:
+		m.push(1)
+	case string:
+		m.push(boolInt(strings.TrimSpace(v) == ""))
+	default:
+		return fmt.Errorf("%s is not a string", describe(v))
+	}
+	return nil
+}
+
+func builtinFormatName(m *machine) error {
+	format, err := m.popString()
+	if err != nil {
+		return err
+	}
+	n, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	names, err := m.popString()
+	if err != nil {
+		return err
+	}
+	list := splitNames(names)
+	if n < 1 || n > len(list) {
+		m.warn("there aren't %d names in %q", n, names)
+		m.push("")
+		return nil
+	}
+	s, err := FormatName(list[n-1], format)
+	if err != nil {
+		return err
+	}
+	m.push(s)
+	return nil
+}
+
+func builtinIf(m *machine) error {
+	elseFn, err := m.popFunc()
+	if err != nil {
+		return err
+	}
+	thenFn, err := m.popFunc()
+	if err != nil {
+		return err
+	}
+	cond, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	if cond > 0 {
+		return m.call(thenFn)
+	}
+	return m.call(elseFn)
+}
+
+func builtinIntToChr(m *machine) error {
+	n, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	if n < 0 || n > 127 {
+		return fmt.Errorf("%d isn't a valid ASCII code", n)
+	}
+	m.push(string(rune(n)))
+	return nil
+}
+
+func builtinIntToStr(m *machine) error {
+	n, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	m.push(strconv.Itoa(n))
+	return nil
+}
+
+func builtinMissing(m *machine) error {
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	_, ok := v.(missing)
+	m.push(boolInt(ok))
+	return nil
+}
+
+func builtinNewline(m *machine) error {
+	m.out.newline()
+	return nil
+}
+
+func builtinNumNames(m *machine) error {
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.push(len(splitNames(s)))
+	return nil
+}
+
+func builtinPop(m *machine) error {
+	_, err := m.pop()
+	return err
+}
+
+func builtinPreamble(m *machine) error {
+	m.push(m.preamble)
+	return nil
+}
+
+func builtinStack(m *machine) error {
+	for len(m.stack) > 0 {
+		if err := builtinTop(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// builtinSubstring pops a length, a start and a string and pushes the
+// substring. A negative start counts from the end of the string, and the
+// substring ends at the start.
+func builtinSubstring(m *machine) error {
+	length, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	start, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.push(substring(s, start, length))
+	return nil
+}
+
+func substring(s string, start, length int) string {
+	n := len(s)
+	if length <= 0 || start == 0 || start > n || start < -n {
+		return ""
+	}
+	if start > 0 {
+		length = min(length, n-start+1)
+		return s[start-1 : start-1+length]
+	}
+	end := n + start + 1
+	length = min(length, end)
+	return s[end-length : end]
+}
+
+func builtinSwap(m *machine) error {
+	b, err := m.pop()
+	if err != nil {
+		return err
+	}
+	a, err := m.pop()
+	if err != nil {
+		return err
+	}
+	m.push(b)
+	m.push(a)
+	return nil
+}
+
+func builtinTextLength(m *machine) error {
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.push(textLength(s))
+	return nil
+}
+
+func builtinTextPrefix(m *machine) error {
+	n, err := m.popInt()
+	if err != nil {
+		return err
+	}
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.push(textPrefix(s, n))
+	return nil
+}
+
+func builtinTop(m *machine) error {
+	v, err := m.pop()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(m.in.log, describe(v))
+	return err
+}
+
+// builtinType pushes the type of the entry, or the empty string if the style
+// has no function for the type.
+func builtinType(m *machine) error {
+	if m.cur == nil {
+		m.push("")
+		return nil
+	}
+	if s, ok := m.syms[m.cur.typ]; ok && s.kind == symFunction {
+		m.push(m.cur.typ)
+	} else {
+		m.push("")
+	}
+	return nil
+}
+
+func builtinWarning(m *machine) error {
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.warn("%s", s)
+	return nil
+}
+
+func builtinWhile(m *machine) error {
+	body, err := m.popFunc()
+	if err != nil {
+		return err
+	}
+	cond, err := m.popFunc()
+	if err != nil {
+		return err
+	}
+	for {
+		if err := m.call(cond); err != nil {
+			return err
+		}
+		ok, err := m.popInt()
+		if err != nil {
+			return err
+		}
+		if ok <= 0 {
+			return nil
+		}
+		if err := m.call(body); err != nil {
+			return err
+		}
+	}
+}
+
+func builtinWidth(m *machine) error {
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.push(width(s))
+	return nil
+}
+
+func builtinWrite(m *machine) error {
+	s, err := m.popString()
+	if err != nil {
+		return err
+	}
+	m.out.write(s)
+	return nil
+}
@@ -0,0 +1,684 @@
+package bst
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	gotok "go/token"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/printer"
+)
+
+// Interpreter runs the commands of a style.
+type Interpreter struct {
+	style *Style
+	log   io.Writer
+}
+
+// Option configures an Interpreter.
+type Option func(in *Interpreter)
+
+// WithLog writes warnings, from warning$ and problems with the database, and
+// the output of top$ and stack$ to w, like the .blg file of bibtex. By
+// default, the interpreter discards them.
+func WithLog(w io.Writer) Option {
+	return func(in *Interpreter) {
+		in.log = w
+	}
+}
+
+// NewInterpreter creates an interpreter for the style.
+func NewInterpreter(style *Style, opts ...Option) *Interpreter {
+	in := &Interpreter{style: style, log: io.Discard}
+	for _, opt := range opts {
+		opt(in)
+	}
+	return in
+}
+
+// Run runs the style for the entries of pkg cited by keys and writes the
+// .bbl output to w. The key "*" cites all entries of pkg, like \nocite{*}.
+func (in *Interpreter) Run(w io.Writer, pkg *ast.Package, keys []string) error {
+	bw := bufio.NewWriter(w)
+	m := newMachine(in, bw, pkg, keys)
+	for _, cmd := range in.style.commands {
+		if err := m.exec(cmd); err != nil {
+			return fmt.Errorf("bst: %w", atPos(cmd.pos, err))
+		}
+	}
+	m.out.flush()
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("bst: write output: %w", err)
+	}
+	return nil
+}
+
+// posError is an error at a position in the style.
+type posError struct {
+	pos gotok.Position
+	err error
+}
+
+func (e *posError) Error() string { return e.pos.String() + ": " + e.err.Error() }
+func (e *posError) Unwrap() error { return e.err }
+
+// atPos returns err with the position pos, unless err already has a more
+// precise position, like the position of a function call in the body of the
+// function that a command runs.
+func atPos(pos gotok.Position, err error) error {
+	var pe *posError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return &posError{pos: pos, err: err}
+}
+
+// symKind is the kind of a name in the symbol table.
+type symKind int
+
+const (
+	symBuiltin   symKind = iota // a built-in function, like write$
+	symFunction                 // a function defined with FUNCTION
+	symField                    // an entry field declared with ENTRY
+	symEntryInt                 // an integer entry variable
+	symEntryStr                 // a string entry variable
+	symGlobalInt                // an integer global variable
+	symGlobalStr                // a string global variable
+	symMacro                    // a macro defined with MACRO
+)
+
+// symbol is a named function or variable.
+type symbol struct {
+	name    string
+	kind    symKind
+	builtin func(m *machine) error
+	body    []*item // for symFunction
+	index   int     // for fields and entry variables, the index in the entry
+	intVal  int     // for symGlobalInt
+	strVal  string  // for symGlobalStr and symMacro
+}
+
+// missing is the value of a field that's missing from the entry.
+type missing struct{}
+
+// entry is a cited entry with its fields and entry variables.
+type entry struct {
+	key    string // the cite key as cited
+	typ    string // the lowercase entry type
+	decl   *ast.BibDecl
+	fields []any // string or missing for each declared field
+	ints   []int
+	strs   []string
+}
+
+// machine is the state of a single run of a style.
+type machine struct {
+	in    *Interpreter
+	pkg   *ast.Package
+	keys  []string
+	out   *outBuffer
+	syms  map[string]*symbol
+	stack []any // int, string, missing or *symbol
+
+	fields, entryInts, entryStrs int // number of each entry variable
+
+	decls    map[string]*ast.BibDecl // by lowercase key
+	order    []*ast.BibDecl          // in source order
+	abbrevs  map[string]ast.Expr     // @string abbreviations by lowercase name
+	preamble string
+	entries  []*entry
+	cur      *entry // the entry of ITERATE or REVERSE, or nil
+	read     bool   // READ has run
+	warnings int
+}
+
+func newMachine(in *Interpreter, w io.Writer, pkg *ast.Package, keys []string) *machine {
+	m := &machine{
+		in:      in,
+		pkg:     pkg,
+		keys:    keys,
+		out:     &outBuffer{w: w},
+		syms:    make(map[string]*symbol),
+		decls:   make(map[string]*ast.BibDecl),
+		abbrevs: make(map[string]ast.Expr),
+	}
+	for name, fn := range builtins {
+		m.syms[name] = &symbol{name: name, kind: symBuiltin, builtin: fn}
+	}
+	m.syms["entry.max$"] = &symbol{name: "entry.max$", kind: symGlobalInt, intVal: 250}
+	m.syms["global.max$"] = &symbol{name: "global.max$", kind: symGlobalInt, intVal: 5000}
+	m.syms["crossref"] = &symbol{name: "crossref", kind: symField, index: m.fields}
+	m.fields++
+	m.syms["sort.key$"] = &symbol{name: "sort.key$", kind: symEntryStr, index: m.entryStrs}
+	m.entryStrs++
+	return m
+}
+
+func (m *machine) warn(format string, args ...any) {
+	m.warnings++
+	_, _ = fmt.Fprintf(m.in.log, "Warning--"+format+"\n", args...)
+}
+
+// define adds a new symbol. Names can't be redefined.
+func (m *machine) define(it *item, kind symKind) (*symbol, error) {
+	if it.kind != itemIdent {
+		return nil, atPos(it.pos, errors.New("expected a name"))
+	}
+	if _, ok := m.syms[it.text]; ok {
+		return nil, atPos(it.pos, fmt.Errorf("%s is already defined", it.text))
+	}
+	s := &symbol{name: it.text, kind: kind}
+	m.syms[it.text] = s
+	return s, nil
+}
+
+// exec runs a top-level command.
+func (m *machine) exec(cmd *command) error {
+	switch cmd.name {
+	case "entry":
+		if m.read {
+			return fmt.Errorf("ENTRY must come before READ")
+		}
+		for i, kind := range []symKind{symField, symEntryInt, symEntryStr} {
+			for _, it := range cmd.args[i].items {
+				s, err := m.define(it, kind)
+				if err != nil {
+					return err
+				}
+				switch kind {
+				case symField:
+					s.index = m.fields
+					m.fields++
+				case symEntryInt:
+					s.index = m.entryInts
+					m.entryInts++
+				case symEntryStr:
+					s.index = m.entryStrs
+					m.entryStrs++
+				}
+			}
+		}
+	case "integers", "strings":
+		kind := symGlobalInt
+		if cmd.name == "strings" {
+			kind = symGlobalStr
+		}
+		for _, it := range cmd.args[0].items {
+			if _, err := m.define(it, kind); err != nil {
+				return err
+			}
+		}
+	case "function":
+		name := cmd.args[0].items
+		if len(name) != 1 {
+			return fmt.Errorf("FUNCTION needs exactly one name")
+		}
+		s, err := m.define(name[0], symFunction)
+		if err != nil {
+			return err
+		}
+		if err := m.check(cmd.args[1]); err != nil {
+			return err
+		}
+		s.body = cmd.args[1].items
+	case "macro":
+		name, value := cmd.args[0].items, cmd.args[1].items
+		if len(name) != 1 || len(value) != 1 || value[0].kind != itemString {
+			return fmt.Errorf("MACRO needs a name and a string")
+		}
+		if m.read {
+			return fmt.Errorf("MACRO must come before READ")
+		}
+		s, err := m.define(name[0], symMacro)
+		if err != nil {
+			return err
+		}
+		s.strVal = value[0].text
+	case "read":
+		if m.read {
+			return fmt.Errorf("READ can only appear once")
+		}
+		m.read = true
+		return m.readEntries()
+	case "execute", "iterate", "reverse":
+		fn, err := m.commandFunc(cmd)
+		if err != nil {
+			return err
+		}
+		if cmd.name == "execute" {
+			return m.call(fn)
+		}
+		if !m.read {
+			return fmt.Errorf("%s must come after READ", strings.ToUpper(cmd.name))
+		}
+		for i := range m.entries {
+			if cmd.name == "reverse" {
+				i = len(m.entries) - 1 - i
+			}
+			m.cur = m.entries[i]
+			err := m.call(fn)
+			m.cur = nil
+			if err != nil {
+				return err
+			}
+		}
+	case "sort":
+		if !m.read {
+			return fmt.Errorf("SORT must come after READ")
+		}
+		key := m.syms["sort.key$"].index
+		sort.SliceStable(m.entries, func(i, j int) bool {
+			return m.entries[i].strs[key] < m.entries[j].strs[key]
+		})
+	}
+	return nil
+}
+
+// commandFunc returns the function argument of EXECUTE, ITERATE or REVERSE.
+func (m *machine) commandFunc(cmd *command) (*symbol, error) {
+	items := cmd.args[0].items
+	if len(items) != 1 || items[0].kind != itemIdent {
+		return nil, fmt.Errorf("%s needs exactly one function name", strings.ToUpper(cmd.name))
+	}
+	s, ok := m.syms[items[0].text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", items[0].text)
+	}
+	return s, nil
+}
+
+// check reports names in a function body that aren't defined. Like bibtex,
+// a function can only use names defined before it.
+func (m *machine) check(block *item) error {
+	for _, it := range block.items {
+		switch it.kind {
+		case itemIdent, itemQuote:
+			if _, ok := m.syms[it.text]; !ok {
+				return atPos(it.pos, fmt.Errorf("unknown function %s", it.text))
+			}
+		case itemBlock:
+			if err := m.check(it); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// call calls the function or pushes the value of the variable s.
+func (m *machine) call(s *symbol) error {
+	switch s.kind {
+	case symBuiltin:
+		if err := s.builtin(m); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	case symFunction:
+		return m.run(s.body)
+	case symField:
+		if m.cur == nil {
+			return fmt.Errorf("field %s used outside of an entry", s.name)
+		}
+		m.push(m.cur.fields[s.index])
+	case symEntryInt, symEntryStr:
+		if m.cur == nil {
+			return fmt.Errorf("entry variable %s used outside of an entry", s.name)
+		}
+		if s.kind == symEntryInt {
+			m.push(m.cur.ints[s.index])
+		} else {
+			m.push(m.cur.strs[s.index])
+		}
+	case symGlobalInt:
+		m.push(s.intVal)
+	case symGlobalStr, symMacro:
+		m.push(s.strVal)
+	}
+	return nil
+}
+
+// run runs the items of a function body.
+func (m *machine) run(items []*item) error {
+	for _, it := range items {
+		switch it.kind {
+		case itemIdent:
+			if err := m.call(m.syms[it.text]); err != nil {
+				return atPos(it.pos, err)
+			}
+		case itemQuote:
+			m.push(m.syms[it.text])
+		case itemString:
+			m.push(it.text)
+		case itemInt:
+			n, _ := strconv.Atoi(it.text)
+			m.push(n)
+		case itemBlock:
+			m.push(&symbol{name: "{}", kind: symFunction, body: it.items})
+		}
+	}
+	return nil
+}
+
+func (m *machine) push(v any) {
+	m.stack = append(m.stack, v)
+}
+
+func (m *machine) pop() (any, error) {
+	if len(m.stack) == 0 {
+		return nil, fmt.Errorf("pop from an empty stack")
+	}
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	return v, nil
+}
+
+func (m *machine) popInt() (int, error) {
+	v, err := m.pop()
+	if err != nil {
+		return 0, err
+	}
+	n, ok := v.(int)
+	if !ok {
+		return 0, fmt.Errorf("%s is not an integer", describe(v))
+	}
+	return n, nil
+}
+
+// popString pops a string. A missing field pops as the empty string.
+func (m *machine) popString() (string, error) {
+	v, err := m.pop()
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case missing:
+		return "", nil
+	}
+	return "", fmt.Errorf("%s is not a string", describe(v))
+}
+
+func (m *machine) popFunc() (*symbol, error) {
+	v, err := m.pop()
+	if err != nil {
+		return nil, err
+	}
+	s, ok := v.(*symbol)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a function", describe(v))
+	}
+	return s, nil
+}
+
+// describe describes a stack value for error messages and stack$.
+func describe(v any) string {
+	switch v := v.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case string:
+		return `"` + v + `"`
+	case missing:
+		return "missing field"
+	case *symbol:
+		if v.body != nil && v.name == "{}" {
+			return "function literal"
+		}
+		return "'" + v.name
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// readEntries reads the cited entries from the package. Like bibtex, a
+// missing field is inherited from the entry named by the crossref field, and
+// an entry cross-referenced by at least two cited entries is cited too.
+func (m *machine) readEntries() error {
+	if m.pkg != nil {
+		names := make([]string, 0, len(m.pkg.Files))
+		for name := range m.pkg.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, d := range m.pkg.Files[name].Entries {
+				switch d := d.(type) {
+				case *ast.AbbrevDecl:
+					m.abbrevs[strings.ToLower(d.Tag.Name)] = d.Tag.Value
+				case *ast.PreambleDecl:
+					s, err := m.exprString(d.Text)
+					if err != nil {
+						return err
+					}
+					m.preamble += s
+				case *ast.BibDecl:
+					if d.Key == nil {
+						continue
+					}
+					key := strings.ToLower(d.Key.Name)
+					if _, ok := m.decls[key]; ok {
+						m.warn("repeated entry %s", d.Key.Name)
+						continue
+					}
+					m.decls[key] = d
+					m.order = append(m.order, d)
+				}
+			}
+		}
+	}
+
+	cited := make(map[string]bool)
+	var decls []*ast.BibDecl
+	var keys []string
+	cite := func(key string, d *ast.BibDecl) {
+		if !cited[strings.ToLower(d.Key.Name)] {
+			cited[strings.ToLower(d.Key.Name)] = true
+			decls = append(decls, d)
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range m.keys {
+		if key == "*" {
+			for _, d := range m.order {
+				cite(d.Key.Name, d)
+			}
+			continue
+		}
+		d, ok := m.decls[strings.ToLower(key)]
+		if !ok {
+			m.warn("I didn't find a database entry for %q", key)
+			continue
+		}
+		cite(key, d)
+	}
+	crossrefs := make(map[string]int)
+	for _, d := range decls {
+		if ref := m.tagString(d, "crossref"); ref != "" {
+			crossrefs[strings.ToLower(ref)]++
+		}
+	}
+	for _, d := range decls {
+		ref := strings.ToLower(m.tagString(d, "crossref"))
+		if parent, ok := m.decls[ref]; ok && crossrefs[ref] >= 2 {
+			cite(parent.Key.Name, parent)
+		}
+	}
+
+	for i, d := range decls {
+		e := &entry{
+			key:    keys[i],
+			typ:    strings.ToLower(d.Type),
+			decl:   d,
+			fields: make([]any, m.fields),
+			ints:   make([]int, m.entryInts),
+			strs:   make([]string, m.entryStrs),
+		}
+		var parent *ast.BibDecl
+		if ref := m.tagString(d, "crossref"); ref != "" {
+			if parent = m.decls[strings.ToLower(ref)]; parent == nil {
+				m.warn("%s cross-references the missing entry %q", d.Key.Name, ref)
+			}
+		}
+		for _, s := range m.syms {
+			if s.kind != symField {
+				continue
+			}
+			e.fields[s.index] = missing{}
+			tag := findTag(d, s.name)
+			if tag == nil && parent != nil && s.name != "crossref" {
+				tag = findTag(parent, s.name)
+			}
+			if tag == nil {
+				continue
+			}
+			v, err := m.exprString(tag.Value)
+			if err != nil {
+				return fmt.Errorf("entry %s field %s: %w", d.Key.Name, tag.Name, err)
+			}
+			e.fields[s.index] = v
+		}
+		m.entries = append(m.entries, e)
+	}
+	return nil
+}
+
+func findTag(d *ast.BibDecl, name string) *ast.TagStmt {
+	for _, t := range d.Tags {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// tagString returns the value of the tag of d or the empty string.
+func (m *machine) tagString(d *ast.BibDecl, name string) string {
+	t := findTag(d, name)
+	if t == nil {
+		return ""
+	}
+	s, err := m.exprString(t.Value)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// exprString returns the value of a field expression as bibtex sees it: the
+// raw text without delimiters, with abbreviations expanded and whitespace
+// collapsed.
+func (m *machine) exprString(x ast.Expr) (string, error) {
+	s, err := m.rawString(x, 0)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(strings.Fields(s), " "), nil
+}
+
+func (m *machine) rawString(x ast.Expr, depth int) (string, error) {
+	if depth > 100 {
+		return "", fmt.Errorf("abbreviations nested too deeply")
+	}
+	switch x := x.(type) {
+	case *ast.Ident:
+		name := strings.ToLower(x.Name)
+		if v, ok := m.abbrevs[name]; ok {
+			return m.rawString(v, depth+1)
+		}
+		if s, ok := m.syms[name]; ok && s.kind == symMacro {
+			return s.strVal, nil
+		}
+		m.warn("%s is an undefined macro", x.Name)
+		return "", nil
+	case *ast.Number:
+		return x.Value, nil
+	case *ast.UnparsedText:
+		return x.Value, nil
+	case *ast.ConcatExpr:
+		a, err := m.rawString(x.X, depth)
+		if err != nil {
+			return "", err
+		}
+		b, err := m.rawString(x.Y, depth)
+		if err != nil {
+			return "", err
+		}
+		return a + b, nil
+	default:
+		return texString(x)
+	}
+}
+
+// texString prints parsed or resolved text back into bibtex syntax without
+// the outer delimiters.
+func texString(x ast.Expr) (string, error) {
+	sb := &strings.Builder{}
+	if err := printer.Fprint(sb, nil, x); err != nil {
+		return "", err
+	}
+	s := sb.String()
+	if len(s) >= 2 {
+		s = s[1 : len(s)-1]
+	}
+	return s, nil
+}
+
+// outBuffer is the .bbl output. Like bibtex, it breaks lines longer than 79
+// characters at whitespace and indents the continuation lines with two
+// spaces.
+type outBuffer struct {
+	w   io.Writer
+	buf []byte
+}
+
+const (
+	maxPrintLine = 79
+	minPrintLine = 3
+)
+
+func (o *outBuffer) write(s string) {
+	o.buf = append(o.buf, s...)
+	for len(o.buf) > maxPrintLine {
+		i := maxPrintLine
+		for i >= minPrintLine && o.buf[i] != ' ' && o.buf[i] != '\t' {
+			i--
+		}
+		if i < minPrintLine {
+			// No whitespace: break with a TeX comment so that TeX joins the
+			// lines.
+			rest := append([]byte(nil), o.buf[maxPrintLine-1:]...)
+			o.buf = append(o.buf[:maxPrintLine-1], '%')
+			o.newline()
+			o.buf = append(o.buf, rest...)
+			continue
+		}
+		rest := append([]byte("  "), o.buf[i+1:]...)
+		o.buf = o.buf[:i]
+		o.newline()
+		o.buf = append(o.buf, rest...)
+	}
+}
+
+// newline writes the buffered line without trailing whitespace. A line of
+// only whitespace isn't written.
+func (o *outBuffer) newline() {
+	if len(o.buf) > 0 {
+		line := strings.TrimRight(string(o.buf), " \t")
+		o.buf = o.buf[:0]
+		if line == "" {
+			return
+		}
+		_, _ = io.WriteString(o.w, line)
+	}
+	_, _ = io.WriteString(o.w, "\n")
+}
+
+// flush writes any remaining output.
+func (o *outBuffer) flush() {
+	if len(o.buf) > 0 {
+		o.newline()
+	}
+}
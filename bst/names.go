@@ -0,0 +1,417 @@
+package bst
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex/ast"
+)
+
+// splitNames splits a list of names separated by "and" at brace depth 0,
+// like num.names$ and format.name$.
+func splitNames(s string) []string {
+	var names []string
+	depth := 0
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		default:
+			if depth == 0 && isSpace(s[i]) && i+4 < len(s) &&
+				strings.EqualFold(s[i+1:i+4], "and") && isSpace(s[i+4]) {
+				names = append(names, s[start:i])
+				start = i + 5
+				i += 4
+			}
+		}
+	}
+	names = append(names, s[start:])
+	for i, n := range names {
+		names[i] = strings.TrimSpace(n)
+	}
+	if len(names) == 1 && names[0] == "" {
+		return nil
+	}
+	return names
+}
+
+// nameToken is a word of a name with the separator that precedes it.
+type nameToken struct {
+	text string
+	sep  byte // ' ', '-', '~' or ',' before the token; 0 for the first token
+}
+
+// parsedName is a name split into tokens with the ranges of each part:
+// tokens[first[0]:first[1]] is the first name.
+type parsedName struct {
+	tokens               []nameToken
+	first, von, last, jr [2]int
+}
+
+// parseName splits a name into its first, von, last and jr parts following
+// the rules of bibtex. The name is in one of the forms "First von Last",
+// "von Last, First" or "von Last, Jr, First". The von part is the longest
+// run of words starting with a lowercase letter that leaves a last name.
+func parseName(s string) (*parsedName, error) {
+	p := &parsedName{}
+	commas := p.tokenize(strings.Trim(s, " \t\n-~"))
+	if len(commas) > 2 {
+		return nil, fmt.Errorf("too many commas in name %q", s)
+	}
+	n := len(p.tokens)
+
+	// vonEnd returns the end of the von part given its start and the end of
+	// the last name. The last name has at least one word.
+	vonEnd := func(vonStart, lastEnd int) int {
+		end := lastEnd - 1
+		for end > vonStart && !isVonToken(p.tokens[end-1].text) {
+			end--
+		}
+		return end
+	}
+	switch len(commas) {
+	case 0:
+		vonStart := 0
+		for vonStart < n-1 && !isVonToken(p.tokens[vonStart].text) {
+			vonStart++
+		}
+		end := vonStart
+		if vonStart == n-1 || n == 0 {
+			// No von part: the last name is the last word and any words
+			// joined to it with hyphens.
+			vonStart = max(n-1, 0)
+			for vonStart > 0 && p.tokens[vonStart].sep == '-' {
+				vonStart--
+			}
+			end = vonStart
+		} else {
+			end = vonEnd(vonStart, n)
+		}
+		p.first = [2]int{0, vonStart}
+		p.von = [2]int{vonStart, end}
+		p.last = [2]int{end, n}
+		p.jr = [2]int{n, n}
+	case 1:
+		end := vonEnd(0, commas[0])
+		p.von = [2]int{0, end}
+		p.last = [2]int{end, commas[0]}
+		p.jr = [2]int{commas[0], commas[0]}
+		p.first = [2]int{commas[0], n}
+	case 2:
+		end := vonEnd(0, commas[0])
+		p.von = [2]int{0, end}
+		p.last = [2]int{end, commas[0]}
+		p.jr = [2]int{commas[0], commas[1]}
+		p.first = [2]int{commas[1], n}
+	}
+	return p, nil
+}
+
+// tokenize appends the words of s to the tokens of p. Words are separated
+// by whitespace, hyphens, ties and commas at brace depth 0. Tokenize returns
+// the token index after each comma.
+func (p *parsedName) tokenize(s string) (commas []int) {
+	depth := 0
+	start := -1
+	var sep byte
+	flush := func(end int) {
+		if start >= 0 {
+			p.tokens = append(p.tokens, nameToken{text: s[start:end], sep: sep})
+			start = -1
+			sep = 0
+		}
+	}
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '{':
+			depth++
+		case ch == '}':
+			if depth > 0 {
+				depth--
+			}
+		case depth > 0:
+		case ch == ',':
+			flush(i)
+			commas = append(commas, len(p.tokens))
+			sep = ','
+			continue
+		case isSpace(ch) || ch == '-' || ch == '~':
+			flush(i)
+			switch {
+			case sep == ',':
+			case ch == '-' || ch == '~':
+				sep = ch
+			case sep == 0:
+				sep = ' '
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	flush(len(s))
+	return commas
+}
+
+// addPart appends the words of a name part to the tokens of p and returns
+// the range of the part.
+func (p *parsedName) addPart(s string) [2]int {
+	start := len(p.tokens)
+	p.tokenize(strings.Trim(s, " \t\n-~"))
+	return [2]int{start, len(p.tokens)}
+}
+
+// isVonToken returns true if the first letter of the token at brace depth 0
+// is lowercase. For a special character, the case of its control sequence,
+// like \ae, or else of its first letter decides.
+func isVonToken(tok string) bool {
+	depth := 0
+	for i := 0; i < len(tok); {
+		switch {
+		case isSpecial(tok, i, depth):
+			end := skipSpecial(tok, i)
+			name, j := controlSeq(tok, i+1)
+			if upper, ok := specialChars[name]; ok {
+				return !upper
+			}
+			for ; j < end; j++ {
+				if r := rune(tok[j]); unicode.IsLetter(r) {
+					return unicode.IsLower(r)
+				}
+			}
+			return false
+		case tok[i] == '{':
+			// A brace group that's not a special character is neither
+			// uppercase nor lowercase.
+			depth++
+			i++
+		case tok[i] == '}':
+			depth--
+			i++
+		case depth > 0:
+			i++
+		default:
+			r, size := utf8.DecodeRuneInString(tok[i:])
+			if unicode.IsUpper(r) {
+				return false
+			}
+			if unicode.IsLower(r) {
+				return true
+			}
+			i += size
+		}
+	}
+	return false
+}
+
+// part returns the tokens of a part range.
+func (p *parsedName) part(r [2]int) []nameToken {
+	return p.tokens[r[0]:r[1]]
+}
+
+// FormatName formats a name in bibtex syntax, like "Knuth, Donald E.", with
+// a format.name$ pattern, like "{ff~}{vv~}{ll}{, jj}".
+//
+// A group in braces with one of the part letters f, v, l or j formats the
+// first, von, last or jr part and is output only if the part is non-empty. A
+// doubled letter, like ff, outputs the full words of the part. A single
+// letter outputs the first letter of each word followed by a period, so that
+// "Jean-Paul" becomes "J.-P.". A special character, like {\"O}, counts as a
+// single letter. Text in the group before and after the letters is copied.
+// Text in braces right after the letters, like {ff{ }}, replaces the default
+// separator between words.
+//
+// Like bibtex, the default separator between words is the hyphen or tie of
+// the name, or else a tie after a word shorter than three characters and
+// before the last word of a part, or else a space. A tie at the end of a
+// group becomes a space unless the formatted part is shorter than three
+// characters, as in "de~Gaulle".
+func FormatName(name, format string) (string, error) {
+	p, err := parseName(name)
+	if err != nil {
+		return "", err
+	}
+	return p.format(format)
+}
+
+// FormatAuthor formats an author with a format.name$ pattern, like FormatName.
+// The parts of the author are already split, so the von part and last name
+// are used as is.
+func FormatAuthor(a *ast.Author, format string) (string, error) {
+	parts := make([]string, 4)
+	for i, x := range []ast.Expr{a.First, a.Prefix, a.Last, a.Suffix} {
+		if x == nil {
+			continue
+		}
+		s, err := texString(x)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+	p := &parsedName{}
+	p.first = p.addPart(parts[0])
+	p.von = p.addPart(parts[1])
+	p.last = p.addPart(parts[2])
+	p.jr = p.addPart(parts[3])
+	return p.format(format)
+}
+
+// format formats the name with a format.name$ pattern.
+func (p *parsedName) format(format string) (string, error) {
+	sb := &strings.Builder{}
+	for i := 0; i < len(format); {
+		if format[i] != '{' {
+			sb.WriteByte(format[i])
+			i++
+			continue
+		}
+		end := matchBrace(format, i)
+		group, err := p.formatGroup(format[i+1 : end])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(group)
+		i = end + 1
+	}
+	return sb.String(), nil
+}
+
+// matchBrace returns the index of the brace that closes the brace s[i] or
+// len(s) if unbalanced.
+func matchBrace(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// formatGroup formats a top-level group of a name format, without the
+// enclosing braces.
+func (p *parsedName) formatGroup(group string) (string, error) {
+	// The part letter is the first letter at depth 0.
+	letterAt := -1
+	for i, depth := 0, 0; i < len(group) && letterAt < 0; i++ {
+		switch ch := group[i]; {
+		case ch == '{':
+			depth++
+		case ch == '}':
+			depth--
+		case depth == 0 && isASCIILetter(ch):
+			letterAt = i
+		}
+	}
+	if letterAt < 0 {
+		return "", fmt.Errorf("no part letter in name format group {%s}", group)
+	}
+
+	var tokens []nameToken
+	switch group[letterAt] | 0x20 {
+	case 'f':
+		tokens = p.part(p.first)
+	case 'v':
+		tokens = p.part(p.von)
+	case 'l':
+		tokens = p.part(p.last)
+	case 'j':
+		tokens = p.part(p.jr)
+	default:
+		return "", fmt.Errorf("%q is an illegal name format letter", group[letterAt])
+	}
+	if len(tokens) == 0 {
+		return "", nil
+	}
+
+	full := false
+	end := letterAt + 1
+	if end < len(group) && group[end]|0x20 == group[letterAt]|0x20 {
+		full = true
+		end++
+	}
+	sep, customSep := "", false
+	if end < len(group) && group[end] == '{' {
+		close := matchBrace(group, end)
+		sep, customSep = group[end+1:close], true
+		end = close + 1
+	}
+
+	sb := &strings.Builder{}
+	sb.WriteString(group[:letterAt])
+	start := sb.Len()
+	for i, tok := range tokens {
+		tokStart := sb.Len()
+		if full {
+			sb.WriteString(tok.text)
+		} else {
+			sb.WriteString(abbreviate(tok.text))
+		}
+		if i == len(tokens)-1 {
+			break
+		}
+		switch {
+		case customSep:
+			sb.WriteString(sep)
+		default:
+			if !full {
+				sb.WriteByte('.')
+			}
+			next := tokens[i+1].sep
+			switch {
+			case next == '-' || next == '~':
+				sb.WriteByte(next)
+			case i == len(tokens)-2 || textLength(sb.String()[tokStart:]) < 3:
+				sb.WriteByte('~')
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+	}
+	after := group[end:]
+	// A trailing tie in a group is discretionary: it stays a tie only if
+	// the group's text is short.
+	if strings.HasSuffix(after, "~") {
+		after = strings.TrimSuffix(after, "~")
+		text := sb.String()[start:] + after
+		if textLength(text) < 3 {
+			after += "~"
+		} else {
+			after += " "
+		}
+	}
+	sb.WriteString(after)
+	return sb.String(), nil
+}
+
+// abbreviate returns the first letter of a name token. A special character,
+// like {\"O}, abbreviates to itself.
+func abbreviate(tok string) string {
+	for i := 0; i < len(tok); {
+		if tok[i] == '{' && i+1 < len(tok) && tok[i+1] == '\\' {
+			return tok[i:skipSpecial(tok, i)]
+		}
+		r, size := utf8.DecodeRuneInString(tok[i:])
+		if unicode.IsLetter(r) {
+			return tok[i : i+size]
+		}
+		i += size
+	}
+	return ""
+}
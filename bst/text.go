@@ -0,0 +1,342 @@
+package bst
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A special character is a brace group at depth 1 that starts with a
+// backslash, like {\"o} or {\ss}. The text functions treat a special
+// character as a single letter.
+
+// specialChars are the control sequences of special characters that bibtex
+// recognizes as letters. The value is true for uppercase letters.
+var specialChars = map[string]bool{
+	"i": false, "j": false, "oe": false, "OE": true, "ae": false, "AE": true,
+	"aa": false, "AA": true, "o": false, "O": true, "l": false, "L": true,
+	"ss": false,
+}
+
+// isSpecial returns true if s[i] starts a special character.
+func isSpecial(s string, i, depth int) bool {
+	return s[i] == '{' && depth == 0 && i+1 < len(s) && s[i+1] == '\\'
+}
+
+// skipSpecial returns the index after the special character starting at
+// s[i].
+func skipSpecial(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+// controlSeq returns the name of the control sequence starting at the
+// backslash s[i] and the index after it.
+func controlSeq(s string, i int) (string, int) {
+	j := i + 1
+	for j < len(s) && isASCIILetter(s[j]) {
+		j++
+	}
+	return s[i+1 : j], j
+}
+
+func isASCIILetter(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// changeCase converts the case of s like change.case$. Mode 't' lowercases
+// all letters except the first and those after a colon and whitespace, 'l'
+// lowercases all letters and 'u' uppercases all letters. Letters in braces
+// keep their case, except in special characters, whose control sequences
+// convert to the matching case, like {\OE} to {\oe}.
+func changeCase(s string, mode byte) string {
+	sb := &strings.Builder{}
+	depth := 0
+	prevColon := false
+	convert := func(s string) string {
+		if mode == 'u' {
+			return strings.ToUpper(s)
+		}
+		return strings.ToLower(s)
+	}
+	for i := 0; i < len(s); {
+		switch {
+		case isSpecial(s, i, depth):
+			end := skipSpecial(s, i)
+			if mode == 't' && (i == 0 || prevColon && i > 0 && isSpace(s[i-1])) {
+				sb.WriteString(s[i:end])
+			} else {
+				sb.WriteString(changeSpecialCase(s[i:end], mode))
+			}
+			prevColon = false
+			i = end
+		case s[i] == '{':
+			depth++
+			prevColon = false
+			sb.WriteByte('{')
+			i++
+		case s[i] == '}':
+			if depth > 0 {
+				depth--
+			}
+			prevColon = false
+			sb.WriteByte('}')
+			i++
+		case depth > 0:
+			sb.WriteByte(s[i])
+			i++
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			ch := string(r)
+			switch mode {
+			case 't':
+				if i > 0 && !(prevColon && isSpace(s[i-1])) {
+					ch = strings.ToLower(ch)
+				}
+				if r == ':' {
+					prevColon = true
+				} else if !unicode.IsSpace(r) {
+					prevColon = false
+				}
+			default:
+				ch = convert(ch)
+			}
+			sb.WriteString(ch)
+			i += size
+		}
+	}
+	return sb.String()
+}
+
+// changeSpecialCase converts the case of the special character sc.
+func changeSpecialCase(sc string, mode byte) string {
+	sb := &strings.Builder{}
+	for i := 0; i < len(sc); {
+		if sc[i] != '\\' {
+			ch := sc[i : i+1]
+			if mode == 'u' {
+				ch = strings.ToUpper(ch)
+			} else {
+				ch = strings.ToLower(ch)
+			}
+			sb.WriteString(ch)
+			i++
+			continue
+		}
+		name, end := controlSeq(sc, i)
+		upper, known := specialChars[name]
+		switch {
+		case !known:
+			sb.WriteString(sc[i:end])
+		case mode == 'u' && (name == "i" || name == "j" || name == "ss"):
+			// These become plain letters: {\ss} to {SS}.
+			sb.WriteString(strings.ToUpper(name))
+			for end < len(sc) && isSpace(sc[end]) {
+				end++
+			}
+		case mode == 'u' && !upper:
+			sb.WriteString(`\` + strings.ToUpper(name))
+		case mode != 'u' && upper:
+			sb.WriteString(`\` + strings.ToLower(name))
+		default:
+			sb.WriteString(sc[i:end])
+		}
+		// Copy the argument of the control sequence up to the next control
+		// sequence.
+		j := end
+		for j < len(sc) && sc[j] != '\\' && !isASCIILetter(sc[j]) {
+			j++
+		}
+		sb.WriteString(sc[end:j])
+		i = j
+	}
+	return sb.String()
+}
+
+// purifiedSpecials are the letters that purify keeps for the control
+// sequences of special characters.
+var purifiedSpecials = map[string]string{
+	"i": "i", "j": "j", "oe": "oe", "OE": "OE", "ae": "ae", "AE": "AE",
+	"aa": "a", "AA": "A", "o": "o", "O": "O", "l": "l", "L": "L", "ss": "ss",
+}
+
+// purify removes non-alphanumeric characters from s like purify$. Whitespace,
+// hyphens and ties become spaces. Special characters keep the letters of
+// their argument and of known control sequences, like o for {\"o} and ss for
+// {\ss}.
+func purify(s string) string {
+	sb := &strings.Builder{}
+	depth := 0
+	for i := 0; i < len(s); {
+		switch {
+		case isSpecial(s, i, depth):
+			end := skipSpecial(s, i)
+			for j := i + 1; j < end; {
+				if s[j] == '\\' {
+					name, k := controlSeq(s, j)
+					sb.WriteString(purifiedSpecials[name])
+					j = k
+					continue
+				}
+				r, size := utf8.DecodeRuneInString(s[j:])
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					sb.WriteRune(r)
+				}
+				j += size
+			}
+			i = end
+		case s[i] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth > 0 {
+				depth--
+			}
+			i++
+		default:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			switch {
+			case unicode.IsSpace(r) || r == '-' || r == '~':
+				sb.WriteByte(' ')
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				sb.WriteRune(r)
+			}
+			i += size
+		}
+	}
+	return sb.String()
+}
+
+// textLength returns the number of text characters in s like text.length$.
+// Braces don't count and special characters count as one character.
+func textLength(s string) int {
+	n := 0
+	depth := 0
+	for i := 0; i < len(s); {
+		switch {
+		case isSpecial(s, i, depth):
+			i = skipSpecial(s, i)
+			n++
+		case s[i] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth > 0 {
+				depth--
+			}
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+			n++
+		}
+	}
+	return n
+}
+
+// textPrefix returns the first n text characters of s like text.prefix$,
+// with closing braces added to balance the prefix.
+func textPrefix(s string, n int) string {
+	depth := 0
+	i := 0
+	for count := 0; i < len(s) && count < n; {
+		switch {
+		case isSpecial(s, i, depth):
+			i = skipSpecial(s, i)
+			count++
+		case s[i] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth > 0 {
+				depth--
+			}
+			i++
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+			count++
+		}
+	}
+	return s[:i] + strings.Repeat("}", depth)
+}
+
+// charWidths are the widths of the ASCII characters in the cmr10 font in
+// hundredths of a point, as used by width$.
+var charWidths = [128]int{
+	' ': 278, '!': 278, '"': 500, '#': 833, '$': 500, '%': 833, '&': 778,
+	'\'': 278, '(': 389, ')': 389, '*': 500, '+': 778, ',': 278, '-': 333,
+	'.': 278, '/': 500, '0': 500, '1': 500, '2': 500, '3': 500, '4': 500,
+	'5': 500, '6': 500, '7': 500, '8': 500, '9': 500, ':': 278, ';': 278,
+	'<': 278, '=': 778, '>': 472, '?': 472, '@': 778, 'A': 750, 'B': 708,
+	'C': 722, 'D': 764, 'E': 681, 'F': 653, 'G': 785, 'H': 750, 'I': 361,
+	'J': 514, 'K': 778, 'L': 625, 'M': 917, 'N': 750, 'O': 778, 'P': 681,
+	'Q': 778, 'R': 736, 'S': 556, 'T': 722, 'U': 750, 'V': 750, 'W': 1028,
+	'X': 750, 'Y': 750, 'Z': 611, '[': 278, '\\': 500, ']': 278, '^': 500,
+	'_': 278, '`': 278, 'a': 500, 'b': 556, 'c': 444, 'd': 556, 'e': 444,
+	'f': 306, 'g': 500, 'h': 556, 'i': 278, 'j': 306, 'k': 528, 'l': 278,
+	'm': 833, 'n': 556, 'o': 500, 'p': 556, 'q': 528, 'r': 392, 's': 394,
+	't': 389, 'u': 556, 'v': 528, 'w': 722, 'x': 528, 'y': 528, 'z': 444,
+	'{': 500, '|': 1000, '}': 500, '~': 500,
+}
+
+// specialWidths are the widths of special characters whose width differs
+// from the width of their first letter.
+var specialWidths = map[string]int{
+	"ss": 500, "ae": 722, "oe": 778, "AE": 903, "OE": 1014,
+}
+
+// width returns the width of s in the cmr10 font like width$. Non-ASCII
+// characters have the width of a lowercase letter, like "o".
+func width(s string) int {
+	w := 0
+	depth := 0
+	for i := 0; i < len(s); {
+		if isSpecial(s, i, depth) {
+			end := skipSpecial(s, i)
+			name, j := controlSeq(s, i+1)
+			switch sw, ok := specialWidths[name]; {
+			case ok:
+				w += sw
+			case name != "":
+				w += charWidths[name[0]]
+			default:
+				j++ // skip a control symbol, like \"
+			}
+			for ; j < end; j++ {
+				if ch := s[j]; ch != '{' && ch != '}' && ch != ' ' && ch < utf8.RuneSelf {
+					w += charWidths[ch]
+				}
+			}
+			i = end
+			continue
+		}
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r < utf8.RuneSelf {
+			w += charWidths[r]
+		} else {
+			w += charWidths['o']
+		}
+		i += size
+	}
+	return w
+}
@@ -0,0 +1,188 @@
+// Bibfmt formats bibtex files.
+//
+// Bibfmt prints bibtex declarations in a canonical layout: lowercase entry
+// types, brace-delimited values, indented and aligned tags, a trailing comma
+// after the last tag, and a single blank line between declarations. Comments
+// are preserved.
+//
+// Without an explicit path, it processes the standard input. Given a file, it
+// operates on that file; given a directory, it operates on all .bib files in
+// that directory, recursively. (Files starting with a period are ignored.) By
+// default, bibfmt prints the reformatted sources to standard output.
+//
+// Usage:
+//
+//	bibfmt [flags] [path ...]
+//
+// The flags are:
+//
+//	-d
+//		Do not print reformatted sources to standard output.
+//		If a file's formatting is different than bibfmt's, print diffs
+//		to standard output.
+//	-l
+//		Do not print reformatted sources to standard output.
+//		If a file's formatting is different from bibfmt's, print its name
+//		to standard output.
+//	-w
+//		Do not print reformatted sources to standard output.
+//		If a file's formatting is different from bibfmt's, overwrite it
+//		with bibfmt's version.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	goscan "go/scanner"
+	gotok "go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jschaf/bibtex/parser"
+	"github.com/jschaf/bibtex/printer"
+)
+
+var (
+	list   = flag.Bool("l", false, "list files whose formatting differs from bibfmt's")
+	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
+	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
+)
+
+// printerConfig is the layout of formatted files.
+var printerConfig = &printer.Config{
+	Mode:   printer.Canonical | printer.AlignTags | printer.TrailingComma,
+	Indent: 2,
+}
+
+var exitCode = 0
+
+func report(err error) {
+	goscan.PrintError(os.Stderr, err)
+	exitCode = 2
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: bibfmt [flags] [path ...]\n")
+	flag.PrintDefaults()
+}
+
+func isBibFile(f fs.DirEntry) bool {
+	// ignore non-bibtex files
+	name := f.Name()
+	return !f.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".bib")
+}
+
+// format parses src and returns it formatted in the canonical layout.
+func format(filename string, src []byte) ([]byte, error) {
+	fset := gotok.NewFileSet()
+	// Don't parse strings so that bibfmt can format files with LaTeX the
+	// string parser doesn't understand. The printer prints unparsed strings
+	// verbatim.
+	f, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	normalize(f)
+	var buf bytes.Buffer
+	if err := printerConfig.Fprint(&buf, fset, f); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// If in == nil, the source is the contents of the file with the given filename.
+func processFile(filename string, in io.Reader, out io.Writer) error {
+	var perm fs.FileMode = 0o644
+	if in == nil {
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		in = f
+		perm = fi.Mode().Perm()
+	}
+
+	src, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+
+	res, err := format(filename, src)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(src, res) {
+		// formatting has changed
+		if *list {
+			fmt.Fprintln(out, filename)
+		}
+		if *write {
+			if err := os.WriteFile(filename, res, perm); err != nil {
+				return err
+			}
+		}
+		if *doDiff {
+			out.Write(diff(filename+".orig", filename, src, res))
+		}
+	}
+
+	if !*list && !*write && !*doDiff {
+		_, err = out.Write(res)
+	}
+
+	return err
+}
+
+func visitFile(path string, f fs.DirEntry, err error) error {
+	if err == nil && isBibFile(f) {
+		err = processFile(path, nil, os.Stdout)
+	}
+	// Don't complain if a file was deleted in the meantime (i.e.
+	// the directory changed concurrently while running bibfmt).
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		report(err)
+	}
+	return nil
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "error: cannot use -w with standard input")
+			os.Exit(2)
+		}
+		if err := processFile("<standard input>", os.Stdin, os.Stdout); err != nil {
+			report(err)
+		}
+		os.Exit(exitCode)
+	}
+
+	for i := 0; i < flag.NArg(); i++ {
+		path := flag.Arg(i)
+		switch dir, err := os.Stat(path); {
+		case err != nil:
+			report(err)
+		case dir.IsDir():
+			_ = filepath.WalkDir(path, visitFile)
+		default:
+			if err := processFile(path, nil, os.Stdout); err != nil {
+				report(err)
+			}
+		}
+	}
+	os.Exit(exitCode)
+}
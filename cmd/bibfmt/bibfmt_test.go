@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "entry type case",
+			src:  "@ARTICLE{key, title = {Foo}}",
+			want: "@article{key,\n  title = {Foo},\n}\n",
+		},
+		{
+			name: "align and indent tags",
+			src:  "@article{key,\n\tauthor = {Bar},\n      title={Foo},\n year = 2004}",
+			want: "@article{key,\n  author = {Bar},\n  title  = {Foo},\n  year   = 2004,\n}\n",
+		},
+		{
+			name: "brace style",
+			src:  "@article(key, title = \"The {NASA} Story\", publisher = acm # \" and \" # {IEEE})",
+			want: "@article{key,\n  title     = {The {NASA} Story},\n  publisher = acm # { and } # {IEEE},\n}\n",
+		},
+		{
+			name: "blank lines between declarations",
+			src:  "@STRING{acm = \"ACM\"}@Preamble(\"foo\")\n\n\n\n@misc{a,}",
+			want: "@string{acm = {ACM}}\n\n@preamble{{foo}}\n\n@misc{a}\n",
+		},
+		{
+			name: "comment declarations",
+			src:  "@Comment{jabref-meta: databaseType:bibtex;}\n@COMMENT(a {)})\n@comment  rest",
+			want: "@comment{jabref-meta: databaseType:bibtex;}\n\n@comment(a {)})\n\n@comment rest\n",
+		},
+		{
+			name: "comments",
+			src:  "% refs\n@article{key, % key\n title = {Foo}}\n% end",
+			want: "% refs\n@article{key, % key\n  title = {Foo},\n}\n% end\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := format("", []byte(tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("format() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFormat_idempotent(t *testing.T) {
+	filename := "../../parser/testdata/vldb.bib"
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	once, err := format(filename, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	twice, err := format(filename, once)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := diff("once", "twice", once, twice); diff != nil {
+		t.Errorf("formatting a formatted file changed it:\n%s", diff)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "change",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: "diff old new\n--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "separate hunks",
+			old:  "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			new:  "0\n1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			want: "diff old new\n--- old\n+++ new\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -7,4 +8,3 @@\n 7\n 8\n 9\n-10\n",
+		},
+		{
+			name: "no newline at end of file",
+			old:  "a",
+			new:  "a\n",
+			want: "diff old new\n--- old\n+++ new\n@@ -1 +1 @@\n-a\n\\ No newline at end of file\n+a\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diff("old", "new", []byte(tt.old), []byte(tt.new))
+			if d := cmp.Diff(tt.want, string(got)); d != "" {
+				t.Errorf("diff() mismatch (-want +got):\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/token"
+)
+
+// normalize rewrites the declarations of f to the canonical style: lowercase
+// declaration commands, brace delimited declarations, and brace delimited
+// string values.
+func normalize(f *ast.File) {
+	for _, d := range f.Entries {
+		switch d := d.(type) {
+		case *ast.BibDecl:
+			d.RawType = "" // print the lowercase Type
+			d.Delim = token.LBrace
+			for _, tag := range d.Tags {
+				normalizeValue(tag.Value)
+			}
+		case *ast.AbbrevDecl:
+			d.RawType = strings.ToLower(d.RawType)
+			d.Delim = token.LBrace
+			normalizeValue(d.Tag.Value)
+		case *ast.PreambleDecl:
+			d.RawType = strings.ToLower(d.RawType)
+			d.Delim = token.LBrace
+			normalizeValue(d.Text)
+		case *ast.CommentDecl:
+			// Keep the delimiter since the comment text may contain
+			// unbalanced parentheses or braces.
+			d.RawType = strings.ToLower(d.RawType)
+		}
+	}
+}
+
+// normalizeValue changes quoted strings in x to brace delimited strings.
+// Bibtex treats {foo} and "foo" the same except that a quoted string may
+// contain unbalanced double quotes only inside braces, which is valid in a
+// brace delimited string too.
+func normalizeValue(x ast.Expr) {
+	switch x := x.(type) {
+	case *ast.UnparsedText:
+		x.Type = token.BraceString
+	case *ast.ParsedText:
+		x.Delim = ast.BraceDelimiter
+	case *ast.ConcatExpr:
+		normalizeValue(x.X)
+		normalizeValue(x.Y)
+	}
+}
@@ -0,0 +1,427 @@
+package bibtex
+
+import (
+	"fmt"
+	goscan "go/scanner"
+	gotok "go/token"
+	"sort"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/asts"
+	"github.com/jschaf/bibtex/render"
+)
+
+// FieldXData is the biblatex field that lists the keys of @xdata entries to
+// inherit all fields from.
+const FieldXData Field = "xdata"
+
+// CrossrefResolver copies the tags of a parent entry, referenced by the
+// crossref tag, into the child entry. For example, CrossrefResolver adds the
+// booktitle and year tags of conf19 to paper:
+//
+//	@inproceedings{paper, title = {Foo}, crossref = {conf19}}
+//	@proceedings{conf19, booktitle = {Proceedings}, year = 2019}
+//
+// A child only inherits tags it doesn't have. By default, a child inherits
+// all tags of the parent except crossref, like bibtex. With
+// WithBiblatexInheritance, inherited tags follow the biblatex inheritance
+// rules, so that the title of a proceedings becomes the booktitle of an
+// inproceedings. Children also inherit the tags of the entries listed in the
+// biblatex xdata tag.
+//
+// A parent may itself have a parent. Cycles and references to missing
+// entries are reported as errors.
+type CrossrefResolver struct {
+	fset         *gotok.FileSet
+	biblatex     bool
+	minCrossrefs int
+}
+
+// CrossrefOption is a functional option to change how a CrossrefResolver
+// resolves cross-references.
+type CrossrefOption func(*CrossrefResolver)
+
+// WithBiblatexInheritance uses the default biblatex inheritance rules instead
+// of the bibtex rules.
+func WithBiblatexInheritance() CrossrefOption {
+	return func(r *CrossrefResolver) {
+		r.biblatex = true
+	}
+}
+
+// WithMinCrossrefs removes parent entries referenced by fewer than n children,
+// like `bibtex -min-crossrefs=n`. The children of a removed parent keep the
+// inherited tags but lose the crossref tag.
+func WithMinCrossrefs(n int) CrossrefOption {
+	return func(r *CrossrefResolver) {
+		r.minCrossrefs = n
+	}
+}
+
+// NewCrossrefResolver creates a resolver for the crossref and xdata tags in an
+// ast.File or ast.Package. The file set fset is used to report the positions
+// of errors; it may be nil.
+func NewCrossrefResolver(fset *gotok.FileSet, opts ...CrossrefOption) *CrossrefResolver {
+	r := &CrossrefResolver{fset: fset}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// crossrefState is the state of a single CrossrefResolver.Resolve call.
+type crossrefState struct {
+	*CrossrefResolver
+	rend     *render.TextRenderer
+	entries  map[string]*ast.BibDecl // by key
+	folded   map[string]*ast.BibDecl // by lowercase key, for case-insensitive lookup
+	done     map[*ast.BibDecl]bool   // true if resolved, false if resolving
+	path     []*ast.BibDecl          // entries being resolved, to report cycles
+	children map[*ast.BibDecl][]*ast.BibDecl
+	errs     goscan.ErrorList
+}
+
+// Resolve copies inherited tags into all entries in root that have a crossref
+// or xdata tag. Resolve returns a scanner.ErrorList with an error for each
+// cycle and missing parent.
+func (r *CrossrefResolver) Resolve(root ast.Node) error {
+	var files []*ast.File
+	switch n := root.(type) {
+	case *ast.Package:
+		names := make([]string, 0, len(n.Files))
+		for name := range n.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, n.Files[name])
+		}
+	case *ast.File:
+		files = []*ast.File{n}
+	default:
+		return fmt.Errorf("crossref resolver: unsupported node %T", root)
+	}
+
+	s := &crossrefState{
+		CrossrefResolver: r,
+		rend:             render.NewTextRenderer(),
+		entries:          make(map[string]*ast.BibDecl),
+		folded:           make(map[string]*ast.BibDecl),
+		done:             make(map[*ast.BibDecl]bool),
+		children:         make(map[*ast.BibDecl][]*ast.BibDecl),
+	}
+	for _, f := range files {
+		for _, decl := range f.Entries {
+			if d, ok := decl.(*ast.BibDecl); ok && d.Key != nil {
+				s.entries[d.Key.Name] = d
+				s.folded[strings.ToLower(d.Key.Name)] = d
+			}
+		}
+	}
+	for _, f := range files {
+		for _, decl := range f.Entries {
+			if d, ok := decl.(*ast.BibDecl); ok {
+				s.resolve(d)
+			}
+		}
+	}
+	if r.minCrossrefs > 0 {
+		switch n := root.(type) {
+		case *ast.Package:
+			s.dropParents(files, n.Scope)
+		case *ast.File:
+			s.dropParents(files, n.Scope)
+		}
+	}
+	s.errs.Sort()
+	return s.errs.Err()
+}
+
+// resolve copies the inherited tags into d after resolving the parents of d.
+func (s *crossrefState) resolve(d *ast.BibDecl) {
+	if _, seen := s.done[d]; seen {
+		return // resolved, or a cycle reported by parent
+	}
+	s.done[d] = false
+	s.path = append(s.path, d)
+	defer func() {
+		s.path = s.path[:len(s.path)-1]
+		s.done[d] = true
+	}()
+
+	// The child's own tags take precedence, then xdata, then crossref.
+	for _, tag := range d.Tags {
+		if tag.Name != FieldXData {
+			continue
+		}
+		for _, key := range strings.Split(s.text(tag.Value), ",") {
+			if parent := s.parent(d, tag, strings.TrimSpace(key)); parent != nil {
+				s.inherit(d, parent, func(string) []string { return nil })
+			}
+		}
+	}
+	for _, tag := range d.Tags {
+		if tag.Name != FieldCrossref {
+			continue
+		}
+		parent := s.parent(d, tag, strings.TrimSpace(s.text(tag.Value)))
+		if parent == nil {
+			continue
+		}
+		s.children[parent] = append(s.children[parent], d)
+		if s.biblatex {
+			s.inherit(d, parent, func(field string) []string {
+				return biblatexTargets(parent.Type, d.Type, field)
+			})
+		} else {
+			s.inherit(d, parent, func(string) []string { return nil })
+		}
+	}
+}
+
+// parent returns the resolved entry for key referenced by the tag of child d,
+// or reports an error and returns nil.
+func (s *crossrefState) parent(d *ast.BibDecl, tag *ast.TagStmt, key string) *ast.BibDecl {
+	if key == "" {
+		return nil
+	}
+	parent, ok := s.entries[key]
+	if !ok {
+		parent, ok = s.folded[strings.ToLower(key)]
+	}
+	if !ok {
+		s.error(tag.Value.Pos(), fmt.Sprintf("%s %q: entry not found", tag.Name, key))
+		return nil
+	}
+	if done, seen := s.done[parent]; seen && !done {
+		keys := make([]string, 0, len(s.path)+1)
+		for _, p := range s.path {
+			keys = append(keys, p.Key.Name)
+		}
+		keys = append(keys, parent.Key.Name)
+		s.error(tag.Value.Pos(), fmt.Sprintf("%s cycle: %s", tag.Name, strings.Join(keys, " -> ")))
+		return nil
+	}
+	s.resolve(parent)
+	return parent
+}
+
+// inherit copies the tags of parent missing from child. The targets function
+// returns the child tags for a parent field; nil means the field name itself.
+func (s *crossrefState) inherit(child, parent *ast.BibDecl, targets func(field string) []string) {
+	has := make(map[string]bool, len(child.Tags))
+	for _, tag := range child.Tags {
+		has[tag.Name] = true
+	}
+	for _, tag := range parent.Tags {
+		if noInherit[tag.Name] {
+			continue
+		}
+		names := targets(tag.Name)
+		if names == nil {
+			names = []string{tag.Name}
+		}
+		for _, name := range names {
+			if has[name] {
+				continue
+			}
+			has[name] = true
+			child.Tags = append(child.Tags, &ast.TagStmt{
+				Name:    name,
+				RawName: name,
+				Value:   asts.CloneExpr(tag.Value),
+			})
+		}
+	}
+}
+
+// dropParents removes the parents with fewer than minCrossrefs children from
+// files and scope, and removes the crossref tag from their children.
+func (s *crossrefState) dropParents(files []*ast.File, scope *ast.Scope) {
+	dropped := make(map[*ast.BibDecl]bool)
+	for parent, children := range s.children {
+		if len(children) >= s.minCrossrefs {
+			continue
+		}
+		dropped[parent] = true
+		for _, child := range children {
+			tags := child.Tags[:0]
+			for _, tag := range child.Tags {
+				if tag.Name != FieldCrossref {
+					tags = append(tags, tag)
+				}
+			}
+			child.Tags = tags
+		}
+	}
+	for _, f := range files {
+		entries := f.Entries[:0]
+		for _, decl := range f.Entries {
+			d, ok := decl.(*ast.BibDecl)
+			if !ok || !dropped[d] {
+				entries = append(entries, decl)
+				continue
+			}
+			for _, sc := range []*ast.Scope{scope, f.Scope} {
+				if sc != nil {
+					if obj := sc.Lookup(d.Key.Name); obj != nil && obj.Decl == d {
+						delete(sc.Objects, d.Key.Name)
+					}
+				}
+			}
+		}
+		f.Entries = entries
+	}
+}
+
+// text returns the plain text of a tag value, like the key of a crossref tag.
+func (s *crossrefState) text(x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.UnparsedText:
+		return x.Value
+	case *ast.Ident:
+		return x.Name
+	case *ast.Number:
+		return x.Value
+	}
+	sb := &strings.Builder{}
+	if err := s.rend.Render(sb, x); err != nil {
+		return ""
+	}
+	return sb.String()
+}
+
+func (s *crossrefState) error(pos gotok.Pos, msg string) {
+	var position gotok.Position
+	if s.fset != nil {
+		position = s.fset.Position(pos)
+	}
+	s.errs.Add(position, msg)
+}
+
+// noInherit are the fields a child never inherits.
+var noInherit = map[string]bool{
+	FieldCrossref:    true,
+	FieldXData:       true,
+	"ids":            true,
+	"xref":           true,
+	"entryset":       true,
+	"entrysubtype":   true,
+	"execute":        true,
+	"label":          true,
+	"options":        true,
+	"presort":        true,
+	"related":        true,
+	"relatedoptions": true,
+	"relatedstring":  true,
+	"relatedtype":    true,
+	"shorthand":      true,
+	"shorthandintro": true,
+	"sortkey":        true,
+}
+
+// An inheritRule maps the fields of a parent with one of the source types to
+// the fields of a child with one of the target types. A field mapped to an
+// empty list isn't inherited.
+type inheritRule struct {
+	sources []string
+	targets []string
+	fields  map[string][]string
+}
+
+var (
+	mainTitleFields = map[string][]string{
+		"title":          {"maintitle"},
+		"subtitle":       {"mainsubtitle"},
+		"titleaddon":     {"maintitleaddon"},
+		"shorttitle":     {},
+		"sorttitle":      {},
+		"indextitle":     {},
+		"indexsorttitle": {},
+	}
+	bookTitleFields = map[string][]string{
+		"title":          {"booktitle"},
+		"subtitle":       {"booksubtitle"},
+		"titleaddon":     {"booktitleaddon"},
+		"shorttitle":     {},
+		"sorttitle":      {},
+		"indextitle":     {},
+		"indexsorttitle": {},
+	}
+)
+
+// biblatexRules are the default inheritance rules from appendix B of the
+// biblatex manual. Fields without a rule keep their name.
+var biblatexRules = []inheritRule{
+	{
+		sources: []string{"mvbook", "book"},
+		targets: []string{"inbook", "bookinbook", "suppbook"},
+		fields:  map[string][]string{"author": {"author", "bookauthor"}},
+	},
+	{
+		sources: []string{"mvbook"},
+		targets: []string{"book", "inbook", "bookinbook", "suppbook"},
+		fields:  mainTitleFields,
+	},
+	{
+		sources: []string{"mvcollection", "mvreference"},
+		targets: []string{"collection", "reference", "incollection", "inreference", "suppcollection"},
+		fields:  mainTitleFields,
+	},
+	{
+		sources: []string{"mvproceedings"},
+		targets: []string{"proceedings", "inproceedings"},
+		fields:  mainTitleFields,
+	},
+	{
+		sources: []string{"book"},
+		targets: []string{"inbook", "bookinbook", "suppbook"},
+		fields:  bookTitleFields,
+	},
+	{
+		sources: []string{"collection", "reference"},
+		targets: []string{"incollection", "inreference", "suppcollection"},
+		fields:  bookTitleFields,
+	},
+	{
+		sources: []string{"proceedings"},
+		targets: []string{"inproceedings"},
+		fields:  bookTitleFields,
+	},
+	{
+		sources: []string{"periodical"},
+		targets: []string{"article", "suppperiodical"},
+		fields: map[string][]string{
+			"title":          {"journaltitle"},
+			"subtitle":       {"journalsubtitle"},
+			"shorttitle":     {},
+			"sorttitle":      {},
+			"indextitle":     {},
+			"indexsorttitle": {},
+		},
+	},
+}
+
+// biblatexTargets returns the child fields for the parent field according to
+// the biblatex inheritance rules, or nil to keep the field name.
+func biblatexTargets(source, target EntryType, field string) []string {
+	for _, rule := range biblatexRules {
+		if !containsString(rule.sources, source) || !containsString(rule.targets, target) {
+			continue
+		}
+		if names, ok := rule.fields[field]; ok {
+			return names
+		}
+	}
+	return nil
+}
+
+func containsString(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
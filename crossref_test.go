@@ -0,0 +1,240 @@
+package bibtex
+
+import (
+	gotok "go/token"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/parser"
+	"github.com/jschaf/bibtex/render"
+)
+
+// entryTags returns the tags of each entry in f as rendered text, by key.
+func entryTags(t *testing.T, f *ast.File) map[string]map[string]string {
+	t.Helper()
+	entries := make(map[string]map[string]string)
+	for _, decl := range f.Entries {
+		d, ok := decl.(*ast.BibDecl)
+		if !ok {
+			continue
+		}
+		tags := make(map[string]string)
+		for _, tag := range d.Tags {
+			if n, ok := tag.Value.(*ast.Number); ok {
+				tags[tag.Name] = n.Value
+				continue
+			}
+			sb := &strings.Builder{}
+			if err := render.NewTextRenderer().Render(sb, tag.Value); err != nil {
+				t.Fatal(err)
+			}
+			tags[tag.Name] = sb.String()
+		}
+		entries[d.Key.Name] = tags
+	}
+	return entries
+}
+
+func TestCrossrefResolver_Resolve(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		opts []CrossrefOption
+		want map[string]map[string]string
+	}{
+		{
+			name: "bibtex",
+			src: `
+				@inproceedings{paper, title = {Foo}, crossref = {conf19}}
+				@proceedings{conf19, title = {Conf}, booktitle = {Proc. Conf}, year = 2019}
+			`,
+			want: map[string]map[string]string{
+				"paper":  {"title": "Foo", "crossref": "conf19", "booktitle": "Proc. Conf", "year": "2019"},
+				"conf19": {"title": "Conf", "booktitle": "Proc. Conf", "year": "2019"},
+			},
+		},
+		{
+			name: "case insensitive key",
+			src: `
+				@inproceedings{paper, crossref = {CONF-19}}
+				@proceedings{conf-19, year = 2019}
+			`,
+			want: map[string]map[string]string{
+				"paper":   {"crossref": "CONF-19", "year": "2019"},
+				"conf-19": {"year": "2019"},
+			},
+		},
+		{
+			name: "nested",
+			src: `
+				@inproceedings{paper, crossref = {conf19}}
+				@proceedings{conf19, year = 2019, crossref = {series}}
+				@mvproceedings{series, publisher = {ACM}}
+			`,
+			want: map[string]map[string]string{
+				"paper":  {"crossref": "conf19", "year": "2019", "publisher": "ACM"},
+				"conf19": {"crossref": "series", "year": "2019", "publisher": "ACM"},
+				"series": {"publisher": "ACM"},
+			},
+		},
+		{
+			name: "biblatex",
+			src: `
+				@inproceedings{paper, title = {Foo}, crossref = {conf19}}
+				@proceedings{conf19, title = {Conf}, subtitle = {Sub}, shorttitle = {C}, year = 2019}
+			`,
+			opts: []CrossrefOption{WithBiblatexInheritance()},
+			want: map[string]map[string]string{
+				"paper":  {"title": "Foo", "crossref": "conf19", "booktitle": "Conf", "booksubtitle": "Sub", "year": "2019"},
+				"conf19": {"title": "Conf", "subtitle": "Sub", "shorttitle": "C", "year": "2019"},
+			},
+		},
+		{
+			name: "biblatex book author",
+			src: `
+				@inbook{chap, crossref = {book}}
+				@book{book, author = {Knuth}, title = {TAOCP}}
+			`,
+			opts: []CrossrefOption{WithBiblatexInheritance()},
+			want: map[string]map[string]string{
+				"chap": {"crossref": "book", "author": "Knuth", "bookauthor": "Knuth", "booktitle": "TAOCP"},
+				"book": {"author": "Knuth", "title": "TAOCP"},
+			},
+		},
+		{
+			name: "xdata",
+			src: `
+				@xdata{acm, publisher = {ACM}, location = {New York}}
+				@xdata{year, year = 2019, publisher = {IEEE}}
+				@book{book, xdata = {acm, year}, location = {NYC}}
+			`,
+			want: map[string]map[string]string{
+				"acm":  {"publisher": "ACM", "location": "New York"},
+				"year": {"year": "2019", "publisher": "IEEE"},
+				"book": {"xdata": "acm, year", "location": "NYC", "publisher": "ACM", "year": "2019"},
+			},
+		},
+		{
+			name: "min crossrefs",
+			src: `
+				@inproceedings{a, crossref = {conf19}}
+				@inproceedings{b, crossref = {conf19}}
+				@inproceedings{c, crossref = {conf20}}
+				@proceedings{conf19, year = 2019}
+				@proceedings{conf20, year = 2020}
+			`,
+			opts: []CrossrefOption{WithMinCrossrefs(2)},
+			want: map[string]map[string]string{
+				"a":      {"crossref": "conf19", "year": "2019"},
+				"b":      {"crossref": "conf19", "year": "2019"},
+				"c":      {"year": "2020"},
+				"conf19": {"year": "2019"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := gotok.NewFileSet()
+			f, err := parser.ParseFile(fset, "", tt.src, parser.ParseStrings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := NewCrossrefResolver(fset, tt.opts...).Resolve(f); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, entryTags(t, f)); diff != "" {
+				t.Errorf("CrossrefResolver.Resolve() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCrossrefResolver_Resolve_minCrossrefsScope(t *testing.T) {
+	src := `
+		@inproceedings{a, crossref = {conf19}}
+		@proceedings{conf19, year = 2019}
+	`
+	fset := gotok.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewCrossrefResolver(fset, WithMinCrossrefs(2)).Resolve(f); err != nil {
+		t.Fatal(err)
+	}
+	if obj := f.Scope.Lookup("conf19"); obj != nil {
+		t.Errorf("expected conf19 removed from scope; got %v", obj)
+	}
+	if obj := f.Scope.Lookup("a"); obj == nil {
+		t.Error("expected a in scope")
+	}
+}
+
+func TestCrossrefResolver_Resolve_package(t *testing.T) {
+	fset := gotok.NewFileSet()
+	paper, err := parser.ParseFile(fset, "a.bib", `@inproceedings{paper, crossref = {conf19}}`, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := parser.ParseFile(fset, "b.bib", `@proceedings{conf19, year = 2019}`, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg := &ast.Package{Files: map[string]*ast.File{"a.bib": paper, "b.bib": conf}}
+	if err := NewCrossrefResolver(fset).Resolve(pkg); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[string]string{
+		"paper": {"crossref": "conf19", "year": "2019"},
+	}
+	if diff := cmp.Diff(want, entryTags(t, paper)); diff != "" {
+		t.Errorf("CrossrefResolver.Resolve() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCrossrefResolver_Resolve_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "missing parent",
+			src:  "@inproceedings{paper,\n  crossref = {conf19}}",
+			want: `2:14: crossref "conf19": entry not found`,
+		},
+		{
+			name: "missing xdata",
+			src:  `@book{book, xdata = {acm}}`,
+			want: `1:21: xdata "acm": entry not found`,
+		},
+		{
+			name: "cycle",
+			src:  "@misc{a, crossref = {b}}\n@misc{b, crossref = {c}}\n@misc{c, crossref = {a}}",
+			want: `3:21: crossref cycle: a -> b -> c -> a`,
+		},
+		{
+			name: "self reference",
+			src:  `@misc{a, crossref = {a}}`,
+			want: `1:21: crossref cycle: a -> a`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := gotok.NewFileSet()
+			f, err := parser.ParseFile(fset, "", tt.src, parser.ParseStrings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = NewCrossrefResolver(fset).Resolve(f)
+			if err == nil {
+				t.Fatal("expected error but had none")
+			}
+			if diff := cmp.Diff(tt.want, err.Error()); diff != "" {
+				t.Errorf("CrossrefResolver.Resolve() error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,531 @@
+package csl
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/names"
+	"github.com/jschaf/bibtex/parser"
+	"github.com/jschaf/bibtex/render"
+)
+
+const testBib = `
+@article{knuth84,
+  title = {Literate Programming},
+  author = {Knuth, Donald E.},
+  journal = {The Computer Journal},
+  volume = 27,
+  number = 2,
+  pages = {97--111},
+  year = 1984,
+  month = may,
+}
+@book{taocp,
+  title = {The Art of Computer Programming},
+  author = {Knuth, Donald E.},
+  edition = {3},
+  publisher = {Addison-Wesley},
+  address = {Reading, MA},
+  year = 1997,
+}
+@book{taocp2,
+  title = {Seminumerical Algorithms},
+  author = {Knuth, Donald E.},
+  publisher = {Addison-Wesley},
+  year = 1997,
+}
+@inproceedings{conf,
+  title = {A Paper},
+  author = {Doe, Jane and van Beethoven, Ludwig and King, Martin Luther and Smith, Anne},
+  booktitle = {Proc. of the Conf},
+  pages = {1210--1225},
+  year = 2020,
+}
+@inproceedings{conf2,
+  title = {Another Paper},
+  author = {Doe, Jane and Roe, Rick and Poe, Edgar and Smith, Anne},
+  booktitle = {Proc. of the Conf},
+  year = 2020,
+}
+@book{edited,
+  title = {Edited Volume},
+  editor = {Doe, John and Roe, Rick},
+  publisher = {Pub},
+  year = 2000,
+}
+@misc{anon,
+  title = {No Author},
+}
+`
+
+// authorDateStyle is an author-date style like APA.
+const authorDateStyle = `<?xml version="1.0" encoding="utf-8"?>
+<style xmlns="http://purl.org/net/xbiblio/csl" class="in-text" version="1.0"
+       demote-non-dropping-particle="never" page-range-format="expanded">
+  <info><title>Test Author-Date</title></info>
+  <macro name="author">
+    <names variable="author">
+      <name name-as-sort-order="all" and="symbol" sort-separator=", "
+            initialize-with=". " delimiter=", " delimiter-precedes-last="always"/>
+      <label form="short" prefix=" (" suffix=")" text-case="capitalize-first"/>
+      <substitute>
+        <names variable="editor"/>
+        <text variable="title"/>
+      </substitute>
+    </names>
+  </macro>
+  <macro name="author-short">
+    <names variable="author">
+      <name form="short" and="symbol" delimiter=", " initialize-with=". "/>
+      <substitute>
+        <names variable="editor"/>
+        <text variable="title" quotes="true"/>
+      </substitute>
+    </names>
+  </macro>
+  <macro name="issued">
+    <choose>
+      <if variable="issued">
+        <date variable="issued"><date-part name="year"/></date>
+      </if>
+      <else>
+        <text term="no date" form="short"/>
+      </else>
+    </choose>
+  </macro>
+  <citation et-al-min="3" et-al-use-first="1" disambiguate-add-year-suffix="true"
+            disambiguate-add-names="true" disambiguate-add-givenname="true">
+    <sort>
+      <key macro="author"/>
+      <key macro="issued"/>
+    </sort>
+    <layout prefix="(" suffix=")" delimiter="; ">
+      <group delimiter=", ">
+        <text macro="author-short"/>
+        <text macro="issued"/>
+      </group>
+    </layout>
+  </citation>
+  <bibliography et-al-min="8" et-al-use-first="6" subsequent-author-substitute="———">
+    <sort>
+      <key macro="author"/>
+      <key variable="issued"/>
+      <key variable="title"/>
+    </sort>
+    <layout suffix=".">
+      <group delimiter=". ">
+        <text macro="author"/>
+        <group prefix="(" suffix=")"><text macro="issued"/></group>
+        <text variable="title" font-style="italic"/>
+        <group delimiter=", ">
+          <text variable="container-title" font-style="italic"/>
+          <group>
+            <text variable="volume" font-style="italic"/>
+            <text variable="issue" prefix="(" suffix=")"/>
+          </group>
+          <text variable="page"/>
+        </group>
+        <group delimiter=": ">
+          <text variable="publisher-place"/>
+          <text variable="publisher"/>
+        </group>
+      </group>
+    </layout>
+  </bibliography>
+</style>`
+
+// numericStyle is a numeric style like IEEE.
+const numericStyle = `<?xml version="1.0" encoding="utf-8"?>
+<style xmlns="http://purl.org/net/xbiblio/csl" class="in-text" version="1.0"
+       page-range-format="minimal">
+  <info><title>Test Numeric</title></info>
+  <locale>
+    <terms>
+      <term name="et-al">and others</term>
+    </terms>
+  </locale>
+  <macro name="edition">
+    <choose>
+      <if is-numeric="edition">
+        <group delimiter=" ">
+          <number variable="edition" form="ordinal"/>
+          <text term="edition" form="short"/>
+        </group>
+      </if>
+    </choose>
+  </macro>
+  <citation>
+    <sort>
+      <key variable="citation-number"/>
+    </sort>
+    <layout prefix="[" suffix="]" delimiter=", ">
+      <text variable="citation-number"/>
+    </layout>
+  </citation>
+  <bibliography et-al-min="3" et-al-use-first="1">
+    <layout>
+      <text variable="citation-number" prefix="[" suffix="] "/>
+      <group delimiter=", " suffix=".">
+        <names variable="author">
+          <name initialize-with=". " and="text" delimiter=", "/>
+          <et-al font-style="italic"/>
+        </names>
+        <text variable="title" quotes="true"/>
+        <group delimiter=" ">
+          <text term="in" text-case="capitalize-first"/>
+          <text variable="container-title" font-style="italic"/>
+        </group>
+        <text macro="edition"/>
+        <text variable="publisher"/>
+        <group delimiter=" ">
+          <label variable="page" form="short"/>
+          <text variable="page"/>
+        </group>
+        <date variable="issued" form="text" date-parts="year-month">
+          <date-part name="month" form="short"/>
+        </date>
+      </group>
+    </layout>
+  </bibliography>
+</style>`
+
+func newTestProcessor(t *testing.T, style string, opts ...Option) *Processor {
+	t.Helper()
+	s, err := ParseStyle(strings.NewReader(style))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := bibtex.Read(strings.NewReader(testBib))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewProcessor(s, entries, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func renderText(t *testing.T, x *ast.ParsedText) string {
+	t.Helper()
+	sb := &strings.Builder{}
+	if err := render.NewTextRenderer().Render(sb, x); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func renderHTML(t *testing.T, x *ast.ParsedText) string {
+	t.Helper()
+	sb := &strings.Builder{}
+	if err := render.NewHTMLRenderer().Render(sb, x); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
+
+func TestProcessor_Cite(t *testing.T) {
+	tests := []struct {
+		style string
+		keys  []string
+		want  string
+	}{
+		{authorDateStyle, []string{"knuth84"}, "(Knuth, 1984)"},
+		{authorDateStyle, []string{"taocp"}, "(Knuth, 1997b)"},
+		{authorDateStyle, []string{"taocp2", "knuth84"}, "(Knuth, 1984; Knuth, 1997a)"},
+		{authorDateStyle, []string{"conf"}, "(Doe, van Beethoven, et al., 2020)"},
+		{authorDateStyle, []string{"conf2"}, "(Doe, Roe, et al., 2020)"},
+		{authorDateStyle, []string{"edited"}, "(Doe & Roe, 2000)"},
+		{authorDateStyle, []string{"anon"}, "(“No Author,” n.d.)"},
+		{numericStyle, []string{"knuth84"}, "[1]"},
+		{numericStyle, []string{"edited", "knuth84", "conf"}, "[1, 2, 3]"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.keys, ","), func(t *testing.T) {
+			p := newTestProcessor(t, tt.style)
+			got, err := p.Cite(tt.keys...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, renderText(t, got)); diff != "" {
+				t.Errorf("Cite() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessor_Cite_sequence(t *testing.T) {
+	// Without a bibliography sort, citation numbers follow the order of the
+	// first cite and uncited entries follow in entry order.
+	p := newTestProcessor(t, numericStyle)
+	cites := [][]string{{"conf"}, {"edited", "conf"}, {"knuth84"}, {"conf", "edited"}}
+	want := []string{"[1]", "[1, 2]", "[3]", "[1, 2]"}
+	got := make([]string, len(cites))
+	for i, keys := range cites {
+		x, err := p.Cite(keys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[i] = renderText(t, x)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Cite() mismatch (-want +got):\n%s", diff)
+	}
+
+	bib, err := p.Bibliography()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotBib := make([]string, len(bib))
+	for i, x := range bib {
+		gotBib[i] = renderText(t, x)
+	}
+	wantBib := []string{
+		"[1] J. Doe and others, “A Paper,” In Proc. of the Conf, pp. 1210–25, 2020.",
+		"[2] “Edited Volume,” Pub, 2000.",
+		"[3] D. E. Knuth, “Literate Programming,” In The Computer Journal, pp. 97–111, May 1984.",
+		"[4] D. E. Knuth, “The Art of Computer Programming,” 3rd ed., Addison-Wesley, 1997.",
+		"[5] D. E. Knuth, “Seminumerical Algorithms,” Addison-Wesley, 1997.",
+		"[6] J. Doe and others, “Another Paper,” In Proc. of the Conf, 2020.",
+		"[7] “No Author.”",
+	}
+	if diff := cmp.Diff(wantBib, gotBib); diff != "" {
+		t.Errorf("Bibliography() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProcessor_Cite_position(t *testing.T) {
+	const noteStyle = `<?xml version="1.0" encoding="utf-8"?>
+<style xmlns="http://purl.org/net/xbiblio/csl" class="note" version="1.0">
+  <info><title>Test Note</title></info>
+  <citation near-note-distance="3">
+    <layout suffix="." delimiter="; ">
+      <choose>
+        <if position="ibid">
+          <text term="ibid" text-case="capitalize-first"/>
+        </if>
+        <else-if position="near-note">
+          <text variable="title" font-style="italic" suffix=" (near)"/>
+        </else-if>
+        <else-if position="subsequent">
+          <text variable="title" font-style="italic"/>
+        </else-if>
+        <else>
+          <group delimiter=", ">
+            <names variable="author"><name form="short"/></names>
+            <text variable="title" font-style="italic"/>
+          </group>
+        </else>
+      </choose>
+    </layout>
+  </citation>
+</style>`
+	p := newTestProcessor(t, noteStyle)
+	cites := [][]string{
+		{"knuth84"},
+		{"knuth84"},
+		{"knuth84", "knuth84", "taocp"},
+		{"edited"},
+		{"anon"},
+		{"taocp"},
+		{"knuth84"},
+	}
+	want := []string{
+		"Knuth, Literate Programming.",
+		"Ibid.",
+		"Ibid.; Ibid.; Knuth, The Art of Computer Programming.",
+		"Edited Volume.",
+		"No Author.",
+		"The Art of Computer Programming (near).",
+		"Literate Programming.",
+	}
+	got := make([]string, len(cites))
+	for i, keys := range cites {
+		x, err := p.Cite(keys...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[i] = renderText(t, x)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Cite() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestProcessor_Cite_unknownKey(t *testing.T) {
+	p := newTestProcessor(t, numericStyle)
+	if _, err := p.Cite("nope"); err == nil {
+		t.Error("Cite(nope) want error; got nil")
+	}
+}
+
+func TestProcessor_Bibliography(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  []string
+	}{
+		{
+			name:  "author-date",
+			style: authorDateStyle,
+			want: []string{
+				"Doe, J., Roe, R., Poe, E., & Smith, A. (2020). Another Paper. Proc. of the Conf.",
+				"Doe, J., van Beethoven, L., King, M. L., & Smith, A. (2020). A Paper. Proc. of the Conf, 1210–1225.",
+				"Doe, J., & Roe, R. (Eds.). (2000). Edited Volume. Pub.",
+				"Knuth, D. E. (1984). Literate Programming. The Computer Journal, 27(2), 97–111.",
+				"———. (1997a). Seminumerical Algorithms. Addison-Wesley.",
+				"———. (1997b). The Art of Computer Programming. Reading, MA: Addison-Wesley.",
+				"No Author. (n.d.).",
+			},
+		},
+		{
+			name:  "numeric",
+			style: numericStyle,
+			want: []string{
+				"[1] D. E. Knuth, “Literate Programming,” In The Computer Journal, pp. 97–111, May 1984.",
+				"[2] D. E. Knuth, “The Art of Computer Programming,” 3rd ed., Addison-Wesley, 1997.",
+				"[3] D. E. Knuth, “Seminumerical Algorithms,” Addison-Wesley, 1997.",
+				"[4] J. Doe and others, “A Paper,” In Proc. of the Conf, pp. 1210–25, 2020.",
+				"[5] J. Doe and others, “Another Paper,” In Proc. of the Conf, 2020.",
+				"[6] “Edited Volume,” Pub, 2000.",
+				"[7] “No Author.”",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProcessor(t, tt.style)
+			bib, err := p.Bibliography()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(bib))
+			for i, x := range bib {
+				got[i] = renderText(t, x)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Bibliography() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessor_Bibliography_HTML(t *testing.T) {
+	p := newTestProcessor(t, numericStyle)
+	bib, err := p.Bibliography()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[4] J. Doe <i>and others</i>, “A Paper,” In <i>Proc. of the Conf</i>, pp. 1210–25, 2020."
+	if diff := cmp.Diff(want, renderHTML(t, bib[3])); diff != "" {
+		t.Errorf("Bibliography() HTML mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithLocale(t *testing.T) {
+	const deDE = `<?xml version="1.0" encoding="utf-8"?>
+<locale xmlns="http://purl.org/net/xbiblio/csl" version="1.0" xml:lang="de-DE">
+  <style-options punctuation-in-quote="false"/>
+  <terms>
+    <term name="and">und</term>
+    <term name="open-quote">„</term>
+    <term name="close-quote">“</term>
+    <term name="month-05" form="short">Mai</term>
+  </terms>
+</locale>`
+	l, err := ParseLocale(strings.NewReader(deDE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Lang != "de-DE" {
+		t.Errorf("ParseLocale() Lang = %q; want de-DE", l.Lang)
+	}
+	p := newTestProcessor(t, numericStyle, WithLocale(l))
+	bib, err := p.Bibliography()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[1] D. E. Knuth, „Literate Programming“, In The Computer Journal, pp. 97–111, Mai 1984."
+	if diff := cmp.Diff(want, renderText(t, bib[0])); diff != "" {
+		t.Errorf("Bibliography() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParseStyle_errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+	}{
+		{"not xml", "{"},
+		{"not style", `<locale xmlns="http://purl.org/net/xbiblio/csl" version="1.0"/>`},
+		{"bad version", `<style xmlns="http://purl.org/net/xbiblio/csl" version="0.8"><citation><layout/></citation></style>`},
+		{"no citation", `<style xmlns="http://purl.org/net/xbiblio/csl" version="1.0"/>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseStyle(strings.NewReader(tt.style)); err == nil {
+				t.Errorf("ParseStyle() want error; got nil")
+			}
+		})
+	}
+}
+
+func TestInitials(t *testing.T) {
+	tests := []struct {
+		given, with string
+		hyphen      bool
+		want        string
+	}{
+		{"Donald E.", ". ", true, "D. E."},
+		{"Jean-Paul", ".", true, "J.-P."},
+		{"Jean-Paul", ". ", false, "J. P."},
+		{"Martin Luther", "", true, "ML"},
+		{"{Ch}ristophe", ". ", true, "Ch."},
+		{"{\\'E}mile", ".", true, "É."},
+	}
+	for _, tt := range tests {
+		x, err := parser.ParseExpr("{Last, " + tt.given + "}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		authors, err := bibtex.ExtractAuthors(x.(*ast.ParsedText))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ins := names.GivenInitials(authors[0])
+		if got := initials(ins, tt.with, tt.hyphen); got != tt.want {
+			t.Errorf("initials(%q, %q, %t) = %q; want %q", tt.given, tt.with, tt.hyphen, got, tt.want)
+		}
+	}
+}
+
+func TestPageRange(t *testing.T) {
+	tests := []struct {
+		format, pages, want string
+	}{
+		{"", "321-28", "321–328"},
+		{"expanded", "42-5", "42–45"},
+		{"minimal", "321-328", "321–8"},
+		{"minimal-two", "321-328", "321–28"},
+		{"chicago", "71-72", "71–72"},
+		{"chicago", "100-104", "100–104"},
+		{"chicago", "101-108", "101–8"},
+		{"chicago", "321-328", "321–28"},
+		{"chicago", "1496-1504", "1496–1504"},
+		{"minimal", "iv-x", "iv–x"},
+	}
+	for _, tt := range tests {
+		root := &node{}
+		if tt.format != "" {
+			root.Attrs = []xml.Attr{{Name: xml.Name{Local: "page-range-format"}, Value: tt.format}}
+		}
+		c := &context{p: &Processor{style: &Style{root: root}, locale: defaultLocale()}}
+		if got := c.pageRange(tt.pages); got != tt.want {
+			t.Errorf("pageRange(%q) with format %q = %q; want %q", tt.pages, tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package csl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+// renderDate renders a cs:date element. Localized dates, with a form
+// attribute, use the date format of the locale with the date parts of n as
+// overrides. Non-localized dates render the date-part children of n.
+func (c *context) renderDate(n *node) result {
+	v := n.attr("variable")
+	d, ok := c.it.dates[v]
+	if !ok || c.suppressed[v] {
+		return c.called(v, nil)
+	}
+	if c.sorting {
+		return c.called(v, textSpans(fmt.Sprintf("%04d%02d%02d", d.year, d.month, d.day)))
+	}
+
+	parts := n.children("date-part")
+	delimiter := n.attr("delimiter")
+	if form := n.attr("form"); form != "" {
+		localized, ok := c.p.locale.dates[form]
+		if !ok {
+			return c.called(v, nil)
+		}
+		overrides := make(map[string]*node)
+		for _, p := range parts {
+			overrides[p.attr("name")] = p
+		}
+		show := map[string]bool{"year": true, "month": true, "day": true}
+		switch n.attr("date-parts") {
+		case "year":
+			show["month"], show["day"] = false, false
+		case "year-month":
+			show["day"] = false
+		}
+		parts = nil
+		for _, p := range localized.children("date-part") {
+			name := p.attr("name")
+			if !show[name] {
+				continue
+			}
+			if o, ok := overrides[name]; ok {
+				p = mergeAttrs(p, o)
+			}
+			parts = append(parts, p)
+		}
+		delimiter = localized.attr("delimiter")
+	}
+
+	var spans []span
+	for _, p := range parts {
+		s := c.datePart(p, d)
+		if s == "" {
+			continue
+		}
+		if p.attr("name") == "year" && v == "issued" && !c.seenYear && !c.p.explicitYearSuffix {
+			c.seenYear = true
+			s += c.it.yearSuffix
+		}
+		if len(spans) > 0 {
+			spans = append(spans, textSpans(delimiter)...)
+		}
+		spans = append(spans, c.decorate(p, textSpans(s))...)
+	}
+	return c.called(v, spans)
+}
+
+// mergeAttrs returns a copy of the date-part p with the attributes of o.
+func mergeAttrs(p, o *node) *node {
+	merged := &node{XMLName: p.XMLName, Attrs: append([]xml.Attr(nil), p.Attrs...)}
+	for _, a := range o.Attrs {
+		replaced := false
+		for i, b := range merged.Attrs {
+			if b.Name.Local == a.Name.Local {
+				merged.Attrs[i] = a
+				replaced = true
+			}
+		}
+		if !replaced {
+			merged.Attrs = append(merged.Attrs, a)
+		}
+	}
+	return merged
+}
+
+// datePart renders the date-part p of d without decoration or the empty
+// string if the part is missing.
+func (c *context) datePart(p *node, d date) string {
+	switch p.attr("name") {
+	case "year":
+		if d.year == 0 {
+			return ""
+		}
+		if p.attr("form") == "short" {
+			return fmt.Sprintf("%02d", d.year%100)
+		}
+		if d.year < 0 {
+			bc, _ := c.p.locale.term("bc", "long", false)
+			return strconv.Itoa(-d.year) + bc
+		}
+		return strconv.Itoa(d.year)
+	case "month":
+		if d.month < 1 || d.month > 12 {
+			return ""
+		}
+		switch p.attr("form") {
+		case "numeric":
+			return strconv.Itoa(d.month)
+		case "numeric-leading-zeros":
+			return fmt.Sprintf("%02d", d.month)
+		default:
+			s, _ := c.p.locale.term(fmt.Sprintf("month-%02d", d.month), p.attr("form"), false)
+			return s
+		}
+	case "day":
+		if d.day == 0 {
+			return ""
+		}
+		switch p.attr("form") {
+		case "numeric-leading-zeros":
+			return fmt.Sprintf("%02d", d.day)
+		case "ordinal":
+			return c.p.locale.ordinal(d.day)
+		default:
+			return strconv.Itoa(d.day)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,247 @@
+package csl
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/names"
+	"github.com/jschaf/bibtex/render"
+)
+
+// item is a bibtex entry mapped onto CSL variables.
+type item struct {
+	key   string
+	typ   string              // the CSL type, like "article-journal"
+	vars  map[string]string   // standard and number variables
+	names map[string]nameList // name variables, like "author"
+	dates map[string]date     // date variables, like "issued"
+
+	// Disambiguation state.
+	etAlUseFirst int  // if > 0, overrides et-al-use-first for names
+	givenLevel   int  // 1 shows initials, 2 shows full given names
+	disambiguate bool // the disambiguate condition is true
+	yearSuffix   string
+	citationNum  int
+
+	// Citation state. LastNote is the 1-based index of the last citation
+	// that cited the item, or 0 if the item wasn't cited.
+	lastNote int
+}
+
+// nameList is the names of a name variable. Others is true if the names end
+// with "and others".
+type nameList struct {
+	names  []name
+	others bool
+}
+
+// name is the plain text parts of a person's name.
+type name struct {
+	given, particle, family, suffix string
+	initials                        []names.Initial // of the given names
+}
+
+// date is a date with optional month and day. Zero means missing.
+type date struct {
+	year, month, day int
+}
+
+// entryTypes maps bibtex entry types to CSL item types.
+var entryTypes = map[string]string{
+	bibtex.EntryArticle:       "article-journal",
+	bibtex.EntryBook:          "book",
+	bibtex.EntryBooklet:       "pamphlet",
+	bibtex.EntryInBook:        "chapter",
+	bibtex.EntryInCollection:  "chapter",
+	bibtex.EntryInProceedings: "paper-conference",
+	"conference":              "paper-conference",
+	bibtex.EntryManual:        "book",
+	bibtex.EntryMastersThesis: "thesis",
+	bibtex.EntryMisc:          "document",
+	bibtex.EntryPhDThesis:     "thesis",
+	bibtex.EntryProceedings:   "book",
+	bibtex.EntryTechReport:    "report",
+	bibtex.EntryUnpublished:   "manuscript",
+	"online":                  "webpage",
+	"www":                     "webpage",
+}
+
+// fieldVars maps bibtex fields to CSL variables with the same meaning for all
+// entry types.
+var fieldVars = map[bibtex.Field]string{
+	bibtex.FieldTitle:   "title",
+	bibtex.FieldSeries:  "collection-title",
+	bibtex.FieldAddress: "publisher-place",
+	bibtex.FieldVolume:  "volume",
+	bibtex.FieldEdition: "edition",
+	bibtex.FieldChapter: "chapter-number",
+	bibtex.FieldNote:    "note",
+	bibtex.FieldURL:     "URL",
+	"abstract":          "abstract",
+	"isbn":              "ISBN",
+	"issn":              "ISSN",
+	"keywords":          "keyword",
+	"language":          "language",
+	"shorttitle":        "title-short",
+	"eventtitle":        "event-title",
+	"venue":             "event-place",
+	"version":           "version",
+}
+
+// newItem maps the entry onto CSL variables.
+func newItem(e bibtex.Entry) *item {
+	it := &item{
+		key:   e.Key,
+		typ:   entryTypes[strings.ToLower(e.Type)],
+		vars:  make(map[string]string),
+		names: make(map[string]nameList),
+		dates: make(map[string]date),
+	}
+	if it.typ == "" {
+		it.typ = "document"
+	}
+	it.vars["citation-key"] = e.Key
+
+	for field, v := range fieldVars {
+		it.setVar(v, fieldText(e, field))
+	}
+	if doi := e.DOI(); doi != "" {
+		it.vars["DOI"] = doi
+	}
+	if first, last := e.Pages(); first != "" {
+		it.vars["page-first"] = first
+		it.vars["page"] = first
+		if last != "" {
+			it.vars["page"] = first + "-" + last
+		}
+	}
+
+	switch strings.ToLower(e.Type) {
+	case bibtex.EntryArticle:
+		it.setVar("container-title", fieldText(e, bibtex.FieldJournal))
+		it.setVar("issue", fieldText(e, bibtex.FieldNumber))
+	case bibtex.EntryInBook:
+		// An inbook is a part of a book, like a chapter, without its own title.
+		it.setVar("container-title", fieldText(e, bibtex.FieldTitle))
+		delete(it.vars, "title")
+	case bibtex.EntryInCollection, bibtex.EntryInProceedings, "conference":
+		it.setVar("container-title", fieldText(e, bibtex.FieldBookTitle))
+	case bibtex.EntryPhDThesis:
+		it.setVar("genre", "PhD thesis")
+	case bibtex.EntryMastersThesis:
+		it.setVar("genre", "Master's thesis")
+	}
+	if _, ok := it.vars["issue"]; !ok {
+		it.setVar("number", fieldText(e, bibtex.FieldNumber))
+	}
+	it.setVar("genre", fieldText(e, bibtex.FieldType))
+	it.setVar("container-title", fieldText(e, "journaltitle"))
+	for _, f := range []bibtex.Field{bibtex.FieldPublisher, bibtex.FieldSchool, bibtex.FieldInstitution, bibtex.FieldOrganization} {
+		if _, ok := it.vars["publisher"]; !ok {
+			it.setVar("publisher", fieldText(e, f))
+		}
+	}
+
+	for _, v := range []string{"author", "editor", "translator"} {
+		if authors, ok := e.Tags[v].(ast.Authors); ok {
+			it.names[v] = toNameList(authors)
+		}
+	}
+	if d, ok := entryDate(e); ok {
+		it.dates["issued"] = d
+	}
+	return it
+}
+
+// setVar sets the variable if value isn't empty.
+func (it *item) setVar(v, value string) {
+	if value != "" {
+		it.vars[v] = value
+	}
+}
+
+// fieldText returns the plain text of a field or the empty string.
+func fieldText(e bibtex.Entry, f bibtex.Field) string {
+	var s string
+	switch x := e.Tags[f].(type) {
+	case *ast.Number:
+		s = x.Value
+	case *ast.Text:
+		s = x.Value
+	case *ast.UnparsedText:
+		s = x.Value
+	case *ast.ParsedText:
+		sb := &strings.Builder{}
+		if err := render.NewTextRenderer().Render(sb, x); err != nil {
+			return ""
+		}
+		s = sb.String()
+	}
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func toNameList(authors ast.Authors) nameList {
+	var l nameList
+	for _, a := range authors {
+		if a.IsOthers() {
+			l.others = true
+			continue
+		}
+		l.names = append(l.names, name{
+			given:    partText(a.First),
+			particle: partText(a.Prefix),
+			family:   partText(a.Last),
+			suffix:   partText(a.Suffix),
+			initials: names.GivenInitials(a),
+		})
+	}
+	return l
+}
+
+func partText(x ast.Expr) string {
+	if x == nil {
+		return ""
+	}
+	sb := &strings.Builder{}
+	if err := render.NewTextRenderer().Render(sb, x); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// entryDate returns the date of the entry from the biblatex date field, like
+// "2019-08-15", or from the year, month and day fields.
+func entryDate(e bibtex.Entry) (date, bool) {
+	if s := fieldText(e, "date"); s != "" {
+		parts := strings.SplitN(s, "-", 3)
+		var d date
+		for i, p := range parts {
+			n, err := strconv.Atoi(p)
+			if err != nil {
+				break
+			}
+			switch i {
+			case 0:
+				d.year = n
+			case 1:
+				d.month = n
+			case 2:
+				d.day = n
+			}
+		}
+		if d.year != 0 {
+			return d, true
+		}
+	}
+	y, ok := e.Year()
+	if !ok {
+		return date{}, false
+	}
+	d := date{year: y, month: int(e.Month())}
+	if day, err := strconv.Atoi(fieldText(e, "day")); err == nil && d.month != 0 {
+		d.day = day
+	}
+	return d, true
+}
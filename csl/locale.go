@@ -0,0 +1,153 @@
+package csl
+
+import (
+	_ "embed"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+//go:embed locales-en-US.xml
+var enUSLocale string
+
+// Locale is a parsed CSL locale: the terms, date formats and options for a
+// language.
+type Locale struct {
+	Lang string // the language, like "en-US"
+
+	terms map[termKey]term
+	dates map[string]*node // date formats by form, "text" or "numeric"
+	// punctuationInQuote moves commas and periods inside closing quotes.
+	punctuationInQuote *bool
+}
+
+type termKey struct {
+	name, form string
+}
+
+type term struct {
+	single, multiple string
+}
+
+// ParseLocale parses a CSL locale file, like locales-de-DE.xml.
+func ParseLocale(r io.Reader) (*Locale, error) {
+	root := &node{}
+	if err := xml.NewDecoder(r).Decode(root); err != nil {
+		return nil, fmt.Errorf("parse csl locale: %w", err)
+	}
+	if root.XMLName.Local != "locale" {
+		return nil, fmt.Errorf("parse csl locale: root element is <%s>, want <locale>", root.XMLName.Local)
+	}
+	return newLocale(root), nil
+}
+
+// defaultLocale returns the built-in en-US locale.
+func defaultLocale() *Locale {
+	l, err := ParseLocale(strings.NewReader(enUSLocale))
+	if err != nil {
+		panic(fmt.Sprintf("parse built-in locale: %s", err))
+	}
+	return l
+}
+
+// newLocale creates a locale from a <locale> element of a locale file or a
+// style.
+func newLocale(n *node) *Locale {
+	l := &Locale{
+		Lang:  n.attr("lang"),
+		terms: make(map[termKey]term),
+		dates: make(map[string]*node),
+	}
+	if opts := n.child("style-options"); opts != nil && opts.hasAttr("punctuation-in-quote") {
+		piq := opts.attr("punctuation-in-quote") == "true"
+		l.punctuationInQuote = &piq
+	}
+	for _, d := range n.children("date") {
+		l.dates[d.attr("form")] = d
+	}
+	if terms := n.child("terms"); terms != nil {
+		for _, t := range terms.children("term") {
+			form := t.attr("form")
+			if form == "" {
+				form = "long"
+			}
+			var tm term
+			if single := t.child("single"); single != nil {
+				tm.single = single.Text
+				tm.multiple = single.Text
+				if multiple := t.child("multiple"); multiple != nil {
+					tm.multiple = multiple.Text
+				}
+			} else {
+				tm.single = t.Text
+				tm.multiple = t.Text
+			}
+			l.terms[termKey{t.attr("name"), form}] = tm
+		}
+	}
+	return l
+}
+
+// merge overrides the terms, dates and options of l with those of o.
+func (l *Locale) merge(o *Locale) {
+	for k, t := range o.terms {
+		l.terms[k] = t
+	}
+	for form, d := range o.dates {
+		l.dates[form] = d
+	}
+	if o.punctuationInQuote != nil {
+		l.punctuationInQuote = o.punctuationInQuote
+	}
+}
+
+// termFallbacks are the forms to try, in order, for each term form.
+var termFallbacks = map[string][]string{
+	"long":       {"long"},
+	"short":      {"short", "long"},
+	"verb":       {"verb", "long"},
+	"verb-short": {"verb-short", "verb", "long"},
+	"symbol":     {"symbol", "short", "long"},
+}
+
+// term returns the term in the form, falling back to other forms following the
+// CSL specification. The boolean is false if the locale has no such term.
+func (l *Locale) term(name, form string, plural bool) (string, bool) {
+	if form == "" {
+		form = "long"
+	}
+	for _, f := range termFallbacks[form] {
+		if t, ok := l.terms[termKey{name, f}]; ok {
+			if plural {
+				return t.multiple, true
+			}
+			return t.single, true
+		}
+	}
+	return "", false
+}
+
+// ordinal returns n with the ordinal suffix of the locale, like "2nd".
+func (l *Locale) ordinal(n int) string {
+	suffix, ok := "", false
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix, ok = l.term(fmt.Sprintf("ordinal-%02d", n%100), "long", false)
+	}
+	if !ok {
+		suffix, ok = l.term(fmt.Sprintf("ordinal-%02d", n%10), "long", false)
+	}
+	if !ok {
+		suffix, _ = l.term("ordinal", "long", false)
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
+
+// longOrdinal returns n as a word, like "second", for 1 to 10, and as an
+// ordinal otherwise.
+func (l *Locale) longOrdinal(n int) string {
+	if s, ok := l.term(fmt.Sprintf("long-ordinal-%02d", n), "long", false); ok {
+		return s
+	}
+	return l.ordinal(n)
+}
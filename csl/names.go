@@ -0,0 +1,337 @@
+package csl
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex/names"
+)
+
+// inheritedNameAttrs maps the attributes of cs:name to the names of the
+// inheritable attributes on cs:style, cs:citation and cs:bibliography.
+var inheritedNameAttrs = map[string]string{
+	"form":      "name-form",
+	"delimiter": "name-delimiter",
+}
+
+// nameAttr returns the attribute of the cs:name element n, inheriting it from
+// the citation or bibliography element and then from the style.
+func (c *context) nameAttr(n *node, attr string) string {
+	if n != nil && n.hasAttr(attr) {
+		return n.attr(attr)
+	}
+	inherited := attr
+	if a, ok := inheritedNameAttrs[attr]; ok {
+		inherited = a
+	}
+	for _, m := range []*node{c.mode, c.p.style.root} {
+		if m.hasAttr(inherited) {
+			return m.attr(inherited)
+		}
+	}
+	return ""
+}
+
+// renderNames renders a cs:names element. If all of its variables are empty,
+// it renders the first non-empty element of cs:substitute.
+func (c *context) renderNames(n *node) result {
+	r := c.renderNameVars(n, n)
+	if !r.rendered {
+		if sub := n.child("substitute"); sub != nil {
+			r.spans = nil
+			c.substituting = true
+			for _, s := range sub.Nodes {
+				var o result
+				if s.XMLName.Local == "names" && s.child("name") == nil {
+					// A names element without a name element inherits the
+					// name, et-al and label elements of the parent.
+					o = c.renderNameVars(s, n)
+					o.spans = c.decorate(s, o.spans)
+				} else {
+					o = c.render(s)
+				}
+				if len(o.spans) > 0 {
+					r.spans = o.spans
+					r.rendered = true
+					break
+				}
+			}
+			c.substituting = false
+		}
+	}
+	if c.mode == c.p.style.bibliography && !c.seenNames && !c.sorting && len(r.spans) > 0 {
+		c.seenNames = true
+		for i := range r.spans {
+			r.spans[i].names = true
+		}
+	}
+	return r
+}
+
+// renderNameVars renders the name variables of the names element n with the
+// name, et-al and label elements of the names element parent.
+func (c *context) renderNameVars(n, parent *node) result {
+	r := result{called: true}
+	nameNode := parent.child("name")
+	if nameNode == nil {
+		nameNode = &node{}
+	}
+	labelNode := parent.child("label")
+	labelFirst := false
+	for _, child := range parent.Nodes {
+		if child.XMLName.Local == "name" {
+			break
+		}
+		labelFirst = labelFirst || child == labelNode
+	}
+
+	delimiter := n.attr("delimiter")
+	if !n.hasAttr("delimiter") {
+		delimiter = c.nameAttr(nil, "names-delimiter")
+	}
+	for _, v := range strings.Fields(n.attr("variable")) {
+		list, ok := c.it.names[v]
+		if !ok || c.suppressed[v] || len(list.names) == 0 {
+			continue
+		}
+		if c.substituting {
+			c.suppressed[v] = true
+		}
+		spans := c.renderNameList(nameNode, parent.child("et-al"), list)
+		if nameNode.attr("form") != "count" && labelNode != nil && !c.sorting {
+			plural := len(list.names) > 1
+			switch labelNode.attr("plural") {
+			case "always":
+				plural = true
+			case "never":
+				plural = false
+			}
+			term, _ := c.p.locale.term(v, labelNode.attr("form"), plural)
+			label := c.decorate(labelNode, textSpans(term))
+			if labelFirst {
+				spans = append(label, spans...)
+			} else {
+				spans = append(spans, label...)
+			}
+		}
+		if len(r.spans) > 0 {
+			r.spans = append(r.spans, textSpans(delimiter)...)
+		}
+		r.spans = append(r.spans, spans...)
+		r.rendered = true
+	}
+	return r
+}
+
+// renderNameList renders the names of a name variable with the cs:name
+// element n and the optional cs:et-al element etAl.
+func (c *context) renderNameList(n, etAl *node, list nameList) []span {
+	names := list.names
+	shown := len(names)
+	truncated := list.others
+	etAlMin, _ := strconv.Atoi(c.nameAttr(n, "et-al-min"))
+	useFirst, _ := strconv.Atoi(c.nameAttr(n, "et-al-use-first"))
+	if c.it.etAlUseFirst > useFirst {
+		useFirst = c.it.etAlUseFirst
+	}
+	if etAlMin > 0 && len(names) >= etAlMin && useFirst > 0 && useFirst < len(names) && !c.sorting {
+		shown = useFirst
+		truncated = true
+	}
+	if c.nameAttr(n, "form") == "count" {
+		return textSpans(strconv.Itoa(shown))
+	}
+
+	delimiter := c.nameAttr(n, "delimiter")
+	if delimiter == "" {
+		delimiter = ", "
+	}
+	and := ""
+	switch c.nameAttr(n, "and") {
+	case "text":
+		and, _ = c.p.locale.term("and", "long", false)
+	case "symbol":
+		and = "&"
+	}
+	if c.sorting {
+		and = ""
+	}
+
+	var spans []span
+	for i := 0; i < shown; i++ {
+		if i > 0 {
+			if i == shown-1 && and != "" && !truncated {
+				if c.delimiterPrecedes(n, "delimiter-precedes-last", shown, i-1) {
+					spans = append(spans, textSpans(delimiter+and+" ")...)
+				} else {
+					spans = append(spans, textSpans(" "+and+" ")...)
+				}
+			} else {
+				spans = append(spans, textSpans(delimiter)...)
+			}
+		}
+		spans = append(spans, c.renderName(n, names[i], i)...)
+	}
+	if !truncated {
+		applyFormat(n, spans)
+		return c.nameAffixes(n, spans)
+	}
+
+	if c.nameAttr(n, "et-al-use-last") == "true" && len(names) >= shown+2 {
+		spans = append(spans, textSpans(delimiter+"… ")...)
+		spans = append(spans, c.renderName(n, names[len(names)-1], len(names)-1)...)
+		applyFormat(n, spans)
+		return c.nameAffixes(n, spans)
+	}
+	term := "et-al"
+	if etAl != nil && etAl.hasAttr("term") {
+		term = etAl.attr("term")
+	}
+	s, _ := c.p.locale.term(term, "long", false)
+	etAlSpans := textSpans(s)
+	if etAl != nil {
+		etAlSpans = c.decorate(etAl, etAlSpans)
+	}
+	if c.delimiterPrecedes(n, "delimiter-precedes-et-al", shown, shown-1) {
+		spans = append(spans, textSpans(delimiter)...)
+	} else {
+		spans = append(spans, textSpans(" ")...)
+	}
+	applyFormat(n, spans)
+	spans = append(spans, etAlSpans...)
+	return c.nameAffixes(n, spans)
+}
+
+func (c *context) nameAffixes(n *node, spans []span) []span {
+	if prefix := n.attr("prefix"); prefix != "" {
+		spans = append(textSpans(prefix), spans...)
+	}
+	return append(spans, textSpans(n.attr("suffix"))...)
+}
+
+// delimiterPrecedes returns true if the delimiter precedes the last name or
+// et al. for the delimiter-precedes-last or delimiter-precedes-et-al
+// attribute. Shown is the number of rendered names and prev the index of the
+// name before the delimiter.
+func (c *context) delimiterPrecedes(n *node, attr string, shown, prev int) bool {
+	switch c.nameAttr(n, attr) {
+	case "always":
+		return true
+	case "never":
+		return false
+	case "after-inverted-name":
+		return c.inverted(n, prev)
+	default: // contextual
+		return shown > 2 || (attr == "delimiter-precedes-et-al" && shown > 1)
+	}
+}
+
+// inverted returns true if the i'th name is rendered family name first.
+func (c *context) inverted(n *node, i int) bool {
+	if c.sorting {
+		return true
+	}
+	switch c.nameAttr(n, "name-as-sort-order") {
+	case "all":
+		return true
+	case "first":
+		return i == 0
+	}
+	return false
+}
+
+// renderName renders the i'th name of a list with the cs:name element n.
+func (c *context) renderName(n *node, nm name, i int) []span {
+	form := c.nameAttr(n, "form")
+	initializeWith := c.nameAttr(n, "initialize-with")
+	// Sort keys use full given names.
+	initialize := initializeWith != "" && c.nameAttr(n, "initialize") != "false" && !c.sorting
+	switch {
+	case c.it.givenLevel >= 2:
+		form, initialize = "long", false
+	case c.it.givenLevel == 1 && form == "short":
+		form = "long"
+		if !initialize {
+			initialize, initializeWith = true, ". "
+		}
+	}
+
+	given := nm.given
+	if initialize {
+		given = initials(nm.initials, initializeWith, c.nameAttr(n, "initialize-with-hyphen") != "false")
+	}
+	var givenPart, familyPart *node
+	for _, p := range n.children("name-part") {
+		switch p.attr("name") {
+		case "given":
+			givenPart = p
+		case "family":
+			familyPart = p
+		}
+	}
+	part := func(p *node, s string) []span {
+		if p == nil || s == "" {
+			return textSpans(s)
+		}
+		return c.decorate(p, textSpans(s))
+	}
+
+	if form == "short" {
+		return part(familyPart, strings.TrimSpace(nm.particle+" "+nm.family))
+	}
+	inverted := c.inverted(n, i)
+	demote := c.nameAttr(n, "demote-non-dropping-particle")
+	demoted := nm.particle != "" && inverted && (demote == "" || demote == "display-and-sort" || demote == "sort-only" && c.sorting)
+	family := nm.family
+	if nm.particle != "" && !demoted {
+		family = nm.particle + " " + family
+	}
+
+	if !inverted {
+		var spans []span
+		if given != "" {
+			spans = append(part(givenPart, given), textSpans(" ")...)
+		}
+		spans = append(spans, part(familyPart, family)...)
+		if nm.suffix != "" {
+			spans = append(spans, textSpans(" "+nm.suffix)...)
+		}
+		return spans
+	}
+
+	sep := c.nameAttr(n, "sort-separator")
+	switch {
+	case c.sorting:
+		sep = " "
+	case sep == "":
+		sep = ", "
+	}
+	spans := part(familyPart, family)
+	if demoted {
+		given = strings.TrimSpace(given + " " + nm.particle)
+	}
+	if given != "" {
+		spans = append(spans, textSpans(sep)...)
+		spans = append(spans, part(givenPart, given)...)
+	}
+	if nm.suffix != "" {
+		spans = append(spans, textSpans(sep+nm.suffix)...)
+	}
+	return spans
+}
+
+// initials formats the initials of the given names, like "J.-P." for
+// "Jean-Paul" with initializeWith ".". Hyphenated names keep the hyphen if
+// hyphen is true.
+func initials(ins []names.Initial, initializeWith string, hyphen bool) string {
+	sb := &strings.Builder{}
+	for i, in := range ins {
+		if i > 0 && in.Hyphen && hyphen {
+			s := strings.TrimRight(sb.String(), " ")
+			sb.Reset()
+			sb.WriteString(s + "-")
+		}
+		sb.WriteString(in.Text + initializeWith)
+	}
+	return strings.TrimSpace(sb.String())
+}
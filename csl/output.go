@@ -0,0 +1,264 @@
+package csl
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex/ast"
+)
+
+// Tri-state values of formatting attributes. Inner elements set formatting
+// first, so an outer element only sets attributes that are still unset.
+const (
+	unset = iota
+	on
+	off
+)
+
+// format is the formatting of a span of text.
+type format struct {
+	italic, bold, smallCaps, underline int
+	valign                             string // "sup", "sub" or ""
+}
+
+// span is a run of formatted output text.
+type span struct {
+	text string
+	f    format
+	// names is true for the output of the first cs:names element of a
+	// bibliography item, for subsequent-author-substitute.
+	names bool
+}
+
+// textSpans returns the spans of unformatted text s.
+func textSpans(s string) []span {
+	if s == "" {
+		return nil
+	}
+	return []span{{text: s}}
+}
+
+// spansText returns the plain text of the spans.
+func spansText(spans []span) string {
+	sb := &strings.Builder{}
+	for _, s := range spans {
+		sb.WriteString(s.text)
+	}
+	return sb.String()
+}
+
+// tri converts a formatting attribute value to a tri-state value. The
+// attribute is on if the value is one of onValues, off for other values,
+// like "normal", and unset if empty.
+func tri(v string, onValues ...string) int {
+	if v == "" {
+		return unset
+	}
+	for _, o := range onValues {
+		if v == o {
+			return on
+		}
+	}
+	return off
+}
+
+// applyFormat applies the formatting attributes of the element n to the
+// spans.
+func applyFormat(n *node, spans []span) {
+	style := tri(n.attr("font-style"), "italic", "oblique")
+	weight := tri(n.attr("font-weight"), "bold")
+	variant := tri(n.attr("font-variant"), "small-caps")
+	decoration := tri(n.attr("text-decoration"), "underline")
+	valign := n.attr("vertical-align")
+	for i := range spans {
+		f := &spans[i].f
+		if f.italic == unset {
+			f.italic = style
+		}
+		if f.bold == unset {
+			f.bold = weight
+		}
+		if f.smallCaps == unset {
+			f.smallCaps = variant
+		}
+		if f.underline == unset {
+			f.underline = decoration
+		}
+		if f.valign == "" && valign != "baseline" {
+			f.valign = valign
+		}
+	}
+}
+
+// stopWords are the English words that title case doesn't capitalize unless
+// first or last.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "as": true, "at": true, "but": true,
+	"by": true, "down": true, "for": true, "from": true, "in": true,
+	"into": true, "nor": true, "of": true, "on": true, "onto": true, "or": true,
+	"over": true, "so": true, "the": true, "till": true, "to": true, "up": true,
+	"via": true, "with": true, "yet": true,
+}
+
+// applyTextCase applies the text-case attribute to the spans.
+func applyTextCase(textCase string, spans []span) {
+	if textCase == "" {
+		return
+	}
+	first := true // at the first word of the output
+	for i := range spans {
+		s := spans[i].text
+		switch textCase {
+		case "lowercase":
+			s = strings.ToLower(s)
+		case "uppercase":
+			s = strings.ToUpper(s)
+		case "capitalize-first":
+			if first {
+				s = capitalizeFirst(s)
+			}
+		case "capitalize-all":
+			s = mapWords(s, func(w string, _, _ bool) string { return capitalizeFirst(w) })
+		case "sentence":
+			if isUpper(s) {
+				s = strings.ToLower(s)
+			}
+			if first {
+				s = capitalizeFirst(s)
+			}
+		case "title":
+			isFirst := first
+			s = mapWords(s, func(w string, firstWord, lastWord bool) string {
+				if stopWords[w] && !(isFirst && firstWord) && !lastWord {
+					return w
+				}
+				return capitalizeFirst(w)
+			})
+		}
+		if strings.TrimSpace(s) != "" {
+			first = false
+		}
+		spans[i].text = s
+	}
+}
+
+// mapWords replaces each word of s with fn(word). Words are separated by
+// spaces.
+func mapWords(s string, fn func(w string, first, last bool) string) string {
+	words := strings.Split(s, " ")
+	for i, w := range words {
+		if w != "" {
+			words[i] = fn(w, i == 0, i == len(words)-1)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// capitalizeFirst uppercases the first letter of s if it's lowercase.
+func capitalizeFirst(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if !unicode.IsLower(r) {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
+
+// isUpper returns true if all letters of s are uppercase.
+func isUpper(s string) bool {
+	hasLetter := false
+	for _, r := range s {
+		if unicode.IsLower(r) {
+			return false
+		}
+		hasLetter = hasLetter || unicode.IsLetter(r)
+	}
+	return hasLetter
+}
+
+// cleanup fixes the punctuation at the boundaries of spans: it drops
+// duplicate punctuation and spaces, like the second period in "Ed..", and
+// moves commas and periods inside closing quotes if punctuationInQuote is
+// true.
+func cleanup(spans []span, closeQuotes []string, punctuationInQuote bool) []span {
+	out := spans[:0]
+	for _, s := range spans {
+		if len(out) > 0 && s.text != "" {
+			prev := &out[len(out)-1]
+			if strings.HasSuffix(prev.text, " ") && strings.HasPrefix(s.text, " ") {
+				s.text = strings.TrimLeft(s.text, " ")
+			}
+			last, _ := utf8.DecodeLastRuneInString(prev.text)
+			if s.text != "" {
+				switch s.text[0] {
+				case '.':
+					if last == '.' || last == '?' || last == '!' {
+						s.text = s.text[1:]
+					}
+				case ',':
+					if last == ',' || last == '?' || last == '!' {
+						s.text = s.text[1:]
+					}
+				}
+			}
+			if punctuationInQuote && s.text != "" && (s.text[0] == '.' || s.text[0] == ',') {
+				for _, q := range closeQuotes {
+					if q != "" && strings.HasSuffix(prev.text, q) {
+						inner := strings.TrimSuffix(prev.text, q)
+						if innerLast, _ := utf8.DecodeLastRuneInString(inner); !strings.ContainsRune(".?!,", innerLast) {
+							inner += s.text[:1]
+						}
+						prev.text = inner + q
+						s.text = s.text[1:]
+						break
+					}
+				}
+			}
+		}
+		if s.text != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// toExpr converts the spans into a ParsedText with LaTeX macros for the
+// formatting: \textit, \textbf, \textsc, \underline, \textsuperscript and
+// \textsubscript.
+func toExpr(spans []span) *ast.ParsedText {
+	txt := &ast.ParsedText{Delim: ast.BraceDelimiter}
+	for i := 0; i < len(spans); {
+		j := i
+		sb := &strings.Builder{}
+		for j < len(spans) && spans[j].f == spans[i].f {
+			sb.WriteString(spans[j].text)
+			j++
+		}
+		var x ast.Expr = &ast.Text{Value: sb.String()}
+		f := spans[i].f
+		wrap := func(macro string) {
+			x = &ast.TextMacro{Name: macro, Values: []ast.Expr{x}}
+		}
+		if f.smallCaps == on {
+			wrap("textsc")
+		}
+		if f.bold == on {
+			wrap("textbf")
+		}
+		if f.italic == on {
+			wrap("textit")
+		}
+		if f.underline == on {
+			wrap("underline")
+		}
+		switch f.valign {
+		case "sup":
+			wrap("textsuperscript")
+		case "sub":
+			wrap("textsubscript")
+		}
+		txt.Values = append(txt.Values, x)
+		i = j
+	}
+	return txt
+}
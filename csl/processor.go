@@ -0,0 +1,497 @@
+package csl
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+)
+
+// Processor formats citations and bibliographies of a set of entries with a
+// style.
+type Processor struct {
+	style  *Style
+	locale *Locale
+	items  map[string]*item
+	// bib is the items in bibliography order. Without a bibliography sort,
+	// the order is the order of the first cite of the items, followed by the
+	// uncited items in the order of the entries passed to NewProcessor.
+	bib []*item
+	// citeOrder is true if the citation numbers follow the order of the
+	// first cite because the style has no bibliography sort.
+	citeOrder bool
+	// numbered is the number of cited items at the start of bib if
+	// citeOrder is true.
+	numbered int
+	// notes is the number of citations formatted by Cite and prev is the
+	// items of the last citation, in order.
+	notes int
+	prev  []*item
+	// explicitYearSuffix is true if the style renders the year-suffix
+	// variable. Otherwise, the suffix follows the first year of the issued
+	// date.
+	explicitYearSuffix bool
+}
+
+type Option func(p *Processor)
+
+// WithLocale overrides the terms and date formats of the built-in en-US
+// locale with those of l. Locales defined in the style override l.
+func WithLocale(l *Locale) Option {
+	return func(p *Processor) {
+		p.locale.merge(l)
+		p.locale.Lang = l.Lang
+	}
+}
+
+// NewProcessor creates a processor for the entries with the style. Entries
+// must be resolved, like with bibtex.WithPresets, so that name fields are
+// ast.Authors. NewProcessor sorts the bibliography and disambiguates the
+// citations of the entries. Without a bibliography sort, citation numbers
+// follow the order of the first cite of each entry; see Processor.Cite.
+func NewProcessor(style *Style, entries []bibtex.Entry, opts ...Option) (*Processor, error) {
+	p := &Processor{
+		style:  style,
+		locale: defaultLocale(),
+		items:  make(map[string]*item, len(entries)),
+	}
+	if style.defaultLocale != "" {
+		p.locale.Lang = style.defaultLocale
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	// Style locales without a language apply to all languages and override
+	// the locale file. Locales matching the language take precedence.
+	for _, match := range []func(lang string) bool{
+		func(lang string) bool { return lang == "" },
+		func(lang string) bool { return lang != "" && strings.HasPrefix(p.locale.Lang, lang) },
+	} {
+		for _, l := range style.locales {
+			if match(l.attr("lang")) {
+				p.locale.merge(newLocale(l))
+			}
+		}
+	}
+	p.explicitYearSuffix = hasVariable(style.root, "year-suffix")
+
+	for i, e := range entries {
+		if _, ok := p.items[e.Key]; ok {
+			return nil, fmt.Errorf("csl processor: duplicate entry key %q", e.Key)
+		}
+		it := newItem(e)
+		it.citationNum = i + 1
+		p.items[e.Key] = it
+		p.bib = append(p.bib, it)
+	}
+	p.citeOrder = style.bibliography == nil || style.bibliography.child("sort") == nil
+	if !p.citeOrder {
+		if err := p.sortItems(p.bib, style.bibliography); err != nil {
+			return nil, err
+		}
+		for i, it := range p.bib {
+			it.citationNum = i + 1
+		}
+	}
+	if err := p.disambiguate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// hasVariable returns true if n or any descendant renders the variable v.
+func hasVariable(n *node, v string) bool {
+	if n.XMLName.Local != "key" && n.attr("variable") == v {
+		return true
+	}
+	for _, c := range n.Nodes {
+		if hasVariable(c, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Cite formats a citation of the entries with the keys. The citation sorts
+// the cites if the style defines a citation sort.
+//
+// Cite records the citation in the sequence of citations, so call Cite for
+// each citation in document order. Without a bibliography sort, the first
+// cite of an entry assigns the next citation number. The sequence determines
+// the position of each cite for the position condition: the first cite of an
+// entry, a subsequent cite, an ibid cite of the entry of the preceding cite,
+// or a near-note cite within near-note-distance citations of the previous
+// cite of the entry. Each citation counts as a note.
+func (p *Processor) Cite(keys ...string) (*ast.ParsedText, error) {
+	items := make([]*item, 0, len(keys))
+	for _, key := range keys {
+		it, ok := p.items[key]
+		if !ok {
+			return nil, fmt.Errorf("csl cite: unknown entry key %q", key)
+		}
+		items = append(items, it)
+	}
+	for _, it := range items {
+		p.number(it)
+	}
+	if err := p.sortItems(items, p.style.citation); err != nil {
+		return nil, err
+	}
+	p.notes++
+	cites := p.positions(items)
+	p.prev = items
+
+	layout := p.style.citation.child("layout")
+	var spans []span
+	for i, it := range items {
+		s, err := p.renderItem(it, p.style.citation, cites[i])
+		if err != nil {
+			return nil, err
+		}
+		if len(s) > 0 && len(spans) > 0 {
+			spans = append(spans, textSpans(layout.attr("delimiter"))...)
+		}
+		spans = append(spans, s...)
+	}
+	spans = decorateLayout(layout, spans)
+	return toExpr(p.cleanup(spans)), nil
+}
+
+// number assigns the next citation number to an item on its first cite if
+// the citation numbers follow the cite order. The item moves to the end of
+// the cited items in the bibliography.
+func (p *Processor) number(it *item) {
+	if !p.citeOrder || it.citationNum <= p.numbered {
+		return
+	}
+	j := it.citationNum - 1
+	copy(p.bib[p.numbered+1:j+1], p.bib[p.numbered:j])
+	p.bib[p.numbered] = it
+	for i := p.numbered; i <= j; i++ {
+		p.bib[i].citationNum = i + 1
+	}
+	p.numbered++
+}
+
+// positions returns the position of the cite of each item of the current
+// citation, note p.notes, and records the cites.
+func (p *Processor) positions(items []*item) []cite {
+	nearDist := 5
+	if d, err := strconv.Atoi(p.style.citation.attr("near-note-distance")); err == nil {
+		nearDist = d
+	}
+	cites := make([]cite, len(items))
+	for i, it := range items {
+		switch {
+		case it.lastNote == 0:
+			cites[i].position = positionFirst
+		case i > 0 && items[i-1] == it, i == 0 && len(p.prev) == 1 && p.prev[0] == it:
+			cites[i].position = positionIbid
+		default:
+			cites[i].position = positionSubsequent
+		}
+		if it.lastNote > 0 {
+			cites[i].nearNote = p.notes-it.lastNote <= nearDist
+		}
+		it.lastNote = p.notes
+	}
+	return cites
+}
+
+// Bibliography formats the bibliography items of all entries in the order
+// of the style's bibliography sort.
+func (p *Processor) Bibliography() ([]*ast.ParsedText, error) {
+	if p.style.bibliography == nil {
+		return nil, fmt.Errorf("csl bibliography: style %q has no bibliography", p.style.Title)
+	}
+	layout := p.style.bibliography.child("layout")
+	substitute := p.style.bibliography.attr("subsequent-author-substitute")
+	doSubstitute := p.style.bibliography.hasAttr("subsequent-author-substitute")
+	prevNames := ""
+	bib := make([]*ast.ParsedText, 0, len(p.bib))
+	for _, it := range p.bib {
+		spans, err := p.renderItem(it, p.style.bibliography, cite{})
+		if err != nil {
+			return nil, err
+		}
+		if doSubstitute {
+			spans, prevNames = substituteNames(spans, prevNames, substitute)
+		}
+		spans = decorateLayout(layout, spans)
+		bib = append(bib, toExpr(p.cleanup(spans)))
+	}
+	return bib, nil
+}
+
+// substituteNames replaces the names of the item with substitute if they
+// match the names of the previous item, prev. It returns the new spans and
+// the names of the item.
+func substituteNames(spans []span, prev, substitute string) ([]span, string) {
+	start, end := -1, -1
+	for i, s := range spans {
+		if s.names {
+			if start < 0 {
+				start = i
+			}
+			end = i + 1
+		}
+	}
+	if start < 0 {
+		return spans, ""
+	}
+	names := spansText(spans[start:end])
+	if names != prev {
+		return spans, names
+	}
+	out := append([]span(nil), spans[:start]...)
+	out = append(out, textSpans(substitute)...)
+	return append(out, spans[end:]...), names
+}
+
+// decorateLayout applies the affixes and formatting of a cs:layout element.
+// Unlike other elements, the affixes of a layout are outside its formatting.
+func decorateLayout(layout *node, spans []span) []span {
+	if len(spans) == 0 {
+		return nil
+	}
+	applyFormat(layout, spans)
+	if prefix := layout.attr("prefix"); prefix != "" {
+		spans = append(textSpans(prefix), spans...)
+	}
+	return append(spans, textSpans(layout.attr("suffix"))...)
+}
+
+// renderItem renders the layout of the citation or bibliography element mode
+// for the cite of the item without the layout affixes.
+func (p *Processor) renderItem(it *item, mode *node, ct cite) ([]span, error) {
+	c := p.newContext(it, mode)
+	c.cite = ct
+	r := c.renderChildren(mode.child("layout"), "")
+	if c.err != nil {
+		return nil, fmt.Errorf("csl render %s: %w", it.key, c.err)
+	}
+	return r.spans, nil
+}
+
+func (p *Processor) cleanup(spans []span) []span {
+	closeQuote, _ := p.locale.term("close-quote", "long", false)
+	closeInner, _ := p.locale.term("close-inner-quote", "long", false)
+	piq := p.locale.punctuationInQuote != nil && *p.locale.punctuationInQuote
+	return cleanup(spans, []string{closeQuote, closeInner}, piq)
+}
+
+// sortItems sorts the items with the cs:sort element of mode, the citation
+// or bibliography element. Items with equal keys keep their order.
+func (p *Processor) sortItems(items []*item, mode *node) error {
+	sortNode := mode.child("sort")
+	if sortNode == nil {
+		return nil
+	}
+	keyNodes := sortNode.children("key")
+	keys := make(map[*item][]string, len(items))
+	for _, it := range items {
+		ks := make([]string, len(keyNodes))
+		for i, k := range keyNodes {
+			s, err := p.sortKey(it, mode, k)
+			if err != nil {
+				return err
+			}
+			ks[i] = s
+		}
+		keys[it] = ks
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := keys[items[i]], keys[items[j]]
+		for k, kn := range keyNodes {
+			x, y := a[k], b[k]
+			if x == y {
+				continue
+			}
+			// Empty keys sort last in both directions.
+			if x == "" || y == "" {
+				return y == ""
+			}
+			if kn.attr("sort") == "descending" {
+				return x > y
+			}
+			return x < y
+		}
+		return false
+	})
+	return nil
+}
+
+// sortKey returns the sort key of the item for the cs:key element k. Dates
+// sort as YYYYMMDD and numbers sort numerically. Text sorts case-insensitive.
+func (p *Processor) sortKey(it *item, mode, k *node) (string, error) {
+	c := p.newContext(it, mode)
+	c.sorting = true
+	var s string
+	if k.hasAttr("macro") {
+		m, ok := p.style.macros[k.attr("macro")]
+		if !ok {
+			return "", fmt.Errorf("csl sort: undefined macro %q", k.attr("macro"))
+		}
+		s = spansText(c.renderChildren(m, "").spans)
+		if c.err != nil {
+			return "", fmt.Errorf("csl sort: %w", c.err)
+		}
+	} else {
+		v := k.attr("variable")
+		switch {
+		case it.names[v].names != nil:
+			s = spansText(c.renderNameList(&node{}, nil, it.names[v]))
+		case it.dates[v] != date{}:
+			d := it.dates[v]
+			s = fmt.Sprintf("%04d%02d%02d", d.year, d.month, d.day)
+		default:
+			s = c.variable(v, "")
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 {
+		return fmt.Sprintf("%020d", n), nil
+	}
+	return strings.ToLower(s), nil
+}
+
+// disambiguate makes ambiguous citations distinct with the disambiguation
+// methods of the style, in order: adding names, adding given names, the
+// disambiguate condition and year suffixes.
+func (p *Processor) disambiguate() error {
+	cite := p.style.citation
+	if cite.attr("disambiguate-add-names") == "true" {
+		err := p.disambiguateStep(func(it *item, level int) bool {
+			n := 0
+			for _, l := range it.names {
+				n = max(n, len(l.names))
+			}
+			if level > n {
+				return false
+			}
+			it.etAlUseFirst = level
+			return true
+		}, func(it *item) { it.etAlUseFirst = 0 })
+		if err != nil {
+			return err
+		}
+	}
+	if cite.attr("disambiguate-add-givenname") == "true" {
+		err := p.disambiguateStep(func(it *item, level int) bool {
+			if level > 2 {
+				return false
+			}
+			it.givenLevel = level
+			return true
+		}, func(it *item) { it.givenLevel = 0 })
+		if err != nil {
+			return err
+		}
+	}
+	groups, err := p.ambiguous()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		for _, it := range g {
+			it.disambiguate = true
+		}
+	}
+	if cite.attr("disambiguate-add-year-suffix") != "true" {
+		return nil
+	}
+	groups, err = p.ambiguous()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		for i, it := range g {
+			it.yearSuffix = yearSuffix(i)
+		}
+	}
+	return nil
+}
+
+// disambiguateStep applies increasing levels of a disambiguation method to
+// each group of ambiguous items until the group is distinct. Set applies a
+// level to an item and returns false if the level doesn't exist. If no
+// level makes any citation of the group distinct, reset undoes the method
+// for the group.
+func (p *Processor) disambiguateStep(set func(it *item, level int) bool, reset func(it *item)) error {
+	groups, err := p.ambiguous()
+	if err != nil {
+		return err
+	}
+	for _, g := range groups {
+		distinct := 1
+		for level := 1; distinct < len(g); level++ {
+			applied := false
+			for _, it := range g {
+				applied = set(it, level) || applied
+			}
+			if !applied {
+				break
+			}
+			texts, err := p.citeTexts(g)
+			if err != nil {
+				return err
+			}
+			distinct = len(texts)
+		}
+		if distinct == 1 {
+			for _, it := range g {
+				reset(it)
+			}
+		}
+	}
+	return nil
+}
+
+// ambiguous returns the groups of items, in bibliography order, whose
+// citations render the same text.
+func (p *Processor) ambiguous() ([][]*item, error) {
+	byText := make(map[string][]*item)
+	var order []string
+	for _, it := range p.bib {
+		spans, err := p.renderItem(it, p.style.citation, cite{})
+		if err != nil {
+			return nil, err
+		}
+		s := spansText(spans)
+		if _, ok := byText[s]; !ok {
+			order = append(order, s)
+		}
+		byText[s] = append(byText[s], it)
+	}
+	var groups [][]*item
+	for _, s := range order {
+		if len(byText[s]) > 1 {
+			groups = append(groups, byText[s])
+		}
+	}
+	return groups, nil
+}
+
+// citeTexts returns the distinct citation texts of the items.
+func (p *Processor) citeTexts(items []*item) (map[string]bool, error) {
+	texts := make(map[string]bool, len(items))
+	for _, it := range items {
+		spans, err := p.renderItem(it, p.style.citation, cite{})
+		if err != nil {
+			return nil, err
+		}
+		texts[spansText(spans)] = true
+	}
+	return texts, nil
+}
+
+// yearSuffix returns the i'th year suffix: "a" to "z", then "aa", "ab" and
+// so on.
+func yearSuffix(i int) string {
+	if i < 26 {
+		return string(rune('a' + i))
+	}
+	return yearSuffix(i/26-1) + yearSuffix(i%26)
+}
@@ -0,0 +1,398 @@
+package csl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// context is the state for rendering one item with the citation or
+// bibliography element of a style.
+type context struct {
+	p    *Processor
+	it   *item
+	mode *node // the citation or bibliography element
+	cite cite  // the position of the cite; first for the bibliography
+
+	sorting      bool            // rendering a sort key
+	substituting bool            // rendering a cs:substitute element
+	suppressed   map[string]bool // variables used by cs:substitute
+	seenNames    bool            // rendered the first cs:names element
+	seenYear     bool            // rendered the implicit year-suffix
+	err          error
+}
+
+// position is the position of a cite in the sequence of citations.
+type position int
+
+const (
+	positionFirst      position = iota // the first cite of the item
+	positionSubsequent                 // a later cite of the item
+	positionIbid                       // a cite of the item of the preceding cite
+)
+
+// cite is the position of a cite of an item.
+type cite struct {
+	position position
+	nearNote bool // the previous cite of the item is within near-note-distance
+}
+
+func (p *Processor) newContext(it *item, mode *node) *context {
+	return &context{p: p, it: it, mode: mode, suppressed: make(map[string]bool)}
+}
+
+// result is the output of an element. Called and rendered track variables for
+// the suppression of cs:group elements: a group is suppressed if it calls at
+// least one variable but all called variables are empty.
+type result struct {
+	spans    []span
+	called   bool // the element called a variable
+	rendered bool // a called variable was non-empty
+}
+
+func (r *result) add(o result) {
+	r.spans = append(r.spans, o.spans...)
+	r.called = r.called || o.called
+	r.rendered = r.rendered || o.rendered
+}
+
+func (c *context) fail(format string, args ...any) {
+	if c.err == nil {
+		c.err = fmt.Errorf(format, args...)
+	}
+}
+
+// renderChildren renders the child elements of n joined by delimiter.
+func (c *context) renderChildren(n *node, delimiter string) result {
+	var r result
+	for _, child := range n.Nodes {
+		o := c.render(child)
+		if len(o.spans) > 0 && len(r.spans) > 0 {
+			r.spans = append(r.spans, textSpans(delimiter)...)
+		}
+		r.add(o)
+	}
+	return r
+}
+
+// render renders a rendering element, like cs:text or cs:group.
+func (c *context) render(n *node) result {
+	var r result
+	switch n.XMLName.Local {
+	case "text":
+		r = c.renderText(n)
+	case "number":
+		r = c.renderNumber(n)
+	case "label":
+		r = c.renderLabel(n, n.attr("variable"))
+	case "group":
+		r = c.renderChildren(n, n.attr("delimiter"))
+		if r.called && !r.rendered {
+			return result{called: true}
+		}
+	case "choose":
+		return c.renderChoose(n)
+	case "date":
+		r = c.renderDate(n)
+	case "names":
+		r = c.renderNames(n)
+	default:
+		return result{}
+	}
+	r.spans = c.decorate(n, r.spans)
+	return r
+}
+
+// decorate applies the text-case, strip-periods, quotes, formatting and affix
+// attributes of n to the spans.
+func (c *context) decorate(n *node, spans []span) []span {
+	if len(spans) == 0 {
+		return nil
+	}
+	applyTextCase(n.attr("text-case"), spans)
+	if n.attr("strip-periods") == "true" {
+		for i := range spans {
+			spans[i].text = strings.ReplaceAll(spans[i].text, ".", "")
+		}
+	}
+	if n.attr("quotes") == "true" {
+		open, _ := c.p.locale.term("open-quote", "long", false)
+		closeQ, _ := c.p.locale.term("close-quote", "long", false)
+		spans = append(append(textSpans(open), spans...), textSpans(closeQ)...)
+	}
+	applyFormat(n, spans)
+	if prefix := n.attr("prefix"); prefix != "" {
+		spans = append(textSpans(prefix), spans...)
+	}
+	spans = append(spans, textSpans(n.attr("suffix"))...)
+	return spans
+}
+
+// renderText renders a cs:text element.
+func (c *context) renderText(n *node) result {
+	switch {
+	case n.hasAttr("variable"):
+		v := n.attr("variable")
+		s := c.variable(v, n.attr("form"))
+		if v == "page" {
+			s = c.pageRange(s)
+		}
+		return c.called(v, textSpans(s))
+	case n.hasAttr("macro"):
+		m, ok := c.p.style.macros[n.attr("macro")]
+		if !ok {
+			c.fail("undefined macro %q", n.attr("macro"))
+			return result{}
+		}
+		return c.renderChildren(m, "")
+	case n.hasAttr("term"):
+		s, _ := c.p.locale.term(n.attr("term"), n.attr("form"), n.attr("plural") == "true")
+		return result{spans: textSpans(s)}
+	default:
+		return result{spans: textSpans(n.attr("value"))}
+	}
+}
+
+// called returns the result of calling the variable v with the output spans.
+func (c *context) called(v string, spans []span) result {
+	if len(spans) > 0 && c.substituting {
+		c.suppressed[v] = true
+	}
+	return result{spans: spans, called: true, rendered: len(spans) > 0}
+}
+
+// variable returns the value of a standard or number variable in the form,
+// "long" or "short".
+func (c *context) variable(v, form string) string {
+	if c.suppressed[v] {
+		return ""
+	}
+	switch v {
+	case "citation-number":
+		return strconv.Itoa(c.it.citationNum)
+	case "year-suffix":
+		return c.it.yearSuffix
+	}
+	if form == "short" {
+		if s, ok := c.it.vars[v+"-short"]; ok {
+			return s
+		}
+	}
+	return c.it.vars[v]
+}
+
+// renderNumber renders a cs:number element.
+func (c *context) renderNumber(n *node) result {
+	v := n.attr("variable")
+	s := c.variable(v, "")
+	if num, err := strconv.Atoi(s); err == nil {
+		switch n.attr("form") {
+		case "ordinal":
+			s = c.p.locale.ordinal(num)
+		case "long-ordinal":
+			s = c.p.locale.longOrdinal(num)
+		case "roman":
+			s = roman(num)
+		}
+	} else if v == "page" {
+		s = c.pageRange(s)
+	}
+	return c.called(v, textSpans(s))
+}
+
+// renderLabel renders a cs:label element for the variable v. A label is only
+// rendered if the variable is non-empty.
+func (c *context) renderLabel(n *node, v string) result {
+	s := c.variable(v, "")
+	if s == "" {
+		return result{}
+	}
+	plural := false
+	switch n.attr("plural") {
+	case "always":
+		plural = true
+	case "never":
+	default:
+		plural = strings.ContainsAny(s, "-–,&")
+	}
+	term, _ := c.p.locale.term(v, n.attr("form"), plural)
+	return result{spans: textSpans(term)}
+}
+
+// renderChoose renders the branch of the first true condition of a cs:choose
+// element.
+func (c *context) renderChoose(n *node) result {
+	for _, branch := range n.Nodes {
+		switch branch.XMLName.Local {
+		case "if", "else-if":
+			if c.test(branch) {
+				return c.renderChildren(branch, "")
+			}
+		case "else":
+			return c.renderChildren(branch, "")
+		}
+	}
+	return result{}
+}
+
+// test evaluates the conditions of a cs:if or cs:else-if element.
+func (c *context) test(n *node) bool {
+	var tests []bool
+	for _, a := range n.Attrs {
+		if a.Name.Local == "match" {
+			continue
+		}
+		for _, v := range strings.Fields(a.Value) {
+			tests = append(tests, c.cond(a.Name.Local, v))
+		}
+	}
+	switch n.attr("match") {
+	case "any":
+		for _, t := range tests {
+			if t {
+				return true
+			}
+		}
+		return false
+	case "none":
+		for _, t := range tests {
+			if t {
+				return false
+			}
+		}
+		return true
+	default:
+		for _, t := range tests {
+			if !t {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// cond evaluates a single condition, like type="book".
+func (c *context) cond(name, v string) bool {
+	switch name {
+	case "type":
+		return c.it.typ == v
+	case "variable":
+		if _, ok := c.it.names[v]; ok {
+			return !c.suppressed[v]
+		}
+		if _, ok := c.it.dates[v]; ok {
+			return !c.suppressed[v]
+		}
+		return c.variable(v, "") != ""
+	case "is-numeric":
+		return isNumeric(c.variable(v, ""))
+	case "disambiguate":
+		return c.it.disambiguate == (v == "true")
+	case "position":
+		switch v {
+		case "first":
+			return c.cite.position == positionFirst
+		case "subsequent":
+			return c.cite.position != positionFirst
+		case "ibid":
+			return c.cite.position == positionIbid
+		case "near-note":
+			return c.cite.position != positionFirst && c.cite.nearNote
+		}
+		// Cites have no locators, so ibid-with-locator is never true.
+		return false
+	default:
+		// Unsupported conditions, like locator and is-uncertain-date.
+		return false
+	}
+}
+
+// isNumeric returns true if s is a number or a range or list of numbers, like
+// "12-14" or "2, 4".
+func isNumeric(s string) bool {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return strings.ContainsRune("-–,& ", r)
+	})
+	if len(fields) == 0 {
+		return false
+	}
+	for _, f := range fields {
+		if _, err := strconv.Atoi(f); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// roman returns n as lowercase roman numerals for 1 to 3999.
+func roman(n int) string {
+	if n <= 0 || n >= 4000 {
+		return strconv.Itoa(n)
+	}
+	numerals := []struct {
+		value int
+		s     string
+	}{
+		{1000, "m"}, {900, "cm"}, {500, "d"}, {400, "cd"}, {100, "c"}, {90, "xc"},
+		{50, "l"}, {40, "xl"}, {10, "x"}, {9, "ix"}, {5, "v"}, {4, "iv"}, {1, "i"},
+	}
+	sb := &strings.Builder{}
+	for _, num := range numerals {
+		for n >= num.value {
+			sb.WriteString(num.s)
+			n -= num.value
+		}
+	}
+	return sb.String()
+}
+
+// pageRange formats a page range, like "321-28", with the page-range-format
+// of the style and the page-range-delimiter of the locale.
+func (c *context) pageRange(s string) string {
+	first, last, ok := strings.Cut(s, "-")
+	if !ok {
+		return s
+	}
+	delim, ok := c.p.locale.term("page-range-delimiter", "long", false)
+	if !ok {
+		delim = "–"
+	}
+	a, errA := strconv.Atoi(first)
+	_, errB := strconv.Atoi(last)
+	if errA != nil || errB != nil || len(last) > len(first) {
+		return first + delim + last
+	}
+	// Expand abbreviated last pages, like 321-8.
+	last = first[:len(first)-len(last)] + last
+	switch c.p.style.root.attr("page-range-format") {
+	case "minimal":
+		last = minimalPages(first, last, 1)
+	case "minimal-two":
+		last = minimalPages(first, last, 2)
+	case "chicago", "chicago-15", "chicago-16":
+		switch {
+		case a < 100 || a%100 == 0:
+		case a%100 < 10:
+			last = minimalPages(first, last, 1)
+		default:
+			last = minimalPages(first, last, 2)
+			if len(first) == 4 && len(last) >= 3 {
+				last = first[:len(first)-len(last)] + last
+			}
+		}
+	}
+	return first + delim + last
+}
+
+// minimalPages returns the changed digits of last compared to first, keeping
+// at least minDigits digits.
+func minimalPages(first, last string, minDigits int) string {
+	if len(first) != len(last) {
+		return last
+	}
+	i := 0
+	for i < len(last)-minDigits && first[i] == last[i] {
+		i++
+	}
+	return last[i:]
+}
@@ -0,0 +1,168 @@
+package bibtex
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/render"
+)
+
+// The accessors below return the plain text of a tag value after resolving.
+// The value must be resolved into text, like with RenderParsedTextResolver,
+// or be a parsed string that renders as plain text. The accessors return the
+// zero value if the tag is missing or if the value isn't text, like an
+// abbreviation that wasn't expanded.
+
+// Title returns the title of the entry or the empty string.
+func (e Entry) Title() string {
+	s, _ := e.text(FieldTitle)
+	return s
+}
+
+// Authors returns the authors of the entry or nil. The author tag must be
+// resolved with an AuthorResolver.
+func (e Entry) Authors() ast.Authors {
+	authors, _ := e.Tags[FieldAuthor].(ast.Authors)
+	return authors
+}
+
+// Editors returns the editors of the entry or nil. The editor tag must be
+// resolved with an AuthorResolver.
+func (e Entry) Editors() ast.Authors {
+	editors, _ := e.Tags[FieldEditor].(ast.Authors)
+	return editors
+}
+
+// Names returns the authors of the entry or else the editors, without a final
+// "others". Others is true if the names end with "and others". Unlike Authors
+// and Editors, Names also extracts the names of unresolved name tags.
+func (e Entry) Names() (names ast.Authors, others bool) {
+	for _, f := range []Field{FieldAuthor, FieldEditor} {
+		switch x := e.Tags[f].(type) {
+		case ast.Authors:
+			names = x
+		case *ast.ParsedText:
+			names, _ = ExtractAuthors(x)
+		}
+		if len(names) > 0 {
+			break
+		}
+	}
+	if n := len(names); n > 0 && names[n-1].IsOthers() {
+		return names[:n-1], true
+	}
+	return names, false
+}
+
+// Year returns the year of the entry. The boolean is false if the entry has no
+// year or if the year isn't an integer.
+func (e Entry) Year() (int, bool) {
+	s, ok := e.text(FieldYear)
+	if !ok {
+		return 0, false
+	}
+	year, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, false
+	}
+	return year, true
+}
+
+// Month returns the month of the entry or 0 if the month is missing or
+// unknown. The month may be a number from 1 to 12, a full English month name,
+// or an abbreviation of at least three letters, like "Sep." or "sept".
+func (e Entry) Month() time.Month {
+	s, ok := e.text(FieldMonth)
+	if !ok {
+		return 0
+	}
+	s = strings.TrimSuffix(strings.TrimSpace(s), ".")
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 1 || n > 12 {
+			return 0
+		}
+		return time.Month(n)
+	}
+	if len(s) < 3 {
+		return 0
+	}
+	s = strings.ToLower(s)
+	for m := time.January; m <= time.December; m++ {
+		if strings.HasPrefix(strings.ToLower(m.String()), s) {
+			return m
+		}
+	}
+	return 0
+}
+
+// Pages returns the first and last page of the page range of the entry, like
+// "2022" and "2034" for "2022--2034". The last page is empty if the pages tag
+// is a single page. Both pages are empty if the entry has no pages tag.
+func (e Entry) Pages() (first, last string) {
+	s, ok := e.text(FieldPages)
+	if !ok {
+		return "", ""
+	}
+	const dashes = "-–—" // hyphen, en dash, em dash
+	i := strings.IndexAny(s, dashes)
+	if i < 0 {
+		return strings.TrimSpace(s), ""
+	}
+	first = strings.TrimSpace(s[:i])
+	last = strings.TrimSpace(strings.TrimLeft(s[i:], dashes))
+	return first, last
+}
+
+// doiPrefixes are the prefixes of a DOI written as a URL or URI.
+var doiPrefixes = []string{
+	"https://doi.org/",
+	"http://doi.org/",
+	"https://dx.doi.org/",
+	"http://dx.doi.org/",
+	"doi:",
+}
+
+// DOI returns the DOI name of the entry, like "10.1145/359545.359563", or the
+// empty string. DOI removes URL prefixes like "https://doi.org/".
+func (e Entry) DOI() string {
+	s, ok := e.text(EntryDOI)
+	if !ok {
+		return ""
+	}
+	s = strings.TrimSpace(s)
+	for _, prefix := range doiPrefixes {
+		if len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix) {
+			return s[len(prefix):]
+		}
+	}
+	return s
+}
+
+// URL returns the URL of the entry or the empty string.
+func (e Entry) URL() string {
+	s, _ := e.text(FieldURL)
+	return strings.TrimSpace(s)
+}
+
+// text returns the plain text of the field. The boolean is false if the field
+// is missing or if the value can't be rendered as text.
+func (e Entry) text(field Field) (string, bool) {
+	switch x := e.Tags[field].(type) {
+	case *ast.Text:
+		return x.Value, true
+	case *ast.Number:
+		return x.Value, true
+	case *ast.UnparsedText:
+		return x.Value, true
+	case *ast.ParsedText:
+		sb := &strings.Builder{}
+		if err := render.NewTextRenderer().Render(sb, x); err != nil {
+			return "", false
+		}
+		return sb.String(), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,179 @@
+package bibtex
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/ast"
+)
+
+// resolveEntry parses and resolves the single entry in src.
+func resolveEntry(t *testing.T, src string, resolvers ...Resolver) Entry {
+	t.Helper()
+	bib := New(WithResolvers(resolvers...))
+	file, err := bib.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := bib.Resolve(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly 1 entry, got %d entries", len(entries))
+	}
+	return entries[0]
+}
+
+func TestEntry_accessors(t *testing.T) {
+	src := `
+		@article{key,
+			title = {The {TeX}book},
+			author = {Knuth, Donald E.},
+			editor = {Doe, Jane and Roe, Rick},
+			year = 1984,
+			month = sep,
+			pages = {2022--2034},
+			doi = {https://doi.org/10.1145/359545.359563},
+			url = {https://example.com/paper.pdf},
+		}`
+	entry := resolveEntry(t, src,
+		NewAbbrevResolver(nil),
+		NewAuthorResolver(FieldAuthor, FieldEditor),
+		ResolverFunc(SimplifyEscapedTextResolver),
+		NewRenderParsedTextResolver(),
+	)
+
+	if got, want := entry.Title(), "The TeXbook"; got != want {
+		t.Errorf("Title() = %q; want %q", got, want)
+	}
+	if got := entry.Authors(); len(got) != 1 || asText(got[0].Last) != "Knuth" {
+		t.Errorf("Authors() = %v; want Knuth", got)
+	}
+	if got := entry.Editors(); len(got) != 2 {
+		t.Errorf("Editors() = %v; want 2 editors", got)
+	}
+	if got, others := entry.Names(); len(got) != 1 || asText(got[0].Last) != "Knuth" || others {
+		t.Errorf("Names() = %v, %t; want Knuth, false", got, others)
+	}
+	if year, ok := entry.Year(); year != 1984 || !ok {
+		t.Errorf("Year() = %d, %t; want 1984, true", year, ok)
+	}
+	if got, want := entry.Month(), time.September; got != want {
+		t.Errorf("Month() = %s; want %s", got, want)
+	}
+	if first, last := entry.Pages(); first != "2022" || last != "2034" {
+		t.Errorf("Pages() = %q, %q; want 2022, 2034", first, last)
+	}
+	if got, want := entry.DOI(), "10.1145/359545.359563"; got != want {
+		t.Errorf("DOI() = %q; want %q", got, want)
+	}
+	if got, want := entry.URL(), "https://example.com/paper.pdf"; got != want {
+		t.Errorf("URL() = %q; want %q", got, want)
+	}
+}
+
+func TestEntry_URL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"{http://a.b/~c}", "http://a.b/~c"},
+		{"{https://a.b/c%20d?e=f~g}", "https://a.b/c%20d?e=f~g"},
+		{`"http://a.b/~c%20d"`, "http://a.b/~c%20d"},
+		{`{\url{http://a.b/~c%20d}}`, "http://a.b/~c%20d"},
+		{"{ https://a.b/c }", "https://a.b/c"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			entries, err := Read(strings.NewReader("@misc{key, url = " + tt.url + "}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := entries[0].URL(); got != tt.want {
+				t.Errorf("URL() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_unresolved(t *testing.T) {
+	// Without resolvers, the accessors still render parsed text but fail
+	// cleanly for authors and abbreviations.
+	src := `@article{key, title = {Foo {Bar}}, author = {Knuth, Donald}, month = sep, year = {2019}}`
+	entry := resolveEntry(t, src)
+
+	if got, want := entry.Title(), "Foo Bar"; got != want {
+		t.Errorf("Title() = %q; want %q", got, want)
+	}
+	if got := entry.Authors(); got != nil {
+		t.Errorf("Authors() = %v; want nil", got)
+	}
+	if got, others := entry.Names(); len(got) != 1 || others {
+		t.Errorf("Names() = %v, %t; want 1 author, false", got, others)
+	}
+	if got := entry.Month(); got != 0 {
+		t.Errorf("Month() = %s; want 0", got)
+	}
+	if year, ok := entry.Year(); year != 2019 || !ok {
+		t.Errorf("Year() = %d, %t; want 2019, true", year, ok)
+	}
+	if got := entry.DOI(); got != "" {
+		t.Errorf("DOI() = %q; want empty", got)
+	}
+}
+
+func TestEntry_Month(t *testing.T) {
+	tests := []struct {
+		month string
+		want  time.Month
+	}{
+		{"{January}", time.January},
+		{"{jan}", time.January},
+		{"{Sept.}", time.September},
+		{"{12}", time.December},
+		{"3", time.March},
+		{"{13}", 0},
+		{"{ju}", 0},
+		{"{Smarch}", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.month, func(t *testing.T) {
+			entry := resolveEntry(t, "@misc{key, month = "+tt.month+"}")
+			if got := entry.Month(); got != tt.want {
+				t.Errorf("Month() = %s; want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntry_Pages(t *testing.T) {
+	tests := []struct {
+		pages       string
+		first, last string
+	}{
+		{"{12}", "12", ""},
+		{"{12-34}", "12", "34"},
+		{"{12 -- 34}", "12", "34"},
+		{"{12–34}", "12", "34"},
+		{"{e123}", "e123", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pages, func(t *testing.T) {
+			entry := resolveEntry(t, "@misc{key, pages = "+tt.pages+"}")
+			first, last := entry.Pages()
+			if diff := cmp.Diff([]string{tt.first, tt.last}, []string{first, last}); diff != "" {
+				t.Errorf("Pages() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func asText(x ast.Expr) string {
+	if t, ok := x.(*ast.Text); ok {
+		return t.Value
+	}
+	return ""
+}
@@ -2,4 +2,7 @@ module github.com/jschaf/bibtex
 
 go 1.22.4
 
-require github.com/google/go-cmp v0.6.0
+require (
+	github.com/google/go-cmp v0.6.0
+	golang.org/x/text v0.22.0
+)
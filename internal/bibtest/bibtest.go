@@ -0,0 +1,20 @@
+// Package bibtest provides helpers for tests that use bibtex entries.
+package bibtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jschaf/bibtex"
+)
+
+// Read reads the entries of the bibtex source with bibtex.Read and fails the
+// test on error.
+func Read(t testing.TB, src string) []bibtex.Entry {
+	t.Helper()
+	entries, err := bibtex.Read(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
@@ -0,0 +1,26 @@
+// Package runes slices strings by runes instead of bytes and builds the
+// letter suffixes of the label and key generators.
+package runes
+
+// First returns the first n runes of s, or s if it has fewer runes.
+func First(s string, n int) string {
+	r := []rune(s)
+	return string(r[:min(n, len(r))])
+}
+
+// Last returns the last n runes of s, or s if it has fewer runes.
+func Last(s string, n int) string {
+	r := []rune(s)
+	return string(r[max(len(r)-n, 0):])
+}
+
+// Suffix returns the suffix for the n-th entry with the same label or key: "a"
+// to "z", then "aa", "ab", and so on.
+func Suffix(n int) string {
+	s := ""
+	for n >= 0 {
+		s = string(rune('a'+n%26)) + s
+		n = n/26 - 1
+	}
+	return s
+}
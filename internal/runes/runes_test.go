@@ -0,0 +1,17 @@
+package runes
+
+import "testing"
+
+func TestSuffix(t *testing.T) {
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{0, "a"}, {1, "b"}, {25, "z"}, {26, "aa"}, {27, "ab"}, {51, "az"}, {52, "ba"}, {701, "zz"}, {702, "aaa"},
+	}
+	for _, tt := range tests {
+		if got := Suffix(tt.n); got != tt.want {
+			t.Errorf("Suffix(%d) = %q; want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Package keygen generates citation keys for entries from key patterns, like
+// "[auth][year][shorttitle]" for "Knuth1984TeXbook", like the citation key
+// patterns of JabRef.
+//
+// A pattern is literal text and field markers in square brackets. The field
+// markers are:
+//
+//   - [auth]: the last name of the first author, like "Knuth".
+//   - [authN]: the first N letters of the last name of the first author, like
+//     "Knu" for [auth3].
+//   - [authEtAl]: the last name of the first author, followed by the last
+//     name of the second author if there are two authors, or by "EtAl" if
+//     there are more, like "SmithJones" or "SmithEtAl".
+//   - [authors]: the last names of all authors.
+//   - [year]: the year, like "1984".
+//   - [shortyear]: the last two digits of the year, like "84".
+//   - [title]: the words of the title, with each word capitalized except for
+//     function words like "of" and "the".
+//   - [shorttitle]: the first three words of the title that aren't function
+//     words, capitalized, like "TeXbook".
+//   - [veryshorttitle]: the first word of the title that isn't a function
+//     word, capitalized.
+//   - [firstpage] and [lastpage]: the first and last page of the pages field.
+//   - [field]: the value of any other field, like [journal] or [volume].
+//
+// The author markers use the editors if an entry has no authors. Field
+// markers use the purified text of a field without accents, like "Godel" for
+// G{\"o}del, without spaces between words.
+//
+// A marker may end with modifiers after colons that change the text of the
+// marker, like [veryshorttitle:lower]. The modifiers are:
+//
+//   - lower: lowercase the text.
+//   - upper: uppercase the text.
+//   - abbr: keep the first letter of each word.
+package keygen
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/internal/runes"
+	"github.com/jschaf/bibtex/purify"
+)
+
+// functionWords are the words that [shorttitle] and [veryshorttitle] skip and
+// that [title] doesn't capitalize.
+var functionWords = map[string]bool{
+	"a": true, "an": true, "the": true,
+	"above": true, "about": true, "across": true, "against": true, "along": true,
+	"among": true, "around": true, "at": true, "before": true, "behind": true,
+	"below": true, "beneath": true, "beside": true, "between": true,
+	"beyond": true, "by": true, "down": true, "during": true, "except": true,
+	"for": true, "from": true, "in": true, "inside": true, "into": true,
+	"like": true, "near": true, "of": true, "off": true, "on": true,
+	"onto": true, "since": true, "to": true, "toward": true, "through": true,
+	"under": true, "until": true, "up": true, "upon": true, "with": true,
+	"within": true, "without": true,
+	"and": true, "but": true, "nor": true, "or": true, "so": true, "yet": true,
+}
+
+// modifiers are the modifiers of a field marker.
+var modifiers = map[string]func(words []string) []string{
+	"lower": func(words []string) []string {
+		return mapWords(words, strings.ToLower)
+	},
+	"upper": func(words []string) []string {
+		return mapWords(words, strings.ToUpper)
+	},
+	"abbr": func(words []string) []string {
+		return mapWords(words, func(w string) string {
+			_, size := utf8.DecodeRuneInString(w)
+			return w[:size]
+		})
+	},
+}
+
+// segment is literal text or a field marker of a pattern.
+type segment struct {
+	literal string
+	marker  string // the name of the field marker, or "" for literal text
+	n       int    // the number of letters of [authN]
+	mods    []string
+}
+
+// Generator generates citation keys from a key pattern.
+type Generator struct {
+	segments []segment
+	rewrite  bool
+}
+
+// Option is a functional option to change how a Generator assigns keys.
+type Option func(g *Generator)
+
+// WithRewrite makes Keys rename the entries of the package to the new keys.
+// Keys changes the key of each BibDecl in place, moves the entry objects in
+// the package and file scopes to the new keys, and updates the crossref and
+// xdata tags that reference the old keys. A crossref tag that references a
+// key through an abbreviation, like crossref = conf, keeps the abbreviation.
+func WithRewrite() Option {
+	return func(g *Generator) {
+		g.rewrite = true
+	}
+}
+
+// New creates a generator for the key pattern. New returns an error if a
+// field marker isn't closed, is empty, or has an unknown modifier.
+func New(pattern string, opts ...Option) (*Generator, error) {
+	g := &Generator{}
+	for _, opt := range opts {
+		opt(g)
+	}
+	rest := pattern
+	for rest != "" {
+		i := strings.IndexByte(rest, '[')
+		if i < 0 {
+			g.segments = append(g.segments, segment{literal: rest})
+			break
+		}
+		if i > 0 {
+			g.segments = append(g.segments, segment{literal: rest[:i]})
+		}
+		j := strings.IndexByte(rest[i:], ']')
+		if j < 0 {
+			return nil, fmt.Errorf("keygen: unclosed field marker %q in pattern %q", rest[i:], pattern)
+		}
+		seg, err := parseMarker(rest[i+1 : i+j])
+		if err != nil {
+			return nil, fmt.Errorf("keygen: %w in pattern %q", err, pattern)
+		}
+		g.segments = append(g.segments, seg)
+		rest = rest[i+j+1:]
+	}
+	return g, nil
+}
+
+// parseMarker parses the text between the brackets of a field marker.
+func parseMarker(s string) (segment, error) {
+	parts := strings.Split(s, ":")
+	seg := segment{marker: strings.TrimSpace(parts[0])}
+	if seg.marker == "" {
+		return segment{}, fmt.Errorf("empty field marker [%s]", s)
+	}
+	if digits, ok := strings.CutPrefix(seg.marker, "auth"); ok {
+		if n, err := strconv.Atoi(digits); err == nil && n > 0 {
+			seg.marker, seg.n = "authN", n
+		}
+	}
+	for _, mod := range parts[1:] {
+		mod = strings.TrimSpace(mod)
+		if _, ok := modifiers[mod]; !ok {
+			return segment{}, fmt.Errorf("unknown modifier %q in field marker [%s]", mod, s)
+		}
+		seg.mods = append(seg.mods, mod)
+	}
+	return seg, nil
+}
+
+// Key returns the key of the entry for the pattern, without a suffix to
+// distinguish it from other keys. Characters that aren't allowed in a
+// citation key, like spaces, commas and braces, are removed. The key is
+// empty if the entry has none of the fields of the pattern and the pattern
+// has no literal text.
+func (g *Generator) Key(e bibtex.Entry) bibtex.CiteKey {
+	sb := &strings.Builder{}
+	for _, seg := range g.segments {
+		if seg.marker == "" {
+			sb.WriteString(seg.literal)
+			continue
+		}
+		words := markerWords(e, seg)
+		for _, mod := range seg.mods {
+			words = modifiers[mod](words)
+		}
+		for _, w := range words {
+			sb.WriteString(w)
+		}
+	}
+	return cleanKey(sb.String())
+}
+
+// Keys returns the new key of each entry, in the order of the entries. The
+// entries should be resolved from the files of pkg, like with
+// bibtex.Biber.Resolve on a separate parse of the files, since resolving
+// changes the tag values of the AST.
+//
+// Keys that collide with another key get the suffixes "a", "b", "c", and so
+// on, in the order of the entries, so the first of "Knuth1984" and
+// "Knuth1984" stays "Knuth1984" and the second becomes "Knuth1984a". A key
+// collides with the keys generated for earlier entries and with the keys of
+// the entries in the package scope, except for the entries that get new keys.
+// Abbreviations are a separate namespace, so a key may equal the name of an
+// abbreviation. Keys compare without case, like the keys of a crossref tag. If
+// the generated key of an entry is empty, the entry keeps its current key and
+// no other entry gets that key. Entries may share a current key, like the
+// duplicate keys of an imported file, and still get separate keys. Pkg may be
+// nil to only check the entries for collisions.
+//
+// With WithRewrite, Keys renames the entries of pkg to the new keys.
+func (g *Generator) Keys(pkg *ast.Package, entries []bibtex.Entry) []bibtex.CiteKey {
+	regenerated := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		regenerated[strings.ToLower(e.Key)] = true
+	}
+	taken := make(map[string]bool, len(entries))
+	if pkg != nil && pkg.Scope != nil {
+		for name := range pkg.Scope.Objects {
+			if !regenerated[strings.ToLower(name)] {
+				taken[strings.ToLower(name)] = true
+			}
+		}
+	}
+	// Reserve the kept keys first so that earlier entries don't take them.
+	keys := make([]bibtex.CiteKey, len(entries))
+	for i, e := range entries {
+		keys[i] = g.Key(e)
+		if keys[i] == "" && e.Key != "" {
+			taken[strings.ToLower(e.Key)] = true
+		}
+	}
+	for i, e := range entries {
+		base := keys[i]
+		if base == "" {
+			keys[i] = e.Key
+			continue
+		}
+		key := base
+		for n := 0; taken[strings.ToLower(key)]; n++ {
+			key = base + runes.Suffix(n)
+		}
+		taken[strings.ToLower(key)] = true
+		keys[i] = key
+	}
+	if g.rewrite && pkg != nil {
+		rewrite(pkg, entries, keys)
+	}
+	return keys
+}
+
+// markerWords returns the words of a field marker for the entry.
+func markerWords(e bibtex.Entry, seg segment) []string {
+	switch seg.marker {
+	case "auth", "authN", "authEtAl", "authors":
+		authors, others := e.Names()
+		if len(authors) == 0 {
+			return nil
+		}
+		switch seg.marker {
+		case "auth":
+			return lastName(authors[0])
+		case "authN":
+			return []string{runes.First(strings.Join(lastName(authors[0]), ""), seg.n)}
+		case "authEtAl":
+			words := lastName(authors[0])
+			switch {
+			case len(authors) > 2 || others:
+				words = append(words, "EtAl")
+			case len(authors) == 2:
+				words = append(words, lastName(authors[1])...)
+			}
+			return words
+		default:
+			var words []string
+			for _, a := range authors {
+				words = append(words, lastName(a)...)
+			}
+			return words
+		}
+
+	case "year", "shortyear":
+		var year string
+		if y, ok := e.Year(); ok {
+			year = strconv.Itoa(y)
+		} else {
+			year = strings.Join(strings.Fields(purify.Fold(e.Tags[bibtex.FieldYear])), "")
+		}
+		if seg.marker == "shortyear" {
+			year = runes.Last(year, 2)
+		}
+		return []string{year}
+
+	case "title", "shorttitle", "veryshorttitle":
+		return titleWords(strings.Fields(purify.Fold(e.Tags[bibtex.FieldTitle])), seg.marker)
+
+	case "firstpage", "lastpage":
+		first, last := e.Pages()
+		if seg.marker == "lastpage" {
+			return []string{last}
+		}
+		return []string{first}
+
+	default:
+		return strings.Fields(purify.Fold(e.Tags[strings.ToLower(seg.marker)]))
+	}
+}
+
+// titleWords returns the words of a title for a title marker.
+func titleWords(words []string, marker string) []string {
+	if marker == "title" {
+		title := make([]string, len(words))
+		for i, w := range words {
+			if i > 0 && functionWords[strings.ToLower(w)] {
+				title[i] = strings.ToLower(w)
+			} else {
+				title[i] = capitalize(w)
+			}
+		}
+		return title
+	}
+	n := 3
+	if marker == "veryshorttitle" {
+		n = 1
+	}
+	title := make([]string, 0, n)
+	for _, w := range words {
+		if len(title) == n {
+			break
+		}
+		if !functionWords[strings.ToLower(w)] {
+			title = append(title, capitalize(w))
+		}
+	}
+	return title
+}
+
+// lastName returns the words of the last name of an author without accents.
+func lastName(a *ast.Author) []string {
+	if a.Last == nil {
+		return nil
+	}
+	return strings.Fields(purify.Fold(a.Last))
+}
+
+// cleanKey removes the characters that aren't allowed in a citation key.
+func cleanKey(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) || strings.ContainsRune(`"#%'(),={}\~`, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func mapWords(words []string, f func(string) string) []string {
+	mapped := make([]string, len(words))
+	for i, w := range words {
+		mapped[i] = f(w)
+	}
+	return mapped
+}
+
+func capitalize(w string) string {
+	if w == "" {
+		return ""
+	}
+	r, size := utf8.DecodeRuneInString(w)
+	return string(unicode.ToUpper(r)) + w[size:]
+}
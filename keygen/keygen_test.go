@@ -0,0 +1,344 @@
+package keygen
+
+import (
+	gotok "go/token"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/internal/bibtest"
+	"github.com/jschaf/bibtex/parser"
+	"github.com/jschaf/bibtex/printer"
+)
+
+const testBib = `
+@book{knuth84, author = {Donald E. Knuth}, title = {The {TeX}book}, year = 1984, pages = {1--483}}
+@article{godel, author = {Kurt G{\"o}del}, title = {{\"U}ber formal unentscheidbare S{\"a}tze der Principia Mathematica}, year = 1931, journal = {Monatshefte f{\"u}r Mathematik}, pages = {173--198}}
+@article{poussin, author = {Charles de la Vall{\'e}e Poussin}, title = {Recherches analytiques sur la th{\'e}orie des nombres premiers}, year = 1896}
+@article{smithJones, author = {Smith, John and Jones, Jane}, title = {On the Theory of Everything}, year = 2019}
+@article{smithOthers, author = {Smith, John and others}, title = {A Study}, year = {n.d.}}
+@inproceedings{procella, author = {Chattopadhyay, Biswapesh and Dutta, Priyam and Liu, Weiran}, title = {Procella: Unifying Serving and Analytical Data at {YouTube}}, year = 2019, pages = {2022--2034}}
+@book{edited, editor = {Lamport, Leslie}, title = {Edited Volume}, year = 1994}
+@misc{empty, note = {Nothing}}
+`
+
+func TestGenerator_Key(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    map[string]string
+	}{
+		{
+			"[auth][year][shorttitle]",
+			map[string]string{
+				"knuth84":     "Knuth1984TeXbook",
+				"godel":       "Godel1931UberFormalUnentscheidbare",
+				"poussin":     "ValleePoussin1896RecherchesAnalytiquesSur",
+				"smithJones":  "Smith2019TheoryEverything",
+				"smithOthers": "SmithndStudy",
+				"procella":    "Chattopadhyay2019ProcellaUnifyingServing",
+				"edited":      "Lamport1994EditedVolume",
+				"empty":       "",
+			},
+		},
+		{
+			"[authEtAl]",
+			map[string]string{
+				"knuth84":     "Knuth",
+				"godel":       "Godel",
+				"poussin":     "ValleePoussin",
+				"smithJones":  "SmithJones",
+				"smithOthers": "SmithEtAl",
+				"procella":    "ChattopadhyayEtAl",
+				"edited":      "Lamport",
+				"empty":       "",
+			},
+		},
+		{
+			"[auth3:lower]:[shortyear]-[veryshorttitle:lower]",
+			map[string]string{
+				"knuth84":     "knu:84-texbook",
+				"godel":       "god:31-uber",
+				"poussin":     "val:96-recherches",
+				"smithJones":  "smi:19-theory",
+				"smithOthers": "smi:nd-study",
+				"procella":    "cha:19-procella",
+				"edited":      "lam:94-edited",
+				"empty":       ":-",
+			},
+		},
+		{
+			"[authors:abbr][firstpage][lastpage]",
+			map[string]string{
+				"knuth84":     "K1483",
+				"godel":       "G173198",
+				"poussin":     "VP",
+				"smithJones":  "SJ",
+				"smithOthers": "S",
+				"procella":    "CDL20222034",
+				"edited":      "L",
+				"empty":       "",
+			},
+		},
+		{
+			"[journal:abbr:upper][title]",
+			map[string]string{
+				"knuth84":     "TheTeXbook",
+				"godel":       "MFMUberFormalUnentscheidbareSatzeDerPrincipiaMathematica",
+				"poussin":     "RecherchesAnalytiquesSurLaTheorieDesNombresPremiers",
+				"smithJones":  "OntheTheoryofEverything",
+				"smithOthers": "AStudy",
+				"procella":    "ProcellaUnifyingServingandAnalyticalDataatYouTube",
+				"edited":      "EditedVolume",
+				"empty":       "",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			g, err := New(tt.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string, len(tt.want))
+			for _, e := range bibtest.Read(t, testBib) {
+				got[e.Key] = g.Key(e)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Key() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNew_errors(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"[auth][year", `keygen: unclosed field marker "[year" in pattern "[auth][year"`},
+		{"[auth][]", `keygen: empty field marker [] in pattern "[auth][]"`},
+		{"[auth:title]", `keygen: unknown modifier "title" in field marker [auth:title] in pattern "[auth:title]"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			_, err := New(tt.pattern)
+			if err == nil {
+				t.Fatalf("New(%q) returned no error; want %q", tt.pattern, tt.want)
+			}
+			if got := err.Error(); got != tt.want {
+				t.Errorf("New(%q) error = %q; want %q", tt.pattern, got, tt.want)
+			}
+		})
+	}
+}
+
+func parsePackage(t *testing.T, fset *gotok.FileSet, fsys fstest.MapFS, paths ...string) *ast.Package {
+	t.Helper()
+	pkg, err := parser.ParsePackageFS(fset, fsys, paths, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkg
+}
+
+func TestGenerator_Keys(t *testing.T) {
+	const src = `
+@article{smithA, author = {Smith, John}, title = {Alpha}, year = 2019}
+@article{smithB, author = {Smith, John}, title = {Beta}, year = 2019}
+@article{smithC, author = {Smith, Jane}, title = {Gamma}, year = 2019}
+@misc{empty, note = {Nothing}}
+`
+	const existing = `@misc{smith2019, title = {Existing}}`
+	fsys := fstest.MapFS{
+		"existing.bib": {Data: []byte(existing)},
+		"smith.bib":    {Data: []byte(src)},
+	}
+	g, err := New("[auth:lower][year]")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the entries of smith.bib get new keys, so the key of the entry in
+	// existing.bib is taken.
+	pkg := parsePackage(t, gotok.NewFileSet(), fsys, "existing.bib", "smith.bib")
+	got := g.Keys(pkg, bibtest.Read(t, src))
+	want := []string{"smith2019a", "smith2019b", "smith2019c", "empty"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Keys() mismatch (-want +got):\n%s", diff)
+	}
+	if pkg.Scope.Lookup("smithA") == nil || pkg.Scope.Lookup("smith2019a") != nil {
+		t.Errorf("Keys() without WithRewrite changed the package scope")
+	}
+
+	// Without a package, the first entry keeps the key.
+	got = g.Keys(nil, bibtest.Read(t, src))
+	want = []string{"smith2019", "smith2019a", "smith2019b", "empty"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Keys(nil) mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerator_Keys_keptKey(t *testing.T) {
+	// The entry without a generated key keeps its key even though an earlier
+	// entry generates the same key.
+	const src = `
+@article{first, author = {Smith, John}, title = {Alpha}, year = 2019}
+@misc{smith2019, note = {Nothing}}
+`
+	g, err := New("[auth:lower][year]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := g.Keys(nil, bibtest.Read(t, src))
+	want := []string{"smith2019a", "smith2019"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Keys() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerator_Keys_rewrite(t *testing.T) {
+	fsys := fstest.MapFS{
+		"confs.bib": {Data: []byte(
+			"@proceedings{conf19, editor = {Lamport, Leslie}, title = {Proceedings}, year = 2019}\n" +
+				"@xdata{acm, editor = {Denning, Peter}, publisher = {ACM}}\n")},
+		"papers.bib": {Data: []byte(
+			"@string{conf = {conf19}}\n" +
+				"@inproceedings{paper, author = {Knuth, Donald}, title = {Literate Programming}, crossref = {CONF19}, xdata = {Acm, other}}\n" +
+				"@inproceedings{abbrev, author = {Knuth, Donald}, title = {Other Programming}, crossref = conf}\n")},
+	}
+	paths := []string{"confs.bib", "papers.bib"}
+	entries, err := bibtex.New(bibtex.WithPresets()).Resolve(parsePackage(t, gotok.NewFileSet(), fsys, paths...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := New("[auth][year][veryshorttitle]", WithRewrite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := gotok.NewFileSet()
+	pkg := parsePackage(t, fset, fsys, paths...)
+	got := g.Keys(pkg, entries)
+	want := []string{"Lamport2019Proceedings", "Denning", "KnuthLiterate", "KnuthOther"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Keys() mismatch (-want +got):\n%s", diff)
+	}
+
+	for _, scope := range []*ast.Scope{pkg.Scope, pkg.Files["confs.bib"].Scope} {
+		if obj := scope.Lookup("Lamport2019Proceedings"); obj == nil || obj.Name != "Lamport2019Proceedings" {
+			t.Errorf("scope.Lookup(%q) = %v; want renamed entry", "Lamport2019Proceedings", obj)
+		}
+		if obj := scope.Lookup("conf19"); obj != nil {
+			t.Errorf("scope.Lookup(%q) = %v; want nil", "conf19", obj)
+		}
+	}
+
+	// The printer keeps the layout of the source around the renamed keys.
+	wantFiles := map[string]string{
+		"confs.bib": "@proceedings{Lamport2019Proceedings, editor = {Lamport, Leslie}, title = {Proceedings}, year = 2019}\n" +
+			"@xdata{Denning, editor = {Denning, Peter}, publisher = {ACM}}\n",
+		"papers.bib": "@string{conf = {conf19}}\n" +
+			"@inproceedings{KnuthLiterate, author = {Knuth, Donald}, title = {Literate Programming}, crossref = {Lamport2019Proceedings}, xdata = {Denning,other}}\n" +
+			"@inproceedings{KnuthOther, author = {Knuth, Donald}, title = {Other Programming}, crossref = conf}\n",
+	}
+	for name, want := range wantFiles {
+		sb := &strings.Builder{}
+		if err := printer.Fprint(sb, fset, pkg.Files[name]); err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, sb.String()); diff != "" {
+			t.Errorf("printed %s mismatch (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func TestGenerator_Keys_duplicateKeys(t *testing.T) {
+	// Entries that share a key, like the duplicates of an imported file, get
+	// their own keys and rename their own BibDecl. A reference to the shared
+	// key follows the first declaration.
+	const src = `@article{dup, author = {Knuth, Donald}, year = 1984}
+@article{dup, author = {Lamport, Leslie}, year = 1986}
+@inbook{chapter, author = {Dijkstra, Edsger}, year = 1976, crossref = {dup}}
+`
+	fsys := fstest.MapFS{"dup.bib": {Data: []byte(src)}}
+	g, err := New("[auth][year]", WithRewrite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := gotok.NewFileSet()
+	pkg := parsePackage(t, fset, fsys, "dup.bib")
+	got := g.Keys(pkg, bibtest.Read(t, src))
+	want := []string{"Knuth1984", "Lamport1986", "Dijkstra1976"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Keys() mismatch (-want +got):\n%s", diff)
+	}
+	for _, key := range want {
+		obj := pkg.Scope.Lookup(key)
+		if obj == nil {
+			t.Errorf("pkg.Scope.Lookup(%q) = nil; want renamed entry", key)
+			continue
+		}
+		if decl, ok := obj.Decl.(*ast.BibDecl); !ok || decl.Key.Name != key {
+			t.Errorf("pkg.Scope.Lookup(%q).Decl = %v; want entry with key %q", key, obj.Decl, key)
+		}
+	}
+
+	wantSrc := `@article{Knuth1984, author = {Knuth, Donald}, year = 1984}
+@article{Lamport1986, author = {Lamport, Leslie}, year = 1986}
+@inbook{Dijkstra1976, author = {Dijkstra, Edsger}, year = 1976, crossref = {Knuth1984}}
+`
+	sb := &strings.Builder{}
+	if err := printer.Fprint(sb, fset, pkg.Files["dup.bib"]); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantSrc, sb.String()); diff != "" {
+		t.Errorf("printed dup.bib mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestGenerator_Keys_emptyKeys(t *testing.T) {
+	// Entries without a key get their own keys and rename the BibDecls
+	// without a key in order.
+	entries := bibtest.Read(t, `
+@article{a, author = {Smith, John}, year = 2019}
+@article{b, author = {Smith, John}, year = 2019}
+@misc{c, note = {Nothing}}
+`)
+	for i := range entries {
+		entries[i].Key = ""
+	}
+	const src = `@article{author = {Smith, John}, year = 2019}
+@article{author = {Smith, John}, year = 2019}
+@misc{note = {Nothing}}
+`
+	fsys := fstest.MapFS{"empty.bib": {Data: []byte(src)}}
+	g, err := New("[auth:lower][year]", WithRewrite())
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := gotok.NewFileSet()
+	pkg := parsePackage(t, fset, fsys, "empty.bib")
+	got := g.Keys(pkg, entries)
+	want := []string{"smith2019", "smith2019a", ""}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Keys() mismatch (-want +got):\n%s", diff)
+	}
+
+	// The new keys have no source position, so the tags move to their own
+	// lines.
+	wantSrc := `@article{smith2019,
+  author = {Smith, John}, year = 2019}
+@article{smith2019a,
+  author = {Smith, John}, year = 2019}
+@misc{note = {Nothing}}
+`
+	sb := &strings.Builder{}
+	if err := printer.Fprint(sb, fset, pkg.Files["empty.bib"]); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantSrc, sb.String()); diff != "" {
+		t.Errorf("printed empty.bib mismatch (-want +got):\n%s", diff)
+	}
+}
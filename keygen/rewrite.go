@@ -0,0 +1,203 @@
+package keygen
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+)
+
+// rewrite renames the entries of pkg to the new keys, where keys[i] is the new
+// key of entries[i], and updates the crossref and xdata tags that reference the
+// old keys. The n-th entry with a key renames the n-th BibDecl with that key,
+// visiting the files sorted by name like bibtex.Biber.Resolve, so entries that
+// share a key each rename their own BibDecl.
+func rewrite(pkg *ast.Package, entries []bibtex.Entry, keys []bibtex.CiteKey) {
+	pending := make(map[string][]bibtex.CiteKey, len(entries)) // old key to new keys
+	for i, e := range entries {
+		pending[e.Key] = append(pending[e.Key], keys[i])
+	}
+	names := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	type rename struct {
+		file *ast.File
+		decl *ast.BibDecl
+		key  string
+	}
+	var renames []rename
+	renamedDecls := make(map[*ast.BibDecl]string, len(entries))
+	firstDecls := make(map[string]*ast.BibDecl) // folded old key to first decl
+	for _, name := range names {
+		f := pkg.Files[name]
+		for _, decl := range f.Entries {
+			decl, ok := decl.(*ast.BibDecl)
+			if !ok {
+				continue
+			}
+			old := ""
+			if decl.Key != nil {
+				old = decl.Key.Name
+			}
+			if _, ok := firstDecls[strings.ToLower(old)]; !ok {
+				firstDecls[strings.ToLower(old)] = decl
+			}
+			queue := pending[old]
+			if len(queue) == 0 {
+				continue
+			}
+			key := queue[0]
+			pending[old] = queue[1:]
+			if key != old {
+				renames = append(renames, rename{file: f, decl: decl, key: key})
+				renamedDecls[decl] = key
+			}
+		}
+	}
+	if len(renames) == 0 {
+		return
+	}
+
+	// A reference to an old key resolves to the entry declared first in the
+	// package scope, so only rewrite it if that entry is renamed.
+	renamed := make(map[string]string, len(renames)) // folded old key to new key
+	for folded, decl := range firstDecls {
+		if decl.Key == nil {
+			continue
+		}
+		if pkg.Scope != nil {
+			if obj, ok := pkg.Scope.Lookup(decl.Key.Name).Decl.(*ast.BibDecl); ok {
+				decl = obj
+			}
+		}
+		if key, ok := renamedDecls[decl]; ok {
+			renamed[folded] = key
+		}
+	}
+
+	// Remove all renamed objects before inserting them under the new keys so
+	// that an entry can take the old key of another renamed entry.
+	scopes := []*ast.Scope{pkg.Scope}
+	for _, f := range pkg.Files {
+		scopes = append(scopes, f.Scope)
+	}
+	for _, scope := range scopes {
+		if scope == nil {
+			continue
+		}
+		for name, obj := range scope.Objects {
+			if decl, ok := obj.Decl.(*ast.BibDecl); ok && obj.Kind == ast.Entry {
+				if _, ok := renamedDecls[decl]; ok {
+					delete(scope.Objects, name)
+				}
+			}
+		}
+	}
+	for _, r := range renames {
+		if r.decl.Key == nil {
+			r.decl.Key = &ast.Ident{NamePos: r.decl.LBrace + 1}
+		}
+		r.decl.Key.Name = r.key
+		obj := r.decl.Key.Obj
+		if obj == nil {
+			obj = ast.NewObj(ast.Entry, r.key)
+			obj.Decl = r.decl
+			r.decl.Key.Obj = obj
+		}
+		obj.Name = r.key
+		for _, scope := range []*ast.Scope{pkg.Scope, r.file.Scope} {
+			if scope != nil {
+				scope.Insert(obj)
+			}
+		}
+	}
+
+	for _, f := range pkg.Files {
+		for _, decl := range f.Entries {
+			decl, ok := decl.(*ast.BibDecl)
+			if !ok {
+				continue
+			}
+			for _, tag := range decl.Tags {
+				switch tag.Name {
+				case bibtex.FieldCrossref:
+					rewriteRefs(tag, renamed, false)
+				case bibtex.FieldXData:
+					rewriteRefs(tag, renamed, true)
+				}
+			}
+		}
+	}
+}
+
+// rewriteRefs replaces the renamed keys referenced by the value of a tag. If
+// list is true, the value is a comma-separated list of keys, like xdata.
+// Abbreviations and values that aren't plain keys are kept.
+func rewriteRefs(tag *ast.TagStmt, renamed map[string]string, list bool) {
+	s, ok := keyText(tag.Value)
+	if !ok {
+		return
+	}
+	refs := []string{s}
+	if list {
+		refs = strings.Split(s, ",")
+	}
+	changed := false
+	for i, ref := range refs {
+		if key, ok := renamed[strings.ToLower(strings.TrimSpace(ref))]; ok {
+			refs[i] = key
+			changed = true
+		} else {
+			refs[i] = strings.TrimSpace(ref)
+		}
+	}
+	if changed {
+		setKeyText(tag.Value, strings.Join(refs, ","))
+	}
+}
+
+// keyText returns the plain text of a tag value that references keys.
+func keyText(x ast.Expr) (string, bool) {
+	switch x := x.(type) {
+	case *ast.UnparsedText:
+		return x.Value, true
+	case *ast.Text:
+		return x.Value, true
+	case *ast.ParsedText:
+		sb := &strings.Builder{}
+		for _, v := range x.Values {
+			switch v := v.(type) {
+			case *ast.Text:
+				sb.WriteString(v.Value)
+			case *ast.TextComma:
+				sb.WriteByte(',')
+			case *ast.TextSpace:
+				sb.WriteByte(' ')
+			default:
+				return "", false
+			}
+		}
+		return sb.String(), true
+	}
+	return "", false
+}
+
+// setKeyText replaces the text of a tag value accepted by keyText.
+func setKeyText(x ast.Expr, s string) {
+	switch x := x.(type) {
+	case *ast.UnparsedText:
+		x.Value = s
+	case *ast.Text:
+		x.Value = s
+	case *ast.ParsedText:
+		pos := x.Pos()
+		if len(x.Values) > 0 {
+			pos = x.Values[0].Pos()
+		}
+		x.Values = []ast.Expr{&ast.Text{ValuePos: pos, Value: s}}
+	}
+}
@@ -0,0 +1,126 @@
+// Package label generates the citation labels of bibliography entries, like
+// "Knu84" for alphabetic styles or "Smith 2019a" for author-year styles.
+package label
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/internal/runes"
+	"github.com/jschaf/bibtex/names"
+	"github.com/jschaf/bibtex/purify"
+)
+
+// Template returns the label of an entry without the suffix that
+// distinguishes entries with the same label.
+type Template func(e bibtex.Entry) string
+
+// Label is the label of an entry.
+type Label struct {
+	Key    bibtex.CiteKey
+	Text   string // the label from the template, like "Knu84"
+	Suffix string // the suffix for entries with the same label, like "a", or ""
+}
+
+// String returns the label with the suffix, like "Knu84a".
+func (l Label) String() string {
+	return l.Text + l.Suffix
+}
+
+// Labels returns the labels of the entries in the same order as the entries.
+// Entries with the same label get the suffixes "a", "b", "c", and so on, in
+// the order of the entries, like the extradate letters of biblatex. The
+// entries should be sorted, like with sort.Entries, so that the suffixes
+// follow the order of the bibliography.
+func Labels(entries []bibtex.Entry, tmpl Template) []Label {
+	labels := make([]Label, len(entries))
+	counts := make(map[string]int, len(entries))
+	for i, e := range entries {
+		labels[i] = Label{Key: e.Key, Text: tmpl(e)}
+		counts[labels[i].Text]++
+	}
+	next := make(map[string]int, len(entries))
+	for i, l := range labels {
+		if counts[l.Text] < 2 {
+			continue
+		}
+		labels[i].Suffix = runes.Suffix(next[l.Text])
+		next[l.Text]++
+	}
+	return labels
+}
+
+// Alpha is the template of the alpha bibtex style. A single name gives the
+// first three letters of the last name, like "Knu84", or the initials of the
+// von part and last name if there are at least two, like "vG90" for "van
+// Gogh". Two to four names give the initials of each name, like "CDL19",
+// where a brace group of a parsed name is one word, like alpha.bst.
+// More than four names give the initials of the first three and a plus
+// sign, like "CDL+19", as do names ending with "and others". Entries without
+// authors or editors use the first three letters of the key field or else
+// the citation key.
+func Alpha(e bibtex.Entry) string {
+	authors, others := e.Names()
+	year := runes.Last(strings.TrimSpace(purify.Expr(e.Tags[bibtex.FieldYear])), 2)
+	if len(authors) == 0 {
+		if key := letters(purify.Expr(e.Tags[bibtex.FieldKey])); key != "" {
+			return runes.First(key, 3) + year
+		}
+		return runes.First(letters(e.Key), 3) + year
+	}
+	if len(authors) == 1 && !others {
+		s := names.LastInitials(authors[0])
+		if len([]rune(s)) < 2 {
+			s = runes.First(letters(names.Citation(authors[0])), 3)
+		}
+		return s + year
+	}
+	sb := &strings.Builder{}
+	n := len(authors)
+	if n > 4 {
+		n, others = 3, true
+	}
+	for _, a := range authors[:n] {
+		sb.WriteString(names.LastInitials(a))
+	}
+	if others {
+		sb.WriteByte('+')
+	}
+	return sb.String() + year
+}
+
+// AuthorYear is the template of author-year styles. A single name gives the
+// von part and last name and the year, like "Smith 2019". Two names are
+// joined with "and", like "Smith and Jones 2019". More names, or names ending
+// with "and others", give the first name and "et al.", like "Smith et al.
+// 2019". Entries without authors or editors use the citation key.
+func AuthorYear(e bibtex.Entry) string {
+	authors, others := e.Names()
+	var name string
+	switch {
+	case len(authors) == 0:
+		name = e.Key
+	case len(authors) == 1 && !others:
+		name = names.Citation(authors[0])
+	case len(authors) == 2 && !others:
+		name = names.Citation(authors[0]) + " and " + names.Citation(authors[1])
+	default:
+		name = names.Citation(authors[0]) + " et al."
+	}
+	year := strings.TrimSpace(purify.Expr(e.Tags[bibtex.FieldYear]))
+	if year == "" {
+		return name
+	}
+	return name + " " + year
+}
+
+// letters returns the letters and digits of s.
+func letters(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,142 @@
+package label
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/internal/bibtest"
+	"github.com/jschaf/bibtex/names"
+	"github.com/jschaf/bibtex/sort"
+)
+
+const testBib = `
+@book{knuth84, author = {Donald E. Knuth}, title = {The {TeX}book}, year = 1984}
+@article{gogh, author = {Vincent van Gogh}, title = {Letters}, year = 1890}
+@article{poussin, author = {Charles de la Vall{\'e}e Poussin}, title = {Primes}, year = 1896}
+@article{godel, author = {Kurt G{\"o}del}, title = {Incompleteness}, year = 1931}
+@inproceedings{procella, author = {Chattopadhyay, Biswapesh and Dutta, Priyam and Liu, Weiran}, title = {Procella}, year = 2019}
+@inproceedings{five, author = {Chattopadhyay, B. and Dutta, P. and Liu, W. and Tinn, O. and Mccormick, A.}, title = {Five}, year = 2019}
+@article{smithB, author = {Smith, John}, title = {Beta}, year = 2019}
+@article{smithA, author = {Smith, John}, title = {Alpha}, year = 2019}
+@article{smithJones, author = {Smith, John and Jones, Jane}, title = {Gamma}, year = 2019}
+@article{smithOthers, author = {Smith, John and others}, title = {Delta}, year = 2019}
+@book{edited, editor = {Lamport, Leslie}, title = {Edited}, year = 1994}
+@misc{anon, key = {Anonymous}, title = {Anonymous Work}, year = 2001}
+@misc{nokey, title = {No Key}}
+`
+
+func labelStrings(labels []Label) map[string]string {
+	m := make(map[string]string, len(labels))
+	for _, l := range labels {
+		m[l.Key] = l.String()
+	}
+	return m
+}
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl Template
+		want map[string]string
+	}{
+		{
+			"alpha", Alpha,
+			map[string]string{
+				"knuth84":     "Knu84",
+				"gogh":        "vG90",
+				"poussin":     "dlVP96",
+				"godel":       "Göd31",
+				"procella":    "CDL19",
+				"five":        "CDL+19",
+				"smithA":      "Smi19a",
+				"smithB":      "Smi19b",
+				"smithJones":  "SJ19",
+				"smithOthers": "S+19",
+				"edited":      "Lam94",
+				"anon":        "Ano01",
+				"nokey":       "nok",
+			},
+		},
+		{
+			"author-year", AuthorYear,
+			map[string]string{
+				"knuth84":     "Knuth 1984",
+				"gogh":        "van Gogh 1890",
+				"poussin":     "de la Vallée Poussin 1896",
+				"godel":       "Gödel 1931",
+				"procella":    "Chattopadhyay et al. 2019b",
+				"five":        "Chattopadhyay et al. 2019a",
+				"smithA":      "Smith 2019a",
+				"smithB":      "Smith 2019b",
+				"smithJones":  "Smith and Jones 2019",
+				"smithOthers": "Smith et al. 2019",
+				"edited":      "Lamport 1994",
+				"anon":        "anon 2001",
+				"nokey":       "nokey",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := bibtest.Read(t, testBib)
+			sort.Entries(entries, sort.NYT)
+			got := labelStrings(Labels(entries, tt.tmpl))
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Labels() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAlpha_parsedNames(t *testing.T) {
+	// Without rendering, a brace group in a name is a single word like in
+	// alpha.bst.
+	const src = `@book{barnes, author = {{Barnes and Noble} and Smith, John}, title = {Books}, year = 2001}`
+	b := bibtex.New(bibtex.WithResolvers(bibtex.NewAuthorResolver(bibtex.FieldAuthor)))
+	f, err := b.Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := b.Resolve(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Alpha(entries[0]), "BS01"; got != want {
+		t.Errorf("Alpha() = %q; want %q", got, want)
+	}
+}
+
+func TestLabels_sortOrder(t *testing.T) {
+	entries := bibtest.Read(t, testBib)
+	sort.Entries(entries, sort.NYT, sort.WithDescending())
+	got := labelStrings(Labels(entries, Alpha))
+	want := map[string]string{"smithA": "Smi19b", "smithB": "Smi19a"}
+	for key, label := range want {
+		if got[key] != label {
+			t.Errorf("Labels() label for %s = %q; want %q", key, got[key], label)
+		}
+	}
+}
+
+func TestLabels_template(t *testing.T) {
+	const src = `
+@article{smithJones, author = {Smith, John and Jones, Jane}, title = {Gamma}, year = 2019}
+@article{smithB, author = {Smith, John}, title = {Beta}, year = 2019}
+@article{smithA, author = {Smith, John}, title = {Alpha}, year = 2019}
+`
+	entries := bibtest.Read(t, src)
+	sort.Entries(entries, sort.NYT)
+	firstName := func(e bibtex.Entry) string {
+		return names.Citation(e.Authors()[0])
+	}
+	want := []Label{
+		{Key: "smithA", Text: "Smith", Suffix: "a"},
+		{Key: "smithB", Text: "Smith", Suffix: "b"},
+		{Key: "smithJones", Text: "Smith", Suffix: "c"},
+	}
+	if diff := cmp.Diff(want, Labels(entries, firstName)); diff != "" {
+		t.Errorf("Labels() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,228 @@
+// Package names formats the names of an ast.Author for display, citations
+// and sorting.
+//
+// The parts of an author are usually the parsed text of bibtex.ExtractAuthors
+// but may also be rendered text, like after bibtex.RenderParsedTextResolver.
+// Parsed text keeps brace groups, so Initials can treat "{Ch}ristophe" as a
+// single initial.
+package names
+
+import (
+	gotok "go/token"
+	"strings"
+	"unicode"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/purify"
+	"github.com/jschaf/bibtex/render"
+)
+
+// FullName returns the name of the author in the order "First von Last, Jr",
+// like "Charles de la Vallée Poussin, Jr.".
+func FullName(a *ast.Author) string {
+	name := joinNonEmpty(" ", partText(a.First), partText(a.Prefix), partText(a.Last))
+	return joinNonEmpty(", ", name, partText(a.Suffix))
+}
+
+// Citation returns the name of the author as used in an author-year citation:
+// the von part and the last name, like "de la Fontaine", or only the last
+// name if there's no von part.
+func Citation(a *ast.Author) string {
+	return joinNonEmpty(" ", partText(a.Prefix), partText(a.Last))
+}
+
+// Initials returns the initials of the first name of the author, like
+// "J.-P. D." for "Jean-Paul Daniel". Words joined with a hyphen keep the
+// hyphen. A brace group at the start of a word is a single initial, so
+// "{Ch}ristophe" becomes "Ch." and "{\'E}mile" becomes "É.".
+func Initials(a *ast.Author) string {
+	sb := &strings.Builder{}
+	for _, in := range GivenInitials(a) {
+		if sb.Len() > 0 {
+			if in.Hyphen {
+				sb.WriteByte('-')
+			} else {
+				sb.WriteByte(' ')
+			}
+		}
+		sb.WriteString(in.Text)
+		sb.WriteByte('.')
+	}
+	return sb.String()
+}
+
+// Initial is the initial of a word of a first name.
+type Initial struct {
+	Text   string // the initial without a period, like "J" or "Ch"
+	Hyphen bool   // the word follows a hyphen, like "Paul" in "Jean-Paul"
+}
+
+// GivenInitials returns the initials of the words of the first name of the
+// author, like Initials, for formatting the initials another way.
+func GivenInitials(a *ast.Author) []Initial {
+	var initials []Initial
+	for _, w := range splitWords(a.First) {
+		if initial := wordInitial(w.values); initial != "" {
+			initials = append(initials, Initial{Text: initial, Hyphen: w.sep == '-'})
+		}
+	}
+	return initials
+}
+
+// LastInitials returns the initials of the von part and the last name of the
+// author, like "vG" for "Vincent van Gogh", as in the labels of the alpha
+// bibtex style. A brace group is a single word, so "{Barnes and Noble}"
+// becomes "B".
+func LastInitials(a *ast.Author) string {
+	sb := &strings.Builder{}
+	for _, part := range []ast.Expr{a.Prefix, a.Last} {
+		for _, w := range splitWords(part) {
+			for _, r := range partText(&ast.ParsedText{Values: w.values}) {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					sb.WriteRune(r)
+					break
+				}
+			}
+		}
+	}
+	return sb.String()
+}
+
+// SortKey returns the key to sort the author by name: the last name, the
+// first name and the suffix, purified like the bibtex purify$ function.
+//
+// UsePrefix places the von part like the biber option of the same name. If
+// true, the von part starts the key, so "van Gogh" sorts under "v".
+// Otherwise, the von part ends the key, so "van Gogh" sorts under "G".
+func SortKey(a *ast.Author, usePrefix bool) string {
+	var parts []string
+	if usePrefix {
+		parts = append(parts, sortPart(a.Prefix))
+	}
+	parts = append(parts, sortPart(a.Last), sortPart(a.First), sortPart(a.Suffix))
+	if !usePrefix {
+		parts = append(parts, sortPart(a.Prefix))
+	}
+	return joinNonEmpty(" ", parts...)
+}
+
+// word is a word of a name part with the separator before it.
+type word struct {
+	sep    byte // ' ' or '-' before the word, or 0 for the first word
+	values []ast.Expr
+}
+
+// splitWords splits a name part into words separated by whitespace, ties and
+// hyphens at brace depth 0.
+func splitWords(x ast.Expr) []word {
+	var values []ast.Expr
+	switch t := x.(type) {
+	case nil:
+		return nil
+	case *ast.ParsedText:
+		values = t.Values
+	default:
+		values = []ast.Expr{x}
+	}
+
+	var words []word
+	var sep byte
+	inWord := false
+	separate := func(s byte) {
+		if sep == 0 || s == '-' {
+			sep = s
+		}
+		inWord = false
+	}
+	add := func(v ast.Expr) {
+		if !inWord {
+			if len(words) == 0 {
+				sep = 0
+			}
+			words = append(words, word{sep: sep})
+			sep, inWord = 0, true
+		}
+		words[len(words)-1].values = append(words[len(words)-1].values, v)
+	}
+	for _, v := range values {
+		switch v := v.(type) {
+		case *ast.TextSpace, *ast.TextNBSP, *ast.TextComma:
+			separate(' ')
+		case *ast.TextHyphen:
+			separate('-')
+		case *ast.Text:
+			start := -1
+			for i, r := range v.Value + " " {
+				if r != '-' && r != '~' && !unicode.IsSpace(r) {
+					if start < 0 {
+						start = i
+					}
+					continue
+				}
+				if start >= 0 {
+					add(&ast.Text{ValuePos: v.ValuePos + gotok.Pos(start), Value: v.Value[start:i]})
+					start = -1
+				}
+				if r == '-' {
+					separate('-')
+				} else if i < len(v.Value) {
+					separate(' ')
+				}
+			}
+		default:
+			add(v)
+		}
+	}
+	return words
+}
+
+// wordInitial returns the initial of a word: the first letter of the word
+// or its first brace group or special character, like {Ch} or \'E.
+func wordInitial(values []ast.Expr) string {
+	for _, v := range values {
+		if t, ok := v.(*ast.Text); ok {
+			for _, r := range t.Value {
+				if unicode.IsLetter(r) || unicode.IsDigit(r) {
+					return string(r)
+				}
+			}
+			continue
+		}
+		if s := partText(v); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// partText returns the rendered text of a name part.
+func partText(x ast.Expr) string {
+	if x == nil {
+		return ""
+	}
+	sb := &strings.Builder{}
+	if err := render.NewTextRenderer().Render(sb, x); err != nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+// sortPart returns the purified text of a name part with single spaces
+// between words.
+func sortPart(x ast.Expr) string {
+	if x == nil {
+		return ""
+	}
+	return strings.Join(strings.Fields(purify.Expr(x)), " ")
+}
+
+// joinNonEmpty joins the non-empty strings with sep.
+func joinNonEmpty(sep string, ss ...string) string {
+	var parts []string
+	for _, s := range ss {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, sep)
+}
@@ -0,0 +1,94 @@
+package names
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/parser"
+)
+
+// extractAuthor extracts the single author of a name field.
+func extractAuthor(t *testing.T, name string) *ast.Author {
+	t.Helper()
+	x, err := parser.ParseExpr("{" + name + "}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authors, err := bibtex.ExtractAuthors(x.(*ast.ParsedText))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(authors) != 1 {
+		t.Fatalf("got %d authors for %q; want 1", len(authors), name)
+	}
+	return authors[0]
+}
+
+func TestNames(t *testing.T) {
+	type names struct {
+		Initials, FullName, Citation, LastInitials, SortKey, SortKeyPrefix string
+	}
+	tests := []struct {
+		name string
+		want names
+	}{
+		{"Donald E. Knuth", names{"D. E.", "Donald E. Knuth", "Knuth", "K", "Knuth Donald E", "Knuth Donald E"}},
+		{"Knuth", names{"", "Knuth", "Knuth", "K", "Knuth", "Knuth"}},
+		{"Jean-Paul Sartre", names{"J.-P.", "Jean-Paul Sartre", "Sartre", "S", "Sartre Jean Paul", "Sartre Jean Paul"}},
+		{"Sartre, Jean-Paul Charles", names{"J.-P. C.", "Jean-Paul Charles Sartre", "Sartre", "S", "Sartre Jean Paul Charles", "Sartre Jean Paul Charles"}},
+		{"Bertrand, {Ch}ristophe", names{"Ch.", "Christophe Bertrand", "Bertrand", "B", "Bertrand Christophe", "Bertrand Christophe"}},
+		{"{\\'E}mile Zola", names{"É.", "Émile Zola", "Zola", "Z", "Zola Emile", "Zola Emile"}},
+		{"Vincent van Gogh", names{"V.", "Vincent van Gogh", "van Gogh", "vG", "Gogh Vincent van", "van Gogh Vincent"}},
+		{
+			"de la Vall{\\'e}e~Poussin, Jr., Charles~Louis",
+			names{
+				"C. L.",
+				"Charles Louis de la Vallée Poussin, Jr.",
+				"de la Vallée Poussin",
+				"dlVP",
+				"Vallee Poussin Charles Louis Jr de la",
+				"de la Vallee Poussin Charles Louis Jr",
+			},
+		},
+		{"Lloyd-Jones, {\\OE}dipus", names{"Œ.", "Œdipus Lloyd-Jones", "Lloyd-Jones", "LJ", "Lloyd Jones OEdipus", "Lloyd Jones OEdipus"}},
+		{"{Barnes and Noble, Inc.}", names{"", "Barnes and Noble, Inc.", "Barnes and Noble, Inc.", "B", "Barnes and Noble Inc", "Barnes and Noble Inc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := extractAuthor(t, tt.name)
+			got := names{
+				Initials:      Initials(a),
+				FullName:      FullName(a),
+				Citation:      Citation(a),
+				LastInitials:  LastInitials(a),
+				SortKey:       SortKey(a, false),
+				SortKeyPrefix: SortKey(a, true),
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("names mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestInitials_renderedText(t *testing.T) {
+	tests := []struct {
+		first string
+		want  string
+	}{
+		{"", ""},
+		{"Jean-Paul Daniel", "J.-P. D."},
+		{"  Martin  Luther ", "M. L."},
+		{"Émile", "É."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.first, func(t *testing.T) {
+			a := &ast.Author{First: &ast.Text{Value: tt.first}, Last: &ast.Text{Value: "Last"}}
+			if got := Initials(a); got != tt.want {
+				t.Errorf("Initials() = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,11 +4,15 @@ package parser
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	gotok "go/token"
 	"io"
+	"io/fs"
 	"os"
+	"sort"
 
 	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/scanner"
 )
 
 // If src != nil, readSource converts src to a []byte if possible;
@@ -91,8 +95,9 @@ func ParseFile(fset *gotok.FileSet, filename string, src interface{}, mode Mode)
 			// ParseFile API and return a valid (but) empty
 			// *ast.File
 			f = &ast.File{
-				Name:  filename,
-				Scope: ast.NewScope(nil),
+				Name:    filename,
+				Scope:   ast.NewScope(nil),
+				Abbrevs: ast.NewScope(nil),
 			}
 		}
 
@@ -115,6 +120,9 @@ func ParseExpr(str string) (ast.Expr, error) {
 	p.init(fset, "", src, ParseStrings)
 	p.next() // consume the '='
 	expr := p.parseExpr()
+	for _, ident := range p.unresolved {
+		ident.Obj = nil // remove unresolved sentinel; there's no scope
+	}
 	p.errors.Sort()
 	err := p.errors.Err()
 	if err != nil {
@@ -123,21 +131,110 @@ func ParseExpr(str string) (ast.Expr, error) {
 	return expr, nil
 }
 
-// ParsePackage calls ParseFile for all files specified by paths.
+// ParsePackage calls ParseFile for all files specified by paths and combines
+// the files into a single package. Position information is recorded in the
+// file set fset, which must not be nil.
 //
 // The mode bits are passed to ParseFile unchanged.
 //
-// If a parse error occurred, an incomplete package and the first error
-// encountered are returned.
-func ParsePackage(paths []string, mode Mode) (pkg *ast.Package, first error) {
-	fset := gotok.NewFileSet()
-	pkg = &ast.Package{}
+// The package scope contains the entries declared in all files and the
+// package abbreviation scope contains the abbreviations. Entries and
+// abbreviations are separate namespaces, so an entry may have the same name
+// as an abbreviation. Identifiers left unresolved by ParseFile, like an
+// abbreviation or crossref key defined in another file, are resolved in the
+// package scopes.
+// If a file declares an entry or abbreviation already declared in an earlier
+// file and the DeclarationErrors mode is set, ParsePackage reports an error
+// and keeps the earlier declaration.
+//
+// If a parse error occurred, an incomplete package and a scanner.ErrorList
+// with the errors of all files, sorted by source position, are returned.
+func ParsePackage(fset *gotok.FileSet, paths []string, mode Mode) (*ast.Package, error) {
+	if fset == nil {
+		panic("parser.ParsePackage: no token.FileSet provided (fset == nil)")
+	}
+	return parsePackage(fset, paths, mode, os.ReadFile)
+}
+
+// ParsePackageFS is like ParsePackage but reads the files specified by paths
+// from the file system fsys, like a bibliography embedded with go:embed.
+func ParsePackageFS(fset *gotok.FileSet, fsys fs.FS, paths []string, mode Mode) (*ast.Package, error) {
+	if fset == nil {
+		panic("parser.ParsePackageFS: no token.FileSet provided (fset == nil)")
+	}
+	return parsePackage(fset, paths, mode, func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	})
+}
+
+func parsePackage(fset *gotok.FileSet, paths []string, mode Mode, readFile func(string) ([]byte, error)) (*ast.Package, error) {
+	pkg := &ast.Package{
+		Scope:   ast.NewScope(nil),
+		Abbrevs: ast.NewScope(nil),
+		Files:   make(map[string]*ast.File, len(paths)),
+	}
+	pkg.Objects = pkg.Scope.Objects
+	var errs scanner.ErrorList
 	for _, filename := range paths {
-		if src, err := ParseFile(fset, filename, nil, mode); err == nil {
-			pkg.Files[filename] = src
-		} else if first == nil {
-			first = err
+		src, err := readFile(filename)
+		if err != nil {
+			errs.Add(gotok.Position{Filename: filename}, err.Error())
+			continue
+		}
+		f, err := ParseFile(fset, filename, src, mode)
+		if list, ok := err.(scanner.ErrorList); ok {
+			errs = append(errs, list...)
+		} else if err != nil {
+			errs.Add(gotok.Position{Filename: filename}, err.Error())
 		}
+		pkg.Files[filename] = f
 	}
-	return
+
+	// Declare the objects of each file in the package scopes. Visit files in
+	// the order given so that the first declaration wins.
+	for _, filename := range paths {
+		f := pkg.Files[filename]
+		if f == nil {
+			continue
+		}
+		errs = append(errs, declareAll(fset, pkg.Abbrevs, f.Abbrevs, mode)...)
+		errs = append(errs, declareAll(fset, pkg.Scope, f.Scope, mode)...)
+	}
+
+	// Resolve identifiers declared in other files.
+	for _, f := range pkg.Files {
+		f.Unresolved = resolveIdents(pkg.Abbrevs, f.Unresolved)
+		f.UnresolvedKeys = resolveIdents(pkg.Scope, f.UnresolvedKeys)
+	}
+
+	errs.Sort()
+	return pkg, errs.Err()
+}
+
+// declareAll inserts the objects of the file scope into the package scope and
+// returns an error for each redeclared object if the DeclarationErrors mode is
+// set.
+func declareAll(fset *gotok.FileSet, pkgScope, scope *ast.Scope, mode Mode) scanner.ErrorList {
+	if scope == nil {
+		return nil
+	}
+	names := make([]string, 0, len(scope.Objects))
+	for name := range scope.Objects {
+		names = append(names, name)
+	}
+	sort.Strings(names) // deterministic error order
+	var errs scanner.ErrorList
+	for _, name := range names {
+		obj := scope.Objects[name]
+		alt := pkgScope.Insert(obj)
+		if alt == nil || alt == obj || mode&DeclarationErrors == 0 {
+			continue
+		}
+		prevDecl := ""
+		if pos := alt.Pos(); pos.IsValid() {
+			prevDecl = fmt.Sprintf("\n\tprevious declaration at %s", fset.Position(pos))
+		}
+		errs.Add(fset.Position(obj.Pos()), fmt.Sprintf("%s %q redeclared%s", obj.Kind, name, prevDecl))
+	}
+	return errs
 }
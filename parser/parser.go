@@ -14,6 +14,7 @@ import (
 // The parser structure holds the parser's internal state.
 type parser struct {
 	file    *gotok.File
+	src     []byte
 	errors  goscan.ErrorList
 	scanner scanner.Scanner
 
@@ -40,13 +41,16 @@ type parser struct {
 	syncCnt int       // number of parser.advance calls without progress
 
 	// Ordinary cite key scopes
-	pkgScope   *ast.Scope   // pkgScope.Outer == nil
-	topScope   *ast.Scope   // top-most scope; may be pkgScope
-	unresolved []*ast.Ident // unresolved cite keys
+	pkgScope       *ast.Scope   // pkgScope.Outer == nil
+	topScope       *ast.Scope   // top-most scope; may be pkgScope
+	abbrevScope    *ast.Scope   // abbreviations; a separate namespace from entries
+	unresolved     []*ast.Ident // unresolved abbreviations
+	unresolvedKeys []*ast.Ident // unresolved crossref keys
 }
 
 func (p *parser) init(fset *gotok.FileSet, filename string, src []byte, mode Mode) {
 	p.file = fset.AddFile(filename, -1, len(src))
+	p.src = src
 	var m scanner.Mode
 	if mode&ParseComments != 0 {
 		m |= scanner.ScanComments
@@ -79,6 +83,66 @@ func (p *parser) closeScope() {
 // verifying internal consistency.
 var unresolved = new(ast.Object)
 
+// If x is an identifier, resolve records it as unresolved. Abbreviations may
+// be used before they're declared so all identifiers are resolved at the end
+// of the file.
+func (p *parser) resolve(x ast.Expr) {
+	ident, ok := x.(*ast.Ident)
+	if !ok {
+		return
+	}
+	assert(ident.Obj == nil, "identifier already declared or resolved")
+	ident.Obj = unresolved
+	p.unresolved = append(p.unresolved, ident)
+}
+
+// resolveCrossref records the entry key referenced by the value of a crossref
+// tag as an unresolved identifier. The identifier is not part of the AST.
+func (p *parser) resolveCrossref(val ast.Expr) {
+	var ident *ast.Ident
+	switch v := val.(type) {
+	case *ast.UnparsedText:
+		ident = &ast.Ident{NamePos: v.ValuePos + 1, Name: v.Value} // skip delimiter
+	case *ast.ParsedText:
+		sb := strings.Builder{}
+		for _, x := range v.Values {
+			t, ok := x.(*ast.Text)
+			if !ok {
+				return // not a plain key
+			}
+			sb.WriteString(t.Value)
+		}
+		ident = &ast.Ident{Name: sb.String()}
+		if len(v.Values) > 0 {
+			ident.NamePos = v.Values[0].Pos()
+		}
+	default:
+		return // abbreviations are resolved when parsed
+	}
+	if ident.Name == "" {
+		return
+	}
+	ident.Obj = unresolved
+	p.unresolvedKeys = append(p.unresolvedKeys, ident)
+}
+
+// declare inserts an object named name for the declaration decl into scope.
+// If ident is not nil, it denotes the new object.
+func (p *parser) declare(decl interface{}, scope *ast.Scope, kind ast.ObjKind, name string, pos gotok.Pos, ident *ast.Ident) {
+	obj := ast.NewObj(kind, name)
+	obj.Decl = decl
+	if ident != nil {
+		ident.Obj = obj
+	}
+	if alt := scope.Insert(obj); alt != nil && p.mode&DeclarationErrors != 0 {
+		prevDecl := ""
+		if pos := alt.Pos(); pos.IsValid() {
+			prevDecl = fmt.Sprintf("\n\tprevious declaration at %s", p.file.Position(pos))
+		}
+		p.error(pos, fmt.Sprintf("%s %q redeclared%s", kind, name, prevDecl))
+	}
+}
+
 // ----------------------------------------------------------------------------
 // Parsing support
 
@@ -294,17 +358,22 @@ func (p *parser) expectOne(tok ...token.Token) (token.Token, gotok.Pos) {
 	return token.Illegal, pos
 }
 
-func (p *parser) expectOptionalTagComma() {
+// expectOptionalTagComma consumes the comma following a tag and returns its
+// position. Returns gotok.NoPos if there was no comma.
+func (p *parser) expectOptionalTagComma() gotok.Pos {
 	if p.tok == token.RBrace || p.tok == token.RParen {
 		// TextComma is optional before a closing ')' or '}'
-		return
+		return gotok.NoPos
 	}
 	switch p.tok {
 	case token.Comma:
+		pos := p.pos
 		p.next()
+		return pos
 	default:
 		p.errorExpected(p.pos, "','")
 		p.advance(stmtStart)
+		return gotok.NoPos
 	}
 }
 
@@ -385,7 +454,9 @@ func (p *parser) parseBasicLit() (l ast.Expr) {
 		p.next()
 
 	case token.Ident:
-		l = p.parseIdent()
+		ident := p.parseIdent()
+		p.resolve(ident)
+		l = ident
 
 	default:
 		p.errorExpected(p.pos, "literal: number or string")
@@ -544,14 +615,66 @@ func (p *parser) parseURLStringLiteral() ast.Expr {
 			p.next()
 		}
 		txt := &ast.Text{
-			ValuePos: pos,
+			ValuePos: pos + 1,
 			Value:    sb.String(),
 		}
 		p.expect(token.DoubleQuote)
-		return txt
+		return &ast.ParsedText{
+			Opener: pos,
+			Depth:  0,
+			Delim:  ast.QuoteDelimiter,
+			Values: []ast.Expr{txt},
+			Closer: p.pos,
+		}
 
 	case token.StringLBrace:
-		return p.parseText(0)
+		p.next()
+		if p.tok == token.StringMacro {
+			// Parse a value like {\url{...}} like other text.
+			values := make([]ast.Expr, 0, 2)
+			for p.tok != token.StringRBrace {
+				text := p.parseText(1)
+				if _, ok := text.(*ast.BadExpr); ok {
+					p.next()
+					return text
+				}
+				values = append(values, text)
+			}
+			p.next() // consume closing '}'
+			return &ast.ParsedText{
+				Opener: pos,
+				Depth:  0,
+				Delim:  ast.BraceDelimiter,
+				Values: values,
+				Closer: p.pos,
+			}
+		}
+
+		// Keep the text verbatim, like ~ and %, without the nested braces.
+		textPos := p.pos
+		depth := 0
+		for depth > 0 || p.tok != token.StringRBrace {
+			switch p.tok {
+			case token.EOF:
+				p.errorExpected(p.pos, "'"+token.StringRBrace.String()+"'")
+				return &ast.BadExpr{From: pos, To: p.pos}
+			case token.StringLBrace:
+				depth++
+			case token.StringRBrace:
+				depth--
+			default:
+				sb.WriteString(p.lit)
+			}
+			p.next()
+		}
+		p.next() // consume closing '}'
+		return &ast.ParsedText{
+			Opener: pos,
+			Depth:  0,
+			Delim:  ast.BraceDelimiter,
+			Values: []ast.Expr{&ast.Text{ValuePos: textPos, Value: sb.String()}},
+			Closer: p.pos,
+		}
 
 	default:
 		p.errorExpected(p.pos, "string literal")
@@ -571,16 +694,6 @@ func (p *parser) parseExpr() (x ast.Expr) {
 	switch {
 	case p.tok.IsLiteral():
 		x = p.parseBasicLit()
-		if p.tok == token.Concat {
-			p.next()
-			opPos := p.pos
-			y := p.parseExpr()
-			x = &ast.ConcatExpr{
-				X:     x,
-				OpPos: opPos,
-				Y:     y,
-			}
-		}
 
 	case p.tok.IsStringLiteral():
 		x = p.parseStringLiteral()
@@ -592,6 +705,18 @@ func (p *parser) parseExpr() (x ast.Expr) {
 			To:   p.pos,
 		}
 		p.next() // make progress
+		return
+	}
+
+	if p.tok == token.Concat {
+		opPos := p.pos
+		p.next()
+		y := p.parseExpr()
+		x = &ast.ConcatExpr{
+			X:     x,
+			OpPos: opPos,
+			Y:     y,
+		}
 	}
 	return
 }
@@ -617,40 +742,56 @@ func (p *parser) parseTagStmt() *ast.TagStmt {
 	}
 	doc := p.leadComment
 	key := p.parseIdent()
-	p.expect(token.Assign)
+	assign := p.expect(token.Assign)
 	val := p.parseExpr()
-	p.expectOptionalTagComma()
+	comma := p.expectOptionalTagComma()
 	return &ast.TagStmt{
 		Doc:     doc,
 		NamePos: key.Pos(),
 		Name:    strings.ToLower(key.Name),
 		RawName: key.Name,
+		Assign:  assign,
 		Value:   val,
+		Comma:   comma,
 	}
 }
 
 func (p *parser) parseStringAccent() ast.Expr {
-	lit := p.lit
+	pos, lit := p.pos, p.lit
 	p.next()
 	if len(lit) <= 2 {
-		p.error(p.pos, "invalid accent string")
-		return &ast.BadExpr{From: p.pos, To: p.pos}
+		p.error(pos, "invalid accent string")
+		return &ast.BadExpr{From: pos, To: p.pos}
 	}
 	if lit[0] != '\\' {
-		p.error(p.pos, "invalid accent string (missing leading '\\')")
-		return &ast.BadExpr{From: p.pos, To: p.pos}
+		p.error(pos, "invalid accent string (missing leading '\\')")
+		return &ast.BadExpr{From: pos, To: p.pos}
+	}
+	// Skip the whitespace in an implicitly braced accent, like '\c c'.
+	offs := 2
+	for offs < len(lit) && strings.IndexByte(" \t\n\r", lit[offs]) >= 0 {
+		offs++
 	}
-	value := lit[2:]
+	value := lit[offs:]
 	if value[0] == '{' && value[len(value)-1] == '}' {
 		value = value[1 : len(value)-1]
+		offs++
+	}
+	// Replace the dotless i and j macros with the letters they produce.
+	switch value {
+	case `\i`:
+		value = "ı"
+	case `\j`:
+		value = "ȷ"
 	}
 	return &ast.TextAccent{
-		ValuePos: p.pos,
+		ValuePos: pos,
 		Accent:   token.Accent(lit[1]),
 		Text: &ast.Text{
-			ValuePos: p.pos + 2,
+			ValuePos: pos + gotok.Pos(offs),
 			Value:    value,
 		},
+		Raw: lit,
 	}
 }
 
@@ -672,33 +813,71 @@ func (p *parser) parsePreambleDecl() *ast.PreambleDecl {
 		defer un(trace(p, "PreambleDecl"))
 	}
 	doc := p.leadComment
+	rawType := p.lit[1:] // drop '@'
 	pos := p.expect(token.Preamble)
-	opener, _ := p.expectOne(token.LBrace, token.LParen)
+	opener, lbrace := p.expectOne(token.LBrace, token.LParen)
 	text := p.parseExpr()
 	closer := p.expectCloser(opener)
 	return &ast.PreambleDecl{
-		Doc:    doc,
-		Entry:  pos,
-		Text:   text,
-		RBrace: closer,
+		Doc:     doc,
+		Entry:   pos,
+		RawType: rawType,
+		Delim:   opener,
+		LBrace:  lbrace,
+		Text:    text,
+		RBrace:  closer,
 	}
 }
 
+func (p *parser) parseCommentDecl() *ast.CommentDecl {
+	if p.trace {
+		defer un(trace(p, "CommentDecl"))
+	}
+	decl := &ast.CommentDecl{
+		Doc:     p.leadComment,
+		RawType: p.lit[1:], // drop '@'
+	}
+	decl.Entry = p.expect(token.Comment)
+	if p.tok == token.LBrace || p.tok == token.LParen {
+		decl.Delim, decl.LBrace = p.tok, p.pos
+		p.next()
+	}
+	// A comment without a delimiter is blank if the rest of the line is.
+	if decl.Delim != token.Illegal || p.tok == token.CommentText {
+		decl.TextPos, decl.Text = p.pos, p.lit
+		p.expect(token.CommentText)
+	}
+	if decl.Delim != token.Illegal {
+		decl.RBrace = p.expectCloser(decl.Delim)
+	}
+	return decl
+}
+
 func (p *parser) parseAbbrevDecl() *ast.AbbrevDecl {
 	if p.trace {
 		defer un(trace(p, "AbbrevDecl"))
 	}
 	doc := p.leadComment
+	rawType := p.lit[1:] // drop '@'
 	pos := p.expect(token.Abbrev)
-	opener, _ := p.expectOne(token.LBrace, token.LParen)
+	opener, lbrace := p.expectOne(token.LBrace, token.LParen)
 	tag := p.parseTagStmt()
 	closer := p.expectCloser(opener)
-	return &ast.AbbrevDecl{
-		Doc:    doc,
-		Entry:  pos,
-		Tag:    tag,
-		RBrace: closer,
+	decl := &ast.AbbrevDecl{
+		Doc:     doc,
+		Entry:   pos,
+		RawType: rawType,
+		Delim:   opener,
+		LBrace:  lbrace,
+		Tag:     tag,
+		RBrace:  closer,
 	}
+	// Abbreviations are case-insensitive and the last definition wins, like
+	// in bibtex, so the object denotes the last declaration.
+	name := strings.ToLower(tag.Name)
+	p.declare(decl, p.abbrevScope, ast.Abbrev, name, tag.NamePos, nil)
+	p.abbrevScope.Lookup(name).Decl = decl
+	return decl
 }
 
 // fixUpFields alters val based on tag type. For example, a url tag doesn't
@@ -741,12 +920,12 @@ func (p *parser) parseBibDecl() *ast.BibDecl {
 		defer un(trace(p, "BibDecl"))
 	}
 	doc := p.leadComment
-	entryType := strings.ToLower(p.lit[1:]) // drop '@', e.g. "@BOOK" -> "book"
+	rawType := p.lit[1:] // drop '@', e.g. "@BOOK" -> "BOOK"
 	pos := p.expect(token.BibEntry)
 	var bibKey *ast.Ident // use first key found as bibKey
 	var extraKeys []*ast.Ident
 	tags := make([]*ast.TagStmt, 0, 8)
-	opener, _ := p.expectOne(token.LBrace, token.LParen)
+	opener, lbrace := p.expectOne(token.LBrace, token.LParen)
 	// A bibtex entry cite key may be all numbers but a tag key cannot.
 	for p.tok == token.Ident || p.tok == token.Number {
 		doc := p.leadComment
@@ -758,6 +937,7 @@ func (p *parser) parseBibDecl() *ast.BibDecl {
 			if !isValidTagName(key) {
 				p.error(key.Pos(), "tag keys must not start with a number")
 			}
+			assign := p.pos
 			p.next()
 			var val ast.Expr
 			if key.Name == "url" && p.tok.IsStringLiteral() {
@@ -766,21 +946,30 @@ func (p *parser) parseBibDecl() *ast.BibDecl {
 				val = p.parseExpr()
 			}
 			fixVal := fixUpFields(key.Name, val)
+			if strings.EqualFold(key.Name, "crossref") {
+				p.resolveCrossref(fixVal)
+			}
 			tag := &ast.TagStmt{
 				Doc:     doc,
 				NamePos: key.Pos(),
 				Name:    strings.ToLower(key.Name),
 				RawName: key.Name,
+				Assign:  assign,
 				Value:   fixVal,
 			}
 			tags = append(tags, tag)
+			if p.tok == token.Comma {
+				tag.Comma = p.pos
+				p.next()
+			}
+			continue
 		default:
 			// Keep going.
 		}
 		switch p.tok {
-		case token.Comma:
-			// It's a cite key.
-			p.next()
+		case token.Comma, token.RBrace, token.RParen:
+			// It's a cite key. The comma is optional for the final key.
+			p.expectOptional(token.Comma)
 			if bibKey == nil {
 				bibKey = key
 			} else {
@@ -793,15 +982,22 @@ func (p *parser) parseBibDecl() *ast.BibDecl {
 	}
 	closer := p.expectCloser(opener)
 	p.expectOptional(token.Comma) // trailing commas allowed
-	return &ast.BibDecl{
-		Type:      entryType,
+	decl := &ast.BibDecl{
+		Type:      strings.ToLower(rawType),
+		RawType:   rawType,
 		Doc:       doc,
 		Entry:     pos,
+		Delim:     opener,
+		LBrace:    lbrace,
 		Key:       bibKey,
 		ExtraKeys: extraKeys,
 		Tags:      tags,
 		RBrace:    closer,
 	}
+	if bibKey != nil {
+		p.declare(decl, p.pkgScope, ast.Entry, bibKey.Name, bibKey.Pos(), bibKey)
+	}
+	return decl
 }
 
 func (p *parser) parseDecl() ast.Decl {
@@ -816,6 +1012,8 @@ func (p *parser) parseDecl() ast.Decl {
 		return p.parseAbbrevDecl()
 	case token.BibEntry:
 		return p.parseBibDecl()
+	case token.Comment:
+		return p.parseCommentDecl()
 	default:
 		pos := p.pos
 		p.errorExpected(pos, "entry")
@@ -846,6 +1044,7 @@ func (p *parser) parseFile() *ast.File {
 
 	p.openScope()
 	p.pkgScope = p.topScope
+	p.abbrevScope = ast.NewScope(nil)
 	var decls []ast.Decl
 	for p.tok != token.EOF && p.tok != token.Illegal {
 		decls = append(decls, p.parseDecl())
@@ -854,22 +1053,36 @@ func (p *parser) parseFile() *ast.File {
 	assert(p.topScope == nil, "unbalanced scopes")
 
 	// resolve global identifiers within the same file
+	return &ast.File{
+		Name:           p.file.Name(),
+		Doc:            doc,
+		Entries:        decls,
+		Scope:          p.pkgScope,
+		Abbrevs:        p.abbrevScope,
+		Unresolved:     resolveIdents(p.abbrevScope, p.unresolved),
+		UnresolvedKeys: resolveIdents(p.pkgScope, p.unresolvedKeys),
+		Comments:       p.comments,
+		Src:            p.src,
+	}
+}
+
+// resolveIdents resolves the identifiers in scope and returns the identifiers
+// that remain unresolved. The unresolved identifiers reuse the storage of
+// idents.
+func resolveIdents(scope *ast.Scope, idents []*ast.Ident) []*ast.Ident {
 	i := 0
-	for _, ident := range p.unresolved {
+	for _, ident := range idents {
 		// i <= index for current ident
-		assert(ident.Obj == unresolved, "object already resolved")
-		ident.Obj = p.pkgScope.Lookup(ident.Name) // also removes unresolved sentinel
+		ident.Obj = scope.Lookup(ident.Name) // also removes unresolved sentinel
 		if ident.Obj == nil {
-			p.unresolved[i] = ident
+			// Abbreviations are case-insensitive. A crossref key also
+			// matches the entry with the lowercase key.
+			ident.Obj = scope.Lookup(strings.ToLower(ident.Name))
+		}
+		if ident.Obj == nil {
+			idents[i] = ident
 			i++
 		}
 	}
-
-	return &ast.File{
-		Doc:        doc,
-		Entries:    decls,
-		Scope:      p.pkgScope,
-		Unresolved: p.unresolved[0:i],
-		Comments:   p.comments,
-	}
+	return idents[0:i]
 }
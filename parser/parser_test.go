@@ -1,14 +1,18 @@
 package parser
 
 import (
+	"fmt"
 	gotok "go/token"
 	"os"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/jschaf/bibtex/ast"
 	"github.com/jschaf/bibtex/asts"
+	"github.com/jschaf/bibtex/scanner"
 	"github.com/jschaf/bibtex/token"
 )
 
@@ -119,6 +123,313 @@ func TestParseFile_AbbrevDecl(t *testing.T) {
 	}
 }
 
+func TestParseFile_CommentDecl(t *testing.T) {
+	tests := []struct {
+		src   string
+		delim token.Token
+		text  string
+	}{
+		{"@comment{foo}", token.LBrace, "foo"},
+		{"@COMMENT { jabref-meta: databaseType:bibtex; }", token.LBrace, " jabref-meta: databaseType:bibtex; "},
+		{"@Comment{a {nested} \"quote}", token.LBrace, `a {nested} "quote`},
+		{"@comment(a {)} b)", token.LParen, "a {)} b"},
+		{"@comment\n\t{foo}", token.LBrace, "foo"},
+		{"@comment rest of line", token.Illegal, "rest of line"},
+		{"@comment", token.Illegal, ""},
+	}
+	for _, tt := range tests {
+		for _, mode := range []Mode{0, ParseComments | ParseStrings} {
+			t.Run(tt.src, func(t *testing.T) {
+				src := tt.src + "\n@misc{key, title = {Foo}}"
+				f, err := ParseFile(gotok.NewFileSet(), "", src, mode)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(f.Entries) != 2 {
+					t.Fatalf("expected 2 entries; got %d", len(f.Entries))
+				}
+				got := f.Entries[0].(*ast.CommentDecl)
+				if got.Delim != tt.delim {
+					t.Errorf("CommentDecl.Delim: got %s, want %s", got.Delim, tt.delim)
+				}
+				if got.Text != tt.text {
+					t.Errorf("CommentDecl.Text: got %q, want %q", got.Text, tt.text)
+				}
+				if _, ok := f.Entries[1].(*ast.BibDecl); !ok {
+					t.Errorf("expected BibDecl after CommentDecl; got %T", f.Entries[1])
+				}
+			})
+		}
+	}
+}
+
+func TestParseFile_scope(t *testing.T) {
+	src := `
+		@string{ACM = "ACM"}
+		@book{knuth84, publisher = acm # " Press", month = jan}
+		@inbook{knuth84a, crossref = {knuth84}, publisher = ieee}
+		@inbook{knuth84b, crossref = "missing"}
+	`
+	for _, mode := range []Mode{0, ParseStrings} {
+		t.Run(fmt.Sprintf("mode %d", mode), func(t *testing.T) {
+			f, err := ParseFile(gotok.NewFileSet(), "", src, mode|DeclarationErrors)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			abbrev := f.Entries[0].(*ast.AbbrevDecl)
+			book := f.Entries[1].(*ast.BibDecl)
+			wantObjs := map[string]*ast.Object{
+				"knuth84":  {Kind: ast.Entry, Name: "knuth84", Decl: book},
+				"knuth84a": {Kind: ast.Entry, Name: "knuth84a", Decl: f.Entries[2]},
+				"knuth84b": {Kind: ast.Entry, Name: "knuth84b", Decl: f.Entries[3]},
+			}
+			if diff := cmp.Diff(wantObjs, f.Scope.Objects, cmpopts.IgnoreFields(ast.Object{}, "Decl")); diff != "" {
+				t.Errorf("Scope.Objects mismatch (-want +got):\n%s", diff)
+			}
+			for name, want := range wantObjs {
+				if got := f.Scope.Lookup(name); got.Decl != want.Decl {
+					t.Errorf("Scope.Lookup(%q).Decl = %T; want %T", name, got.Decl, want.Decl)
+				}
+			}
+			wantAbbrevs := map[string]*ast.Object{
+				"acm": {Kind: ast.Abbrev, Name: "acm", Decl: abbrev},
+			}
+			if diff := cmp.Diff(wantAbbrevs, f.Abbrevs.Objects, cmpopts.IgnoreFields(ast.Object{}, "Decl")); diff != "" {
+				t.Errorf("Abbrevs.Objects mismatch (-want +got):\n%s", diff)
+			}
+			if book.Key.Obj != f.Scope.Lookup("knuth84") {
+				t.Errorf("BibDecl.Key.Obj not set to scope object")
+			}
+			if ident, ok := book.Tags[0].Value.(*ast.ConcatExpr).X.(*ast.Ident); !ok || ident.Obj != f.Abbrevs.Lookup("acm") {
+				t.Errorf("abbrev reference not resolved to abbrev object")
+			}
+
+			var unresolved, unresolvedKeys []string
+			for _, ident := range f.Unresolved {
+				unresolved = append(unresolved, ident.Name)
+			}
+			for _, ident := range f.UnresolvedKeys {
+				unresolvedKeys = append(unresolvedKeys, ident.Name)
+			}
+			if diff := cmp.Diff([]string{"jan", "ieee"}, unresolved); diff != "" {
+				t.Errorf("Unresolved mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff([]string{"missing"}, unresolvedKeys); diff != "" {
+				t.Errorf("UnresolvedKeys mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseFile_DeclarationErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "duplicate entry",
+			src:  "@book{foo, title = {A}}\n@misc{foo}",
+			want: "2:7: entry \"foo\" redeclared\n\tprevious declaration at 1:7",
+		},
+		{
+			name: "duplicate abbrev",
+			src:  "@string{acm = {A}}\n@string{ACM = {B}}",
+			want: "2:9: abbrev \"acm\" redeclared\n\tprevious declaration at 1:9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFile(gotok.NewFileSet(), "", tt.src, 0); err != nil {
+				t.Fatalf("expected no error without DeclarationErrors; got %v", err)
+			}
+			_, err := ParseFile(gotok.NewFileSet(), "", tt.src, DeclarationErrors)
+			if err == nil {
+				t.Fatal("expected error but had none")
+			}
+			if diff := cmp.Diff(tt.want, err.Error()); diff != "" {
+				t.Errorf("ParseFile() error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	// An entry and an abbreviation may share a name.
+	src := "@string{foo = {A}}\n@misc{foo}"
+	if _, err := ParseFile(gotok.NewFileSet(), "", src, DeclarationErrors); err != nil {
+		t.Errorf("expected no error for entry and abbrev with same name; got %v", err)
+	}
+}
+
+func TestParseFile_abbrevRedefined(t *testing.T) {
+	// Abbreviations are case-insensitive and the last definition wins.
+	src := `@string{ACM = "ACM"} @string{acm = "IEEE"} @book{key, publisher = Acm}`
+	f, err := ParseFile(gotok.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := f.Entries[1].(*ast.AbbrevDecl)
+	if obj := f.Abbrevs.Lookup("acm"); obj == nil || obj.Decl != last {
+		t.Errorf("Abbrevs.Lookup(%q) = %v; want last definition", "acm", obj)
+	}
+	if ident := f.Entries[2].(*ast.BibDecl).Tags[0].Value.(*ast.Ident); ident.Obj == nil || ident.Obj.Decl != last {
+		t.Errorf("abbrev reference resolved to %v; want last definition", ident.Obj)
+	}
+	if len(f.Unresolved) != 0 {
+		t.Errorf("Unresolved = %v; want none", f.Unresolved)
+	}
+}
+
+func TestParseFile_scope_sharedName(t *testing.T) {
+	src := `
+		@string{acm = "ACM"}
+		@book{acm, publisher = acm}
+		@inbook{chapter, crossref = {acm}}
+	`
+	f, err := ParseFile(gotok.NewFileSet(), "", src, DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	abbrev := f.Entries[0].(*ast.AbbrevDecl)
+	book := f.Entries[1].(*ast.BibDecl)
+	if obj := f.Scope.Lookup("acm"); obj == nil || obj.Kind != ast.Entry || obj.Decl != book {
+		t.Errorf("Scope.Lookup(%q) = %v; want entry object for the book", "acm", obj)
+	}
+	if obj := f.Abbrevs.Lookup("acm"); obj == nil || obj.Kind != ast.Abbrev || obj.Decl != abbrev {
+		t.Errorf("Abbrevs.Lookup(%q) = %v; want abbrev object", "acm", obj)
+	}
+	if book.Key.Obj != f.Scope.Lookup("acm") {
+		t.Errorf("BibDecl.Key.Obj not set to scope object")
+	}
+	if ident := book.Tags[0].Value.(*ast.Ident); ident.Obj == nil || ident.Obj.Decl != abbrev {
+		t.Errorf("abbrev reference resolved to %v; want abbrev object", ident.Obj)
+	}
+	if len(f.Unresolved) != 0 || len(f.UnresolvedKeys) != 0 {
+		t.Errorf("expected all identifiers resolved; got Unresolved %v, UnresolvedKeys %v", f.Unresolved, f.UnresolvedKeys)
+	}
+
+	// The crossref key resolves to the entry across files too.
+	fsys := fstest.MapFS{
+		"abbrevs.bib": {Data: []byte(`@string{acm = "ACM"}`)},
+		"books.bib":   {Data: []byte(`@book{acm, publisher = acm}`)},
+		"papers.bib":  {Data: []byte(`@inbook{chapter, crossref = {acm}}`)},
+	}
+	pkg, err := ParsePackageFS(gotok.NewFileSet(), fsys, []string{"abbrevs.bib", "books.bib", "papers.bib"}, DeclarationErrors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj := pkg.Scope.Lookup("acm"); obj == nil || obj.Decl != pkg.Files["books.bib"].Entries[0] {
+		t.Errorf("Package.Scope.Lookup(%q) = %v; want entry object for the book", "acm", obj)
+	}
+	if obj := pkg.Abbrevs.Lookup("acm"); obj == nil || obj.Decl != pkg.Files["abbrevs.bib"].Entries[0] {
+		t.Errorf("Package.Abbrevs.Lookup(%q) = %v; want abbrev object", "acm", obj)
+	}
+	if keys := pkg.Files["papers.bib"].UnresolvedKeys; len(keys) != 0 {
+		t.Errorf("expected crossref key resolved across files; got %v", keys)
+	}
+}
+
+func TestParsePackageFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"abbrevs.bib": {Data: []byte(`@string{acm = "ACM"}`)},
+		"confs.bib":   {Data: []byte(`@proceedings{conf19, publisher = ACM}`)},
+		"papers.bib":  {Data: []byte(`@inproceedings{paper, crossref = {conf19}, month = jan}`)},
+	}
+	fset := gotok.NewFileSet()
+	pkg, err := ParsePackageFS(fset, fsys, []string{"abbrevs.bib", "confs.bib", "papers.bib"}, ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(pkg.Files), 3; got != want {
+		t.Fatalf("len(Package.Files) = %d; want %d", got, want)
+	}
+	for name, f := range pkg.Files {
+		if f.Name != name {
+			t.Errorf("File.Name = %q; want %q", f.Name, name)
+		}
+	}
+
+	acm := pkg.Files["abbrevs.bib"].Entries[0]
+	conf := pkg.Files["confs.bib"].Entries[0]
+	wantObjs := map[string]*ast.Object{
+		"conf19": {Kind: ast.Entry, Name: "conf19", Decl: conf},
+		"paper":  {Kind: ast.Entry, Name: "paper", Decl: pkg.Files["papers.bib"].Entries[0]},
+	}
+	if diff := cmp.Diff(wantObjs, pkg.Scope.Objects, cmpopts.IgnoreFields(ast.Object{}, "Decl")); diff != "" {
+		t.Errorf("Package.Scope.Objects mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(pkg.Scope.Objects, pkg.Objects, cmpopts.IgnoreFields(ast.Object{}, "Decl")); diff != "" {
+		t.Errorf("Package.Objects mismatch (-want +got):\n%s", diff)
+	}
+	wantAbbrevs := map[string]*ast.Object{
+		"acm": {Kind: ast.Abbrev, Name: "acm", Decl: acm},
+	}
+	if diff := cmp.Diff(wantAbbrevs, pkg.Abbrevs.Objects, cmpopts.IgnoreFields(ast.Object{}, "Decl")); diff != "" {
+		t.Errorf("Package.Abbrevs.Objects mismatch (-want +got):\n%s", diff)
+	}
+
+	// Identifiers declared in other files are resolved.
+	if ident := conf.(*ast.BibDecl).Tags[0].Value.(*ast.Ident); ident.Obj == nil || ident.Obj.Decl != acm {
+		t.Errorf("abbrev reference not resolved across files; got %v", ident.Obj)
+	}
+	var unresolved []string
+	for _, ident := range pkg.Files["papers.bib"].Unresolved {
+		unresolved = append(unresolved, ident.Name)
+	}
+	if diff := cmp.Diff([]string{"jan"}, unresolved); diff != "" {
+		t.Errorf("Unresolved mismatch (-want +got):\n%s", diff)
+	}
+
+	// All files share the file set.
+	pos := fset.Position(conf.Pos())
+	if pos.Filename != "confs.bib" || pos.Line != 1 {
+		t.Errorf("position of conf19 = %s; want confs.bib:1", pos)
+	}
+}
+
+func TestParsePackageFS_errors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.bib": {Data: []byte("@book{foo}\n@misc{bar, title = }")},
+		"b.bib": {Data: []byte("@misc{foo}\n@misc{baz, = }")},
+	}
+	pkg, err := ParsePackageFS(gotok.NewFileSet(), fsys, []string{"a.bib", "b.bib", "missing.bib"}, DeclarationErrors)
+	if pkg == nil || len(pkg.Files) != 2 {
+		t.Fatalf("expected incomplete package with 2 files; got %v", pkg)
+	}
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		t.Fatalf("expected scanner.ErrorList; got %T: %v", err, err)
+	}
+	var got []string
+	for _, e := range list {
+		got = append(got, e.Error())
+	}
+	want := []string{
+		`a.bib:2:20: expected literal: number or string, found 'RBrace'`,
+		"b.bib:1:7: entry \"foo\" redeclared\n\tprevious declaration at a.bib:1:7",
+		`b.bib:2:12: expected 'RBrace', found 'Assign'`,
+		`missing.bib: open missing.bib: file does not exist`,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParsePackageFS() errors mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestParsePackage(t *testing.T) {
+	path := "testdata/vldb.bib"
+	pkg, err := ParsePackage(gotok.NewFileSet(), []string{path}, ParseComments|ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := pkg.Files[path]
+	if f == nil || len(f.Entries) == 0 {
+		t.Fatalf("expected entries in %s", path)
+	}
+	if pkg.Scope == nil || len(pkg.Scope.Objects) != len(f.Scope.Objects) {
+		t.Errorf("expected package scope with the objects of %s", path)
+	}
+}
+
 func TestParseFile_BibDecl_NoParseStrings(t *testing.T) {
 	tests := []struct {
 		src    string
@@ -194,6 +505,9 @@ func TestParseFile_BibDecl_NoParseStrings(t *testing.T) {
 			if diff := cmp.Diff(wantBib.Key, gotBib.Key, cmpIdentName()); diff != "" {
 				t.Errorf("BibDecl keys mismatch (-want +got):\n%s", diff)
 			}
+			if diff := cmp.Diff(wantBib.ExtraKeys, gotBib.ExtraKeys, cmpIdentName()); diff != "" {
+				t.Errorf("BibDecl extra keys mismatch (-want +got):\n%s", diff)
+			}
 			if diff := cmp.Diff(wantBib.Tags, gotBib.Tags, cmpTagEntry()); diff != "" {
 				t.Errorf("BibDecl keys mismatch (-want +got):\n%s", diff)
 			}
@@ -294,6 +608,13 @@ func TestParseFile_BibDecl_ModeParseStrings(t *testing.T) {
 				asts.BraceText(0, asts.Macro("href", "https://nyt.com/"),
 					asts.BraceText(1, "Dollar", " ", asts.Escaped('$'), "140"))),
 		},
+		{
+			name:   "article title concat",
+			src:    `@article { cite_key, title = "foo" # {bar} # baz }`,
+			keysFn: asts.WithBibKeys("cite_key"),
+			tagsFn: asts.WithBibTags("title",
+				asts.Concat(asts.QuotedText(0, "foo"), asts.Concat(asts.BraceText(0, "bar"), asts.Ident("baz")))),
+		},
 		{
 			name:   "article title escaped ampersand",
 			src:    `@article { cite_key, title = {foo \& bar} }`,
@@ -331,6 +652,12 @@ func TestParseExpr_BibDecl_ModeParseStrings(t *testing.T) {
 	}{
 		{"{foo}", asts.BraceText(0, "foo")},
 		{`"foo"`, asts.QuotedText(0, "foo")},
+		{`{\v{S}korpil}`, asts.BraceText(0, asts.AccentedText(token.AccentCaron, "S"), "korpil")},
+		{`{\"\i}`, asts.BraceText(0, asts.AccentedText(token.AccentUmlaut, "ı"))},
+		{`{\'{\j}}`, asts.BraceText(0, asts.AccentedText(token.AccentAcute, "ȷ"))},
+		{`{\'{æ}}`, asts.BraceText(0, asts.AccentedText(token.AccentAcute, "æ"))},
+		{`{\k a}`, asts.BraceText(0, asts.AccentedText(token.AccentOgonek, "a"))},
+		{`{{\o}}`, asts.BraceText(0, asts.BraceText(1, asts.Macro("o")))},
 	}
 	for _, tt := range tests {
 		t.Run(tt.src, func(t *testing.T) {
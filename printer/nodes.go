@@ -0,0 +1,477 @@
+// This file implements printing of AST nodes.
+
+package printer
+
+import (
+	"fmt"
+	gotok "go/token"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/token"
+)
+
+// ----------------------------------------------------------------------------
+// Files
+
+func (p *printer) file(f *ast.File) error {
+	p.comments = f.Comments
+	if p.Mode&Canonical == 0 {
+		p.src = f.Src
+	}
+	for _, d := range f.Entries {
+		sep := "\n\n"
+		if p.Mode&Canonical != 0 {
+			sep = p.canonicalDeclSep(d)
+		}
+		if p.output.Len() == 0 {
+			sep = ""
+		}
+		if err := p.decl(d, sep); err != nil {
+			return err
+		}
+	}
+	p.flushComments(gotok.NoPos)
+	if p.last >= 0 {
+		// Keep the whitespace at the end of the source, if any.
+		lo, _ := p.gapStart()
+		p.write(string(p.src[spaceBefore(p.src, lo, len(p.src)):]))
+		return nil
+	}
+	if n := p.output.Len(); n > 0 && p.output.Bytes()[n-1] != '\n' {
+		p.write("\n")
+	}
+	return nil
+}
+
+// canonicalDeclSep prints the comments before d and returns the separator
+// between d and the preceding declaration or comment. Declarations are
+// separated by a single blank line. A comment right before a declaration in
+// the source stays attached to the declaration.
+func (p *printer) canonicalDeclSep(d ast.Decl) string {
+	p.flushComments(d.Pos())
+	if !p.lineComment {
+		return "\n\n"
+	}
+	if position, ok := p.position(d.Pos()); ok && position.Line > p.lastLine+1 {
+		return "\n\n"
+	}
+	return "\n"
+}
+
+// ----------------------------------------------------------------------------
+// Declarations
+
+// declDelims returns the opening and closing delimiter for a declaration
+// opened by tok.
+func declDelims(tok token.Token) (string, string) {
+	if tok == token.LParen {
+		return "(", ")"
+	}
+	return "{", "}"
+}
+
+func (p *printer) decl(d ast.Decl, sep string) error {
+	switch d := d.(type) {
+	case *ast.BibDecl:
+		return p.bibDecl(d, sep)
+	case *ast.AbbrevDecl:
+		return p.abbrevDecl(d, sep)
+	case *ast.PreambleDecl:
+		return p.preambleDecl(d, sep)
+	case *ast.CommentDecl:
+		return p.commentDecl(d, sep)
+	case *ast.BadDecl:
+		return fmt.Errorf("printer: cannot print BadDecl at %s", p.posString(d.Pos()))
+	default:
+		return fmt.Errorf("printer: unsupported declaration %T", d)
+	}
+}
+
+func (p *printer) bibDecl(d *ast.BibDecl, sep string) error {
+	typ := d.RawType
+	if typ == "" {
+		typ = d.Type
+	}
+	opener, closer := declDelims(d.Delim)
+	p.print(d.Entry, sep, "@"+typ)
+	p.print(d.LBrace, "", opener)
+	if d.Key != nil {
+		p.print(d.Key.NamePos, "", d.Key.Name)
+		if len(d.Tags) > 0 || len(d.ExtraKeys) > 0 {
+			next := d.RBrace
+			switch {
+			case len(d.ExtraKeys) > 0 && (len(d.Tags) == 0 || isBefore(d.ExtraKeys[0].Pos(), d.Tags[0].Pos())):
+				next = d.ExtraKeys[0].Pos()
+			case len(d.Tags) > 0:
+				next = d.Tags[0].Pos()
+			}
+			p.print(p.punctPos(",", next), "", ",")
+		}
+	}
+
+	// Extra keys may appear anywhere between the tags, so interleave them
+	// by position.
+	tagSep := "\n" + strings.Repeat(" ", p.Indent)
+	keySep := " "
+	width := 0
+	trailingComma := false
+	if p.Mode&Canonical != 0 {
+		p.indent = tagSep[1:]
+		defer func() { p.indent = "" }()
+		keySep = tagSep
+		if p.Mode&AlignTags != 0 {
+			width = tagNameWidth(d.Tags)
+		}
+		trailingComma = p.Mode&TrailingComma != 0
+	}
+	extra := d.ExtraKeys
+	for i, tag := range d.Tags {
+		for len(extra) > 0 && isBefore(extra[0].Pos(), tag.Pos()) {
+			p.print(extra[0].NamePos, keySep, extra[0].Name)
+			p.print(p.punctPos(",", tag.Pos()), "", ",")
+			extra = extra[1:]
+		}
+		isLast := i == len(d.Tags)-1 && len(extra) == 0 && !trailingComma
+		if err := p.tagStmt(tag, tagSep, isLast, width); err != nil {
+			return err
+		}
+	}
+	for i, key := range extra {
+		p.print(key.NamePos, keySep, key.Name)
+		switch {
+		case i < len(extra)-1:
+			p.print(p.punctPos(",", extra[i+1].Pos()), "", ",")
+		case trailingComma:
+			p.print(gotok.NoPos, "", ",")
+		default:
+			// Keep a trailing comma of the source.
+			if pos := p.punctPos(",", d.RBrace); pos.IsValid() {
+				p.print(pos, "", ",")
+			}
+		}
+	}
+
+	closerSep := ""
+	if len(d.Tags) > 0 || (p.Mode&Canonical != 0 && len(d.ExtraKeys) > 0) {
+		closerSep = "\n"
+	}
+	p.print(d.RBrace, closerSep, closer)
+	return nil
+}
+
+// tagNameWidth returns the length of the longest printed tag name.
+func tagNameWidth(tags []*ast.TagStmt) int {
+	width := 0
+	for _, t := range tags {
+		width = max(width, len(tagName(t)))
+	}
+	return width
+}
+
+// isBefore returns true if x appears before y in the source. Invalid
+// positions appear before all valid positions.
+func isBefore(x, y gotok.Pos) bool {
+	return !x.IsValid() || !y.IsValid() || x < y
+}
+
+func (p *printer) abbrevDecl(d *ast.AbbrevDecl, sep string) error {
+	typ := d.RawType
+	if typ == "" {
+		typ = "string"
+	}
+	opener, closer := declDelims(d.Delim)
+	p.print(d.Entry, sep, "@"+typ)
+	p.print(d.LBrace, "", opener)
+	if err := p.tagStmt(d.Tag, "", true, 0); err != nil {
+		return err
+	}
+	p.print(d.RBrace, "", closer)
+	return nil
+}
+
+func (p *printer) preambleDecl(d *ast.PreambleDecl, sep string) error {
+	typ := d.RawType
+	if typ == "" {
+		typ = "preamble"
+	}
+	opener, closer := declDelims(d.Delim)
+	p.print(d.Entry, sep, "@"+typ)
+	p.print(d.LBrace, "", opener)
+	if err := p.expr(d.Text, ""); err != nil {
+		return err
+	}
+	p.print(d.RBrace, "", closer)
+	return nil
+}
+
+func (p *printer) commentDecl(d *ast.CommentDecl, sep string) error {
+	typ := d.RawType
+	if typ == "" {
+		typ = "comment"
+	}
+	p.print(d.Entry, sep, "@"+typ)
+	if d.Delim == token.Illegal {
+		// The comment extends to the end of the line.
+		if d.Text != "" {
+			p.print(d.TextPos, " ", d.Text)
+		}
+		return nil
+	}
+	opener, closer := declDelims(d.Delim)
+	p.print(d.LBrace, "", opener)
+	p.print(d.TextPos, "", d.Text)
+	p.print(d.RBrace, "", closer)
+	return nil
+}
+
+// ----------------------------------------------------------------------------
+// Statements
+
+func (p *printer) stmt(s ast.Stmt, sep string) error {
+	switch s := s.(type) {
+	case *ast.TagStmt:
+		return p.tagStmt(s, sep, true, 0)
+	case *ast.BadStmt:
+		return fmt.Errorf("printer: cannot print BadStmt at %s", p.posString(s.Pos()))
+	default:
+		return fmt.Errorf("printer: unsupported statement %T", s)
+	}
+}
+
+// tagStmt prints a tag with the name padded to width. A tag without a comma
+// in the source ends with a comma unless isLast is true, so that adding tags
+// keeps the declaration valid. In Canonical mode, a tag ends with a comma if
+// and only if isLast is false.
+func (p *printer) tagStmt(t *ast.TagStmt, sep string, isLast bool, width int) error {
+	name := tagName(t)
+	if n := width - len(name); n > 0 {
+		name += strings.Repeat(" ", n)
+	}
+	p.print(t.NamePos, sep, name)
+	p.print(t.Assign, " ", "=")
+	if err := p.expr(t.Value, " "); err != nil {
+		return fmt.Errorf("tag %q: %w", t.Name, err)
+	}
+	switch {
+	case p.Mode&Canonical != 0:
+		if !isLast {
+			p.print(t.Comma, "", ",")
+		}
+	case t.Comma.IsValid():
+		p.print(t.Comma, "", ",")
+	case !isLast:
+		p.print(gotok.NoPos, "", ",")
+	}
+	return nil
+}
+
+// tagName returns the name of the tag as it appeared in the source.
+func tagName(t *ast.TagStmt) string {
+	if t.RawName != "" {
+		return t.RawName
+	}
+	return t.Name
+}
+
+// ----------------------------------------------------------------------------
+// Expressions
+
+func (p *printer) expr(x ast.Expr, sep string) error {
+	switch x := x.(type) {
+	case *ast.Ident:
+		p.print(x.NamePos, sep, x.Name)
+	case *ast.Number:
+		p.print(x.ValuePos, sep, x.Value)
+	case *ast.UnparsedText:
+		if x.Type == token.String {
+			p.print(x.ValuePos, sep, `"`+x.Value+`"`)
+		} else {
+			p.print(x.ValuePos, sep, "{"+x.Value+"}")
+		}
+	case *ast.ParsedText:
+		opener, closer := textDelims(x.Delim)
+		p.print(x.Opener, sep, opener)
+		start := p.output.Len() - len(opener)
+		if err := p.texts(x.Values); err != nil {
+			return err
+		}
+		p.write(closer)
+		if off, ok := p.offset(x.Opener); ok && p.last == off {
+			// The string is a single token for the whitespace after it.
+			p.lastLit = string(p.output.Bytes()[start:])
+		}
+	case *ast.ConcatExpr:
+		if err := p.expr(x.X, sep); err != nil {
+			return err
+		}
+		p.print(x.OpPos, " ", "#")
+		return p.expr(x.Y, " ")
+	case *ast.BadExpr:
+		return fmt.Errorf("printer: cannot print BadExpr at %s", p.posString(x.Pos()))
+	default:
+		// A text node outside of ParsedText, usually from a resolver that
+		// simplified the tag value. Wrap it in braces to make it a valid
+		// bibtex string.
+		p.print(gotok.NoPos, sep, "{")
+		if err := p.text(x); err != nil {
+			return err
+		}
+		p.write("}")
+	}
+	return nil
+}
+
+// textDelims returns the opening and closing delimiter for parsed text.
+func textDelims(delim ast.TextDelimiter) (string, string) {
+	if delim == ast.QuoteDelimiter {
+		return `"`, `"`
+	}
+	return "{", "}"
+}
+
+func (p *printer) texts(xs []ast.Expr) error {
+	for _, x := range xs {
+		if err := p.text(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// text prints an expression that's part of a bibtex string. The whitespace in
+// strings is part of the AST so text ignores positions.
+func (p *printer) text(x ast.Expr) error {
+	switch x := x.(type) {
+	case *ast.ParsedText:
+		opener, closer := textDelims(x.Delim)
+		p.write(opener)
+		if err := p.texts(x.Values); err != nil {
+			return err
+		}
+		p.write(closer)
+	case *ast.Text:
+		p.write(x.Value)
+	case *ast.TextSpace:
+		if x.Value == "" {
+			p.write(" ")
+		} else {
+			p.write(x.Value)
+		}
+	case *ast.TextComma:
+		p.write(",")
+	case *ast.TextEscaped:
+		p.write(`\` + x.Value)
+	case *ast.TextHyphen:
+		p.write("-")
+	case *ast.TextMath:
+		p.write("$" + x.Value + "$")
+	case *ast.TextNBSP:
+		p.write("~")
+	case *ast.TextAccent:
+		if x.Raw != "" {
+			p.write(x.Raw)
+		} else {
+			p.write(`\` + string(x.Accent) + "{" + x.Text.Value + "}")
+		}
+	case *ast.TextMacro:
+		if strings.HasPrefix(x.Name, `\`) {
+			p.write(x.Name) // single non-alphabetical char macro, like '\,'
+		} else {
+			p.write(`\` + x.Name)
+		}
+		for _, v := range x.Values {
+			if t, ok := v.(*ast.ParsedText); ok && t.Delim == ast.BraceDelimiter {
+				if err := p.text(t); err != nil {
+					return err
+				}
+				continue
+			}
+			p.write("{")
+			if err := p.text(v); err != nil {
+				return err
+			}
+			p.write("}")
+		}
+	case ast.Authors:
+		return p.authors(x)
+	case *ast.Ident:
+		p.write(x.Name)
+	case *ast.Number:
+		p.write(x.Value)
+	case *ast.UnparsedText:
+		p.write(x.Value)
+	case *ast.BadExpr:
+		return fmt.Errorf("printer: cannot print BadExpr at %s", p.posString(x.Pos()))
+	default:
+		return fmt.Errorf("printer: unsupported text expression %T", x)
+	}
+	return nil
+}
+
+// authors prints authors in the "von Last, Jr, First" form separated by "and".
+// Unlike the "First von Last" form, the comma form is unambiguous for last
+// names with multiple words.
+func (p *printer) authors(as ast.Authors) error {
+	for i, a := range as {
+		if i > 0 {
+			p.write(" and ")
+		}
+		if a.IsOthers() {
+			p.write("others")
+			continue
+		}
+		first, err := p.namePart(a.First)
+		if err != nil {
+			return err
+		}
+		prefix, err := p.namePart(a.Prefix)
+		if err != nil {
+			return err
+		}
+		last, err := p.namePart(a.Last)
+		if err != nil {
+			return err
+		}
+		suffix, err := p.namePart(a.Suffix)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSpace(prefix + " " + last)
+		if suffix != "" {
+			name += ", " + suffix
+		}
+		if first != "" || suffix != "" {
+			name += ", " + first
+		}
+		p.write(name)
+	}
+	return nil
+}
+
+// namePart returns the printed text of a part of an author name.
+func (p *printer) namePart(x ast.Expr) (string, error) {
+	if x == nil {
+		return "", nil
+	}
+	var np printer
+	np.init(&p.Config, nil)
+	if t, ok := x.(*ast.ParsedText); ok && t.Depth == 0 {
+		// A part extracted from a name field, which has no delimiters.
+		if err := np.texts(t.Values); err != nil {
+			return "", err
+		}
+		return np.output.String(), nil
+	}
+	if err := np.text(x); err != nil {
+		return "", err
+	}
+	return np.output.String(), nil
+}
+
+func (p *printer) posString(pos gotok.Pos) string {
+	if position, ok := p.position(pos); ok {
+		return position.String()
+	}
+	return "unknown position"
+}
@@ -0,0 +1,306 @@
+// Package printer implements printing of AST nodes back to bibtex source.
+//
+// The printer is source-faithful: between two tokens that carry source
+// positions, the printer copies the whitespace that separates them in the
+// source text of the file, ast.File.Src. Printing an unmodified ast.File
+// parsed with parser.ParseFile reproduces the source byte for byte. Because
+// the printer copies the whitespace between tokens instead of moving tokens
+// to their original line and column, a token that changes width, like a
+// renamed entry key, keeps the spacing around it. Nodes without positions,
+// like nodes created by a program, and nodes printed without the source of
+// their file use a default layout.
+//
+// In Canonical mode, the printer ignores the source layout and prints all
+// nodes with the default layout. Source positions only place comments.
+package printer
+
+import (
+	"bytes"
+	"fmt"
+	gotok "go/token"
+	"io"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+)
+
+// A Mode value is a set of flags (or 0). They control printing.
+type Mode uint
+
+const (
+	Canonical     Mode = 1 << iota // ignore source layout and use the default layout
+	AlignTags                      // align "=" of tags in an entry; Canonical mode only
+	TrailingComma                  // end the last tag of an entry with a comma; Canonical mode only
+)
+
+// A Config node controls the output of Fprint.
+type Config struct {
+	Mode   Mode // default: 0
+	Indent int  // number of spaces to indent tags of nodes using the default layout; 2 if 0
+}
+
+// A printer holds the state of a single Fprint call.
+type printer struct {
+	Config
+	fset   *gotok.FileSet
+	output bytes.Buffer
+
+	// Comments
+	comments []*ast.TexCommentGroup // all comments of the file being printed
+	cindex   int                    // index of the next comment to print
+
+	// Source of the file being printed. Used to copy the whitespace between
+	// tokens.
+	src     []byte
+	srcFile *gotok.File // the file of src in fset; nil until known
+	last    int         // offset of the last printed token in src, or -1
+	lastLit string      // text of the last printed token
+	added   bool        // true if tokens without positions followed the last token
+
+	// Canonical mode state.
+	lastLine    int    // source line of the last printed token or comment
+	lineComment bool   // true if the last printed text was a comment
+	indent      string // indentation of comments in the current declaration
+}
+
+func (p *printer) init(cfg *Config, fset *gotok.FileSet) {
+	p.Config = *cfg
+	if p.Indent == 0 {
+		p.Indent = 2
+	}
+	p.fset = fset
+	p.last = -1
+}
+
+// position returns the source position of pos if known.
+func (p *printer) position(pos gotok.Pos) (gotok.Position, bool) {
+	if p.fset == nil || !pos.IsValid() {
+		return gotok.Position{}, false
+	}
+	return p.fset.Position(pos), true
+}
+
+// offset returns the offset of pos in the source of the file being printed
+// if pos is in the source.
+func (p *printer) offset(pos gotok.Pos) (int, bool) {
+	if p.src == nil || p.fset == nil || !pos.IsValid() {
+		return 0, false
+	}
+	if p.srcFile == nil {
+		f := p.fset.File(pos)
+		if f == nil || f.Size() != len(p.src) {
+			return 0, false
+		}
+		p.srcFile = f
+	}
+	if base := p.srcFile.Base(); int(pos) < base || int(pos) > base+p.srcFile.Size() {
+		return 0, false
+	}
+	return p.srcFile.Offset(pos), true
+}
+
+// write writes s to the output.
+func (p *printer) write(s string) {
+	p.output.WriteString(s)
+	p.lastLine += strings.Count(s, "\n")
+}
+
+// print writes the text of a token at pos. If pos is in the source, print
+// first writes the whitespace that precedes pos in the source. Otherwise,
+// print writes sep before the text.
+func (p *printer) print(pos gotok.Pos, sep, text string) {
+	if pos.IsValid() {
+		p.flushComments(pos)
+	}
+	if p.Mode&Canonical != 0 {
+		p.canonicalMoveTo(pos, sep)
+	} else {
+		p.moveTo(pos, sep)
+	}
+	p.write(text)
+	if off, ok := p.offset(pos); ok {
+		p.last, p.lastLit, p.added = off, text, false
+	} else {
+		p.added = true
+	}
+}
+
+// canonicalMoveTo writes sep. If the last printed text was a comment, it also
+// ensures that the next text starts on a new line.
+func (p *printer) canonicalMoveTo(pos gotok.Pos, sep string) {
+	if p.lineComment && !strings.HasPrefix(sep, "\n") {
+		sep = "\n" + p.indent
+	}
+	p.write(sep)
+	p.lineComment = false
+	if position, ok := p.position(pos); ok {
+		p.lastLine = position.Line
+	}
+}
+
+// moveTo writes the whitespace that precedes pos in the source, or sep if pos
+// isn't in the source. moveTo also writes sep if pos appears before the last
+// printed token, likely because the AST was modified, or if the source
+// before pos has text that wasn't printed or replaced, like a removed tag,
+// and no whitespace.
+func (p *printer) moveTo(pos gotok.Pos, sep string) {
+	off, ok := p.offset(pos)
+	if !ok {
+		p.write(sep)
+		return
+	}
+	lo, exact := p.gapStart()
+	if off < lo {
+		p.write(sep)
+		return
+	}
+	i := spaceBefore(p.src, lo, off)
+	if i == off && i > lo && exact && !p.added {
+		p.write(sep)
+		return
+	}
+	p.write(string(p.src[i:off]))
+}
+
+// gapStart returns the offset in the source where the whitespace after the
+// last printed token may start. If the last token is unchanged from the
+// source, that's the end of the token and exact is true. Otherwise, the width
+// of the token in the source is unknown, and the gap may start right after
+// the first byte of the token.
+func (p *printer) gapStart() (lo int, exact bool) {
+	if p.last < 0 {
+		return 0, true
+	}
+	if end := p.last + len(p.lastLit); end <= len(p.src) && string(p.src[p.last:end]) == p.lastLit {
+		return end, true
+	}
+	return p.last + 1, false
+}
+
+// spaceBefore returns the start of the whitespace that ends at offset end of
+// src, not going back further than lo.
+func spaceBefore(src []byte, lo, end int) int {
+	i := end
+	for i > lo && isSpace(src[i-1]) {
+		i--
+	}
+	return i
+}
+
+// isSpace returns true for the whitespace characters skipped by the scanner.
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+// punctPos returns the position of the punctuation text, like the comma
+// after an entry key, if it appears in the source between the last printed
+// token and next, separated from them only by whitespace. Otherwise,
+// punctPos returns gotok.NoPos.
+func (p *printer) punctPos(text string, next gotok.Pos) gotok.Pos {
+	if p.last < 0 || p.Mode&Canonical != 0 {
+		return gotok.NoPos
+	}
+	// After the last token.
+	lo, exact := p.gapStart()
+	i := lo
+	for i < len(p.src) && isSpace(p.src[i]) {
+		i++
+	}
+	if exact && bytes.HasPrefix(p.src[i:], []byte(text)) {
+		return gotok.Pos(p.srcFile.Base() + i)
+	}
+	// Before the next token.
+	off, ok := p.offset(next)
+	if !ok || off < lo {
+		return gotok.NoPos
+	}
+	i = spaceBefore(p.src, lo, off) - len(text)
+	if i >= lo && string(p.src[i:i+len(text)]) == text {
+		return gotok.Pos(p.srcFile.Base() + i)
+	}
+	return gotok.NoPos
+}
+
+// flushComments prints all comments that appear before pos in the source. If
+// pos is not valid, flushComments prints all remaining comments.
+func (p *printer) flushComments(pos gotok.Pos) {
+	for p.cindex < len(p.comments) {
+		g := p.comments[p.cindex]
+		if pos.IsValid() && g.Pos() >= pos {
+			return
+		}
+		p.cindex++
+		for _, c := range g.List {
+			if p.Mode&Canonical != 0 {
+				p.canonicalComment(c)
+				continue
+			}
+			sep := "\n"
+			if p.output.Len() == 0 {
+				sep = ""
+			}
+			p.print(c.Start, sep, c.Text)
+		}
+	}
+}
+
+// canonicalComment prints a comment in Canonical mode. A comment on the same
+// line as the preceding token stays on that line. Otherwise, the comment
+// starts a new line, preceded by a blank line if there was one in the source
+// outside of a declaration.
+func (p *printer) canonicalComment(c *ast.TexComment) {
+	line := p.lastLine
+	if position, ok := p.position(c.Start); ok {
+		line = position.Line
+	}
+	sep := ""
+	switch {
+	case p.output.Len() == 0:
+	case line == p.lastLine && !p.lineComment:
+		sep = " "
+	case line > p.lastLine+1 && p.indent == "":
+		sep = "\n\n"
+	default:
+		sep = "\n" + p.indent
+	}
+	p.write(sep + c.Text)
+	p.lastLine = line
+	p.lineComment = true
+}
+
+// Fprint "pretty-prints" an AST node to output for a given configuration cfg.
+// Position information is interpreted relative to the file set fset, which
+// may be nil if the node has no positions. The node type must be *ast.File,
+// ast.Decl, ast.Stmt, or ast.Expr.
+func (cfg *Config) Fprint(output io.Writer, fset *gotok.FileSet, node ast.Node) error {
+	var p printer
+	p.init(cfg, fset)
+	if err := p.printNode(node); err != nil {
+		return err
+	}
+	if _, err := output.Write(p.output.Bytes()); err != nil {
+		return fmt.Errorf("printer: write output: %w", err)
+	}
+	return nil
+}
+
+// Fprint "pretty-prints" an AST node to output using the default
+// configuration.
+func Fprint(output io.Writer, fset *gotok.FileSet, node ast.Node) error {
+	return (&Config{}).Fprint(output, fset, node)
+}
+
+func (p *printer) printNode(node ast.Node) error {
+	switch n := node.(type) {
+	case *ast.File:
+		return p.file(n)
+	case ast.Decl:
+		return p.decl(n, "")
+	case ast.Stmt:
+		return p.stmt(n, "")
+	case ast.Expr:
+		return p.expr(n, "")
+	default:
+		return fmt.Errorf("printer: unsupported node type %T", node)
+	}
+}
@@ -0,0 +1,306 @@
+package printer
+
+import (
+	"bytes"
+	gotok "go/token"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/asts"
+	"github.com/jschaf/bibtex/parser"
+)
+
+func TestFprint_roundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"empty", ""},
+		{"article", "@article{key,\n  title = {Foo},\n  year = 2004\n}\n"},
+		{"upper case type", "@ARTICLE{key,\n  TITLE = {Foo},\n}\n"},
+		{"paren delimiters", "@article(key, title = \"Foo\")\n"},
+		{"extra spaces", "@article {  key,\n  title   =    {Foo bar} ,\n\n\n  year=2004}\n"},
+		{"string", "@String{pub-ACM = \"ACM Press\"}\n"},
+		{"string paren", "@string ( acm = {ACM} )\n"},
+		{"preamble concat", "@Preamble{\n    \"\\ifx {T}\\fi\" #\n    \"\\foo\"\n}\n"},
+		{"concat ident", "@article{key,\n  publisher = pub-ACM # \" and \" # {IEEE},\n}\n"},
+		{"comments", "% lead\n%% second\n\n@article{key, % line\n  % tag doc\n  title = {Foo}, % after\n}\n% trailing\n"},
+		{"nested braces", "@article{key, title = {The {NASA} {{Big}} data}}\n"},
+		{"accents", "@article{key, author = {Fran{\\c    c}oise and H\\\"{a}berle and Beno{\\^i}t}}\n"},
+		{"escapes and math", "@article{key, title = {Foo \\& Bar \\$1 $e=mc^2$ a~b, c}}\n"},
+		{"macros", "@article{key, title = {\\textsc f oo \\LaTeX{} \\, x}}\n"},
+		{"url", "@article{key,\n  url = \"https://example.com/foo--bar/~baz/#\",\n  howPublished = \"\\url{https://foo.com/~x}\"\n}\n"},
+		{"url braces", "@article{key, url = {\\url{https://foo.com/~x}}}\n"},
+		{"url verbatim", "@article{key, url = {https://foo.com/~x%20y}}\n"},
+		{"multiline string", "@article{key,\n  title = {A long\n           title},\n}\n"},
+		{"extra keys", "@article{key, a = 1, extra, b = 2}\n"},
+		{"number key", "@article{111, key = bar}\n"},
+		{"key only", "@misc{key}\n"},
+		{"comment", "@Comment{jabref-meta: databaseType:bibtex;}\n"},
+		{"comment paren", "@comment ( a {)} b )\n"},
+		{"comment rest of line", "@comment rest of {line\n@misc{key}\n"},
+		{"comment next line", "@comment\n{foo}\n@comment\n@misc{key}\n"},
+		{"tabs", "@article{key,\n\ttitle\t= {Foo},\n\tyear =\t2004\n}\n"},
+		{"trailing whitespace", "@misc{a}   \n@misc{b, title = {B}} \t\n  \n"},
+		{"no final newline", "@misc{key, title = {Foo}}"},
+		{"leading whitespace", "\n\t @misc{key}\n"},
+		{"space before comma", "@article{key , title = {Foo} ,year = 1 , extra ,}\n"},
+		{"crlf", "@misc{a,\r\n  title = {A}\r\n}\r\n"},
+	}
+	for _, tt := range tests {
+		for _, mode := range []parser.Mode{parser.ParseComments, parser.ParseComments | parser.ParseStrings} {
+			t.Run(tt.name, func(t *testing.T) {
+				fset := gotok.NewFileSet()
+				f, err := parser.ParseFile(fset, "", tt.src, mode)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got := &bytes.Buffer{}
+				if err := Fprint(got, fset, f); err != nil {
+					t.Fatal(err)
+				}
+				if diff := cmp.Diff(tt.src, got.String()); diff != "" {
+					t.Errorf("Fprint() mismatch (-want +got):\n%s", diff)
+				}
+			})
+		}
+	}
+}
+
+func TestFprint_roundTripFiles(t *testing.T) {
+	for _, filename := range []string{"../parser/testdata/vldb.bib"} {
+		src, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, mode := range []parser.Mode{parser.ParseComments, parser.ParseComments | parser.ParseStrings} {
+			fset := gotok.NewFileSet()
+			f, err := parser.ParseFile(fset, filename, src, mode)
+			if err != nil {
+				t.Fatalf("ParseFile(%s): %v", filename, err)
+			}
+			got := &bytes.Buffer{}
+			if err := Fprint(got, fset, f); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(src, got.Bytes()) {
+				t.Errorf("Fprint(%s) with mode %d is not byte-identical to the source", filename, mode)
+			}
+		}
+	}
+}
+
+func TestFprint_nodes(t *testing.T) {
+	tests := []struct {
+		name string
+		node ast.Node
+		want string
+	}{
+		{
+			name: "bib decl without positions",
+			node: newBibDecl("article", "key",
+				asts.WithBibTags("title", asts.BraceText(0, "Foo", " ", "Bar"), "year", asts.Text("2004"))),
+			want: "@article{key,\n  title = {Foo Bar},\n  year = {2004}\n}",
+		},
+		{
+			name: "bib decl without tags",
+			node: newBibDecl("misc", "key"),
+			want: "@misc{key}",
+		},
+		{
+			name: "quoted text",
+			node: asts.QuotedText(0, "foo", " ", "{Bar}"),
+			want: `"foo {Bar}"`,
+		},
+		{
+			name: "concat",
+			node: asts.Concat(asts.Ident("jan"), asts.UnparsedText(" 1")),
+			want: `jan # " 1"`,
+		},
+		{
+			name: "macro with args",
+			node: asts.BraceText(0, asts.Macro("emph", "foo")),
+			want: `{\emph{foo}}`,
+		},
+		{
+			name: "authors",
+			node: &ast.TagStmt{Name: "author", Value: ast.Authors{
+				{First: asts.Text("Ludwig"), Prefix: asts.Text("van"), Last: asts.Text("Beethoven"), Suffix: asts.Text("")},
+				{First: asts.Text(""), Prefix: asts.Text(""), Last: asts.Text("others"), Suffix: asts.Text("")},
+			}},
+			want: `author = {van Beethoven, Ludwig and others}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := &strings.Builder{}
+			if err := Fprint(got, nil, tt.node); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got.String()); diff != "" {
+				t.Errorf("Fprint() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFprint_modified(t *testing.T) {
+	src := "@article{key,\n  title = {Foo},\n  year = 2004\n}\n\n@book{other,\n  title = {Bar},\n}\n"
+	fset := gotok.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decl := f.Entries[0].(*ast.BibDecl)
+	decl.Tags = append(decl.Tags, &ast.TagStmt{Name: "note", Value: asts.BraceText(0, "Added")})
+
+	got := &strings.Builder{}
+	if err := Fprint(got, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	want := "@article{key,\n  title = {Foo},\n  year = 2004,\n  note = {Added}\n}\n\n@book{other,\n  title = {Bar},\n}\n"
+	if diff := cmp.Diff(want, got.String()); diff != "" {
+		t.Errorf("Fprint() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFprint_renamedKey(t *testing.T) {
+	const src = "@book{conf19 , title = {T}, year = 2019}\n@misc{other,\n\tcrossref = {conf19}\n}"
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"Conf2019", "@book{Conf2019 , title = {T}, year = 2019}\n@misc{other,\n\tcrossref = {conf19}\n}"},
+		{"c", "@book{c , title = {T}, year = 2019}\n@misc{other,\n\tcrossref = {conf19}\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			fset := gotok.NewFileSet()
+			f, err := parser.ParseFile(fset, "", src, parser.ParseStrings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f.Entries[0].(*ast.BibDecl).Key.Name = tt.key
+			got := &strings.Builder{}
+			if err := Fprint(got, fset, f); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got.String()); diff != "" {
+				t.Errorf("Fprint() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFprint_changedValue(t *testing.T) {
+	src := "@article{key,\n\ttitle = {T}}\n@misc{other, title = {T},   year = 2019}\n"
+	fset := gotok.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range f.Entries {
+		d.(*ast.BibDecl).Tags[0].Value = asts.BraceText(0, "A", " ", "Longer", " ", "Title")
+	}
+	got := &strings.Builder{}
+	if err := Fprint(got, fset, f); err != nil {
+		t.Fatal(err)
+	}
+	want := "@article{key,\n\ttitle = {A Longer Title}}\n@misc{other, title = {A Longer Title},   year = 2019}\n"
+	if diff := cmp.Diff(want, got.String()); diff != "" {
+		t.Errorf("Fprint() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestConfig_Fprint_canonical(t *testing.T) {
+	tests := []struct {
+		name string
+		mode Mode
+		src  string
+		want string
+	}{
+		{
+			name: "layout",
+			src:  "@article{  key,title={Foo},\n\n\n    year=2004}",
+			want: "@article{key,\n  title = {Foo},\n  year = 2004\n}\n",
+		},
+		{
+			name: "align tags",
+			mode: AlignTags,
+			src:  "@article{key, title={Foo}, year=2004, journal = {Bar}}",
+			want: "@article{key,\n  title   = {Foo},\n  year    = 2004,\n  journal = {Bar}\n}\n",
+		},
+		{
+			name: "trailing comma",
+			mode: TrailingComma,
+			src:  "@article{key, title={Foo}, year=2004}",
+			want: "@article{key,\n  title = {Foo},\n  year = 2004,\n}\n",
+		},
+		{
+			name: "no trailing comma",
+			src:  "@article{key, title={Foo}, year=2004,}",
+			want: "@article{key,\n  title = {Foo},\n  year = 2004\n}\n",
+		},
+		{
+			name: "extra keys",
+			mode: TrailingComma,
+			src:  "@article{key, a = 1, extra, b = 2, other}",
+			want: "@article{key,\n  a = 1,\n  extra,\n  b = 2,\n  other,\n}\n",
+		},
+		{
+			name: "declarations",
+			src:  "@string{a={A}}@preamble{ \"foo\" # \"bar\" }\n\n\n\n@misc{key}",
+			want: "@string{a = {A}}\n\n@preamble{\"foo\" # \"bar\"}\n\n@misc{key}\n",
+		},
+		{
+			name: "comments",
+			src: "% lead\n\n\n@article{key, % line\n   % tag doc\n     title = {Foo}, % after\n}\n" +
+				"% doc\n@misc{a}\n\n% trailing\n",
+			want: "% lead\n\n@article{key, % line\n  % tag doc\n  title = {Foo} % after\n}\n" +
+				"% doc\n@misc{a}\n\n% trailing\n",
+		},
+		{
+			name: "comment before value",
+			src:  "@article{key, title = % odd\n {Foo}}",
+			want: "@article{key,\n  title = % odd\n  {Foo}\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fset := gotok.NewFileSet()
+			f, err := parser.ParseFile(fset, "", tt.src, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := &strings.Builder{}
+			cfg := &Config{Mode: Canonical | tt.mode}
+			if err := cfg.Fprint(got, fset, f); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, got.String()); diff != "" {
+				t.Errorf("Fprint() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFprint_badDecl(t *testing.T) {
+	fset := gotok.NewFileSet()
+	f, _ := parser.ParseFile(fset, "", "@article{key, title = {Foo}}\nfoo bar", 0)
+	if err := Fprint(&strings.Builder{}, fset, f); err == nil {
+		t.Error("expected error when printing BadDecl but had none")
+	}
+}
+
+func newBibDecl(typ, key string, opts ...func(*ast.BibDecl)) *ast.BibDecl {
+	decl := &ast.BibDecl{}
+	asts.WithBibType(typ)(decl)
+	asts.WithBibKeys(key)(decl)
+	for _, opt := range opts {
+		opt(decl)
+	}
+	return decl
+}
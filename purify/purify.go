@@ -0,0 +1,129 @@
+// Package purify normalizes bibtex text for sorting, labels and comparison,
+// like the purify$ function of bibtex.
+package purify
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/render"
+)
+
+// specialLetters are the control sequences of special characters that
+// purify$ keeps as letters, like {\ss} to "ss".
+var specialLetters = map[string]bool{
+	"i": true, "j": true, "oe": true, "OE": true, "ae": true, "AE": true,
+	"aa": true, "AA": true, "o": true, "O": true, "l": true, "L": true,
+	"ss": true,
+}
+
+// foldLetters maps the letters without a decomposition to the plain letters
+// that Fold replaces them with. Letters with a special character in bibtex
+// fold to the same text as the special character, like ß to "ss" for {\ss}.
+var foldLetters = map[rune]string{
+	'ø': "o", 'Ø': "O",
+	'ß': "ss",
+	'æ': "ae", 'Æ': "AE",
+	'œ': "oe", 'Œ': "OE",
+	'ł': "l", 'Ł': "L",
+	'ı': "i", 'ȷ': "j",
+	'đ': "d", 'Đ': "D",
+	'ð': "d", 'Ð': "D",
+	'þ': "th", 'Þ': "Th",
+	'ŋ': "ng", 'Ŋ': "Ng",
+}
+
+// Expr returns the purified text of x, like the purify$ function of bibtex.
+// Expr keeps letters and digits, replaces whitespace, hyphens and ties with
+// a space, and removes all other characters. Control sequences are removed,
+// except for the special characters that are letters, like \ss and \o, so
+// {\"o} becomes "o" and {\ss} becomes "ss". The arguments of a macro, like
+// \emph{Text}, are purified like other text.
+//
+// Letters outside of the ASCII range keep their accents, like "ö". Use Fold
+// to remove them.
+func Expr(x ast.Expr) string {
+	sb := &strings.Builder{}
+	purify(sb, x)
+	return sb.String()
+}
+
+// Fold returns the purified text of x like Expr with the accents removed from
+// all letters, like "ö" to "o", and with letters like "ß" replaced by plain
+// letters. The text of a field is the same for Fold whether it's written with
+// special characters, like {\"o}, or in Unicode, like "ö".
+func Fold(x ast.Expr) string {
+	return FoldString(Expr(x))
+}
+
+// FoldString removes the accents from the letters of s and replaces letters
+// like "ß" with plain letters, like Fold.
+func FoldString(s string) string {
+	s = render.StripAccents(s)
+	if isASCII(s) {
+		return s
+	}
+	sb := &strings.Builder{}
+	for _, r := range s {
+		if f, ok := foldLetters[r]; ok {
+			sb.WriteString(f)
+		} else {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func purify(sb *strings.Builder, x ast.Expr) {
+	switch x := x.(type) {
+	case *ast.ParsedText:
+		for _, v := range x.Values {
+			purify(sb, v)
+		}
+	case *ast.Text:
+		writeString(sb, x.Value)
+	case *ast.Number:
+		writeString(sb, x.Value)
+	case *ast.UnparsedText:
+		writeString(sb, x.Value)
+	case *ast.TextMath:
+		writeString(sb, x.Value)
+	case *ast.TextAccent:
+		purify(sb, x.Text)
+	case *ast.TextMacro:
+		if specialLetters[x.Name] {
+			sb.WriteString(x.Name)
+		}
+		for _, v := range x.Values {
+			purify(sb, v)
+		}
+	case *ast.TextSpace, *ast.TextNBSP, *ast.TextHyphen:
+		sb.WriteByte(' ')
+	case *ast.ConcatExpr:
+		purify(sb, x.X)
+		purify(sb, x.Y)
+	}
+}
+
+// writeString writes the letters and digits of s. Whitespace, hyphens and
+// ties become a space.
+func writeString(sb *strings.Builder, s string) {
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '~':
+			sb.WriteByte(' ')
+		}
+	}
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
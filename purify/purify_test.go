@@ -0,0 +1,72 @@
+package purify
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/parser"
+)
+
+func TestExpr(t *testing.T) {
+	tests := []struct {
+		text     string
+		want     string
+		wantFold string
+	}{
+		{"", "", ""},
+		{"Knuth", "Knuth", "Knuth"},
+		{"The {TeX}book: A Guide", "The TeXbook A Guide", "The TeXbook A Guide"},
+		{"Jean-Paul Sartre", "Jean Paul Sartre", "Jean Paul Sartre"},
+		{"Brinch~Hansen", "Brinch Hansen", "Brinch Hansen"},
+		{"G{\\\"o}del", "Godel", "Godel"},
+		{"G\\\"odel", "Godel", "Godel"},
+		{"Gödel", "Gödel", "Godel"},
+		{"Nguyễn", "Nguyễn", "Nguyen"},
+		{"Stra{\\ss}e", "Strasse", "Strasse"},
+		{"Straße", "Straße", "Strasse"},
+		{"{\\O}rsted and {\\AE}sop", "Orsted and AEsop", "Orsted and AEsop"},
+		{"Ørsted", "Ørsted", "Orsted"},
+		{"{\\c{C}}elik", "Celik", "Celik"},
+		{"\\emph{Deep} Learning", "Deep Learning", "Deep Learning"},
+		{"R\\&D, Inc.", "RD Inc", "RD Inc"},
+		{"$O(n^2)$ Sorting", "On2 Sorting", "On2 Sorting"},
+		{"{\\'E}mile   Zola", "Emile Zola", "Emile Zola"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			x, err := parser.ParseExpr("{" + tt.text + "}")
+			if err != nil {
+				t.Fatal(err)
+			}
+			txt := x.(*ast.ParsedText)
+			if diff := cmp.Diff(tt.want, Expr(txt)); diff != "" {
+				t.Errorf("Expr() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantFold, Fold(txt)); diff != "" {
+				t.Errorf("Fold() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExpr_nodes(t *testing.T) {
+	tests := []struct {
+		name string
+		x    ast.Expr
+		want string
+	}{
+		{"nil", nil, ""},
+		{"number", &ast.Number{Value: "2019"}, "2019"},
+		{"ident", &ast.Ident{Name: "jan"}, ""},
+		{"text", &ast.Text{Value: "Vallée-Poussin"}, "Vallée Poussin"},
+		{"concat", &ast.ConcatExpr{X: &ast.Text{Value: "a."}, Y: &ast.Number{Value: "1"}}, "a1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, Expr(tt.x)); diff != "" {
+				t.Errorf("Expr() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package bibtex
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/render"
+)
+
+// exprRenderer renders an expression, like render.MarkdownRenderer.
+type exprRenderer interface {
+	Render(w io.Writer, x ast.Expr) error
+}
+
+// RenderMarkdown writes the entry as Markdown: the cite key and type followed
+// by a list of the tags sorted by field name, like:
+//
+//	**knuth1984** (article)
+//
+//	- **author**: Donald E. Knuth
+//	- **title**: Literate Programming
+//
+// The tag values must be resolved into text, like with
+// RenderParsedTextResolver, or be parsed strings.
+func (e Entry) RenderMarkdown(w io.Writer) error {
+	r := render.NewMarkdownRenderer()
+	header := "**" + renderString(r, e.Key) + "** (" + renderString(r, e.Type) + ")\n\n"
+	return e.renderTags(w, r, header, func(field Field, value string) string {
+		return "- **" + renderString(r, field) + "**: " + value + "\n"
+	})
+}
+
+// RenderANSI writes the entry as text for a terminal with ANSI escape codes:
+// the bold cite key and type followed by an indented line for each tag, sorted
+// by field name, like:
+//
+//	knuth1984 (article)
+//	  author: Donald E. Knuth
+//	  title: Literate Programming
+//
+// The tag values must be resolved into text, like with
+// RenderParsedTextResolver, or be parsed strings.
+func (e Entry) RenderANSI(w io.Writer) error {
+	r := render.NewANSIRenderer()
+	header := "\x1b[1m" + renderString(r, e.Key) + "\x1b[22m (" + renderString(r, e.Type) + ")\n"
+	return e.renderTags(w, r, header, func(field Field, value string) string {
+		return "  " + renderString(r, field) + ": " + value + "\n"
+	})
+}
+
+// renderTags writes the header followed by a line for each tag.
+func (e Entry) renderTags(w io.Writer, r exprRenderer, header string, line func(Field, string) string) error {
+	fields := make([]Field, 0, len(e.Tags))
+	for field := range e.Tags {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	sb := &strings.Builder{}
+	for _, field := range fields {
+		sb.Reset()
+		if err := r.Render(sb, e.Tags[field]); err != nil {
+			return fmt.Errorf("render entry %s field %s: %w", e.Key, field, err)
+		}
+		if _, err := io.WriteString(w, line(field, sb.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderString renders s as plain text with r, escaping s if needed.
+func renderString(r exprRenderer, s string) string {
+	sb := &strings.Builder{}
+	_ = r.Render(sb, &ast.Text{Value: s})
+	return sb.String()
+}
@@ -2,53 +2,213 @@ package render
 
 import (
 	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
 	"github.com/jschaf/bibtex/token"
 )
 
-// Mapping of base characters to their accented versions
+// accentMap maps an accent and a base character to the precomposed accented
+// character. The key is the accent marker followed by the base character.
 var accentMap = map[string]rune{
-	// AccentGrave accent (`)
-	"`a": 'à', "`e": 'è', "`i": 'ì', "`o": 'ò', "`u": 'ù',
-	"`A": 'À', "`E": 'È', "`I": 'Ì', "`O": 'Ò', "`U": 'Ù',
+	// AccentGrave: grave (`)
+	"`A": 'À', "`E": 'È', "`I": 'Ì', "`N": 'Ǹ', "`O": 'Ò', "`U": 'Ù', "`W": 'Ẁ', "`Y": 'Ỳ',
+	"`a": 'à', "`e": 'è', "`i": 'ì', "`n": 'ǹ', "`o": 'ò', "`u": 'ù', "`w": 'ẁ', "`y": 'ỳ',
+	"`Â": 'Ầ', "`Ê": 'Ề', "`Ô": 'Ồ', "`Ü": 'Ǜ', "`â": 'ầ', "`ê": 'ề', "`ô": 'ồ', "`ü": 'ǜ',
+	"`Ă": 'Ằ', "`ă": 'ằ', "`Ē": 'Ḕ', "`ē": 'ḕ', "`Ō": 'Ṑ', "`ō": 'ṑ', "`Ơ": 'Ờ', "`ơ": 'ờ',
+	"`Ư": 'Ừ', "`ư": 'ừ',
+
+	// AccentAcute: acute (')
+	"'A": 'Á', "'C": 'Ć', "'E": 'É', "'G": 'Ǵ', "'I": 'Í', "'K": 'Ḱ', "'L": 'Ĺ', "'M": 'Ḿ',
+	"'N": 'Ń', "'O": 'Ó', "'P": 'Ṕ', "'R": 'Ŕ', "'S": 'Ś', "'U": 'Ú', "'W": 'Ẃ', "'Y": 'Ý',
+	"'Z": 'Ź', "'a": 'á', "'c": 'ć', "'e": 'é', "'g": 'ǵ', "'i": 'í', "'k": 'ḱ', "'l": 'ĺ',
+	"'m": 'ḿ', "'n": 'ń', "'o": 'ó', "'p": 'ṕ', "'r": 'ŕ', "'s": 'ś', "'u": 'ú', "'w": 'ẃ',
+	"'y": 'ý', "'z": 'ź', "'Â": 'Ấ', "'Å": 'Ǻ', "'Æ": 'Ǽ', "'Ç": 'Ḉ', "'Ê": 'Ế', "'Ï": 'Ḯ',
+	"'Ô": 'Ố', "'Õ": 'Ṍ', "'Ø": 'Ǿ', "'Ü": 'Ǘ', "'â": 'ấ', "'å": 'ǻ', "'æ": 'ǽ', "'ç": 'ḉ',
+	"'ê": 'ế', "'ï": 'ḯ', "'ô": 'ố', "'õ": 'ṍ', "'ø": 'ǿ', "'ü": 'ǘ', "'Ă": 'Ắ', "'ă": 'ắ',
+	"'Ē": 'Ḗ', "'ē": 'ḗ', "'Ō": 'Ṓ', "'ō": 'ṓ', "'Ũ": 'Ṹ', "'ũ": 'ṹ', "'Ơ": 'Ớ', "'ơ": 'ớ',
+	"'Ư": 'Ứ', "'ư": 'ứ',
+
+	// AccentCircumflex: circumflex (^)
+	"^A": 'Â', "^C": 'Ĉ', "^E": 'Ê', "^G": 'Ĝ', "^H": 'Ĥ', "^I": 'Î', "^J": 'Ĵ', "^O": 'Ô',
+	"^S": 'Ŝ', "^U": 'Û', "^W": 'Ŵ', "^Y": 'Ŷ', "^Z": 'Ẑ', "^a": 'â', "^c": 'ĉ', "^e": 'ê',
+	"^g": 'ĝ', "^h": 'ĥ', "^i": 'î', "^j": 'ĵ', "^o": 'ô', "^s": 'ŝ', "^u": 'û', "^w": 'ŵ',
+	"^y": 'ŷ', "^z": 'ẑ', "^Ạ": 'Ậ', "^ạ": 'ậ', "^Ẹ": 'Ệ', "^ẹ": 'ệ', "^Ọ": 'Ộ', "^ọ": 'ộ',
+
+	// AccentUmlaut: umlaut or diaeresis (")
+	`"A`: 'Ä', `"E`: 'Ë', `"H`: 'Ḧ', `"I`: 'Ï', `"O`: 'Ö', `"U`: 'Ü', `"W`: 'Ẅ', `"X`: 'Ẍ',
+	`"Y`: 'Ÿ', `"a`: 'ä', `"e`: 'ë', `"h`: 'ḧ', `"i`: 'ï', `"o`: 'ö', `"t`: 'ẗ', `"u`: 'ü',
+	`"w`: 'ẅ', `"x`: 'ẍ', `"y`: 'ÿ', `"Õ`: 'Ṏ', `"õ`: 'ṏ', `"Ū`: 'Ṻ', `"ū`: 'ṻ',
+
+	// AccentTilde: tilde (~)
+	"~A": 'Ã', "~E": 'Ẽ', "~I": 'Ĩ', "~N": 'Ñ', "~O": 'Õ', "~U": 'Ũ', "~V": 'Ṽ', "~Y": 'Ỹ',
+	"~a": 'ã', "~e": 'ẽ', "~i": 'ĩ', "~n": 'ñ', "~o": 'õ', "~u": 'ũ', "~v": 'ṽ', "~y": 'ỹ',
+	"~Â": 'Ẫ', "~Ê": 'Ễ', "~Ô": 'Ỗ', "~â": 'ẫ', "~ê": 'ễ', "~ô": 'ỗ', "~Ă": 'Ẵ', "~ă": 'ẵ',
+	"~Ơ": 'Ỡ', "~ơ": 'ỡ', "~Ư": 'Ữ', "~ư": 'ữ',
+
+	// AccentMacron: macron (=)
+	"=A": 'Ā', "=E": 'Ē', "=G": 'Ḡ', "=I": 'Ī', "=O": 'Ō', "=U": 'Ū', "=Y": 'Ȳ', "=a": 'ā',
+	"=e": 'ē', "=g": 'ḡ', "=i": 'ī', "=o": 'ō', "=u": 'ū', "=y": 'ȳ', "=Ä": 'Ǟ', "=Æ": 'Ǣ',
+	"=Õ": 'Ȭ', "=Ö": 'Ȫ', "=Ü": 'Ǖ', "=ä": 'ǟ', "=æ": 'ǣ', "=õ": 'ȭ', "=ö": 'ȫ', "=ü": 'ǖ',
+	"=Ǫ": 'Ǭ', "=ǫ": 'ǭ', "=Ȧ": 'Ǡ', "=ȧ": 'ǡ', "=Ȯ": 'Ȱ', "=ȯ": 'ȱ', "=Ḷ": 'Ḹ', "=ḷ": 'ḹ',
+	"=Ṛ": 'Ṝ', "=ṛ": 'ṝ',
+
+	// AccentDot: dot above (.)
+	".A": 'Ȧ', ".B": 'Ḃ', ".C": 'Ċ', ".D": 'Ḋ', ".E": 'Ė', ".F": 'Ḟ', ".G": 'Ġ', ".H": 'Ḣ',
+	".I": 'İ', ".M": 'Ṁ', ".N": 'Ṅ', ".O": 'Ȯ', ".P": 'Ṗ', ".R": 'Ṙ', ".S": 'Ṡ', ".T": 'Ṫ',
+	".W": 'Ẇ', ".X": 'Ẋ', ".Y": 'Ẏ', ".Z": 'Ż', ".a": 'ȧ', ".b": 'ḃ', ".c": 'ċ', ".d": 'ḋ',
+	".e": 'ė', ".f": 'ḟ', ".g": 'ġ', ".h": 'ḣ', ".m": 'ṁ', ".n": 'ṅ', ".o": 'ȯ', ".p": 'ṗ',
+	".r": 'ṙ', ".s": 'ṡ', ".t": 'ṫ', ".w": 'ẇ', ".x": 'ẋ', ".y": 'ẏ', ".z": 'ż', ".Ś": 'Ṥ',
+	".ś": 'ṥ', ".Š": 'Ṧ', ".š": 'ṧ', ".ſ": 'ẛ', ".Ṣ": 'Ṩ', ".ṣ": 'ṩ',
+
+	// AccentBreve: breve (u)
+	"uA": 'Ă', "uE": 'Ĕ', "uG": 'Ğ', "uI": 'Ĭ', "uO": 'Ŏ', "uU": 'Ŭ', "ua": 'ă', "ue": 'ĕ',
+	"ug": 'ğ', "ui": 'ĭ', "uo": 'ŏ', "uu": 'ŭ', "uȨ": 'Ḝ', "uȩ": 'ḝ', "uẠ": 'Ặ', "uạ": 'ặ',
+
+	// AccentCaron: caron or háček (v)
+	"vA": 'Ǎ', "vC": 'Č', "vD": 'Ď', "vE": 'Ě', "vG": 'Ǧ', "vH": 'Ȟ', "vI": 'Ǐ', "vK": 'Ǩ',
+	"vL": 'Ľ', "vN": 'Ň', "vO": 'Ǒ', "vR": 'Ř', "vS": 'Š', "vT": 'Ť', "vU": 'Ǔ', "vZ": 'Ž',
+	"va": 'ǎ', "vc": 'č', "vd": 'ď', "ve": 'ě', "vg": 'ǧ', "vh": 'ȟ', "vi": 'ǐ', "vj": 'ǰ',
+	"vk": 'ǩ', "vl": 'ľ', "vn": 'ň', "vo": 'ǒ', "vr": 'ř', "vs": 'š', "vt": 'ť', "vu": 'ǔ',
+	"vz": 'ž', "vÜ": 'Ǚ', "vü": 'ǚ', "vƷ": 'Ǯ', "vʒ": 'ǯ',
+
+	// AccentRing: ring above (r)
+	"rA": 'Å', "rU": 'Ů', "ra": 'å', "ru": 'ů', "rw": 'ẘ', "ry": 'ẙ',
+
+	// AccentDoubleAcute: double acute (H)
+	"HO": 'Ő', "HU": 'Ű', "Ho": 'ő', "Hu": 'ű',
+
+	// AccentCedilla: cedilla (c)
+	"cC": 'Ç', "cD": 'Ḑ', "cE": 'Ȩ', "cG": 'Ģ', "cH": 'Ḩ', "cK": 'Ķ', "cL": 'Ļ', "cN": 'Ņ',
+	"cR": 'Ŗ', "cS": 'Ş', "cT": 'Ţ', "cc": 'ç', "cd": 'ḑ', "ce": 'ȩ', "cg": 'ģ', "ch": 'ḩ',
+	"ck": 'ķ', "cl": 'ļ', "cn": 'ņ', "cr": 'ŗ', "cs": 'ş', "ct": 'ţ', "cĆ": 'Ḉ', "cć": 'ḉ',
+	"cĔ": 'Ḝ', "cĕ": 'ḝ',
+
+	// AccentOgonek: ogonek (k)
+	"kA": 'Ą', "kE": 'Ę', "kI": 'Į', "kO": 'Ǫ', "kU": 'Ų', "ka": 'ą', "ke": 'ę', "ki": 'į',
+	"ko": 'ǫ', "ku": 'ų', "kŌ": 'Ǭ', "kō": 'ǭ',
 
-	// AccentAcute accent (')
-	"'a": 'á', "'e": 'é', "'i": 'í', "'o": 'ó', "'u": 'ú', "'y": 'ý',
-	"'A": 'Á', "'E": 'É', "'I": 'Í', "'O": 'Ó', "'U": 'Ú', "'Y": 'Ý',
+	// AccentDotUnder: dot below (d)
+	"dA": 'Ạ', "dB": 'Ḅ', "dD": 'Ḍ', "dE": 'Ẹ', "dH": 'Ḥ', "dI": 'Ị', "dK": 'Ḳ', "dL": 'Ḷ',
+	"dM": 'Ṃ', "dN": 'Ṇ', "dO": 'Ọ', "dR": 'Ṛ', "dS": 'Ṣ', "dT": 'Ṭ', "dU": 'Ụ', "dV": 'Ṿ',
+	"dW": 'Ẉ', "dY": 'Ỵ', "dZ": 'Ẓ', "da": 'ạ', "db": 'ḅ', "dd": 'ḍ', "de": 'ẹ', "dh": 'ḥ',
+	"di": 'ị', "dk": 'ḳ', "dl": 'ḷ', "dm": 'ṃ', "dn": 'ṇ', "do": 'ọ', "dr": 'ṛ', "ds": 'ṣ',
+	"dt": 'ṭ', "du": 'ụ', "dv": 'ṿ', "dw": 'ẉ', "dy": 'ỵ', "dz": 'ẓ', "dÂ": 'Ậ', "dÊ": 'Ệ',
+	"dÔ": 'Ộ', "dâ": 'ậ', "dê": 'ệ', "dô": 'ộ', "dĂ": 'Ặ', "dă": 'ặ', "dƠ": 'Ợ', "dơ": 'ợ',
+	"dƯ": 'Ự', "dư": 'ự', "dṠ": 'Ṩ', "dṡ": 'ṩ',
 
-	// AccentCircumflex accent (^)
-	"^a": 'â', "^e": 'ê', "^i": 'î', "^o": 'ô', "^u": 'û',
-	"^A": 'Â', "^E": 'Ê', "^I": 'Î', "^O": 'Ô', "^U": 'Û',
+	// AccentBarUnder: bar below (b)
+	"bB": 'Ḇ', "bD": 'Ḏ', "bK": 'Ḵ', "bL": 'Ḻ', "bN": 'Ṉ', "bR": 'Ṟ', "bT": 'Ṯ', "bZ": 'Ẕ',
+	"bb": 'ḇ', "bd": 'ḏ', "bh": 'ẖ', "bk": 'ḵ', "bl": 'ḻ', "bn": 'ṉ', "br": 'ṟ', "bt": 'ṯ',
+	"bz": 'ẕ',
+}
 
-	// AccentUmlaut/diaeresis (")
-	`"a`: 'ä', `"e`: 'ë', `"i`: 'ï', `"o`: 'ö', `"u`: 'ü',
-	`"A`: 'Ä', `"E`: 'Ë', `"I`: 'Ï', `"O`: 'Ö', `"U`: 'Ü',
+// baseChars maps a precomposed accented character to the character without
+// the outermost accent, the inverse of accentMap.
+var baseChars = func() map[rune]rune {
+	m := make(map[rune]rune, len(accentMap))
+	for key, r := range accentMap {
+		base, _ := utf8.DecodeRuneInString(key[1:]) // accents are a single byte
+		m[r] = base
+	}
+	return m
+}()
 
-	// AccentTilde (~)
-	"~a": 'ã', "~n": 'ñ', "~o": 'õ',
-	"~A": 'Ã', "~N": 'Ñ', "~O": 'Õ',
+// combiningMarks maps accents to the Unicode combining mark for the accent.
+// Accents without a precomposed character render as the base character
+// followed by the combining mark.
+var combiningMarks = map[token.Accent]rune{
+	token.AccentGrave:       '\u0300',
+	token.AccentAcute:       '\u0301',
+	token.AccentCircumflex:  '\u0302',
+	token.AccentTilde:       '\u0303',
+	token.AccentMacron:      '\u0304',
+	token.AccentBreve:       '\u0306',
+	token.AccentDot:         '\u0307',
+	token.AccentUmlaut:      '\u0308',
+	token.AccentRing:        '\u030a',
+	token.AccentDoubleAcute: '\u030b',
+	token.AccentCaron:       '\u030c',
+	token.AccentDotUnder:    '\u0323',
+	token.AccentCedilla:     '\u0327',
+	token.AccentOgonek:      '\u0328',
+	token.AccentBarUnder:    '\u0331',
+}
 
-	// AccentCedilla (c)
-	"cc": 'ç', "cC": 'Ç',
+// dotlessLetters maps the dotless i and j, written \i and \j in LaTeX, to the
+// dotted letters. An accent above a dotless letter replaces the dot, so \'\i
+// is í.
+var dotlessLetters = map[string]string{"ı": "i", "ȷ": "j"}
 
-	// AccentDot (.)
-	".c": 'ċ', ".e": 'ė', ".g": 'ġ', ".i": 'ı', ".z": 'ż',
-	".C": 'Ċ', ".E": 'Ė', ".G": 'Ġ', ".I": 'İ', ".Z": 'Ż',
+// isAccentBelow returns true if the accent is placed below the base character.
+func isAccentBelow(accent token.Accent) bool {
+	switch accent {
+	case token.AccentCedilla, token.AccentOgonek, token.AccentDotUnder, token.AccentBarUnder:
+		return true
+	}
+	return false
 }
 
-// RenderAccent renders an accented character.
+// RenderAccent renders an accented character as a single precomposed rune,
+// like é for the acute accent and "e". The text must be a single character.
+// RenderAccent returns an error if there's no precomposed rune; use
+// RenderAccentText to fall back to a combining mark.
 func RenderAccent(accent token.Accent, text string) (rune, error) {
 	if len(text) == 0 {
 		return 0, fmt.Errorf("cannot render accent %q for empty text", accent)
 	}
-	if len(text) > 1 {
+	if utf8.RuneCountInString(text) > 1 {
 		return 0, fmt.Errorf("cannot render accent %q for multi-rune text %q", accent, text)
 	}
 	if accent == 0 {
 		return 0, fmt.Errorf("cannot render accent for empty accent")
 	}
+	if dotted, ok := dotlessLetters[text]; ok && !isAccentBelow(accent) {
+		if accent == token.AccentDot {
+			r, _ := utf8.DecodeRuneInString(dotted)
+			return r, nil
+		}
+		text = dotted
+	}
 	accented, ok := accentMap[(string(accent) + text)]
 	if !ok {
 		return 0, fmt.Errorf("invalid combination: cannot apply %q accent to character %q", accent, text)
 	}
 	return accented, nil
 }
+
+// RenderAccentText renders an accented character. RenderAccentText returns
+// the precomposed rune if one exists. Otherwise, it returns the text followed
+// by the Unicode combining mark for the accent, like "q\u0301" for \'q.
+func RenderAccentText(accent token.Accent, text string) (string, error) {
+	if r, err := RenderAccent(accent, text); err == nil {
+		return string(r), nil
+	}
+	mark, ok := combiningMarks[accent]
+	if !ok {
+		return "", fmt.Errorf("unknown accent %q", accent)
+	}
+	if utf8.RuneCountInString(text) != 1 {
+		return "", fmt.Errorf("cannot render accent %q for text %q; want a single character", accent, text)
+	}
+	return text + string(mark), nil
+}
+
+// StripAccents removes the accents from the characters of s, like "Vallée"
+// to "Vallee". Precomposed characters become their base character and
+// combining marks are removed.
+func StripAccents(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Mn, r) {
+			return -1
+		}
+		for {
+			base, ok := baseChars[r]
+			if !ok {
+				return r
+			}
+			r = base
+		}
+	}, s)
+}
@@ -0,0 +1,23 @@
+package render
+
+import "testing"
+
+func TestStripAccents(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"", ""},
+		{"Vallee", "Vallee"},
+		{"Vallée Poussin", "Vallee Poussin"},
+		{"Gödel, Erdős", "Godel, Erdos"},
+		{"Nguyễn", "Nguyen"},
+		{"Çelik", "Celik"},
+		{"q́", "q"},
+		{"Ørsted", "Ørsted"},
+	}
+	for _, tt := range tests {
+		if got := StripAccents(tt.s); got != tt.want {
+			t.Errorf("StripAccents(%q) = %q; want %q", tt.s, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"io"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+)
+
+// ANSI escape codes to set and reset text attributes.
+const (
+	ansiBold         = "\x1b[1m"
+	ansiBoldOff      = "\x1b[22m"
+	ansiItalic       = "\x1b[3m"
+	ansiItalicOff    = "\x1b[23m"
+	ansiUnderline    = "\x1b[4m"
+	ansiUnderlineOff = "\x1b[24m"
+)
+
+// ansiEscape removes control characters, except tab and newline, so that
+// text can't inject escape codes into the terminal.
+func ansiEscape(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' && r != '\t' && r != '\n' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func renderANSIHref(w io.Writer, args []string) error {
+	_, err := io.WriteString(w, args[1]+" <"+ansiUnderline+args[0]+ansiUnderlineOff+">")
+	return err
+}
+
+func renderANSIMath(tex string) string {
+	return "$" + ansiEscape(tex) + "$"
+}
+
+// ansiMacros are the default macro renderers for ANSIRenderer.
+var ansiMacros = map[string]macroDef{
+	"emph":   {nargs: 1, render: wrapArg(ansiItalic, ansiItalicOff)},
+	"textit": {nargs: 1, render: wrapArg(ansiItalic, ansiItalicOff)},
+	"textbf": {nargs: 1, render: wrapArg(ansiBold, ansiBoldOff)},
+	"textsc": {nargs: 1, render: wrapArg("", "")},
+	"texttt": {nargs: 1, render: wrapArg("", "")},
+	"url":    {nargs: 1, render: wrapArg(ansiUnderline, ansiUnderlineOff)},
+	"href":   {nargs: 2, render: renderANSIHref},
+}
+
+// ANSIRenderer renders an ast.Expr as text for a terminal. ANSIRenderer
+// renders emphasis macros, like \emph{foo}, with ANSI escape codes for italic
+// and bold text, and underlines URLs. Math renders between dollar signs, like
+// $x^2$.
+type ANSIRenderer struct {
+	m markup
+}
+
+type ANSIOption func(r *ANSIRenderer)
+
+// WithANSIMacro renders the LaTeX macro name, without the leading backslash,
+// with fn, overriding any default rendering. The macro takes nargs arguments.
+func WithANSIMacro(name string, nargs int, fn MacroFunc) ANSIOption {
+	return func(r *ANSIRenderer) {
+		r.m.macros[name] = macroDef{nargs: nargs, render: fn}
+	}
+}
+
+func NewANSIRenderer(opts ...ANSIOption) *ANSIRenderer {
+	r := &ANSIRenderer{
+		m: newMarkup("ansi", ansiEscape, renderANSIMath, " ", ansiMacros),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *ANSIRenderer) Render(w io.Writer, x ast.Expr) error {
+	return r.m.render(w, x)
+}
@@ -0,0 +1,43 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/parser"
+)
+
+func TestANSIRenderer_Render(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{foo bar}`, `foo bar`},
+		{`{a*b_c & <d>}`, `a*b_c & <d>`},
+		{`{A~B}`, `A B`},
+		{`{\emph{foo} bar}`, "\x1b[3mfoo\x1b[23m bar"},
+		{`{\textit{a b}}`, "\x1b[3ma b\x1b[23m"},
+		{`{\textbf{Bold}, yes}`, "\x1b[1mBold\x1b[22m, yes"},
+		{`{\textsc{Abc} \texttt{x}}`, `Abc x`},
+		{`{\url{http://x.com}}`, "\x1b[4mhttp://x.com\x1b[24m"},
+		{`{\href{http://x.com}{the site}}`, "the site <\x1b[4mhttp://x.com\x1b[24m>"},
+		{`{see $x^2$}`, `see $x^2$`},
+		{`{Stra{\ss}e {\"o}}`, `Straße ö`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			x, err := parser.ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sb := &strings.Builder{}
+			if err := NewANSIRenderer().Render(sb, x); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, sb.String()); diff != "" {
+				t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
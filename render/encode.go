@@ -0,0 +1,189 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/token"
+)
+
+// accentDecomps maps a precomposed accented rune to its accent and base, the
+// reverse of accentMap. Bases are ASCII letters because the parser can't read
+// nested accents, like \'{\"u}.
+var accentDecomps = func() map[rune]accentDecomp {
+	m := make(map[rune]accentDecomp, len(accentMap))
+	for key, r := range accentMap {
+		accent, size := utf8.DecodeRuneInString(key)
+		base := key[size:]
+		if len(base) != 1 {
+			continue // non-ASCII base
+		}
+		m[r] = accentDecomp{accent: token.Accent(accent), base: base}
+	}
+	return m
+}()
+
+type accentDecomp struct {
+	accent token.Accent
+	base   string
+}
+
+// markAccents maps a Unicode combining mark to its accent, the inverse of
+// combiningMarks.
+var markAccents = func() map[rune]token.Accent {
+	m := make(map[rune]token.Accent, len(combiningMarks))
+	for accent, mark := range combiningMarks {
+		m[mark] = accent
+	}
+	return m
+}()
+
+// encodeMacros maps runes to the LaTeX macro that produces the rune. Each
+// macro must be in textMacros.
+var encodeMacros = map[rune]string{
+	'ø': "o", 'Ø': "O",
+	'ß': "ss",
+	'æ': "ae", 'Æ': "AE",
+	'œ': "oe", 'Œ': "OE",
+	'ł': "l", 'Ł': "L",
+	'ı': "i", 'ȷ': "j",
+	'đ': "dj", 'Đ': "DJ",
+	'ð': "dh", 'Ð': "DH",
+	'þ': "th", 'Þ': "TH",
+	'ŋ': "ng", 'Ŋ': "NG",
+
+	'–': "textendash",
+	'—': "textemdash",
+	'‘': "textquoteleft",
+	'’': "textquoteright",
+	'“': "textquotedblleft",
+	'”': "textquotedblright",
+	'‚': "quotesinglbase",
+	'„': "quotedblbase",
+	'«': "guillemotleft",
+	'»': "guillemotright",
+	'‹': "guilsinglleft",
+	'›': "guilsinglright",
+	'¡': "textexclamdown",
+	'¿': "textquestiondown",
+	'…': "textellipsis",
+	'•': "textbullet",
+	'·': "textperiodcentered",
+	'␣': "textvisiblespace",
+
+	'§': "textsection",
+	'¶': "textparagraph",
+	'†': "textdagger",
+	'‡': "textdaggerdbl",
+	'©': "textcopyright",
+	'®': "textregistered",
+	'™': "texttrademark",
+	'°': "textdegree",
+	'£': "textsterling",
+	'€': "texteuro",
+	'¢': "textcent",
+	'¥': "textyen",
+	'½': "textonehalf",
+	'¼': "textonequarter",
+	'¾': "textthreequarters",
+	'µ': "textmu",
+	'ª': "textordfeminine",
+	'º': "textordmasculine",
+
+	'\\': "textbackslash",
+	'~':  "textasciitilde",
+	'^':  "textasciicircum",
+
+	'\u00a0': "nobreakspace",
+	'\u202f': `\,`,
+}
+
+// EncodeLaTeX encodes the Unicode string s into 7-bit LaTeX, the reverse of
+// TextRenderer. For example, EncodeLaTeX encodes "Škorpil & Søn" as:
+//
+//	{{\v{S}}korpil \& S{\o}n}
+//
+// EncodeLaTeX escapes the special characters & % $ # _ { } with a backslash,
+// encodes accented letters with accent macros, like \"{a}, and encodes other
+// characters, like ß and –, with the text macro that produces them, like \ss
+// and \textendash. An ASCII letter followed by a combining mark, like
+// "q\u0301" from RenderAccentText, encodes as an accent macro, like \'{q}.
+// Accents and macros are wrapped in braces so that bibtex
+// treats them as a special character.
+//
+// The result is a brace delimited ast.ParsedText suitable as the value of an
+// ast.TagStmt. Parsing the printed result and rendering it with TextRenderer
+// returns s, except that runs of whitespace collapse to a single space.
+// EncodeLaTeX returns an error if s contains a character other than printable
+// ASCII and whitespace that has no LaTeX encoding.
+func EncodeLaTeX(s string) (*ast.ParsedText, error) {
+	txt := &ast.ParsedText{Delim: ast.BraceDelimiter}
+	text := &strings.Builder{} // pending plain text
+	flush := func() {
+		if text.Len() > 0 {
+			txt.Values = append(txt.Values, &ast.Text{Value: text.String()})
+			text.Reset()
+		}
+	}
+	special := func(x ast.Expr) {
+		flush()
+		txt.Values = append(txt.Values, &ast.ParsedText{
+			Depth:  1,
+			Delim:  ast.BraceDelimiter,
+			Values: []ast.Expr{x},
+		})
+	}
+
+	for i, w := 0, 0; i < len(s); i += w {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		w = size
+		switch {
+		case r == utf8.RuneError && size == 1:
+			return nil, fmt.Errorf("encode latex: invalid UTF-8 at byte offset %d", i)
+
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			flush()
+			j := i
+			for j < len(s) && strings.IndexByte(" \t\n\r", s[j]) >= 0 {
+				j++
+			}
+			txt.Values = append(txt.Values, &ast.TextSpace{Value: s[i:j]})
+			w = j - i
+
+		case r == ',':
+			flush()
+			txt.Values = append(txt.Values, &ast.TextComma{})
+
+		case strings.ContainsRune(`&%$#_{}`, r):
+			flush()
+			txt.Values = append(txt.Values, &ast.TextEscaped{Value: string(r)})
+
+		case encodeMacros[r] != "":
+			special(&ast.TextMacro{Name: encodeMacros[r]})
+
+		case r < utf8.RuneSelf && r >= ' ' && r != 0x7f:
+			mark, markSize := utf8.DecodeRuneInString(s[i+size:])
+			if accent, ok := markAccents[mark]; ok && isASCIILetter(r) {
+				special(&ast.TextAccent{Accent: accent, Text: &ast.Text{Value: string(r)}})
+				w += markSize
+				break
+			}
+			text.WriteRune(r)
+
+		default:
+			d, ok := accentDecomps[r]
+			if !ok {
+				return nil, fmt.Errorf("encode latex: no LaTeX encoding for %q at byte offset %d", r, i)
+			}
+			special(&ast.TextAccent{Accent: d.accent, Text: &ast.Text{Value: d.base}})
+		}
+	}
+	flush()
+	return txt, nil
+}
+
+func isASCIILetter(r rune) bool {
+	return 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+}
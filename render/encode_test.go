@@ -0,0 +1,132 @@
+package render
+
+import (
+	"bytes"
+	gotok "go/token"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/parser"
+	"github.com/jschaf/bibtex/printer"
+)
+
+var spaceRun = regexp.MustCompile(`[ \t\n\r]+`)
+
+func TestEncodeLaTeX(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string // printed LaTeX
+	}{
+		{"plain text", "{plain text}"},
+		{"Škorpil & Søn", `{{\v{S}}korpil \& S{\o}n}`},
+		{"50% of $5 #1 a_b {x}", `{50\% of \$5 \#1 a\_b \{x\}}`},
+		{"Straße", `{Stra{\ss}e}`},
+		{"Erdős, Paul", `{Erd{\H{o}}s, Paul}`},
+		{"1–2—3", `{1{\textendash}2{\textemdash}3}`},
+		{`a\b~c^d`, `{a{\textbackslash}b{\textasciitilde}c{\textasciicircum}d}`},
+		{"ı and ȷ", `{{\i} and {\j}}`},
+		{"a b", `{a{\nobreakspace}b}`},
+		{"line\n\tbreak", "{line\n\tbreak}"},
+		{"Zürich Ça ą ā ṣ", `{Z{\"{u}}rich {\c{C}}a {\k{a}} {\={a}} {\d{s}}}`},
+		{"q\u0301 x\u0323y", `{{\'{q}} {\d{x}}y}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			txt, err := EncodeLaTeX(tt.s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			buf := &bytes.Buffer{}
+			if err := printer.Fprint(buf, gotok.NewFileSet(), txt); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, buf.String()); diff != "" {
+				t.Errorf("EncodeLaTeX() mismatch (-want +got):\n%s", diff)
+			}
+
+			// Round trip through the parser and the text renderer.
+			x, err := parser.ParseExpr(buf.String())
+			if err != nil {
+				t.Fatalf("parse encoded text %q: %v", buf.String(), err)
+			}
+			sb := &strings.Builder{}
+			if err := NewTextRenderer().Render(sb, x); err != nil {
+				t.Fatal(err)
+			}
+			// The text renderer collapses whitespace runs.
+			wantText := spaceRun.ReplaceAllString(tt.s, " ")
+			if diff := cmp.Diff(wantText, sb.String()); diff != "" {
+				t.Errorf("round trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEncodeLaTeX_errors(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"日本", `encode latex: no LaTeX encoding for '日' at byte offset 0`},
+		{"aǘ", `encode latex: no LaTeX encoding for 'ǘ' at byte offset 1`},
+		{"a\xffb", `encode latex: invalid UTF-8 at byte offset 1`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			_, err := EncodeLaTeX(tt.s)
+			if err == nil {
+				t.Fatal("expected error but had none")
+			}
+			if diff := cmp.Diff(tt.want, err.Error()); diff != "" {
+				t.Errorf("EncodeLaTeX() error mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEncodeLaTeX_accentMap(t *testing.T) {
+	// Every precomposed rune with an ASCII base round trips.
+	for r := range accentDecomps {
+		txt, err := EncodeLaTeX(string(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb := &strings.Builder{}
+		if err := NewTextRenderer().Render(sb, txt); err != nil {
+			t.Fatal(err)
+		}
+		if sb.String() != string(r) {
+			t.Errorf("round trip %q: got %q", r, sb.String())
+		}
+	}
+}
+
+func TestEncodeLaTeX_combiningMarks(t *testing.T) {
+	// Every accent on every ASCII letter round trips, whether the accent
+	// renders as a precomposed rune or as a combining mark. The decomposed
+	// form, like "e\u0301", encodes to the same accent.
+	letters := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	for accent, mark := range combiningMarks {
+		for _, base := range letters {
+			want, err := RenderAccentText(accent, string(base))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range []string{want, string(base) + string(mark)} {
+				txt, err := EncodeLaTeX(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sb := &strings.Builder{}
+				if err := NewTextRenderer().Render(sb, txt); err != nil {
+					t.Fatal(err)
+				}
+				if sb.String() != want {
+					t.Errorf("round trip %q: got %q; want %q", s, sb.String(), want)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package render
+
+import (
+	"html"
+	"io"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+)
+
+// safeURLSchemes are the URL schemes rendered as links. URLs with other
+// schemes, like javascript:, render as plain text.
+var safeURLSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+	"doi":    true,
+}
+
+// isSafeURL reports whether the unescaped URL u is relative or uses one of
+// safeURLSchemes. Browsers ignore whitespace and control characters in a
+// scheme, so isSafeURL drops them before reading the scheme.
+func isSafeURL(u string) bool {
+	u = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+	scheme, _, ok := strings.Cut(u, ":")
+	if !ok || strings.ContainsAny(scheme, "/?#") {
+		return true // relative URL
+	}
+	return safeURLSchemes[strings.ToLower(scheme)]
+}
+
+func renderHTMLURL(w io.Writer, args []string) error {
+	if !isSafeURL(html.UnescapeString(args[0])) {
+		_, err := io.WriteString(w, args[0])
+		return err
+	}
+	_, err := io.WriteString(w, `<a href="`+args[0]+`">`+args[0]+`</a>`)
+	return err
+}
+
+func renderHTMLHref(w io.Writer, args []string) error {
+	if !isSafeURL(html.UnescapeString(args[0])) {
+		_, err := io.WriteString(w, args[1])
+		return err
+	}
+	_, err := io.WriteString(w, `<a href="`+args[0]+`">`+args[1]+`</a>`)
+	return err
+}
+
+func renderHTMLMath(tex string) string {
+	return `<span class="math inline">\(` + html.EscapeString(tex) + `\)</span>`
+}
+
+// htmlMacros are the default macro renderers for HTMLRenderer.
+var htmlMacros = map[string]macroDef{
+	"emph":   {nargs: 1, render: wrapArg("<em>", "</em>")},
+	"textit": {nargs: 1, render: wrapArg("<i>", "</i>")},
+	"textbf": {nargs: 1, render: wrapArg("<b>", "</b>")},
+	"textsc": {nargs: 1, render: wrapArg(`<span style="font-variant: small-caps">`, "</span>")},
+	"texttt": {nargs: 1, render: wrapArg("<code>", "</code>")},
+	"url":    {nargs: 1, render: renderHTMLURL},
+	"href":   {nargs: 2, render: renderHTMLHref},
+
+	"underline":       {nargs: 1, render: wrapArg("<u>", "</u>")},
+	"textsuperscript": {nargs: 1, render: wrapArg("<sup>", "</sup>")},
+	"textsubscript":   {nargs: 1, render: wrapArg("<sub>", "</sub>")},
+}
+
+// HTMLRenderer renders an ast.Expr as HTML. HTMLRenderer escapes text and
+// renders formatting macros, like \emph{foo}, as the matching HTML element,
+// like <em>foo</em>. \url and \href render as links only for relative URLs and
+// the http, https, mailto and doi schemes; other URLs render as plain text.
+// Math renders as a MathJax inline span, like:
+//
+//	<span class="math inline">\(x^2\)</span>
+type HTMLRenderer struct {
+	m markup
+}
+
+type HTMLOption func(r *HTMLRenderer)
+
+// WithHTMLMacro renders the LaTeX macro name, without the leading backslash,
+// with fn, overriding any default rendering. The macro takes nargs arguments.
+func WithHTMLMacro(name string, nargs int, fn MacroFunc) HTMLOption {
+	return func(r *HTMLRenderer) {
+		r.m.macros[name] = macroDef{nargs: nargs, render: fn}
+	}
+}
+
+func NewHTMLRenderer(opts ...HTMLOption) *HTMLRenderer {
+	r := &HTMLRenderer{
+		m: newMarkup("html", html.EscapeString, renderHTMLMath, "&nbsp;", htmlMacros),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *HTMLRenderer) Render(w io.Writer, x ast.Expr) error {
+	return r.m.render(w, x)
+}
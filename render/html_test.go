@@ -0,0 +1,101 @@
+package render
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/parser"
+)
+
+func TestHTMLRenderer_Render(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{foo bar}`, `foo bar`},
+		{`{Fish & <Chips>}`, `Fish &amp; &lt;Chips&gt;`},
+		{`{50\% \& \$5}`, `50% &amp; $5`},
+		{`{A~B}`, `A&nbsp;B`},
+		{`{\emph{foo} bar}`, `<em>foo</em> bar`},
+		{`{\emph {foo}}`, `<em>foo</em>`},
+		{`{\textit{a b}}`, `<i>a b</i>`},
+		{`{\textbf{Bold}, yes}`, `<b>Bold</b>, yes`},
+		{`{\textsc{Abc}}`, `<span style="font-variant: small-caps">Abc</span>`},
+		{`{\texttt{a<b}}`, `<code>a&lt;b</code>`},
+		{`{\emph{\textbf{x}}}`, `<em><b>x</b></em>`},
+		{`{\underline{u} x\textsuperscript{2}\textsubscript{i}}`, `<u>u</u> x<sup>2</sup><sub>i</sub>`},
+		{`{\url{http://x.com/a?b=1&c=2}}`, `<a href="http://x.com/a?b=1&amp;c=2">http://x.com/a?b=1&amp;c=2</a>`},
+		{`{\href{http://x.com}{the site}}`, `<a href="http://x.com">the site</a>`},
+		{`{\url{mailto:a@x.com}}`, `<a href="mailto:a@x.com">mailto:a@x.com</a>`},
+		{`{\href{HTTPS://x.com}{the site}}`, `<a href="HTTPS://x.com">the site</a>`},
+		{`{\href{doi:10.1/x}{the doi}}`, `<a href="doi:10.1/x">the doi</a>`},
+		{`{\url{/a/b:c}}`, `<a href="/a/b:c">/a/b:c</a>`},
+		{`{\url{a?b=c:d}}`, `<a href="a?b=c:d">a?b=c:d</a>`},
+		{`{\url{javascript:alert(1)}}`, `javascript:alert(1)`},
+		{`{\href{JavaScript:alert(1)}{the site}}`, `the site`},
+		{`{\href{java\ script:alert(1)}{the site}}`, `the site`},
+		{`{\href{data:text/html,x}{the site}}`, `the site`},
+		{`{see $x^2 < y$}`, `see <span class="math inline">\(x^2 &lt; y\)</span>`},
+		{`{Stra{\ss}e {\"o}}`, `Straße ö`},
+		{`{\ss e}`, `ße`},
+		{`{\unknown{A}}`, `A`},
+		{`{a--b}`, `a--b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			x, err := parser.ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sb := &strings.Builder{}
+			if err := NewHTMLRenderer().Render(sb, x); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, sb.String()); diff != "" {
+				t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHTMLRenderer_Render_macroValues(t *testing.T) {
+	x := &ast.TextMacro{Name: "textbf", Values: []ast.Expr{&ast.Text{Value: "a&b"}}}
+	sb := &strings.Builder{}
+	if err := NewHTMLRenderer().Render(sb, x); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(`<b>a&amp;b</b>`, sb.String()); diff != "" {
+		t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestHTMLRenderer_Render_override(t *testing.T) {
+	strong := func(w io.Writer, args []string) error {
+		_, err := io.WriteString(w, "<strong>"+args[0]+"</strong>")
+		return err
+	}
+	link := func(w io.Writer, args []string) error {
+		_, err := io.WriteString(w, `<a class="doi" href="https://doi.org/`+args[0]+`">`+args[0]+`</a>`)
+		return err
+	}
+	r := NewHTMLRenderer(
+		WithHTMLMacro("textbf", 1, strong),
+		WithHTMLMacro("doi", 1, link),
+	)
+
+	x, err := parser.ParseExpr(`{\textbf{A} \doi{10.1/x} \emph{B}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb := &strings.Builder{}
+	if err := r.Render(sb, x); err != nil {
+		t.Fatal(err)
+	}
+	want := `<strong>A</strong> <a class="doi" href="https://doi.org/10.1/x">10.1/x</a> <em>B</em>`
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+	}
+}
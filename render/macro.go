@@ -0,0 +1,103 @@
+package render
+
+// textMacros maps LaTeX text-mode macros that take no arguments to the Unicode
+// text they produce, like \ss to ß.
+var textMacros = map[string]string{
+	// Letters.
+	"aa": "å", "AA": "Å",
+	"ae": "æ", "AE": "Æ",
+	"cc": "ç", // the cedilla accent without braces, like Fran{\cc}oise
+	"dh": "ð", "DH": "Ð",
+	"dj": "đ", "DJ": "Đ",
+	"i": "ı", "j": "ȷ",
+	"l": "ł", "L": "Ł",
+	"ng": "ŋ", "NG": "Ŋ",
+	"o": "ø", "O": "Ø",
+	"oe": "œ", "OE": "Œ",
+	"ss": "ß", "SS": "SS",
+	"th": "þ", "TH": "Þ",
+
+	// Punctuation.
+	"textendash":         "–",
+	"textemdash":         "—",
+	"textquoteleft":      "‘",
+	"textquoteright":     "’",
+	"lq":                 "‘",
+	"rq":                 "’",
+	"textquotedblleft":   "“",
+	"textquotedblright":  "”",
+	"quotesinglbase":     "‚",
+	"quotedblbase":       "„",
+	"guillemotleft":      "«",
+	"guillemotright":     "»",
+	"guilsinglleft":      "‹",
+	"guilsinglright":     "›",
+	"textexclamdown":     "¡",
+	"textquestiondown":   "¿",
+	"textellipsis":       "…",
+	"ldots":              "…",
+	"dots":               "…",
+	"textbullet":         "•",
+	"textperiodcentered": "·",
+	"textvisiblespace":   "␣",
+
+	// Symbols.
+	"S":                 "§",
+	"textsection":       "§",
+	"P":                 "¶",
+	"textparagraph":     "¶",
+	"dag":               "†",
+	"textdagger":        "†",
+	"ddag":              "‡",
+	"textdaggerdbl":     "‡",
+	"copyright":         "©",
+	"textcopyright":     "©",
+	"textregistered":    "®",
+	"texttrademark":     "™",
+	"textdegree":        "°",
+	"pounds":            "£",
+	"textsterling":      "£",
+	"texteuro":          "€",
+	"euro":              "€",
+	"textcent":          "¢",
+	"textyen":           "¥",
+	"textdollar":        "$",
+	"textbackslash":     `\`,
+	"textbar":           "|",
+	"textless":          "<",
+	"textgreater":       ">",
+	"textunderscore":    "_",
+	"textasciitilde":    "~",
+	"textasciicircum":   "^",
+	"textbraceleft":     "{",
+	"textbraceright":    "}",
+	"textonehalf":       "½",
+	"textonequarter":    "¼",
+	"textthreequarters": "¾",
+	"textmu":            "µ",
+	"textordfeminine":   "ª",
+	"textordmasculine":  "º",
+
+	// Logos.
+	"TeX":    "TeX",
+	"LaTeX":  "LaTeX",
+	"BibTeX": "BibTeX",
+
+	// Spacing and hyphenation.
+	"nobreakspace": "\u00a0",
+	`\ `:           " ",
+	`\,`:           "\u202f", // narrow no-break space
+	`\-`:           "",       // discretionary hyphen
+	`\/`:           "",       // italic correction
+	`\@`:           "",       // spacing after a period
+}
+
+// MacroText returns the Unicode text for a LaTeX text-mode macro that takes no
+// arguments, like "ß" for \ss or "–" for \textendash. The name excludes the
+// leading backslash for macros made of letters, like "ss", and includes it for
+// single-character macros, like `\,`. MacroText returns false for unknown
+// macros.
+func MacroText(name string) (string, bool) {
+	s, ok := textMacros[name]
+	return s, ok
+}
@@ -0,0 +1,122 @@
+package render
+
+import (
+	"io"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+)
+
+// markdownEscaper escapes the characters that start inline Markdown syntax,
+// like emphasis, links, code spans, entities and raw HTML. Block syntax, like
+// "# heading", only applies at the start of a line, so it's not escaped
+// because the renderer writes inline text.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	`*`, `\*`,
+	`_`, `\_`,
+	`[`, `\[`,
+	`]`, `\]`,
+	`<`, `\<`,
+	`>`, `\>`,
+	`&`, `\&`,
+	`|`, `\|`,
+	`~`, `\~`,
+	`$`, `\$`,
+)
+
+// markdownUnescaper reverses markdownEscaper.
+var markdownUnescaper = strings.NewReplacer(
+	`\\`, `\`,
+	"\\`", "`",
+	`\*`, `*`,
+	`\_`, `_`,
+	`\[`, `[`,
+	`\]`, `]`,
+	`\<`, `<`,
+	`\>`, `>`,
+	`\&`, `&`,
+	`\|`, `|`,
+	`\~`, `~`,
+	`\$`, `$`,
+)
+
+// markdownDestEscaper escapes characters that end a link destination. The URL
+// is already escaped by markdownEscaper.
+var markdownDestEscaper = strings.NewReplacer(
+	`(`, `\(`,
+	`)`, `\)`,
+	` `, `%20`,
+)
+
+// renderMarkdownURL renders a URL as a link. Like HTMLRenderer, it renders
+// a URL with an unsafe scheme, like javascript:, as plain text.
+func renderMarkdownURL(w io.Writer, args []string) error {
+	if !isSafeURL(markdownUnescaper.Replace(args[0])) {
+		_, err := io.WriteString(w, args[0])
+		return err
+	}
+	_, err := io.WriteString(w, "["+args[0]+"]("+markdownDestEscaper.Replace(args[0])+")")
+	return err
+}
+
+func renderMarkdownHref(w io.Writer, args []string) error {
+	if !isSafeURL(markdownUnescaper.Replace(args[0])) {
+		_, err := io.WriteString(w, args[1])
+		return err
+	}
+	_, err := io.WriteString(w, "["+args[1]+"]("+markdownDestEscaper.Replace(args[0])+")")
+	return err
+}
+
+// renderMarkdownMath renders math between dollar signs. The TeX is escaped
+// like text so that characters like _ and * don't start emphasis.
+func renderMarkdownMath(tex string) string {
+	return "$" + markdownEscaper.Replace(tex) + "$"
+}
+
+// markdownMacros are the default macro renderers for MarkdownRenderer.
+var markdownMacros = map[string]macroDef{
+	"emph":   {nargs: 1, render: wrapArg("*", "*")},
+	"textit": {nargs: 1, render: wrapArg("*", "*")},
+	"textbf": {nargs: 1, render: wrapArg("**", "**")},
+	"textsc": {nargs: 1, render: wrapArg("", "")},
+	"texttt": {nargs: 1, render: wrapArg("<code>", "</code>")},
+	"url":    {nargs: 1, render: renderMarkdownURL},
+	"href":   {nargs: 2, render: renderMarkdownHref},
+}
+
+// MarkdownRenderer renders an ast.Expr as inline CommonMark Markdown.
+// MarkdownRenderer escapes Markdown metacharacters in text, renders emphasis
+// macros, like \emph{foo}, as *foo*, and renders \url and \href as links,
+// except for URLs with unsafe schemes, like javascript:.
+// Math renders escaped between dollar signs, like $x^2 \< y\_1$.
+type MarkdownRenderer struct {
+	m markup
+}
+
+type MarkdownOption func(r *MarkdownRenderer)
+
+// WithMarkdownMacro renders the LaTeX macro name, without the leading
+// backslash, with fn, overriding any default rendering. The macro takes nargs
+// arguments.
+func WithMarkdownMacro(name string, nargs int, fn MacroFunc) MarkdownOption {
+	return func(r *MarkdownRenderer) {
+		r.m.macros[name] = macroDef{nargs: nargs, render: fn}
+	}
+}
+
+func NewMarkdownRenderer(opts ...MarkdownOption) *MarkdownRenderer {
+	r := &MarkdownRenderer{
+		m: newMarkup("markdown", markdownEscaper.Replace, renderMarkdownMath, "&nbsp;", markdownMacros),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *MarkdownRenderer) Render(w io.Writer, x ast.Expr) error {
+	return r.m.render(w, x)
+}
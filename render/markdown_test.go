@@ -0,0 +1,55 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/parser"
+)
+
+func TestMarkdownRenderer_Render(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{foo bar}`, `foo bar`},
+		{`{a*b_c [d] <e> f|g}`, `a\*b\_c \[d\] \<e\> f\|g`},
+		{"{`code` & co}", "\\`code\\` \\& co"},
+		{`{50\% \& \$5 \_}`, `50% \& \$5 \_`},
+		{`{back\textbackslash slash}`, `back\\slash`},
+		{`{A~B}`, `A&nbsp;B`},
+		{`{\emph{foo} bar}`, `*foo* bar`},
+		{`{\textit{a b}}`, `*a b*`},
+		{`{\textbf{Bold}, yes}`, `**Bold**, yes`},
+		{`{\textsc{Abc}}`, `Abc`},
+		{`{\texttt{a_b}}`, `<code>a\_b</code>`},
+		{`{\emph{\textbf{x}}}`, `***x***`},
+		{`{\url{http://x.com/a_(b)}}`, `[http://x.com/a\_(b)](http://x.com/a\_\(b\))`},
+		{`{\href{http://x.com}{the *site*}}`, `[the \*site\*](http://x.com)`},
+		{`{\url{javascript:alert(1)}}`, `javascript:alert(1)`},
+		{`{\href{JavaScript:alert(1)}{x}}`, `x`},
+		{`{\url{mailto:a@b.c}}`, `[mailto:a@b.c](mailto:a@b.c)`},
+		{`{\emph{}a\emph}`, `a`},
+		{`{see $x^2 < y$}`, `see $x^2 \< y$`},
+		{`{$a_1 * b_2$}`, `$a\_1 \* b\_2$`},
+		{`{$\alpha<b>$}`, `$\\alpha\<b\>$`},
+		{`{Stra{\ss}e {\"o}}`, `Straße ö`},
+		{`{# 1. - +}`, `# 1. - +`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			x, err := parser.ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sb := &strings.Builder{}
+			if err := NewMarkdownRenderer().Render(sb, x); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, sb.String()); diff != "" {
+				t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
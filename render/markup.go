@@ -0,0 +1,224 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+)
+
+// MacroFunc writes a LaTeX macro in a markup language, like HTML. Args holds
+// the rendered markup of each macro argument. Missing arguments are empty
+// strings.
+type MacroFunc func(w io.Writer, args []string) error
+
+type macroDef struct {
+	nargs  int
+	render MacroFunc
+}
+
+// wrapArg returns a MacroFunc that writes the first argument between open and
+// close. If the argument is empty or missing, like in \emph{}, the MacroFunc
+// writes nothing.
+func wrapArg(open, close string) MacroFunc {
+	return func(w io.Writer, args []string) error {
+		if args[0] == "" {
+			return nil
+		}
+		_, err := io.WriteString(w, open+args[0]+close)
+		return err
+	}
+}
+
+// markup renders an ast.Expr into a markup language. The HTML, Markdown and
+// ANSI renderers differ only in how they escape text and render math, the
+// non-breaking space, and macros.
+type markup struct {
+	name   string // name of the renderer for errors, like "html"
+	escape func(s string) string
+	math   func(tex string) string
+	nbsp   string
+	macros map[string]macroDef
+}
+
+func newMarkup(name string, escape, math func(string) string, nbsp string, macros map[string]macroDef) markup {
+	m := markup{
+		name:   name,
+		escape: escape,
+		math:   math,
+		nbsp:   nbsp,
+		macros: make(map[string]macroDef, len(macros)),
+	}
+	for name, def := range macros {
+		m.macros[name] = def
+	}
+	return m
+}
+
+func (m markup) render(w io.Writer, x ast.Expr) error {
+	switch t := x.(type) {
+	case *ast.ParsedText:
+		return m.renderValues(w, t.Values)
+	case *ast.ConcatExpr:
+		if err := m.render(w, t.X); err != nil {
+			return err
+		}
+		return m.render(w, t.Y)
+	case *ast.TextMacro:
+		return m.renderMacro(w, t, nil)
+	case *ast.TextComma:
+		_, err := io.WriteString(w, m.escape(","))
+		return err
+	case *ast.Text:
+		_, err := io.WriteString(w, m.escape(t.Value))
+		return err
+	case *ast.Number:
+		_, err := io.WriteString(w, m.escape(t.Value))
+		return err
+	case *ast.TextEscaped:
+		_, err := io.WriteString(w, m.escape(t.Value))
+		return err
+	case *ast.TextHyphen:
+		_, err := io.WriteString(w, m.escape("-"))
+		return err
+	case *ast.TextMath:
+		_, err := io.WriteString(w, m.math(t.Value))
+		return err
+	case *ast.TextNBSP:
+		_, err := io.WriteString(w, m.nbsp)
+		return err
+	case *ast.TextSpace:
+		_, err := io.WriteString(w, " ")
+		return err
+	case *ast.TextAccent:
+		s, err := RenderAccentText(t.Accent, t.Text.Value)
+		if err != nil {
+			return fmt.Errorf("render accent: %w", err)
+		}
+		_, err = io.WriteString(w, m.escape(s))
+		return err
+	case ast.Authors:
+		for i, a := range t {
+			if i > 0 {
+				if _, err := io.WriteString(w, " and "); err != nil {
+					return err
+				}
+			}
+			if err := m.render(w, a); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *ast.Author:
+		return m.renderAuthor(w, t)
+	default:
+		return fmt.Errorf("%s renderer - unhandled ast.Expr type %T, %v", m.name, t, t)
+	}
+}
+
+// renderValues renders the values of a ParsedText. A macro with arguments
+// takes its missing arguments from the values that follow it, like the brace
+// group in \emph{foo}.
+func (m markup) renderValues(w io.Writer, values []ast.Expr) error {
+	for i := 0; i < len(values); i++ {
+		v := values[i]
+		if _, ok := v.(*ast.TextSpace); ok && i > 0 && isControlWord(values[i-1]) {
+			continue // TeX ignores spaces after a control word, like "\ss "
+		}
+		mac, ok := v.(*ast.TextMacro)
+		if !ok {
+			if err := m.render(w, v); err != nil {
+				return err
+			}
+			continue
+		}
+		var args []ast.Expr
+		if def, ok := m.macros[mac.Name]; ok {
+			for j := i + 1; j < len(values) && len(mac.Values)+len(args) < def.nargs; j++ {
+				if _, ok := values[j].(*ast.TextSpace); ok {
+					continue // TeX skips spaces before an argument
+				}
+				args = append(args, values[j])
+				i = j
+			}
+		}
+		if err := m.renderMacro(w, mac, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderMacro renders a macro with its own values followed by the extra
+// arguments in args.
+func (m markup) renderMacro(w io.Writer, mac *ast.TextMacro, args []ast.Expr) error {
+	def, ok := m.macros[mac.Name]
+	if !ok {
+		if s, ok := MacroText(mac.Name); ok && len(mac.Values) == 0 {
+			_, err := io.WriteString(w, m.escape(s))
+			return err
+		}
+		// Skip the command and write the args.
+		for _, v := range mac.Values {
+			if err := m.render(w, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	all := make([]ast.Expr, 0, len(mac.Values)+len(args))
+	all = append(all, mac.Values...)
+	all = append(all, args...)
+	rendered := make([]string, max(def.nargs, len(all)))
+	for i, arg := range all {
+		sb := &strings.Builder{}
+		if err := m.render(sb, arg); err != nil {
+			return fmt.Errorf("render macro %s: %w", mac.Name, err)
+		}
+		rendered[i] = sb.String()
+	}
+	if err := def.render(w, rendered); err != nil {
+		return fmt.Errorf("render macro %s: %w", mac.Name, err)
+	}
+	return nil
+}
+
+// renderAuthor renders an author in the order first, prefix, last, followed
+// by the suffix after a comma, like "Martin Luther King, Jr.".
+func (m markup) renderAuthor(w io.Writer, a *ast.Author) error {
+	sep := ""
+	for _, part := range []ast.Expr{a.First, a.Prefix, a.Last} {
+		if isEmptyExpr(part) {
+			continue
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		if err := m.render(w, part); err != nil {
+			return err
+		}
+		sep = " "
+	}
+	if !isEmptyExpr(a.Suffix) {
+		if _, err := io.WriteString(w, m.escape(",")+" "); err != nil {
+			return err
+		}
+		return m.render(w, a.Suffix)
+	}
+	return nil
+}
+
+// isEmptyExpr returns true if x is nil or renders as empty text.
+func isEmptyExpr(x ast.Expr) bool {
+	switch t := x.(type) {
+	case nil:
+		return true
+	case *ast.Text:
+		return t.Value == ""
+	case *ast.ParsedText:
+		return len(t.Values) == 0
+	}
+	return false
+}
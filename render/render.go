@@ -29,6 +29,7 @@ func Defaults() []NodeRenderer {
 		ast.KindUnparsedText:    NodeRendererFunc(renderUnparsedText),
 		ast.KindParsedText:      NodeRendererFunc(renderParsedText),
 		ast.KindText:            NodeRendererFunc(renderText),
+		ast.KindTextAccent:      NodeRendererFunc(renderTextAccent),
 		ast.KindTextComma:       NodeRendererFunc(renderTextComma),
 		ast.KindTextEscaped:     NodeRendererFunc(renderTextEscaped),
 		ast.KindTextHyphen:      NodeRendererFunc(renderTextHyphen),
@@ -42,6 +43,7 @@ func Defaults() []NodeRenderer {
 		ast.KindBadDecl:         NodeRendererFunc(renderBadDecl),
 		ast.KindAbbrevDecl:      NodeRendererFunc(renderAbbrevDecl),
 		ast.KindBibDecl:         NodeRendererFunc(renderBibDecl),
+		ast.KindCommentDecl:     NodeRendererFunc(renderCommentDecl),
 		ast.KindPreambleDecl:    NodeRendererFunc(renderPreambleDecl),
 		ast.KindFile:            NodeRendererFunc(renderFile),
 		ast.KindPackage:         NodeRendererFunc(renderPackage),
@@ -101,13 +103,25 @@ func renderParsedText(w io.Writer, n ast.Node, entering bool) (ast.WalkStatus, e
 }
 
 func renderText(w io.Writer, n ast.Node, _ bool) (ast.WalkStatus, error) {
-	txt := n.(*ast.TextSpace)
+	txt := n.(*ast.Text)
 	if _, err := w.Write([]byte(txt.Value)); err != nil {
 		return ast.WalkStop, fmt.Errorf("default renderText: %w", err)
 	}
 	return ast.WalkContinue, nil
 }
 
+func renderTextAccent(w io.Writer, n ast.Node, _ bool) (ast.WalkStatus, error) {
+	acc := n.(*ast.TextAccent)
+	s, err := RenderAccentText(acc.Accent, acc.Text.Value)
+	if err != nil {
+		return ast.WalkStop, fmt.Errorf("default renderTextAccent: %w", err)
+	}
+	if _, err := w.Write([]byte(s)); err != nil {
+		return ast.WalkStop, fmt.Errorf("default renderTextAccent: %w", err)
+	}
+	return ast.WalkContinue, nil
+}
+
 func renderTextComma(w io.Writer, _ ast.Node, _ bool) (ast.WalkStatus, error) {
 	if _, err := w.Write([]byte(",")); err != nil {
 		return ast.WalkStop, fmt.Errorf("default renderTextComma: %w", err)
@@ -158,8 +172,15 @@ func renderTextSpace(w io.Writer, n ast.Node, _ bool) (ast.WalkStatus, error) {
 	return ast.WalkContinue, nil
 }
 
-func renderTextMacro(io.Writer, ast.Node, bool) (ast.WalkStatus, error) {
-	// Skip the command and write the args.
+func renderTextMacro(w io.Writer, n ast.Node, entering bool) (ast.WalkStatus, error) {
+	// Write the text of symbol macros, like \ss. Otherwise, skip the command
+	// and write the args.
+	m := n.(*ast.TextMacro)
+	if s, ok := MacroText(m.Name); ok && entering && len(m.Values) == 0 {
+		if _, err := w.Write([]byte(s)); err != nil {
+			return ast.WalkStop, fmt.Errorf("default renderTextMacro: %w", err)
+		}
+	}
 	return ast.WalkContinue, nil
 }
 
@@ -187,6 +208,10 @@ func renderBibDecl(io.Writer, ast.Node, bool) (ast.WalkStatus, error) {
 	return ast.WalkContinue, nil
 }
 
+func renderCommentDecl(io.Writer, ast.Node, bool) (ast.WalkStatus, error) {
+	return ast.WalkContinue, nil
+}
+
 func renderPreambleDecl(io.Writer, ast.Node, bool) (ast.WalkStatus, error) {
 	return ast.WalkContinue, nil
 }
@@ -210,7 +235,10 @@ func NewTextRenderer() *TextRenderer {
 func (p TextRenderer) Render(w io.Writer, x ast.Expr) error {
 	switch t := x.(type) {
 	case *ast.ParsedText:
-		for _, v := range t.Values {
+		for i, v := range t.Values {
+			if _, ok := v.(*ast.TextSpace); ok && i > 0 && isControlWord(t.Values[i-1]) {
+				continue // TeX ignores spaces after a control word, like "\ss "
+			}
 			if err := p.Render(w, v); err != nil {
 				return err
 			}
@@ -224,6 +252,12 @@ func (p TextRenderer) Render(w io.Writer, x ast.Expr) error {
 			return err
 		}
 	case *ast.TextMacro:
+		if s, ok := MacroText(t.Name); ok && len(t.Values) == 0 {
+			if _, err := io.WriteString(w, s); err != nil {
+				return err
+			}
+			return nil
+		}
 		for _, v := range t.Values {
 			if err := p.Render(w, v); err != nil {
 				return err
@@ -267,12 +301,11 @@ func (p TextRenderer) Render(w io.Writer, x ast.Expr) error {
 			return err
 		}
 	case *ast.TextAccent:
-		r, err := RenderAccent(t.Accent, t.Text.Value)
+		s, err := RenderAccentText(t.Accent, t.Text.Value)
 		if err != nil {
 			return fmt.Errorf("render accent: %w", err)
 		}
-		_, err = w.Write([]byte(string(r)))
-		if err != nil {
+		if _, err := io.WriteString(w, s); err != nil {
 			return err
 		}
 	default:
@@ -283,3 +316,10 @@ func (p TextRenderer) Render(w io.Writer, x ast.Expr) error {
 	}
 	return nil
 }
+
+// isControlWord returns true if x is a TeX control word without arguments,
+// like \ss. A control word is a backslash followed by letters.
+func isControlWord(x ast.Expr) bool {
+	m, ok := x.(*ast.TextMacro)
+	return ok && len(m.Values) == 0 && m.Name != "" && m.Name[0] != '\\'
+}
@@ -0,0 +1,50 @@
+package bibtex
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const renderEntrySrc = `
+	@article{knuth_84,
+		title = {\emph{Literate} Programming},
+		author = {Knuth, Donald E. and Doe, Jane},
+		year = 1984,
+		note = {See \url{http://x.com/a_b}},
+	}`
+
+func TestEntry_RenderMarkdown(t *testing.T) {
+	entry := resolveEntry(t, renderEntrySrc, NewAuthorResolver(FieldAuthor))
+	sb := &strings.Builder{}
+	if err := entry.RenderMarkdown(sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `**knuth\_84** (article)
+
+- **author**: Donald E. Knuth and Jane Doe
+- **note**: See [http://x.com/a\_b](http://x.com/a\_b)
+- **title**: *Literate* Programming
+- **year**: 1984
+`
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("RenderMarkdown() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEntry_RenderANSI(t *testing.T) {
+	entry := resolveEntry(t, renderEntrySrc, NewAuthorResolver(FieldAuthor))
+	sb := &strings.Builder{}
+	if err := entry.RenderANSI(sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "\x1b[1mknuth_84\x1b[22m (article)\n" +
+		"  author: Donald E. Knuth and Jane Doe\n" +
+		"  note: See \x1b[4mhttp://x.com/a_b\x1b[24m\n" +
+		"  title: \x1b[3mLiterate\x1b[23m Programming\n" +
+		"  year: 1984\n"
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("RenderANSI() mismatch (-want +got):\n%s", diff)
+	}
+}
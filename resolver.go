@@ -1,7 +1,10 @@
 package bibtex
 
 import (
+	"errors"
 	"fmt"
+	goscan "go/scanner"
+	gotok "go/token"
 	"strings"
 
 	"github.com/jschaf/bibtex/ast"
@@ -69,9 +72,21 @@ func (r *RenderParsedTextResolver) Resolve(root ast.Node) error {
 
 		switch tag.Value.(type) {
 		case ast.Authors:
-			// Descend into authors and render each author part, like first
-			// name and last name.
-			return ast.WalkContinue, nil
+			// Render each author part, like first name and last name.
+			for _, a := range tag.Value.(ast.Authors) {
+				for _, part := range []*ast.Expr{&a.First, &a.Prefix, &a.Last, &a.Suffix} {
+					txt, ok := (*part).(*ast.ParsedText)
+					if !ok {
+						continue
+					}
+					sb := &strings.Builder{}
+					if err := r.rend.Render(sb, txt); err != nil {
+						return ast.WalkStop, fmt.Errorf("render author tag=%q: %w", tag.Name, err)
+					}
+					*part = &ast.Text{ValuePos: txt.Pos(), Value: sb.String()}
+				}
+			}
+			return ast.WalkSkipChildren, nil
 
 		case *ast.ParsedText:
 			sb := &strings.Builder{}
@@ -95,6 +110,7 @@ func (r *RenderParsedTextResolver) Resolve(root ast.Node) error {
 // AuthorResolver extracts ast.Authors from the expression value of a tag
 // statement.
 type AuthorResolver struct {
+	fset *gotok.FileSet
 	tags map[string]struct{} // tag names to extract authors from
 }
 
@@ -106,7 +122,17 @@ func NewAuthorResolver(tags ...string) AuthorResolver {
 	return AuthorResolver{tags: m}
 }
 
+// WithFileSet returns a copy of the resolver that reports the positions of
+// errors using fset.
+func (a AuthorResolver) WithFileSet(fset *gotok.FileSet) AuthorResolver {
+	a.fset = fset
+	return a
+}
+
+// Resolve replaces the values of the tags with ast.Authors. Resolve returns
+// a scanner.ErrorList with an error for each tag with invalid names.
 func (a AuthorResolver) Resolve(root ast.Node) error {
+	var errs goscan.ErrorList
 	err := ast.Walk(root, func(n ast.Node, isEntering bool) (ast.WalkStatus, error) {
 		if !isEntering {
 			return ast.WalkSkipChildren, nil
@@ -123,7 +149,16 @@ func (a AuthorResolver) Resolve(root ast.Node) error {
 			return ast.WalkStop, fmt.Errorf("author resolver tag %q expression was not ParsedText; got %T", tag.Name, tag.Value)
 		}
 		authors, err := ExtractAuthors(txt)
-		if err != nil {
+		var authErr *AuthorError
+		switch {
+		case errors.As(err, &authErr):
+			var position gotok.Position
+			if a.fset != nil {
+				position = a.fset.Position(authErr.Pos)
+			}
+			errs.Add(position, fmt.Sprintf("%s: %s", tag.Name, authErr.Msg))
+			return ast.WalkSkipChildren, nil
+		case err != nil:
 			return ast.WalkStop, fmt.Errorf("author resolver resolution: %w", err)
 		}
 		tag.Value = authors
@@ -131,7 +166,10 @@ func (a AuthorResolver) Resolve(root ast.Node) error {
 		return ast.WalkSkipChildren, nil
 	})
 	if err != nil {
-		return fmt.Errorf("simplify escaped text resolver: %w", err)
+		return fmt.Errorf("author resolver: %w", err)
+	}
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
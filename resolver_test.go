@@ -6,6 +6,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/jschaf/bibtex/ast"
 	"github.com/jschaf/bibtex/asts"
+	"github.com/jschaf/bibtex/parser"
 	"github.com/jschaf/bibtex/token"
 )
 
@@ -89,3 +90,45 @@ func TestRenderParsedTextResolver_Resolve(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderParsedTextResolver_Resolve_latex(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`{{\v{S}}korpil}`, "Škorpil"},
+		{`{Erd\H{o}s}`, "Erdős"},
+		{`{\k{a}}`, "ą"},
+		{`{\r{a}}`, "å"},
+		{`{\u{g}}`, "ğ"},
+		{`{\={a}\d{s}\b{k}\c{t}}`, "āṣḵţ"},
+		{`{{\o}{\ss}{\ae}{\l}{\O}{\AE}}`, "øßæłØÆ"},
+		{`{Sm\"{\i}th \'\i\ \^{\j}}`, "Smïth í ĵ"},
+		{`{\.\i}`, "i"},
+		{`{\'{æ}}`, "ǽ"},
+		{`{\v{q}}`, "q̌"},
+		{`{\r\i}`, "ı̊"},
+		{`{1\textendash 2\textemdash 3}`, "1–2—3"},
+		{`{Stra\ss e}`, "Straße"},
+		{`{\LaTeX{} and \TeX}`, "LaTeX and TeX"},
+		{`{\cc}`, "ç"},
+		{`{\c c}`, "ç"},
+		{"{\\v\n s}", "š"},
+		{`{Rock\rq n\lq Roll}`, "Rock’n‘Roll"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			x, err := parser.ParseExpr(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			stmt := &ast.TagStmt{Name: "title", RawName: "title", Value: x}
+			if err := NewRenderParsedTextResolver().Resolve(stmt); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, stmt.Value.(*ast.Text).Value); diff != "" {
+				t.Errorf("RenderParsedTextResolver.Resolve() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -2,4 +2,11 @@ package scanner
 
 import "go/scanner"
 
-type ErrorList scanner.ErrorList
+// An Error describes a syntax error in a bibtex source file. It's the same
+// type as the go/scanner Error so that the error lists of both packages are
+// interchangeable.
+type Error = scanner.Error
+
+// ErrorList is a list of *Errors. The zero value for an ErrorList is an empty
+// ErrorList ready to use.
+type ErrorList = scanner.ErrorList
@@ -43,6 +43,7 @@ type Scanner struct {
 	prev       token.Token // previous token
 	endQuoteCh rune        // '"' or '}'
 	braceDepth int         // the brace depth in a string; starts at 0
+	commentEnd rune        // closing delimiter of an @comment body: '}' or ')'
 
 	// public state - ok to modify
 	ErrorCount int // number of errors encountered
@@ -138,6 +139,10 @@ func (s *Scanner) Init(file *gotok.File, src []byte, err ErrorHandler, mode Mode
 	s.offset = 0
 	s.rdOffset = 0
 	s.lineOffset = 0
+	s.prev = token.Illegal
+	s.endQuoteCh = 0
+	s.braceDepth = 0
+	s.commentEnd = 0
 	s.ErrorCount = 0
 
 	s.next()
@@ -151,11 +156,13 @@ func (s *Scanner) errorf(offset int, format string, args ...interface{}) {
 }
 
 func (s *Scanner) skipWhitespace() {
-	for s.ch == ' ' || s.ch == '\t' || s.ch == '\n' || s.ch == '\r' {
+	for isWhitespace(s.ch) {
 		s.next()
 	}
 }
 
+func isWhitespace(ch rune) bool { return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' }
+
 func lower(ch rune) rune     { return ('a' - 'A') | ch } // returns lower-case ch if ch is an ASCII letter
 func isDecimal(ch rune) bool { return '0' <= ch && ch <= '9' }
 
@@ -261,13 +268,90 @@ func (s *Scanner) scanBraceString() string {
 			break
 		}
 		if ch == '{' {
-			s.next()
 			s.scanBraceString()
 		}
 	}
 	return string(s.src[offs : s.offset-1])
 }
 
+// scanCommentOpen scans the token following an @comment command. If the
+// comment is delimited, like @comment{foo}, it returns the opening delimiter
+// and the next call to Scan returns the comment body. Like the delimiter of
+// other entries, the delimiter may follow on a later line. Otherwise, the
+// comment is the rest of the line, like @comment foo, and scanCommentOpen
+// returns it as token.CommentText. If the rest of the line is blank,
+// scanCommentOpen returns false.
+func (s *Scanner) scanCommentOpen() (pos gotok.Pos, tok token.Token, lit string, ok bool) {
+	if ch := s.peekNonWhitespace(); ch == '{' || ch == '(' {
+		s.skipWhitespace()
+	}
+	for s.ch == ' ' || s.ch == '\t' {
+		s.next()
+	}
+	pos = s.file.Pos(s.offset)
+	switch s.ch {
+	case '{':
+		s.next()
+		s.commentEnd = '}'
+		tok = token.LBrace
+	case '(':
+		s.next()
+		s.commentEnd = ')'
+		tok = token.LParen
+	default:
+		offs := s.offset
+		for s.ch != '\n' && s.ch >= 0 {
+			s.next()
+		}
+		lit = strings.TrimRight(string(s.src[offs:s.offset]), "\r")
+		if lit == "" {
+			return pos, token.Illegal, "", false
+		}
+		tok = token.CommentText
+	}
+	return pos, tok, lit, true
+}
+
+// peekNonWhitespace returns the current character or, if it's whitespace, the
+// first character after it without advancing the scanner. It returns -1 at
+// EOF.
+func (s *Scanner) peekNonWhitespace() rune {
+	if !isWhitespace(s.ch) {
+		return s.ch
+	}
+	for _, b := range s.src[s.rdOffset:] {
+		if !isWhitespace(rune(b)) {
+			return rune(b)
+		}
+	}
+	return eof
+}
+
+// scanCommentBody scans the body of a delimited @comment up to, but not
+// including, the closing delimiter. Braces in the body must be balanced.
+func (s *Scanner) scanCommentBody() string {
+	offs := s.offset
+	depth := 0
+	for {
+		if s.ch < 0 {
+			s.error(offs, "comment not terminated")
+			break
+		}
+		if depth == 0 && s.ch == s.commentEnd {
+			break
+		}
+		switch s.ch {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		s.next()
+	}
+	s.commentEnd = 0
+	return string(s.src[offs:s.offset])
+}
+
 func (s *Scanner) scanTexComment() string {
 	offs := s.offset - 1 // initial '%' already consumed
 	for s.ch != '\n' && s.ch >= 0 {
@@ -303,82 +387,101 @@ func (s *Scanner) scanStringMath() (token.Token, string) {
 func (s *Scanner) scanStringEscape() (token.Token, string) {
 	offs := s.offset - 1 // initial backslash already consumed
 	switch s.ch {
-	case '\\', '$', '&', '%', '{', '}', '_':
+	case '\\', '$', '&', '%', '#', '{', '}', '_':
 		// a single non-alphabetical character
 		s.next()
 		return token.StringBackslash, string(s.src[offs:s.offset])
-	case rune(token.AccentAcute),
-		rune(token.AccentCedilla),
-		rune(token.AccentCircumflex),
-		rune(token.AccentDot),
-		rune(token.AccentGrave),
-		rune(token.AccentTilde),
-		rune(token.AccentUmlaut):
-		return s.scanSpecialCharStringAccent()
 	case ',', ';', '[', ']', '(', ')':
 		// any single non-alphabetical character can be macro.
 		s.next()
 		return token.StringMacro, string(s.src[offs:s.offset])
 	}
 
-	// It must be a macro made up of ascii letters.
+	if token.IsAccent(s.ch) && !token.Accent(s.ch).IsLetter() {
+		s.next() // consume accent marker, like '"' or '^'
+		return s.scanStringAccent(offs)
+	}
+
+	// A macro name is either made up of ascii letters or is a single
+	// non-letter char, like '\-' or '\/'.
 	lo := s.offset
-	for !s.isSpecialStringChar(s.ch) && s.ch != 0 {
+	for IsAsciiLetter(s.ch) {
 		s.next()
 	}
 	name := string(s.src[lo:s.offset])
+	// A letter accent, like \v{s}, is a single letter macro followed by the
+	// accented letter in braces, after whitespace, or the dotless \i or \j.
+	// Other macros that begin with an accent letter, like \cs, aren't accents.
+	if len(name) == 1 && token.IsAccent(rune(name[0])) && (s.ch == '{' || isWhitespace(s.ch) || s.atDotless()) {
+		return s.scanStringAccent(offs)
+	}
 	if len(name) == 0 {
-		s.error(offs, "expected macro name after backslash, got nothing")
-		return token.Illegal, string(s.src[offs : s.offset-1])
-	}
-	// Check that it's only ascii letters.
-	for _, c := range name {
-		if !IsAsciiLetter(c) {
-			s.errorf(offs, "expected command name to only contain ascii letters, got %q", name)
-			return token.Illegal, string(s.src[offs : s.offset-1])
+		if s.ch == eof {
+			s.error(offs, "expected macro name after backslash, got nothing")
+			return token.Illegal, string(s.src[offs:s.offset])
 		}
+		s.next()
+		return token.StringMacro, string(s.src[offs:s.offset])
 	}
 	return token.StringMacro, name
 }
 
-// scanSpecialCharStringAccent scans a string that begins with a backslash
-// followed by a special char like \'o, \'{o} or \^{o}.
-func (s *Scanner) scanSpecialCharStringAccent() (token.Token, string) {
-	offs := s.offset - 1 // initial backslash already consumed
-	s.next()             // consume accent marker, like '"' or '^'
-	if s.ch == '{' {
+// scanStringAccent scans the accented letter of an accent sequence that
+// begins at offs, like \'o, \'{o}, \c c or \"\i. The accent marker is already
+// consumed.
+func (s *Scanner) scanStringAccent(offs int) (token.Token, string) {
+	braced := false
+	switch s.ch {
+	case '{':
 		s.next() // consume left brace '{'
-		if !IsAsciiLetter(s.ch) {
-			s.errorf(offs, "expected braced ascii letter after accent sequence %q , got %q", string(s.src[offs:s.offset-1]), s.ch)
-			return token.Illegal, ""
-		}
-		s.next() // consume the letter that's accented
+		braced = true
+	case ' ', '\t', '\n', '\r':
+		// Handle implicit braces like '\c c'. Consume whitespace until the
+		// next valid char, like in {\c   c}.
+		s.skipWhitespace()
+	}
+	if !s.scanAccentBase() {
+		s.errorf(offs, "expected letter after accent sequence %q , got %q", string(s.src[offs:s.offset]), s.ch)
+		return token.Illegal, ""
+	}
+	if braced {
 		if s.ch != '}' {
-			s.errorf(offs, "expected right brace after accent sequence %q , got %q", string(s.src[offs:s.offset-1]), s.ch)
+			s.errorf(offs, "expected right brace after accent sequence %q , got %q", string(s.src[offs:s.offset]), s.ch)
 			return token.Illegal, ""
 		}
 		s.next() // consume right brace
-	} else if s.ch == ' ' { // handle implicit braces like '\c c'
-		// Construct accent string e.g. '\c'
-		tokStr := string(s.src[offs:s.offset])
-		// consume spaces until next valid char (like in {\c   c})
-		for s.ch == ' ' {
-			s.next()
-		}
-		// append accented char, e.g. 'c'
-		tokStr += string(s.src[s.offset])
-		s.next() // consume the letter that's accented
-		return token.StringAccent, tokStr
-	} else {
-		if !IsAsciiLetter(s.ch) {
-			s.errorf(offs, "expected ascii letter after accent sequence %q , got %q", string(s.src[offs:s.offset-1]), s.ch)
-			return token.Illegal, ""
-		}
-		s.next() // consume the letter that's accented
 	}
 	return token.StringAccent, string(s.src[offs:s.offset])
 }
 
+// scanAccentBase consumes the letter an accent applies to: any letter,
+// including non-ASCII letters, or the dotless \i or \j. It reports whether
+// the current char begins a valid letter.
+func (s *Scanner) scanAccentBase() bool {
+	if s.atDotless() {
+		s.next() // consume backslash
+		s.next() // consume 'i' or 'j'
+		return true
+	}
+	if !unicode.IsLetter(s.ch) {
+		return false
+	}
+	s.next()
+	return true
+}
+
+// atDotless returns true if the scanner is at the dotless i or j macro, \i or
+// \j, not followed by another letter.
+func (s *Scanner) atDotless() bool {
+	if s.ch != '\\' || s.rdOffset >= len(s.src) {
+		return false
+	}
+	if ch := s.src[s.rdOffset]; ch != 'i' && ch != 'j' {
+		return false
+	}
+	return s.rdOffset+1 >= len(s.src) || !IsAsciiLetter(rune(s.src[s.rdOffset+1]))
+}
+
 func (s *Scanner) isSpecialStringChar(ch rune) bool {
 	if ch == '"' {
 		// A double quote is only special at brace depth 0 when we started the
@@ -441,8 +544,10 @@ func (s *Scanner) scanInString() (pos gotok.Pos, tok token.Token, lit string) {
 			s.braceDepth -= 1
 		}
 	case ' ', '\r', '\n', '\t':
+		offs := s.offset - 1 // initial whitespace already consumed
 		tok = token.StringSpace
 		s.skipWhitespace()
+		lit = string(s.src[offs:s.offset])
 	case ',':
 		tok = token.StringComma
 		lit = ","
@@ -480,8 +585,24 @@ func (s *Scanner) scanInString() (pos gotok.Pos, tok token.Token, lit string) {
 // Scan adds line information to the file with Init. Token positions are
 // relative to the file.
 func (s *Scanner) Scan() (pos gotok.Pos, tok token.Token, lit string) {
-	if s.endQuoteCh == '}' || s.endQuoteCh == '"' {
+	switch {
+	case s.endQuoteCh == '}' || s.endQuoteCh == '"':
 		return s.scanInString()
+	case s.prev == token.Comment:
+		// A blank line after @comment is an empty comment, so scan the next
+		// token normally.
+		var ok bool
+		if pos, tok, lit, ok = s.scanCommentOpen(); ok {
+			s.prev = tok
+			return
+		}
+		s.prev = token.Illegal
+	case s.commentEnd != 0:
+		pos = s.file.Pos(s.offset)
+		tok = token.CommentText
+		lit = s.scanCommentBody()
+		s.prev = tok
+		return
 	}
 
 	s.skipWhitespace()
@@ -530,7 +651,8 @@ func (s *Scanner) Scan() (pos gotok.Pos, tok token.Token, lit string) {
 			// a brace string. If preceded by '=', it's a string for a tag. If
 			// preceded by an LBrace, it's a value in a block like:
 			//   @preamble { {foo} }
-			if s.prev == token.Assign || s.prev == token.LBrace {
+			// If preceded by '#', it's the right operand of a concatenation.
+			if s.prev == token.Assign || s.prev == token.LBrace || s.prev == token.Concat {
 				if s.mode&ScanStrings != 0 {
 					s.endQuoteCh = '}'
 					tok = token.StringLBrace
@@ -563,6 +685,9 @@ func (s *Scanner) Scan() (pos gotok.Pos, tok token.Token, lit string) {
 		}
 	}
 
-	s.prev = tok
+	if tok != token.TexComment {
+		// Comments don't affect the brace heuristic.
+		s.prev = tok
+	}
 	return
 }
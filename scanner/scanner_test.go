@@ -235,20 +235,20 @@ func tok(s string) stringTok {
 	// Conditional tests
 	switch {
 	case strings.TrimSpace(s) == "":
-		return stringTok{t: token.StringSpace, lit: "", raw: s}
+		return stringTok{t: token.StringSpace, lit: s, raw: s}
 	case strings.HasPrefix(s, "$"):
 		if !strings.HasSuffix(s, "$") || len(s) < 2 {
 			panic("tok begins with $ but doesn't end with $")
 		}
 		return stringTok{t: token.StringMath, lit: s[1 : len(s)-1], raw: s}
 	case strings.HasPrefix(s, `\`) && len(s) > 1 && IsAsciiLetter(rune(s[1])):
-		return stringTok{t: token.StringMacro, lit: s, raw: s}
+		return stringTok{t: token.StringMacro, lit: s[1:], raw: s}
 	}
 
 	switch s {
-	case `\\`, `\$`, `\&`, `\%`, `\{`, `\}`, `\_`:
+	case `\\`, `\$`, `\&`, `\%`, `\#`, `\{`, `\}`, `\_`:
 		return stringTok{t: token.StringBackslash, lit: s, raw: s}
-	case `\,`, `\;`, `\[`, `\]`, `\(`, `\)`:
+	case `\,`, `\;`, `\[`, `\]`, `\(`, `\)`, `\-`, `\/`, `\|`:
 		return stringTok{t: token.StringMacro, lit: s, raw: s}
 	case `"`:
 		return stringTok{t: token.DoubleQuote, lit: ``, raw: `"`}
@@ -269,6 +269,11 @@ func tok(s string) stringTok {
 	return stringTok{t: token.StringContents, lit: s, raw: s}
 }
 
+// acc returns the stringTok for an accent sequence, like \'{e}.
+func acc(s string) stringTok {
+	return stringTok{t: token.StringAccent, lit: s, raw: s}
+}
+
 // toks returns a slice of stringTok by converting each string t into a
 // stringTok via the tok function.
 func toks(t ...string) []stringTok {
@@ -307,9 +312,13 @@ func TestScanner_Scan_scanInString(t *testing.T) {
 		// Pound sign
 		{`="#"`, toks("=", `"`, `#`, `"`), nil},
 		{`={#}`, toks("=", `{`, `#`, `}`), nil},
+		{`={\#1}`, toks("=", `{`, `\#`, `1`, `}`), nil},
 		// Latex commands
 		// TODO: Fix tokenizer with latex commands.
 		// {`="\url{foo$}"`, toks("=", `"`, `\url`, `{`, "foo$", `}`, `"`), nil},
+		{`={a\-b}`, toks("=", `{`, "a", `\-`, "b", `}`), nil},
+		{`={\path|a@b|}`, toks("=", `{`, `\path`, "|a@b|", `}`), nil},
+		{`={\emph{a}}`, toks("=", `{`, `\emph`, "{", "a", "}", `}`), nil},
 		// Escaped backslashes and special chars
 		{`="\\a"`, toks("=", `"`, `\\`, `a`, `"`), nil},
 		{`={\\a}`, toks("=", `{`, `\\`, `a`, `}`), nil},
@@ -330,7 +339,31 @@ func TestScanner_Scan_scanInString(t *testing.T) {
 		{`={a{z"}b"}`, toks("=", `{`, "a", "{", `z"`, "}", `b"`, `}`), nil},
 		{`="{Fo}o"`, toks("=", `"`, "{", "Fo", "}", "o", `"`), nil},
 		{`={{Fo}o}`, toks(`=`, "{", "{", "Fo", "}", "o", `}`), nil},
+		// Accents
+		{`={\'e}`, []stringTok{tok("="), tok(`{`), acc(`\'e`), tok(`}`)}, nil},
+		{`={\'{e}}`, []stringTok{tok("="), tok(`{`), acc(`\'{e}`), tok(`}`)}, nil},
+		{`={\"\i}`, []stringTok{tok("="), tok(`{`), acc(`\"\i`), tok(`}`)}, nil},
+		{`={\'{\i}}`, []stringTok{tok("="), tok(`{`), acc(`\'{\i}`), tok(`}`)}, nil},
+		{`={\v{S}korpil}`, []stringTok{tok("="), tok(`{`), acc(`\v{S}`), tok("korpil"), tok(`}`)}, nil},
+		{`={\H{o}}`, []stringTok{tok("="), tok(`{`), acc(`\H{o}`), tok(`}`)}, nil},
+		{`={\k a}`, []stringTok{tok("="), tok(`{`), acc(`\k a`), tok(`}`)}, nil},
+		{`={\=\j}`, []stringTok{tok("="), tok(`{`), acc(`\=\j`), tok(`}`)}, nil},
+		{"={\\c\n\tc}", []stringTok{tok("="), tok(`{`), acc("\\c\n\tc"), tok(`}`)}, nil},
+		{`={\v\i}`, []stringTok{tok("="), tok(`{`), acc(`\v\i`), tok(`}`)}, nil},
+		{`={\cc}`, toks("=", `{`, `\cc`, `}`), nil},
+		{`={\rq}`, toks("=", `{`, `\rq`, `}`), nil},
+		{`={\bm{x}}`, toks("=", `{`, `\bm`, "{", "x", "}", `}`), nil},
+		{`={\cs}`, toks("=", `{`, `\cs`, `}`), nil},
+		{`={\dh}`, toks("=", `{`, `\dh`, `}`), nil},
+		{`={\'{æ}}`, []stringTok{tok("="), tok(`{`), acc(`\'{æ}`), tok(`}`)}, nil},
+		{`={\bf a}`, toks("=", `{`, `\bf`, " ", "a", `}`), nil},
+		{`={\cite{a}}`, toks("=", `{`, `\cite`, "{", "a", "}", `}`), nil},
+		{`={\ss}`, toks("=", `{`, `\ss`, `}`), nil},
+		{`={\item}`, toks("=", `{`, `\item`, `}`), nil},
 		// Invalid
+		{`={\'1}`, []stringTok{
+			tok("="), tok("{"), {t: token.Illegal, lit: "", raw: `\'`}, tok("1"), tok("}"),
+		}, errs(`expected letter after accent sequence "\\'" , got '1'`)},
 		{`"{ {$x}"`, []stringTok{
 			tok(`"`), tok("{"), tok(" "), tok("{"),
 			{t: token.Illegal, lit: `$x}"`, raw: `$x}"`},
@@ -384,6 +417,78 @@ func TestScanner_Scan_scanInString(t *testing.T) {
 	}
 }
 
+func TestScanner_Scan_comment(t *testing.T) {
+	type scanned struct {
+		tok  token.Token
+		offs int
+		lit  string
+	}
+	tests := []struct {
+		src  string
+		want []scanned
+		errs []string
+	}{
+		{"@comment{foo}", []scanned{
+			{token.Comment, 0, "@comment"}, {token.LBrace, 8, ""}, {token.CommentText, 9, "foo"},
+			{token.RBrace, 12, ""}, {token.EOF, 13, ""},
+		}, nil},
+		{"@COMMENT { a {b} \"c }", []scanned{
+			{token.Comment, 0, "@COMMENT"}, {token.LBrace, 9, ""}, {token.CommentText, 10, ` a {b} "c `},
+			{token.RBrace, 20, ""}, {token.EOF, 21, ""},
+		}, nil},
+		{"@comment(a {)} b)", []scanned{
+			{token.Comment, 0, "@comment"}, {token.LParen, 8, ""}, {token.CommentText, 9, "a {)} b"},
+			{token.RParen, 16, ""}, {token.EOF, 17, ""},
+		}, nil},
+		{"@comment{}", []scanned{
+			{token.Comment, 0, "@comment"}, {token.LBrace, 8, ""}, {token.CommentText, 9, ""},
+			{token.RBrace, 9, ""}, {token.EOF, 10, ""},
+		}, nil},
+		{"@comment rest of {line\r\n@misc", []scanned{
+			{token.Comment, 0, "@comment"}, {token.CommentText, 9, "rest of {line"},
+			{token.BibEntry, 24, "@misc"}, {token.EOF, 29, ""},
+		}, nil},
+		{"@comment\n{foo}", []scanned{
+			{token.Comment, 0, "@comment"}, {token.LBrace, 9, ""}, {token.CommentText, 10, "foo"},
+			{token.RBrace, 13, ""}, {token.EOF, 14, ""},
+		}, nil},
+		{"@comment \r\n\t(foo)", []scanned{
+			{token.Comment, 0, "@comment"}, {token.LParen, 12, ""}, {token.CommentText, 13, "foo"},
+			{token.RParen, 16, ""}, {token.EOF, 17, ""},
+		}, nil},
+		{"@comment\n% tex\n@misc", []scanned{
+			{token.Comment, 0, "@comment"}, {token.TexComment, 9, "% tex"},
+			{token.BibEntry, 15, "@misc"}, {token.EOF, 20, ""},
+		}, nil},
+		{"@comment{a {b}", []scanned{
+			{token.Comment, 0, "@comment"}, {token.LBrace, 8, ""}, {token.CommentText, 9, "a {b}"},
+			{token.EOF, 14, ""},
+		}, errs("comment not terminated")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			ec := &errorCollector{}
+			fset := gotok.NewFileSet()
+			var s Scanner
+			s.Init(fset.AddFile("", fset.Base(), len(tt.src)), []byte(tt.src), ec.asHandler(), ScanComments)
+			var got []scanned
+			for {
+				pos, tok, lit := s.Scan()
+				got = append(got, scanned{tok, fset.Position(pos).Offset, lit})
+				if tok == token.EOF {
+					break
+				}
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(scanned{})); diff != "" {
+				t.Errorf("Scan() mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.errs, ec.msgs); diff != "" {
+				t.Errorf("Errors mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestScanner_Scan_Errors(t *testing.T) {
 	tests := []struct {
 		src string
@@ -0,0 +1,330 @@
+// Package sort sorts bibliography entries with the sorting schemes of biber,
+// like name-year-title, or with custom chains of fields.
+//
+// Sort keys compare by the kind of field. Name fields, like author, compare
+// by the purified sort key of each name, like names.SortKey. Year fields
+// compare as numbers. The volume and number fields compare as text padded
+// with zeros to four characters, so "9" sorts before "10". Other fields
+// compare as purified text, like the purify$ function of bibtex.
+//
+// Text compares with the root collation of the Unicode Collation Algorithm,
+// which orders letters of all scripts alphabetically, like "Дмитриев" before
+// "Ёлкин" before "Жуков". Use WithCollator for a locale-specific collation,
+// like the CompareString method of a golang.org/x/text/collate.Collator, or
+// for Collate, a simpler fixed collation.
+package sort
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/names"
+	"github.com/jschaf/bibtex/purify"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// Key is a key of a sort scheme. The value of the key for an entry is the
+// value of the first field of Fields in the entry, so a key can fall back
+// from a field like sortname to the author field.
+type Key struct {
+	Fields     []bibtex.Field
+	Descending bool
+}
+
+// Scheme is a list of keys to sort entries by. Entries compare by the first
+// key and then by each following key while the keys are equal. An empty
+// scheme keeps the order of the entries.
+type Scheme []Key
+
+// The sort keys of the biber sorting schemes. Like biber, the name key falls
+// back to the title, so entries without names sort among the names.
+var (
+	nameKey   = Key{Fields: []bibtex.Field{"sortname", bibtex.FieldAuthor, bibtex.FieldEditor, "translator", "sorttitle", bibtex.FieldTitle}}
+	titleKey  = Key{Fields: []bibtex.Field{"sorttitle", bibtex.FieldTitle}}
+	yearKey   = Key{Fields: []bibtex.Field{"sortyear", bibtex.FieldYear}}
+	volumeKey = Key{Fields: []bibtex.Field{bibtex.FieldVolume}}
+)
+
+// The sorting schemes of biber.
+var (
+	// NTY sorts by name, title, year and volume, the biber default.
+	NTY = Scheme{nameKey, titleKey, yearKey, volumeKey}
+	// NYT sorts by name, year, title and volume.
+	NYT = Scheme{nameKey, yearKey, titleKey, volumeKey}
+	// NYVT sorts by name, year, volume and title.
+	NYVT = Scheme{nameKey, yearKey, volumeKey, titleKey}
+	// YNT sorts by year, name and title.
+	YNT = Scheme{yearKey, nameKey, titleKey}
+	// YDNT sorts by year descending, name and title.
+	YDNT = Scheme{{Fields: yearKey.Fields, Descending: true}, nameKey, titleKey}
+	// Citation keeps the order of the entries, like the order of citation in a
+	// document. Biber calls this scheme "none".
+	Citation = Scheme{}
+)
+
+// Option configures how Entries sorts.
+type Option func(s *sorter)
+
+// WithStable keeps the order of entries with equal keys. Otherwise, entries
+// with equal keys sort by their citation key.
+func WithStable() Option {
+	return func(s *sorter) {
+		s.stable = true
+	}
+}
+
+// WithDescending reverses the order of the sort.
+func WithDescending() Option {
+	return func(s *sorter) {
+		s.descending = true
+	}
+}
+
+// WithUsePrefix sorts names by the von part first, like the useprefix option
+// of biber, so "van Gogh" sorts under "v" instead of "G".
+func WithUsePrefix() Option {
+	return func(s *sorter) {
+		s.usePrefix = true
+	}
+}
+
+// WithCollator compares text with a collator instead of the Unicode root
+// collation. Compare returns a negative number if a sorts before b, zero if
+// a and b are equal, and a positive number otherwise.
+func WithCollator(compare func(a, b string) int) Option {
+	return func(s *sorter) {
+		s.collate = compare
+	}
+}
+
+type sorter struct {
+	stable     bool
+	descending bool
+	usePrefix  bool
+	collate    func(a, b string) int
+}
+
+// Entries sorts the entries in place with the scheme.
+func Entries(entries []bibtex.Entry, scheme Scheme, opts ...Option) {
+	s := &sorter{collate: collate.New(language.Und).CompareString}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if len(scheme) == 0 {
+		if s.descending {
+			slices.Reverse(entries)
+		}
+		return
+	}
+
+	type keyed struct {
+		entry  bibtex.Entry
+		values []value
+	}
+	items := make([]keyed, len(entries))
+	for i, e := range entries {
+		values := make([]value, len(scheme))
+		for j, k := range scheme {
+			values[j] = s.value(e, k)
+		}
+		items[i] = keyed{entry: e, values: values}
+	}
+	cmp := func(a, b keyed) int {
+		for i, k := range scheme {
+			c := s.compare(a.values[i], b.values[i], k.Descending != s.descending)
+			if c != 0 {
+				return c
+			}
+		}
+		if s.stable {
+			return 0
+		}
+		c := strings.Compare(a.entry.Key, b.entry.Key)
+		if s.descending {
+			return -c
+		}
+		return c
+	}
+	if s.stable {
+		slices.SortStableFunc(items, cmp)
+	} else {
+		slices.SortFunc(items, cmp)
+	}
+	for i, it := range items {
+		entries[i] = it.entry
+	}
+}
+
+// value is the value of a sort key for an entry.
+type value struct {
+	missing bool
+	isNum   bool
+	num     int
+	texts   []string // a single text or the sort keys of each name
+}
+
+// value returns the value of the key for the entry.
+func (s *sorter) value(e bibtex.Entry, k Key) value {
+	for _, f := range k.Fields {
+		x, ok := e.Tags[f]
+		if !ok || x == nil {
+			continue
+		}
+		switch {
+		case isNameField(f):
+			if keys := s.nameKeys(x); len(keys) > 0 {
+				return value{texts: keys}
+			}
+		case isYearField(f):
+			txt := strings.TrimSpace(purify.Expr(x))
+			if txt == "" {
+				continue
+			}
+			if n, err := strconv.Atoi(txt); err == nil {
+				return value{isNum: true, num: n}
+			}
+			return value{texts: []string{txt}}
+		case isPaddedField(f):
+			txt := strings.TrimSpace(purify.Expr(x))
+			if txt == "" {
+				continue
+			}
+			if len(txt) < 4 {
+				txt = strings.Repeat("0", 4-len(txt)) + txt
+			}
+			return value{texts: []string{txt}}
+		default:
+			txt := strings.Join(strings.Fields(purify.Expr(x)), " ")
+			if txt == "" {
+				continue
+			}
+			return value{texts: []string{txt}}
+		}
+	}
+	return value{missing: true}
+}
+
+// nameKeys returns the sort keys of the names in a name field. The names
+// are ast.Authors or parsed text that's not resolved yet.
+func (s *sorter) nameKeys(x ast.Expr) []string {
+	var authors ast.Authors
+	switch x := x.(type) {
+	case ast.Authors:
+		authors = x
+	case *ast.ParsedText:
+		as, err := bibtex.ExtractAuthors(x)
+		if err != nil {
+			return []string{strings.Join(strings.Fields(purify.Expr(x)), " ")}
+		}
+		authors = as
+	default:
+		return []string{strings.Join(strings.Fields(purify.Expr(x)), " ")}
+	}
+	keys := make([]string, 0, len(authors))
+	for _, a := range authors {
+		if a.IsOthers() {
+			continue
+		}
+		keys = append(keys, names.SortKey(a, s.usePrefix))
+	}
+	return keys
+}
+
+// compare compares two values of a key. Missing values sort last in both
+// directions. Numbers sort before text.
+func (s *sorter) compare(a, b value, descending bool) int {
+	switch {
+	case a.missing || b.missing:
+		return boolCompare(a.missing, b.missing)
+	case a.isNum != b.isNum:
+		return boolCompare(b.isNum, a.isNum)
+	}
+	var c int
+	if a.isNum {
+		c = a.num - b.num
+	} else {
+		for i := 0; i < len(a.texts) && i < len(b.texts) && c == 0; i++ {
+			c = s.collate(a.texts[i], b.texts[i])
+		}
+		if c == 0 {
+			c = len(a.texts) - len(b.texts)
+		}
+	}
+	if descending {
+		return -c
+	}
+	return c
+}
+
+// boolCompare orders false before true.
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func isNameField(f bibtex.Field) bool {
+	switch strings.ToLower(f) {
+	case bibtex.FieldAuthor, bibtex.FieldEditor, "sortname", "translator", "bookauthor":
+		return true
+	}
+	return false
+}
+
+func isYearField(f bibtex.Field) bool {
+	switch strings.ToLower(f) {
+	case bibtex.FieldYear, "sortyear":
+		return true
+	}
+	return false
+}
+
+func isPaddedField(f bibtex.Field) bool {
+	switch strings.ToLower(f) {
+	case bibtex.FieldVolume, bibtex.FieldNumber:
+		return true
+	}
+	return false
+}
+
+// Collate compares text with a fixed collation that doesn't depend on the
+// locale or on Unicode collation tables, for use with WithCollator. Collate
+// compares the text without accents and case first, like "Émile" and
+// "emile", then with accents, and then by case, with lowercase before
+// uppercase. Letters without a Latin base letter compare by code point, so
+// Collate only orders Latin text alphabetically.
+func Collate(a, b string) int {
+	if c := strings.Compare(primary(a), primary(b)); c != 0 {
+		return c
+	}
+	if c := strings.Compare(strings.ToLower(a), strings.ToLower(b)); c != 0 {
+		return c
+	}
+	return strings.Compare(swapCase(a), swapCase(b))
+}
+
+// primary returns the text without accents and case.
+func primary(s string) string {
+	return strings.ToLower(purify.FoldString(s))
+}
+
+// swapCase swaps uppercase and lowercase letters so that lowercase sorts
+// first in a byte comparison.
+func swapCase(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsUpper(r) {
+			return unicode.ToLower(r)
+		}
+		return unicode.ToUpper(r)
+	}, s)
+}
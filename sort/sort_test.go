@@ -0,0 +1,164 @@
+package sort
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/internal/bibtest"
+)
+
+const testBib = `
+@book{knuth84, author = {Donald E. Knuth}, title = {The {TeX}book}, year = 1984}
+@book{knuth68, author = {Donald E. Knuth}, title = {Fundamental Algorithms}, year = 1968, volume = 10}
+@book{knuth69, author = {Donald E. Knuth}, title = {Seminumerical Algorithms}, year = 1968, volume = 9}
+@article{gogh, author = {Vincent van Gogh}, title = {Letters}, year = 1890}
+@article{zola, author = {{\'E}mile Zola}, title = {J'accuse}, year = 1898}
+@article{eaton, author = {Eaton, Amos}, title = {Geology}, year = 1818}
+@misc{anon, title = {Anonymous Work}, year = 2001}
+@article{monet, author = {Monet, Claude and others}, title = {Impressions}, year = {n.d.}}
+`
+
+func keys(entries []bibtex.Entry) []string {
+	ks := make([]string, len(entries))
+	for i, e := range entries {
+		ks[i] = e.Key
+	}
+	return ks
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme Scheme
+		opts   []Option
+		want   []string
+	}{
+		{
+			"nty", NTY, nil,
+			[]string{"anon", "eaton", "gogh", "knuth68", "knuth69", "knuth84", "monet", "zola"},
+		},
+		{
+			"nyt", NYT, nil,
+			[]string{"anon", "eaton", "gogh", "knuth68", "knuth69", "knuth84", "monet", "zola"},
+		},
+		{
+			"nyvt", NYVT, nil,
+			[]string{"anon", "eaton", "gogh", "knuth69", "knuth68", "knuth84", "monet", "zola"},
+		},
+		{
+			"nyt useprefix", NYT, []Option{WithUsePrefix()},
+			[]string{"anon", "eaton", "knuth68", "knuth69", "knuth84", "monet", "gogh", "zola"},
+		},
+		{
+			"ydnt", YDNT, nil,
+			[]string{"anon", "knuth84", "knuth68", "knuth69", "zola", "gogh", "eaton", "monet"},
+		},
+		{
+			"nyt descending", NYT, []Option{WithDescending()},
+			[]string{"zola", "monet", "knuth84", "knuth69", "knuth68", "gogh", "eaton", "anon"},
+		},
+		{
+			"citation", Citation, nil,
+			[]string{"knuth84", "knuth68", "knuth69", "gogh", "zola", "eaton", "anon", "monet"},
+		},
+		{
+			"citation descending", Citation, []Option{WithDescending()},
+			[]string{"monet", "anon", "eaton", "zola", "gogh", "knuth69", "knuth68", "knuth84"},
+		},
+		{
+			"custom", Scheme{{Fields: []bibtex.Field{"year"}}, {Fields: []bibtex.Field{"volume"}, Descending: true}}, nil,
+			[]string{"eaton", "gogh", "zola", "knuth68", "knuth69", "knuth84", "anon", "monet"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := bibtest.Read(t, testBib)
+			Entries(entries, tt.scheme, tt.opts...)
+			if diff := cmp.Diff(tt.want, keys(entries)); diff != "" {
+				t.Errorf("Entries() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEntries_stable(t *testing.T) {
+	const src = `
+@misc{b, title = {Same}}
+@misc{c, title = {Same}}
+@misc{a, title = {Same}}
+`
+	scheme := Scheme{{Fields: []bibtex.Field{bibtex.FieldTitle}}}
+
+	entries := bibtest.Read(t, src)
+	Entries(entries, scheme, WithStable())
+	if diff := cmp.Diff([]string{"b", "c", "a"}, keys(entries)); diff != "" {
+		t.Errorf("Entries(WithStable()) mismatch (-want +got):\n%s", diff)
+	}
+
+	entries = bibtest.Read(t, src)
+	Entries(entries, scheme)
+	if diff := cmp.Diff([]string{"a", "b", "c"}, keys(entries)); diff != "" {
+		t.Errorf("Entries() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEntries_titleAsName(t *testing.T) {
+	const src = `
+@article{smith, author = {Smith, John}, title = {Alpha}}
+@misc{none, title = {No author}, sorttitle = {Nobody}}
+@article{adams, author = {Adams, Ann}, title = {Zeta}}
+`
+	entries := bibtest.Read(t, src)
+	Entries(entries, NTY)
+	if diff := cmp.Diff([]string{"adams", "none", "smith"}, keys(entries)); diff != "" {
+		t.Errorf("Entries() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEntries_collator(t *testing.T) {
+	const src = `
+@misc{a, title = {alpha}}
+@misc{b, title = {Beta}}
+`
+	entries := bibtest.Read(t, src)
+	Entries(entries, Scheme{{Fields: []bibtex.Field{bibtex.FieldTitle}}}, WithCollator(strings.Compare))
+	if diff := cmp.Diff([]string{"b", "a"}, keys(entries)); diff != "" {
+		t.Errorf("Entries(WithCollator()) mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestEntries_nonLatin(t *testing.T) {
+	const src = `
+@misc{zhukov, author = {Жуков, Георгий}}
+@misc{thor, author = {Þórðarson, Þórbergur}}
+@misc{yolkin, author = {Ёлкин, Иван}}
+@misc{omega, author = {Ωρίων}}
+@misc{ngomo, author = {Ŋomo, Abel}}
+@misc{alpha, author = {Άλφα}}
+@misc{oak, author = {Oak, Ann}}
+@misc{dmitriev, author = {Дмитриев, Олег}}
+@misc{nyx, author = {Nyx, Nora}}
+@misc{eleni, author = {ελένη}}
+@misc{zeta, author = {Zeta, Zoe}}
+`
+	// The Unicode root collation orders letters alphabetically in each
+	// script, like ŋ after n, þ after z, and Ё between Д and Ж.
+	entries := bibtest.Read(t, src)
+	Entries(entries, NTY)
+	want := []string{"nyx", "ngomo", "oak", "zeta", "thor", "alpha", "eleni", "omega", "dmitriev", "yolkin", "zhukov"}
+	if diff := cmp.Diff(want, keys(entries)); diff != "" {
+		t.Errorf("Entries() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCollate(t *testing.T) {
+	want := []string{"eaton", "Eaton", "Ébert", "ebert2", "Ezra", "Ørsted", "Oster", "straße", "strasser", "Zola"}
+	got := []string{"Zola", "Ørsted", "strasser", "Ezra", "Ébert", "Eaton", "straße", "eaton", "Oster", "ebert2"}
+	slices.SortFunc(got, Collate)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Collate() order mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,135 @@
+package style
+
+import (
+	"github.com/jschaf/bibtex"
+)
+
+var acmNames = nameList{
+	format: fullFirst,
+	sep:    ", ",
+	and2:   " and ",
+	andN:   ", and ",
+	etAl:   ", et al.",
+}
+
+var acmMonths = monthNames{
+	"Jan.", "Feb.", "March", "April", "May", "June",
+	"July", "Aug.", "Sept.", "Oct.", "Nov.", "Dec.",
+}
+
+// ACM returns the ACM Reference Format, like:
+//
+//	Donald E. Knuth. 1984. Literate Programming. Comput. J. 27, 2 (May 1984),
+//	97–111. https://doi.org/10.1093/comjnl/27.2.97
+//
+// ACM lists all authors.
+func ACM() *Style {
+	return &Style{name: "ACM", format: formatACM}
+}
+
+func formatACM(b *builder, e bibtex.Entry) {
+	if names, others := toNames(e.Authors()); len(names) > 0 {
+		b.text(acmNames.join(names, others))
+	} else if names, others := toNames(e.Editors()); len(names) > 0 {
+		b.text(acmNames.join(names, others))
+		b.text(editorLabel(len(names) > 1 || others, " (Ed.)", " (Eds.)"))
+	}
+	b.sep(". ")
+	b.text(year(e))
+	b.sep(". ")
+
+	title := field(e, bibtex.FieldTitle)
+	pages, _ := pageRange(e, false)
+	switch e.Type {
+	case bibtex.EntryArticle:
+		b.value(title)
+		b.sep(". ")
+		b.emph(field(e, bibtex.FieldJournal))
+		b.sep(" ")
+		b.text(joinNonEmpty(", ", fieldText(e, bibtex.FieldVolume), fieldText(e, bibtex.FieldNumber)))
+		if d := date(e, acmMonths); d != "" {
+			b.sep(" ")
+			b.text("(" + d + ")")
+		}
+		b.sep(", ")
+		b.text(pages)
+
+	case bibtex.EntryBook, bibtex.EntryProceedings:
+		b.emph(title)
+		if ed := edition(e); ed != "" {
+			b.sep(" ")
+			b.text("(" + acmEdition(ed) + ")")
+		}
+		if v := fieldText(e, bibtex.FieldVolume); v != "" {
+			b.sep(", ")
+			b.text("Vol. " + v)
+		}
+		b.sep(". ")
+		b.text(joinNonEmpty(", ", fieldText(e, bibtex.FieldPublisher), fieldText(e, bibtex.FieldAddress)))
+
+	case bibtex.EntryInBook, bibtex.EntryInCollection, bibtex.EntryInProceedings, "conference":
+		if e.Type != bibtex.EntryInBook {
+			b.value(title)
+			b.sep(". ")
+			b.text("In ")
+		}
+		b.emph(bookTitle(e))
+		if ed := edition(e); ed != "" {
+			b.sep(" ")
+			b.text("(" + acmEdition(ed) + ")")
+		}
+		if names, others := toNames(e.Editors()); len(names) > 0 {
+			b.sep(", ")
+			b.text(acmNames.join(names, others))
+			b.text(editorLabel(len(names) > 1 || others, " (Ed.)", " (Eds.)"))
+		}
+		if ch := fieldText(e, bibtex.FieldChapter); ch != "" {
+			b.sep(", ")
+			b.text("Chapter " + ch)
+		}
+		b.sep(". ")
+		b.text(joinNonEmpty(", ", fieldText(e, bibtex.FieldPublisher), fieldText(e, bibtex.FieldAddress)))
+		b.sep(", ")
+		b.text(pages)
+
+	case bibtex.EntryPhDThesis, bibtex.EntryMastersThesis:
+		b.emph(title)
+		b.sep(". ")
+		b.text(thesisType(e, "Ph.D. Dissertation", "Master's thesis"))
+		b.sep(". ")
+		b.text(joinNonEmpty(", ", fieldText(e, bibtex.FieldSchool), fieldText(e, bibtex.FieldAddress)))
+
+	case bibtex.EntryTechReport:
+		b.emph(title)
+		b.sep(". ")
+		b.text(joinNonEmpty(" ", reportType(e, "Technical Report"), fieldText(e, bibtex.FieldNumber)))
+		b.sep(". ")
+		b.text(joinNonEmpty(", ", fieldText(e, bibtex.FieldInstitution), fieldText(e, bibtex.FieldAddress)))
+
+	default:
+		b.value(title)
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldHowPublished))
+		b.sep(". ")
+		b.text(joinNonEmpty(", ", fieldText(e, bibtex.FieldOrganization), fieldText(e, bibtex.FieldAddress)))
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldNote))
+	}
+
+	b.end(".")
+	if doi := e.DOI(); doi != "" {
+		b.sep(" ")
+		b.url("https://doi.org/" + doi)
+	} else if url := e.URL(); url != "" {
+		b.sep(" ")
+		b.url(url)
+	}
+}
+
+// acmEdition formats an edition like "2nd. ed.".
+func acmEdition(ed string) string {
+	if ed[0] >= '0' && ed[0] <= '9' {
+		return ed + ". ed."
+	}
+	return ed + " ed."
+}
@@ -0,0 +1,172 @@
+package style
+
+import (
+	"github.com/jschaf/bibtex"
+)
+
+var apaNames = nameList{
+	format:   initialsLast,
+	sep:      ", ",
+	and2:     ", & ",
+	andN:     ", & ",
+	max:      20,
+	keep:     19,
+	etAl:     ", et al.",
+	ellipsis: true,
+}
+
+// apaEditors are editor names in the middle of a reference, like
+// "In D. E. Knuth & J. Doe (Eds.),".
+var apaEditors = nameList{
+	format: initialsFirst,
+	sep:    ", ",
+	and2:   " & ",
+	andN:   ", & ",
+	etAl:   ", et al.",
+}
+
+// APA returns the reference style of the 7th edition of the Publication
+// Manual of the American Psychological Association, like:
+//
+//	Knuth, D. E. (1984). Literate Programming. The Computer Journal, 27(2),
+//	97–111. https://doi.org/10.1093/comjnl/27.2.97
+//
+// Lists of 21 or more authors show the first 19 authors, an ellipsis, and the
+// last author. Titles keep the case of the entry rather than the sentence case
+// of APA, since resolved titles no longer have the braces that protect words
+// like {NASA} from case changes.
+func APA() *Style {
+	return &Style{name: "APA", format: formatAPA}
+}
+
+func formatAPA(b *builder, e bibtex.Entry) {
+	title := field(e, bibtex.FieldTitle)
+	if names, others := toNames(e.Authors()); len(names) > 0 {
+		b.text(apaNames.join(names, others))
+		b.sep(" ")
+	} else if names, others := toNames(e.Editors()); len(names) > 0 {
+		b.text(apaNames.join(names, others))
+		b.sep(" ")
+		b.text(editorLabel(len(names) > 1 || others, "(Ed.)", "(Eds.)"))
+		b.sep(". ")
+	} else if title != nil {
+		// Without authors, the title takes the place of the authors.
+		writeTitle(b, e, title)
+		title = nil
+		b.sep(". ")
+	}
+	b.text("(" + apaDate(e) + ")")
+	b.sep(". ")
+
+	pages, multiple := pageRange(e, false)
+	switch e.Type {
+	case bibtex.EntryArticle:
+		b.value(title)
+		b.sep(". ")
+		b.emph(field(e, bibtex.FieldJournal))
+		if v := field(e, bibtex.FieldVolume); v != nil {
+			b.sep(", ")
+			b.emph(v)
+			if n := fieldText(e, bibtex.FieldNumber); n != "" {
+				b.sep("")
+				b.text("(" + n + ")")
+			}
+		}
+		b.sep(", ")
+		b.text(pages)
+
+	case bibtex.EntryBook, bibtex.EntryProceedings:
+		b.emph(title)
+		if s := joinNonEmpty(", ", apaEdition(e), volumeLabel(e)); s != "" {
+			b.sep(" ")
+			b.text("(" + s + ")")
+		}
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldPublisher))
+
+	case bibtex.EntryInBook, bibtex.EntryInCollection, bibtex.EntryInProceedings, "conference":
+		if e.Type != bibtex.EntryInBook {
+			b.value(title)
+			b.sep(". ")
+		}
+		b.text("In ")
+		if names, others := toNames(e.Editors()); len(names) > 0 {
+			b.text(apaEditors.join(names, others))
+			b.sep(" ")
+			b.text(editorLabel(len(names) > 1 || others, "(Ed.)", "(Eds.)"))
+			b.sep(", ")
+		}
+		b.emph(bookTitle(e))
+		if s := joinNonEmpty(", ", apaEdition(e), volumeLabel(e), pagesLabel(pages, multiple)); s != "" {
+			b.sep(" ")
+			b.text("(" + s + ")")
+		}
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldPublisher))
+
+	case bibtex.EntryPhDThesis, bibtex.EntryMastersThesis:
+		b.emph(title)
+		b.sep(" ")
+		kind := thesisType(e, "Doctoral dissertation", "Master's thesis")
+		b.text("[" + joinNonEmpty(", ", kind, fieldText(e, bibtex.FieldSchool)) + "]")
+
+	case bibtex.EntryTechReport:
+		b.emph(title)
+		if n := fieldText(e, bibtex.FieldNumber); n != "" {
+			b.sep(" ")
+			b.text("(" + reportType(e, "Report") + " No. " + n + ")")
+		}
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldInstitution))
+
+	default:
+		b.emph(title)
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldHowPublished))
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldOrganization))
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldNote))
+	}
+
+	b.end(".")
+	if doi := e.DOI(); doi != "" {
+		b.sep(" ")
+		b.url("https://doi.org/" + doi)
+	} else if url := e.URL(); url != "" {
+		b.sep(" ")
+		b.url(url)
+	}
+}
+
+// apaDate returns the date of the entry, like "2019", or "n.d." if the entry
+// has no year. Unpublished and miscellaneous entries include the month, like
+// "2019, September".
+func apaDate(e bibtex.Entry) string {
+	y := year(e)
+	if y == "" {
+		return "n.d."
+	}
+	if e.Type == bibtex.EntryMisc || e.Type == bibtex.EntryUnpublished {
+		if m := fullMonths.name(e.Month()); m != "" {
+			return y + ", " + m
+		}
+	}
+	return y
+}
+
+// apaEdition formats the edition, like "2nd ed.".
+func apaEdition(e bibtex.Entry) string {
+	if ed := edition(e); ed != "" {
+		return ed + " ed."
+	}
+	return ""
+}
+
+// volumeLabel formats the volume, like "Vol. 2".
+func volumeLabel(e bibtex.Entry) string {
+	if v := fieldText(e, bibtex.FieldVolume); v != "" {
+		return "Vol. " + v
+	}
+	return ""
+}
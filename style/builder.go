@@ -0,0 +1,370 @@
+package style
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/render"
+)
+
+// builder builds the expression of a reference-list item. The builder writes
+// separators lazily: sep sets the separator written before the next value, so
+// optional fields that are missing don't leave doubled separators. The
+// builder drops the leading punctuation of a separator that would double the
+// punctuation already written, like the period after "Ed.".
+type builder struct {
+	values  []ast.Expr
+	pending string // separator to write before the next value
+	last    rune   // last rune of the plain text written so far
+	quote   bool   // if the closing quote of quoted is pending
+}
+
+func (b *builder) expr() *ast.ParsedText {
+	b.flush()
+	return &ast.ParsedText{Delim: ast.BraceDelimiter, Values: b.values}
+}
+
+// sep sets the separator to write before the next value, replacing any
+// pending separator. The separator is dropped if nothing was written yet.
+func (b *builder) sep(s string) {
+	b.pending = s
+}
+
+// end writes the final punctuation, like the period ending an item.
+func (b *builder) end(p string) {
+	b.sep(p)
+	b.flush()
+}
+
+func (b *builder) flush() {
+	p := b.pending
+	b.pending = ""
+	if len(b.values) == 0 {
+		return
+	}
+	p = trimPunct(b.last, p)
+	if b.quote {
+		// Punctuation goes inside the closing quote, like “Title,” in.
+		b.quote = false
+		i := 0
+		for i < len(p) && strings.IndexByte(".,;:", p[i]) >= 0 {
+			i++
+		}
+		p = p[:i] + "”" + p[i:]
+	}
+	if p != "" {
+		b.append(&ast.Text{Value: p}, p)
+	}
+}
+
+// trimPunct removes the leading punctuation of s if it would double the
+// punctuation mark last, like the period in ". " after "Ed.".
+func trimPunct(last rune, s string) string {
+	if s == "" {
+		return s
+	}
+	switch s[0] {
+	case '.':
+		if last == '.' || last == '?' || last == '!' {
+			return s[1:]
+		}
+	case ',':
+		if last == '?' || last == '!' {
+			return s[1:]
+		}
+	}
+	return s
+}
+
+func (b *builder) append(x ast.Expr, plain string) {
+	b.values = append(b.values, x)
+	if r, _ := utf8.DecodeLastRuneInString(plain); r != utf8.RuneError {
+		b.last = r
+	}
+}
+
+// text writes the literal text s.
+func (b *builder) text(s string) {
+	if s == "" {
+		return
+	}
+	b.flush()
+	b.append(&ast.Text{Value: s}, s)
+}
+
+// value writes the expression x. Does nothing if x is nil.
+func (b *builder) value(x ast.Expr) {
+	if x == nil {
+		return
+	}
+	b.flush()
+	b.append(x, plainText(x))
+}
+
+// macro writes x as the argument of the LaTeX macro name, like \emph{x}. Does
+// nothing if x is nil.
+func (b *builder) macro(name string, x ast.Expr) {
+	if x == nil {
+		return
+	}
+	b.flush()
+	b.append(&ast.TextMacro{Name: name, Values: []ast.Expr{x}}, plainText(x))
+}
+
+// emph writes x in italics.
+func (b *builder) emph(x ast.Expr) {
+	b.macro("emph", x)
+}
+
+// url writes the URL u as a link.
+func (b *builder) url(u string) {
+	if u == "" {
+		return
+	}
+	b.macro("url", &ast.Text{Value: u})
+}
+
+// quoted writes x between curly double quotes. The punctuation of the next
+// separator goes inside the closing quote, like “Title,”.
+func (b *builder) quoted(x ast.Expr) {
+	if x == nil {
+		return
+	}
+	b.text("“")
+	b.value(x)
+	b.quote = true
+}
+
+// plainText returns x rendered as plain text.
+func plainText(x ast.Expr) string {
+	sb := &strings.Builder{}
+	if err := render.NewTextRenderer().Render(sb, x); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// field returns the value of the field or nil if the field is missing, empty,
+// or not text, like an unexpanded abbreviation.
+func field(e bibtex.Entry, f bibtex.Field) ast.Expr {
+	var x ast.Expr
+	switch t := e.Tags[f].(type) {
+	case *ast.Number:
+		x = &ast.Text{Value: t.Value}
+	case *ast.UnparsedText:
+		x = &ast.Text{Value: t.Value}
+	case *ast.Text, *ast.ParsedText:
+		x = t
+	default:
+		return nil
+	}
+	if plainText(x) == "" {
+		return nil
+	}
+	return x
+}
+
+// fieldText returns the plain text of the field or the empty string.
+func fieldText(e bibtex.Entry, f bibtex.Field) string {
+	x := field(e, f)
+	if x == nil {
+		return ""
+	}
+	return plainText(x)
+}
+
+// year returns the year of the entry as text or the empty string.
+func year(e bibtex.Entry) string {
+	return fieldText(e, bibtex.FieldYear)
+}
+
+// pageRange formats the pages of the entry with an en dash, like
+// "2022–2034". If abbrev is true, the last page drops the digits shared with
+// the first page, following the Chicago Manual of Style, like "2022–34".
+func pageRange(e bibtex.Entry, abbrev bool) (pages string, multiple bool) {
+	first, last := e.Pages()
+	if last == "" {
+		return first, false
+	}
+	if abbrev {
+		last = abbrevLastPage(first, last)
+	}
+	return first + "–" + last, true
+}
+
+// abbrevLastPage abbreviates the last page of a range following the Chicago
+// Manual of Style 9.61. Non-numeric pages aren't abbreviated.
+func abbrevLastPage(first, last string) string {
+	n, err := strconv.Atoi(first)
+	if err != nil || n < 100 || n%100 == 0 || len(first) != len(last) {
+		return last
+	}
+	if _, err := strconv.Atoi(last); err != nil {
+		return last
+	}
+	i := 0 // length of the common prefix
+	for i < len(first) && first[i] == last[i] {
+		i++
+	}
+	minDigits := 2
+	if n%100 < 10 {
+		minDigits = 1 // 101–8, 1103–4
+	}
+	if keep := len(last) - minDigits; i > keep {
+		i = keep
+	}
+	return last[i:]
+}
+
+// pagesLabel prefixes the pages with "p. " or "pp. ".
+func pagesLabel(pages string, multiple bool) string {
+	if pages == "" {
+		return ""
+	}
+	if multiple {
+		return "pp. " + pages
+	}
+	return "p. " + pages
+}
+
+// ordinalWords maps spelled-out editions to their number.
+var ordinalWords = map[string]int{
+	"first": 1, "second": 2, "third": 3, "fourth": 4, "fifth": 5,
+	"sixth": 6, "seventh": 7, "eighth": 8, "ninth": 9, "tenth": 10,
+}
+
+// edition formats the edition of the entry as an ordinal, like "2nd" for the
+// edition "2", "2nd", or "Second". Returns the empty string for a first
+// edition. Editions that aren't numbers or ordinals, like "Revised", are
+// returned as is.
+func edition(e bibtex.Entry) string {
+	s := strings.TrimSuffix(fieldText(e, bibtex.FieldEdition), ".")
+	if s == "" {
+		return ""
+	}
+	n, ok := ordinalWords[strings.ToLower(s)]
+	if !ok {
+		digits := strings.TrimRight(s, "stndrdthSTNDRDTH")
+		var err error
+		if n, err = strconv.Atoi(digits); err != nil {
+			return s
+		}
+	}
+	if n == 1 {
+		return ""
+	}
+	return ordinal(n)
+}
+
+// ordinal returns n with the English ordinal suffix, like "2nd" or "11th".
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return strconv.Itoa(n) + suffix
+}
+
+// monthNames are the names of the months for a style, indexed by
+// time.Month-1.
+type monthNames [12]string
+
+// fullMonths are the full English month names.
+var fullMonths = func() monthNames {
+	var m monthNames
+	for i := range m {
+		m[i] = time.Month(i + 1).String()
+	}
+	return m
+}()
+
+func (m monthNames) name(month time.Month) string {
+	if month < time.January || month > time.December {
+		return ""
+	}
+	return m[month-1]
+}
+
+// date returns the month name and year joined by a space, like "Sep. 2019",
+// or only the year if the entry has no month.
+func date(e bibtex.Entry, months monthNames) string {
+	y := year(e)
+	if y == "" {
+		return ""
+	}
+	if m := months.name(e.Month()); m != "" {
+		return m + " " + y
+	}
+	return y
+}
+
+// joinNonEmpty joins the non-empty strings with sep.
+func joinNonEmpty(sep string, ss ...string) string {
+	parts := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// bookTitle returns the title of the book containing the entry: the book
+// title of an incollection, or the title of an inbook.
+func bookTitle(e bibtex.Entry) ast.Expr {
+	if e.Type == bibtex.EntryInBook {
+		return field(e, bibtex.FieldTitle)
+	}
+	return field(e, bibtex.FieldBookTitle)
+}
+
+// editorLabel returns the label for one editor or for multiple editors.
+func editorLabel(plural bool, one, many string) string {
+	if plural {
+		return many
+	}
+	return one
+}
+
+// thesisType returns the type field of a thesis or the default for a PhD or
+// masters thesis.
+func thesisType(e bibtex.Entry, phd, masters string) string {
+	if t := fieldText(e, bibtex.FieldType); t != "" {
+		return t
+	}
+	if e.Type == bibtex.EntryPhDThesis {
+		return phd
+	}
+	return masters
+}
+
+// reportType returns the type field of a report or def.
+func reportType(e bibtex.Entry, def string) string {
+	if t := fieldText(e, bibtex.FieldType); t != "" {
+		return t
+	}
+	return def
+}
+
+// writeTitle writes the title of the entry in the place of the authors. The
+// title of a standalone work, like a book, is in italics.
+func writeTitle(b *builder, e bibtex.Entry, title ast.Expr) {
+	switch e.Type {
+	case bibtex.EntryBook, bibtex.EntryProceedings, bibtex.EntryTechReport, bibtex.EntryManual, bibtex.EntryBooklet:
+		b.emph(title)
+	default:
+		b.value(title)
+	}
+}
@@ -0,0 +1,141 @@
+package style
+
+import (
+	"github.com/jschaf/bibtex"
+)
+
+var chicagoNames = nameList{
+	format: firstInverted,
+	sep:    ", ",
+	and2:   ", and ",
+	andN:   ", and ",
+	max:    10,
+	keep:   7,
+	etAl:   ", et al.",
+}
+
+// chicagoEditors are editor names in the middle of a reference, like
+// "edited by Donald E. Knuth and Jane Doe".
+var chicagoEditors = nameList{
+	format: fullFirst,
+	sep:    ", ",
+	and2:   " and ",
+	andN:   ", and ",
+	max:    10,
+	keep:   7,
+	etAl:   ", et al.",
+}
+
+// Chicago returns the author-date reference style of the 17th edition of the
+// Chicago Manual of Style, like:
+//
+//	Knuth, Donald E. 1984. “Literate Programming.” The Computer Journal 27
+//	(2): 97–111. https://doi.org/10.1093/comjnl/27.2.97.
+//
+// Lists of more than ten authors show the first seven authors followed by
+// "et al.". Page ranges are abbreviated, like "2022–34".
+func Chicago() *Style {
+	return &Style{name: "Chicago", format: formatChicago}
+}
+
+func formatChicago(b *builder, e bibtex.Entry) {
+	title := field(e, bibtex.FieldTitle)
+	if names, others := toNames(e.Authors()); len(names) > 0 {
+		b.text(chicagoNames.join(names, others))
+	} else if names, others := toNames(e.Editors()); len(names) > 0 {
+		b.text(chicagoNames.join(names, others))
+		b.text(editorLabel(len(names) > 1 || others, ", ed.", ", eds."))
+	} else {
+		// Without authors, the title takes the place of the authors.
+		writeTitle(b, e, title)
+		title = nil
+	}
+	b.sep(". ")
+	if y := year(e); y != "" {
+		b.text(y)
+	} else {
+		b.text("n.d.")
+	}
+	b.sep(". ")
+
+	pages, _ := pageRange(e, true)
+	switch e.Type {
+	case bibtex.EntryArticle:
+		b.quoted(title)
+		b.sep(". ")
+		b.emph(field(e, bibtex.FieldJournal))
+		b.sep(" ")
+		b.text(fieldText(e, bibtex.FieldVolume))
+		if n := fieldText(e, bibtex.FieldNumber); n != "" {
+			b.sep(" ")
+			b.text("(" + n + ")")
+		}
+		b.sep(": ")
+		b.text(pages)
+
+	case bibtex.EntryBook, bibtex.EntryProceedings:
+		b.emph(title)
+		b.sep(". ")
+		if ed := edition(e); ed != "" {
+			b.text(ed + " ed.")
+		}
+		b.sep(". ")
+		b.text(volumeLabel(e))
+		b.sep(". ")
+		b.text(joinNonEmpty(": ", fieldText(e, bibtex.FieldAddress), fieldText(e, bibtex.FieldPublisher)))
+
+	case bibtex.EntryInBook, bibtex.EntryInCollection, bibtex.EntryInProceedings, "conference":
+		if e.Type != bibtex.EntryInBook {
+			b.quoted(title)
+			b.sep(". ")
+			b.text("In ")
+		}
+		b.emph(bookTitle(e))
+		if ed := edition(e); ed != "" {
+			b.sep(", ")
+			b.text(ed + " ed.")
+		}
+		if names, others := toNames(e.Editors()); len(names) > 0 {
+			b.sep(", ")
+			b.text("edited by " + chicagoEditors.join(names, others))
+		}
+		b.sep(", ")
+		b.text(pages)
+		b.sep(". ")
+		b.text(joinNonEmpty(": ", fieldText(e, bibtex.FieldAddress), fieldText(e, bibtex.FieldPublisher)))
+
+	case bibtex.EntryPhDThesis, bibtex.EntryMastersThesis:
+		b.quoted(title)
+		b.sep(". ")
+		b.text(joinNonEmpty(", ", thesisType(e, "PhD diss.", "Master's thesis"), fieldText(e, bibtex.FieldSchool)))
+
+	case bibtex.EntryTechReport:
+		b.emph(title)
+		b.sep(". ")
+		b.text(joinNonEmpty(" ", reportType(e, "Technical Report"), fieldText(e, bibtex.FieldNumber)))
+		b.sep(". ")
+		b.text(joinNonEmpty(": ", fieldText(e, bibtex.FieldAddress), fieldText(e, bibtex.FieldInstitution)))
+
+	default:
+		b.quoted(title)
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldHowPublished))
+		if m := fullMonths.name(e.Month()); m != "" {
+			b.sep(", ")
+			b.text(m)
+		}
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldOrganization))
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldNote))
+	}
+
+	if doi := e.DOI(); doi != "" {
+		b.sep(". ")
+		b.url("https://doi.org/" + doi)
+	} else if url := e.URL(); url != "" {
+		b.sep(". ")
+		b.url(url)
+	}
+	b.end(".")
+}
@@ -0,0 +1,171 @@
+package style
+
+import (
+	"github.com/jschaf/bibtex"
+)
+
+var ieeeNames = nameList{
+	format: initialsFirst,
+	sep:    ", ",
+	and2:   " and ",
+	andN:   ", and ",
+	max:    6,
+	keep:   1,
+	etAl:   " et al.",
+}
+
+var ieeeMonths = monthNames{
+	"Jan.", "Feb.", "Mar.", "Apr.", "May", "Jun.",
+	"Jul.", "Aug.", "Sep.", "Oct.", "Nov.", "Dec.",
+}
+
+// IEEE returns the IEEE reference style from the IEEE Reference Guide, like:
+//
+//	D. E. Knuth, “Literate Programming,” Comput. J., vol. 27, no. 2,
+//	pp. 97–111, May 1984, doi: 10.1093/comjnl/27.2.97.
+//
+// Lists of more than six authors show the first author followed by "et al.".
+func IEEE() *Style {
+	return &Style{name: "IEEE", format: formatIEEE}
+}
+
+func formatIEEE(b *builder, e bibtex.Entry) {
+	if names, others := toNames(e.Authors()); len(names) > 0 {
+		b.text(ieeeNames.join(names, others))
+		b.sep(", ")
+	} else if names, others := toNames(e.Editors()); len(names) > 0 {
+		b.text(ieeeNames.join(names, others))
+		b.text(editorLabel(len(names) > 1 || others, ", Ed.", ", Eds."))
+		b.sep(", ")
+	}
+
+	title := field(e, bibtex.FieldTitle)
+	pages, multiple := pageRange(e, false)
+	switch e.Type {
+	case bibtex.EntryArticle:
+		b.quoted(title)
+		b.sep(", ")
+		b.emph(field(e, bibtex.FieldJournal))
+		ieeeVolume(b, e)
+		b.sep(", ")
+		b.text(pagesLabel(pages, multiple))
+		b.sep(", ")
+		b.text(date(e, ieeeMonths))
+
+	case bibtex.EntryBook, bibtex.EntryProceedings:
+		b.emph(title)
+		b.sep(", ")
+		if ed := edition(e); ed != "" {
+			b.text(ed + " ed.")
+		}
+		ieeeVolume(b, e)
+		b.sep(". ")
+		b.text(joinNonEmpty(": ", fieldText(e, bibtex.FieldAddress), fieldText(e, bibtex.FieldPublisher)))
+		b.sep(", ")
+		b.text(year(e))
+
+	case bibtex.EntryInBook, bibtex.EntryInCollection:
+		if e.Type == bibtex.EntryInCollection {
+			b.quoted(title)
+			b.sep(", ")
+			b.text("in ")
+		}
+		b.emph(bookTitle(e))
+		b.sep(", ")
+		if ed := edition(e); ed != "" {
+			b.text(ed + " ed.")
+		}
+		if ch := fieldText(e, bibtex.FieldChapter); ch != "" {
+			b.sep(", ")
+			b.text("ch. " + ch)
+		}
+		if names, others := toNames(e.Editors()); len(names) > 0 {
+			b.sep(", ")
+			b.text(ieeeNames.join(names, others))
+			b.text(editorLabel(len(names) > 1 || others, ", Ed.", ", Eds."))
+		}
+		b.sep(". ")
+		b.text(joinNonEmpty(": ", fieldText(e, bibtex.FieldAddress), fieldText(e, bibtex.FieldPublisher)))
+		b.sep(", ")
+		b.text(year(e))
+		b.sep(", ")
+		b.text(pagesLabel(pages, multiple))
+
+	case bibtex.EntryInProceedings, "conference":
+		b.quoted(title)
+		b.sep(", ")
+		b.text("in ")
+		b.emph(field(e, bibtex.FieldBookTitle))
+		b.sep(", ")
+		b.text(fieldText(e, bibtex.FieldAddress))
+		b.sep(", ")
+		b.text(date(e, ieeeMonths))
+		b.sep(", ")
+		b.text(pagesLabel(pages, multiple))
+
+	case bibtex.EntryPhDThesis, bibtex.EntryMastersThesis:
+		b.quoted(title)
+		b.sep(", ")
+		b.text(thesisType(e, "Ph.D. dissertation", "M.S. thesis"))
+		b.sep(", ")
+		b.text(fieldText(e, bibtex.FieldSchool))
+		b.sep(", ")
+		b.text(fieldText(e, bibtex.FieldAddress))
+		b.sep(", ")
+		b.text(year(e))
+
+	case bibtex.EntryTechReport:
+		b.quoted(title)
+		b.sep(", ")
+		b.text(fieldText(e, bibtex.FieldInstitution))
+		b.sep(", ")
+		b.text(fieldText(e, bibtex.FieldAddress))
+		b.sep(", ")
+		b.text(joinNonEmpty(" ", reportType(e, "Tech. Rep."), fieldText(e, bibtex.FieldNumber)))
+		b.sep(", ")
+		b.text(date(e, ieeeMonths))
+
+	default:
+		if e.Type == bibtex.EntryManual || e.Type == bibtex.EntryBooklet {
+			b.emph(title)
+			b.sep(", ")
+		} else {
+			b.quoted(title)
+			b.sep(", ")
+		}
+		b.text(fieldText(e, bibtex.FieldHowPublished))
+		b.sep(", ")
+		b.text(fieldText(e, bibtex.FieldOrganization))
+		b.sep(", ")
+		b.text(fieldText(e, bibtex.FieldAddress))
+		b.sep(", ")
+		b.text(date(e, ieeeMonths))
+		b.sep(". ")
+		b.text(fieldText(e, bibtex.FieldNote))
+	}
+
+	if doi := e.DOI(); doi != "" {
+		b.sep(", ")
+		b.text("doi: " + doi)
+		b.end(".")
+	} else if url := e.URL(); url != "" {
+		b.end(".")
+		b.sep(" ")
+		b.text("[Online]. Available: ")
+		b.url(url)
+	} else {
+		b.end(".")
+	}
+}
+
+// ieeeVolume writes the volume and number, like "vol. 12, no. 3".
+func ieeeVolume(b *builder, e bibtex.Entry) {
+	if v := fieldText(e, bibtex.FieldVolume); v != "" {
+		b.sep(", ")
+		b.text("vol. " + v)
+	}
+	if n := fieldText(e, bibtex.FieldNumber); n != "" {
+		b.sep(", ")
+		b.text("no. " + n)
+	}
+}
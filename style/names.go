@@ -0,0 +1,119 @@
+package style
+
+import (
+	"strings"
+
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/names"
+)
+
+// name is the plain text of the parts of a person's name.
+type name struct {
+	first, prefix, last, suffix string
+	initials                    string // the initials of first, like "J.-P."
+}
+
+// toNames converts authors into names. Others is true if the authors end with
+// "and others".
+func toNames(authors ast.Authors) (list []name, others bool) {
+	for _, a := range authors {
+		if a.IsOthers() {
+			others = true
+			continue
+		}
+		list = append(list, name{
+			first:    partText(a.First),
+			prefix:   partText(a.Prefix),
+			last:     partText(a.Last),
+			suffix:   partText(a.Suffix),
+			initials: names.Initials(a),
+		})
+	}
+	return list, others
+}
+
+func partText(x ast.Expr) string {
+	if x == nil {
+		return ""
+	}
+	return plainText(x)
+}
+
+// family returns the prefix and last name, like "van Beethoven".
+func (n name) family() string {
+	return joinNonEmpty(" ", n.prefix, n.last)
+}
+
+// direct formats the name with the given names first, like
+// "M. L. King, Jr.".
+func (n name) direct(given string) string {
+	s := joinNonEmpty(" ", given, n.family())
+	return joinNonEmpty(", ", s, n.suffix)
+}
+
+// inverted formats the name with the family name first, like
+// "King, M. L., Jr.".
+func (n name) inverted(given string) string {
+	return joinNonEmpty(", ", n.family(), given, n.suffix)
+}
+
+// Formatters for a single name. The index is the position of the name in the
+// list.
+var (
+	initialsFirst = func(_ int, n name) string { return n.direct(n.initials) }
+	fullFirst     = func(_ int, n name) string { return n.direct(n.first) }
+	initialsLast  = func(_ int, n name) string { return n.inverted(n.initials) }
+	// firstInverted inverts only the first name, like the Chicago style:
+	// "Knuth, Donald E., and Jane Doe".
+	firstInverted = func(i int, n name) string {
+		if i == 0 {
+			return n.inverted(n.first)
+		}
+		return n.direct(n.first)
+	}
+)
+
+// nameList formats a list of names.
+type nameList struct {
+	format func(i int, n name) string
+	sep    string // between names, like ", "
+	and2   string // between exactly two names, like " and "
+	andN   string // before the last of three or more names, like ", and "
+	// A list with more than max names shows the first keep names followed by
+	// etAl. A max of 0 shows all names.
+	max, keep int
+	etAl      string // after a truncated list, like " et al."
+	// ellipsis truncates a list with more than max names by showing the first
+	// keep names, an ellipsis, and the last name, like APA.
+	ellipsis bool
+}
+
+// join formats the names. If others is true, the list ends with etAl.
+func (l nameList) join(names []name, others bool) string {
+	if len(names) == 0 {
+		return ""
+	}
+	formatted := make([]string, len(names))
+	for i, n := range names {
+		formatted[i] = l.format(i, n)
+	}
+	if l.max > 0 && len(names) > l.max {
+		if l.ellipsis {
+			last := formatted[len(formatted)-1]
+			return strings.Join(formatted[:l.keep], l.sep) + l.sep + ". . . " + last
+		}
+		return strings.Join(formatted[:l.keep], l.sep) + l.etAl
+	}
+	if others {
+		return strings.Join(formatted, l.sep) + l.etAl
+	}
+	switch len(formatted) {
+	case 1:
+		return formatted[0]
+	case 2:
+		return formatted[0] + l.and2 + formatted[1]
+	default:
+		n := len(formatted)
+		return strings.Join(formatted[:n-1], l.sep) + l.andN + formatted[n-1]
+	}
+}
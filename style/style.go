@@ -0,0 +1,54 @@
+// Package style formats bibtex entries into reference-list items in common
+// citation styles, like IEEE and APA.
+//
+// A Style formats an entry into an ast.Expr that uses LaTeX macros for
+// formatting, like \emph for italic titles and \url for links. Render the
+// expression with any renderer from the render package to get plain text,
+// HTML or Markdown.
+//
+// Entries must be resolved before formatting. The author and editor tags
+// must be resolved into ast.Authors, like with bibtex.WithPresets.
+package style
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+)
+
+// Renderer renders an ast.Expr, like render.TextRenderer,
+// render.HTMLRenderer, or render.MarkdownRenderer.
+type Renderer interface {
+	Render(w io.Writer, x ast.Expr) error
+}
+
+// Style formats an entry into a reference-list item.
+type Style struct {
+	name   string
+	format func(b *builder, e bibtex.Entry)
+}
+
+// Name returns the name of the style, like "IEEE".
+func (s *Style) Name() string {
+	return s.name
+}
+
+// Format formats the entry into a reference-list item. The layout of the item
+// depends on the entry type. Unknown entry types use the layout of
+// bibtex.EntryMisc.
+func (s *Style) Format(e bibtex.Entry) *ast.ParsedText {
+	b := &builder{}
+	s.format(b, e)
+	return b.expr()
+}
+
+// Render formats the entry into a reference-list item and writes it to w with
+// the renderer r.
+func (s *Style) Render(w io.Writer, r Renderer, e bibtex.Entry) error {
+	if err := r.Render(w, s.Format(e)); err != nil {
+		return fmt.Errorf("render %s style for entry %s: %w", s.name, e.Key, err)
+	}
+	return nil
+}
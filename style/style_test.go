@@ -0,0 +1,335 @@
+package style
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/internal/bibtest"
+	"github.com/jschaf/bibtex/parser"
+	"github.com/jschaf/bibtex/render"
+)
+
+const testBib = `
+@article{procella,
+  title = {Procella: Unifying serving and analytical data at {YouTube}},
+  author = {Chattopadhyay, Biswapesh and Dutta, Priyam and Liu, Weiran and
+            Tinn, Ott and Mccormick, Andrew and Mokashi, Aniket and Harvey, Paul and
+            Gonzalez, Hector and Lomax, David and Mittal, Sagar and others},
+  journal = {Proceedings of the VLDB Endowment},
+  volume = {12},
+  number = {12},
+  pages = {2022--2034},
+  year = {2019},
+  month = aug,
+  doi = {10.14778/3352063.3352121},
+}
+@article{knuth84,
+  title = {Literate Programming},
+  author = {Knuth, Donald E.},
+  journal = {The Computer Journal},
+  volume = 27,
+  number = 2,
+  pages = {97--111},
+  year = 1984,
+  month = may,
+}
+@book{taocp,
+  title = {The Art of Computer Programming},
+  author = {Knuth, Donald E.},
+  edition = {Third},
+  volume = 1,
+  publisher = {Addison-Wesley},
+  address = {Reading, MA},
+  year = 1997,
+}
+@book{edited,
+  title = {Edited Volume},
+  editor = {Doe, Jane and Roe, Rick},
+  publisher = {Pub},
+  year = 2000,
+}
+@inproceedings{conf,
+  title = {A Paper?},
+  author = {Doe, Jane and van Beethoven, Ludwig and King, Martin Luther},
+  booktitle = {Proc. of the Conf},
+  address = {Berlin},
+  pages = {10--20},
+  year = 2020,
+  publisher = {ACM},
+}
+@incollection{coll,
+  title = {A Chapter},
+  author = {Lee, Jean-Paul},
+  booktitle = {The Book},
+  editor = {Smith, Ann},
+  pages = {101--108},
+  publisher = {Springer},
+  address = {Cham},
+  year = 2018,
+  edition = 2,
+}
+@phdthesis{thesis,
+  title = {My Thesis},
+  author = {Doe, Jane},
+  school = {MIT},
+  year = 2010,
+}
+@techreport{tr,
+  title = {A Report},
+  author = {Doe, Jane},
+  institution = {Google},
+  number = {TR-1},
+  year = 2011,
+  month = jan,
+}
+@misc{web,
+  title = {A Web Page},
+  author = {Doe, Jane},
+  howpublished = {Blog},
+  year = 2012,
+  month = mar,
+  url = {https://example.com/a_b},
+}
+@misc{nodate,
+  title = {No Date},
+}
+`
+
+func TestStyle_Render(t *testing.T) {
+	tests := []struct {
+		style *Style
+		want  []string
+	}{
+		{
+			style: IEEE(),
+			want: []string{
+				"B. Chattopadhyay et al., “Procella: Unifying serving and analytical data at YouTube,” Proceedings of the VLDB Endowment, vol. 12, no. 12, pp. 2022–2034, Aug. 2019, doi: 10.14778/3352063.3352121.",
+				"D. E. Knuth, “Literate Programming,” The Computer Journal, vol. 27, no. 2, pp. 97–111, May 1984.",
+				"D. E. Knuth, The Art of Computer Programming, 3rd ed., vol. 1. Reading, MA: Addison-Wesley, 1997.",
+				"J. Doe and R. Roe, Eds., Edited Volume. Pub, 2000.",
+				"J. Doe, L. van Beethoven, and M. L. King, “A Paper?” in Proc. of the Conf, Berlin, 2020, pp. 10–20.",
+				"J.-P. Lee, “A Chapter,” in The Book, 2nd ed., A. Smith, Ed. Cham: Springer, 2018, pp. 101–108.",
+				"J. Doe, “My Thesis,” Ph.D. dissertation, MIT, 2010.",
+				"J. Doe, “A Report,” Google, Tech. Rep. TR-1, Jan. 2011.",
+				"J. Doe, “A Web Page,” Blog, Mar. 2012. [Online]. Available: https://example.com/a_b",
+				"“No Date.”",
+			},
+		},
+		{
+			style: ACM(),
+			want: []string{
+				"Biswapesh Chattopadhyay, Priyam Dutta, Weiran Liu, Ott Tinn, Andrew Mccormick, Aniket Mokashi, Paul Harvey, Hector Gonzalez, David Lomax, Sagar Mittal, et al. 2019. Procella: Unifying serving and analytical data at YouTube. Proceedings of the VLDB Endowment 12, 12 (Aug. 2019), 2022–2034. https://doi.org/10.14778/3352063.3352121",
+				"Donald E. Knuth. 1984. Literate Programming. The Computer Journal 27, 2 (May 1984), 97–111.",
+				"Donald E. Knuth. 1997. The Art of Computer Programming (3rd. ed.), Vol. 1. Addison-Wesley, Reading, MA.",
+				"Jane Doe and Rick Roe (Eds.). 2000. Edited Volume. Pub.",
+				"Jane Doe, Ludwig van Beethoven, and Martin Luther King. 2020. A Paper? In Proc. of the Conf. ACM, Berlin, 10–20.",
+				"Jean-Paul Lee. 2018. A Chapter. In The Book (2nd. ed.), Ann Smith (Ed.). Springer, Cham, 101–108.",
+				"Jane Doe. 2010. My Thesis. Ph.D. Dissertation. MIT.",
+				"Jane Doe. 2011. A Report. Technical Report TR-1. Google.",
+				"Jane Doe. 2012. A Web Page. Blog. https://example.com/a_b",
+				"No Date.",
+			},
+		},
+		{
+			style: APA(),
+			want: []string{
+				"Chattopadhyay, B., Dutta, P., Liu, W., Tinn, O., Mccormick, A., Mokashi, A., Harvey, P., Gonzalez, H., Lomax, D., Mittal, S., et al. (2019). Procella: Unifying serving and analytical data at YouTube. Proceedings of the VLDB Endowment, 12(12), 2022–2034. https://doi.org/10.14778/3352063.3352121",
+				"Knuth, D. E. (1984). Literate Programming. The Computer Journal, 27(2), 97–111.",
+				"Knuth, D. E. (1997). The Art of Computer Programming (3rd ed., Vol. 1). Addison-Wesley.",
+				"Doe, J., & Roe, R. (Eds.). (2000). Edited Volume. Pub.",
+				"Doe, J., van Beethoven, L., & King, M. L. (2020). A Paper? In Proc. of the Conf (pp. 10–20). ACM.",
+				"Lee, J.-P. (2018). A Chapter. In A. Smith (Ed.), The Book (2nd ed., pp. 101–108). Springer.",
+				"Doe, J. (2010). My Thesis [Doctoral dissertation, MIT].",
+				"Doe, J. (2011). A Report (Report No. TR-1). Google.",
+				"Doe, J. (2012, March). A Web Page. Blog. https://example.com/a_b",
+				"No Date. (n.d.).",
+			},
+		},
+		{
+			style: Chicago(),
+			want: []string{
+				"Chattopadhyay, Biswapesh, Priyam Dutta, Weiran Liu, Ott Tinn, Andrew Mccormick, Aniket Mokashi, Paul Harvey, Hector Gonzalez, David Lomax, Sagar Mittal, et al. 2019. “Procella: Unifying serving and analytical data at YouTube.” Proceedings of the VLDB Endowment 12 (12): 2022–34. https://doi.org/10.14778/3352063.3352121.",
+				"Knuth, Donald E. 1984. “Literate Programming.” The Computer Journal 27 (2): 97–111.",
+				"Knuth, Donald E. 1997. The Art of Computer Programming. 3rd ed. Vol. 1. Reading, MA: Addison-Wesley.",
+				"Doe, Jane, and Rick Roe, eds. 2000. Edited Volume. Pub.",
+				"Doe, Jane, Ludwig van Beethoven, and Martin Luther King. 2020. “A Paper?” In Proc. of the Conf, 10–20. Berlin: ACM.",
+				"Lee, Jean-Paul. 2018. “A Chapter.” In The Book, 2nd ed., edited by Ann Smith, 101–8. Cham: Springer.",
+				"Doe, Jane. 2010. “My Thesis.” PhD diss., MIT.",
+				"Doe, Jane. 2011. A Report. Technical Report TR-1. Google.",
+				"Doe, Jane. 2012. “A Web Page.” Blog, March. https://example.com/a_b.",
+				"No Date. n.d.",
+			},
+		},
+	}
+	entries := bibtest.Read(t, testBib)
+	for _, tt := range tests {
+		t.Run(tt.style.Name(), func(t *testing.T) {
+			got := make([]string, len(entries))
+			for i, e := range entries {
+				sb := &strings.Builder{}
+				if err := tt.style.Render(sb, render.NewTextRenderer(), e); err != nil {
+					t.Fatal(err)
+				}
+				got[i] = sb.String()
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestStyle_Render_renderers(t *testing.T) {
+	entries := bibtest.Read(t, `
+		@article{knuth84,
+			title = {Literate Programming},
+			author = {Knuth, Donald E.},
+			journal = {The Computer Journal},
+			volume = 27,
+			year = 1984,
+			url = {https://example.com/a_b},
+		}`)
+	tests := []struct {
+		name     string
+		renderer Renderer
+		want     string
+	}{
+		{
+			"html", render.NewHTMLRenderer(),
+			`Knuth, D. E. (1984). Literate Programming. <em>The Computer Journal</em>, <em>27</em>. ` +
+				`<a href="https://example.com/a_b">https://example.com/a_b</a>`,
+		},
+		{
+			"markdown", render.NewMarkdownRenderer(),
+			`Knuth, D. E. (1984). Literate Programming. *The Computer Journal*, *27*. ` +
+				`[https://example.com/a\_b](https://example.com/a\_b)`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sb := &strings.Builder{}
+			if err := APA().Render(sb, tt.renderer, entries[0]); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.want, sb.String()); diff != "" {
+				t.Errorf("Render() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNameList_join(t *testing.T) {
+	names := func(n int) []name {
+		ns := make([]name, n)
+		for i := range ns {
+			ns[i] = name{first: "Ann", last: string(rune('A' + i)), initials: "A."}
+		}
+		return ns
+	}
+	tests := []struct {
+		name   string
+		list   nameList
+		names  []name
+		others bool
+		want   string
+	}{
+		{"ieee one", ieeeNames, names(1), false, "A. A"},
+		{"ieee two", ieeeNames, names(2), false, "A. A and A. B"},
+		{"ieee three", ieeeNames, names(3), false, "A. A, A. B, and A. C"},
+		{"ieee six", ieeeNames, names(6), false, "A. A, A. B, A. C, A. D, A. E, and A. F"},
+		{"ieee seven", ieeeNames, names(7), false, "A. A et al."},
+		{"ieee others", ieeeNames, names(2), true, "A. A, A. B et al."},
+		{"apa two", apaNames, names(2), false, "A, A., & B, A."},
+		{"apa twenty", apaNames, names(20), false, "A, A., B, A., C, A., D, A., E, A., F, A., G, A., H, A., I, A., J, A., K, A., L, A., M, A., N, A., O, A., P, A., Q, A., R, A., S, A., & T, A."},
+		{"apa twenty-one", apaNames, names(21), false, "A, A., B, A., C, A., D, A., E, A., F, A., G, A., H, A., I, A., J, A., K, A., L, A., M, A., N, A., O, A., P, A., Q, A., R, A., S, A., . . . U, A."},
+		{"chicago two", chicagoNames, names(2), false, "A, Ann, and Ann B"},
+		{"chicago eleven", chicagoNames, names(11), false, "A, Ann, Ann B, Ann C, Ann D, Ann E, Ann F, Ann G, et al."},
+		{"prefix and suffix", chicagoNames, []name{{first: "Ludwig", prefix: "van", last: "Beethoven", suffix: "Jr."}}, false, "van Beethoven, Ludwig, Jr."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.list.join(tt.names, tt.others); got != tt.want {
+				t.Errorf("join() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToNames_initials(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Knuth", ""},
+		{"Donald Knuth", "D."},
+		{"Donald E. Knuth", "D. E."},
+		{"Jean-Paul Sartre", "J.-P."},
+		{"{\\'E}mile Zola", "É."},
+		{"Bertrand, {Ch}ristophe", "Ch."},
+	}
+	for _, tt := range tests {
+		x, err := parser.ParseExpr("{" + tt.name + "}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		authors, err := bibtex.ExtractAuthors(x.(*ast.ParsedText))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := toNames(authors); got[0].initials != tt.want {
+			t.Errorf("toNames(%q) initials = %q, want %q", tt.name, got[0].initials, tt.want)
+		}
+	}
+}
+
+func TestAbbrevLastPage(t *testing.T) {
+	// Examples from the Chicago Manual of Style 9.61.
+	tests := []struct {
+		first, last, want string
+	}{
+		{"3", "10", "10"},
+		{"71", "72", "72"},
+		{"96", "117", "117"},
+		{"100", "104", "104"},
+		{"1100", "1113", "1113"},
+		{"101", "108", "8"},
+		{"808", "833", "33"},
+		{"1103", "1104", "4"},
+		{"321", "328", "28"},
+		{"498", "532", "532"},
+		{"1087", "1089", "89"},
+		{"1496", "1500", "500"},
+		{"11564", "11615", "615"},
+		{"12991", "13001", "3001"},
+		{"xii", "xv", "xv"},
+	}
+	for _, tt := range tests {
+		if got := abbrevLastPage(tt.first, tt.last); got != tt.want {
+			t.Errorf("abbrevLastPage(%q, %q) = %q, want %q", tt.first, tt.last, got, tt.want)
+		}
+	}
+}
+
+func TestEdition(t *testing.T) {
+	tests := []struct {
+		edition, want string
+	}{
+		{"1", ""},
+		{"First", ""},
+		{"2", "2nd"},
+		{"2nd", "2nd"},
+		{"Second", "2nd"},
+		{"third", "3rd"},
+		{"11", "11th"},
+		{"21", "21st"},
+		{"Revised", "Revised"},
+	}
+	for _, tt := range tests {
+		entries := bibtest.Read(t, "@book{k, edition = {"+tt.edition+"}}")
+		if got := edition(entries[0]); got != tt.want {
+			t.Errorf("edition(%q) = %q, want %q", tt.edition, got, tt.want)
+		}
+	}
+}
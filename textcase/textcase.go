@@ -0,0 +1,433 @@
+// Package textcase converts the case of parsed bibtex text, like the
+// change.case$ function of bibtex.
+//
+// Text in brace groups keeps its case, so "{NASA}" is never changed. A brace
+// group that starts with a control sequence, like {\'E} or {\ss}, is a
+// special character and converts like a single letter.
+package textcase
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/jschaf/bibtex/ast"
+)
+
+// Mode is the case to convert text into.
+type Mode int
+
+const (
+	// Sentence uppercases the first letter of the text and the first letter
+	// after a colon, and lowercases all other letters, like "A study of
+	// {NASA}: Past and present".
+	Sentence Mode = iota
+	// Title uppercases the first letter of each word except for English small
+	// words, like "a" and "of", that aren't the first or last word or after a
+	// colon. Each word of a hyphenated compound is capitalized the same way,
+	// like "State-of-the-Art". Other letters keep their case.
+	Title
+	// Upper uppercases all letters.
+	Upper
+	// Lower lowercases all letters.
+	Lower
+)
+
+func (m Mode) String() string {
+	switch m {
+	case Sentence:
+		return "Sentence"
+	case Title:
+		return "Title"
+	case Upper:
+		return "Upper"
+	case Lower:
+		return "Lower"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+// smallWords are the English words that title case doesn't capitalize unless
+// first or last.
+var smallWords = map[string]bool{
+	"a": true, "an": true, "and": true, "as": true, "at": true, "but": true,
+	"by": true, "down": true, "for": true, "from": true, "in": true,
+	"into": true, "nor": true, "of": true, "on": true, "onto": true, "or": true,
+	"over": true, "so": true, "the": true, "till": true, "to": true, "up": true,
+	"via": true, "with": true, "yet": true,
+}
+
+// specialLetters are the control sequences of special characters that are
+// letters, with the control sequence of the other case. The sequences \i,
+// \j and \ss have no uppercase sequence and uppercase to plain text.
+var specialLetters = map[string]string{
+	"oe": "OE", "OE": "oe", "ae": "AE", "AE": "ae", "aa": "AA", "AA": "aa",
+	"o": "O", "O": "o", "l": "L", "L": "l", "i": "I", "j": "J", "ss": "SS",
+}
+
+// Convert returns a copy of the parsed text with the case of its letters at
+// brace depth 0 converted into mode. Convert doesn't modify txt.
+func Convert(txt *ast.ParsedText, mode Mode) *ast.ParsedText {
+	out := clone(txt).(*ast.ParsedText)
+	c := &converter{}
+	c.collect(out.Values)
+	words := c.words()
+	capNext := true // at the start of the text or after a colon
+	for i, w := range words {
+		switch mode {
+		case Upper, Lower:
+			for _, u := range w {
+				c.setCase(u, mode == Upper)
+			}
+		case Sentence:
+			first := firstCased(w)
+			for j, u := range w {
+				c.setCase(u, capNext && j == first)
+			}
+		case Title:
+			parts := splitHyphens(w)
+			for j, part := range parts {
+				capitalize := !smallWords[part.lowerText()] ||
+					j == 0 && (capNext || i == 0) ||
+					j == len(parts)-1 && i == len(words)-1
+				if !capitalize {
+					for _, u := range part {
+						c.setCase(u, false)
+					}
+				} else if first := firstCased(part); first >= 0 {
+					c.setCase(part[first], true)
+				}
+			}
+		}
+		capNext = w.endsWith(':')
+	}
+	c.flush()
+	return out
+}
+
+// Transformer converts the case of the parsed text of the tags with the
+// given names, like "title".
+type Transformer struct {
+	Mode Mode
+	Tags []string
+}
+
+func (t Transformer) Transform(node ast.Node) error {
+	err := ast.Walk(node, func(n ast.Node, isEntering bool) (ast.WalkStatus, error) {
+		if !isEntering {
+			return ast.WalkSkipChildren, nil
+		}
+		tag, ok := n.(*ast.TagStmt)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		txt, ok := tag.Value.(*ast.ParsedText)
+		if !ok {
+			return ast.WalkSkipChildren, nil
+		}
+		for _, name := range t.Tags {
+			if strings.EqualFold(tag.Name, name) {
+				tag.Value = Convert(txt, t.Mode)
+				break
+			}
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	if err != nil {
+		return fmt.Errorf("text case transform: %w", err)
+	}
+	return nil
+}
+
+// unitKind is the kind of a unit of text.
+type unitKind int
+
+const (
+	runeUnit      unitKind = iota // a rune of text or punctuation
+	letterUnit                    // a special character or accented letter
+	protectedUnit                 // a brace group or math that keeps its case
+	spaceUnit                     // whitespace that separates words
+)
+
+// unit is a single character of text at brace depth 0.
+type unit struct {
+	kind unitKind
+	r    rune // the rune of a rune unit or the lowercase letter of a letter unit
+
+	text int // for a rune unit, the index of the text in converter.texts or -1
+	i    int // for a rune unit, the index of the rune in the text
+
+	vals []ast.Expr // for a letter unit, the values with the special character
+	j    int        // for a letter unit, the index of the special character
+}
+
+// isLetter returns true if the case of the unit can change.
+func (u *unit) isLetter() bool {
+	switch u.kind {
+	case runeUnit:
+		return u.text >= 0 && unicode.IsLetter(u.r)
+	case letterUnit:
+		return true
+	}
+	return false
+}
+
+// textRunes are the runes of a text node while converting.
+type textRunes struct {
+	t     *ast.Text
+	runes []rune
+}
+
+type converter struct {
+	texts []textRunes
+	units []*unit
+}
+
+// collect splits the values at brace depth 0 into units.
+func (c *converter) collect(vals []ast.Expr) {
+	for j, v := range vals {
+		switch v := v.(type) {
+		case *ast.Text:
+			c.texts = append(c.texts, textRunes{t: v, runes: []rune(v.Value)})
+			text := len(c.texts) - 1
+			for i, r := range c.texts[text].runes {
+				kind := runeUnit
+				if unicode.IsSpace(r) {
+					kind = spaceUnit
+				}
+				c.units = append(c.units, &unit{kind: kind, r: r, text: text, i: i})
+			}
+		case *ast.TextSpace, *ast.TextNBSP:
+			c.units = append(c.units, &unit{kind: spaceUnit, r: ' ', text: -1})
+		case *ast.TextHyphen:
+			c.units = append(c.units, &unit{kind: runeUnit, r: '-', text: -1})
+		case *ast.TextComma:
+			c.units = append(c.units, &unit{kind: runeUnit, r: ',', text: -1})
+		case *ast.TextEscaped:
+			c.units = append(c.units, &unit{kind: runeUnit, r: []rune(v.Value)[0], text: -1})
+		case *ast.TextAccent:
+			c.units = append(c.units, &unit{kind: letterUnit, r: letterOf(v), vals: vals, j: j})
+		case *ast.TextMacro:
+			if _, ok := specialLetters[v.Name]; ok {
+				c.units = append(c.units, &unit{kind: letterUnit, r: letterOf(v), vals: vals, j: j})
+			} else {
+				c.units = append(c.units, &unit{kind: protectedUnit, text: -1})
+			}
+		case *ast.ParsedText:
+			if isSpecialChar(v) {
+				c.units = append(c.units, &unit{kind: letterUnit, r: letterOf(v), vals: vals, j: j})
+			} else {
+				c.units = append(c.units, &unit{kind: protectedUnit, text: -1})
+			}
+		default:
+			c.units = append(c.units, &unit{kind: protectedUnit, text: -1})
+		}
+	}
+}
+
+// word is the units of a word between spaces.
+type word []*unit
+
+// words splits the units into words separated by spaces.
+func (c *converter) words() []word {
+	var words []word
+	var w word
+	for _, u := range c.units {
+		if u.kind == spaceUnit {
+			if len(w) > 0 {
+				words = append(words, w)
+			}
+			w = nil
+			continue
+		}
+		w = append(w, u)
+	}
+	if len(w) > 0 {
+		words = append(words, w)
+	}
+	return words
+}
+
+// splitHyphens splits a word into the parts of a hyphenated compound.
+func splitHyphens(w word) []word {
+	var parts []word
+	start := 0
+	for i, u := range w {
+		if u.kind == runeUnit && u.r == '-' {
+			parts = append(parts, w[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, w[start:])
+}
+
+// lowerText returns the lowercase letters of the word.
+func (w word) lowerText() string {
+	sb := &strings.Builder{}
+	for _, u := range w {
+		if u.isLetter() {
+			sb.WriteRune(unicode.ToLower(u.r))
+		}
+	}
+	return sb.String()
+}
+
+// endsWith returns true if the last rune of the word is r.
+func (w word) endsWith(r rune) bool {
+	return len(w) > 0 && w[len(w)-1].kind == runeUnit && w[len(w)-1].r == r
+}
+
+// firstCased returns the index of the first letter or protected unit of the
+// word or -1 if none. A protected unit keeps its case, so a word starting
+// with {NASA} is never capitalized.
+func firstCased(w word) int {
+	for i, u := range w {
+		if u.isLetter() || u.kind == protectedUnit {
+			return i
+		}
+	}
+	return -1
+}
+
+// setCase sets the case of the unit if it's a letter.
+func (c *converter) setCase(u *unit, upper bool) {
+	if !u.isLetter() {
+		return
+	}
+	if u.kind == letterUnit {
+		u.vals[u.j] = setSpecialCase(u.vals[u.j], upper)
+		return
+	}
+	rs := c.texts[u.text].runes
+	if upper {
+		rs[u.i] = unicode.ToUpper(rs[u.i])
+	} else {
+		rs[u.i] = unicode.ToLower(rs[u.i])
+	}
+}
+
+// flush writes the converted runes into the text nodes.
+func (c *converter) flush() {
+	for _, t := range c.texts {
+		t.t.Value = string(t.runes)
+	}
+}
+
+// setSpecialCase returns the special character or accented letter x in the
+// case given by upper.
+func setSpecialCase(x ast.Expr, upper bool) ast.Expr {
+	switch x := x.(type) {
+	case *ast.ParsedText:
+		for i, v := range x.Values {
+			x.Values[i] = setSpecialCase(v, upper)
+		}
+	case *ast.Text:
+		if upper {
+			x.Value = strings.ToUpper(x.Value)
+		} else {
+			x.Value = strings.ToLower(x.Value)
+		}
+	case *ast.TextAccent:
+		old := x.Text.Value
+		setSpecialCase(x.Text, upper)
+		if i := strings.LastIndex(x.Raw, old); i >= 0 {
+			x.Raw = x.Raw[:i] + x.Text.Value + x.Raw[i+len(old):]
+		}
+	case *ast.TextMacro:
+		other, ok := specialLetters[x.Name]
+		if !ok {
+			for i, v := range x.Values {
+				x.Values[i] = setSpecialCase(v, upper)
+			}
+			return x
+		}
+		if upper == unicode.IsUpper(rune(x.Name[0])) {
+			return x
+		}
+		switch x.Name {
+		case "i", "j", "ss":
+			return &ast.Text{ValuePos: x.Cmd, Value: other}
+		}
+		x.Name = other
+	}
+	return x
+}
+
+// letterOf returns the lowercase letter of a special character or accented
+// letter.
+func letterOf(x ast.Expr) rune {
+	switch x := x.(type) {
+	case *ast.ParsedText:
+		for _, v := range x.Values {
+			if r := letterOf(v); r != 0 {
+				return r
+			}
+		}
+	case *ast.Text:
+		for _, r := range x.Value {
+			if unicode.IsLetter(r) {
+				return unicode.ToLower(r)
+			}
+		}
+	case *ast.TextAccent:
+		return letterOf(x.Text)
+	case *ast.TextMacro:
+		if _, ok := specialLetters[x.Name]; ok {
+			return unicode.ToLower([]rune(x.Name)[0])
+		}
+		for _, v := range x.Values {
+			if r := letterOf(v); r != 0 {
+				return r
+			}
+		}
+	}
+	return 0
+}
+
+// isSpecialChar returns true if the brace group starts with a control
+// sequence, like {\'E} or {\ss}.
+func isSpecialChar(x *ast.ParsedText) bool {
+	if len(x.Values) == 0 {
+		return false
+	}
+	switch x.Values[0].(type) {
+	case *ast.TextAccent, *ast.TextMacro, *ast.TextEscaped:
+		return true
+	}
+	return false
+}
+
+// clone returns a deep copy of the text nodes of x that Convert may modify.
+func clone(x ast.Expr) ast.Expr {
+	switch x := x.(type) {
+	case *ast.ParsedText:
+		c := *x
+		c.Values = cloneAll(x.Values)
+		return &c
+	case *ast.Text:
+		c := *x
+		return &c
+	case *ast.TextAccent:
+		c := *x
+		t := *x.Text
+		c.Text = &t
+		return &c
+	case *ast.TextMacro:
+		c := *x
+		c.Values = cloneAll(x.Values)
+		return &c
+	}
+	return x
+}
+
+func cloneAll(xs []ast.Expr) []ast.Expr {
+	if xs == nil {
+		return nil
+	}
+	out := make([]ast.Expr, len(xs))
+	for i, x := range xs {
+		out[i] = clone(x)
+	}
+	return out
+}
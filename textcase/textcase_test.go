@@ -0,0 +1,94 @@
+package textcase
+
+import (
+	gotok "go/token"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jschaf/bibtex/ast"
+	"github.com/jschaf/bibtex/parser"
+	"github.com/jschaf/bibtex/printer"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		text string
+		mode Mode
+		want string
+	}{
+		{"", Sentence, ""},
+		{"The Art of Computer Programming", Sentence, "The art of computer programming"},
+		{"the art of computer programming", Sentence, "The art of computer programming"},
+		{"A Study of {NASA}: Past And Present", Sentence, "A study of {NASA}: Past and present"},
+		{"{NASA} Missions", Sentence, "{NASA} missions"},
+		{"{\\'E}COLE Normale", Sentence, "{\\'E}cole normale"},
+		{"{\\'e}cole Normale", Sentence, "{\\'E}cole normale"},
+		{"\\'Ecole Normale", Sentence, "\\'Ecole normale"},
+		{"Self-Organizing Maps", Sentence, "Self-organizing maps"},
+		{"Using $O(N)$ Space", Sentence, "Using $O(N)$ space"},
+		{"Die Stra{\\ss}e", Sentence, "Die stra{\\ss}e"},
+		{"the art of computer programming", Title, "The Art of Computer Programming"},
+		{"a study of {NASA}: the past and present", Title, "A Study of {NASA}: The Past and Present"},
+		{"state-of-the-art methods", Title, "State-of-the-Art Methods"},
+		{"what to look for", Title, "What to Look For"},
+		{"a built-in type", Title, "A Built-in Type"},
+		{"types that are built-in", Title, "Types That Are Built-In"},
+		{"{\\'e}cole of {iPhone} design", Title, "{\\'E}cole of {iPhone} Design"},
+		{"the {\\oe}uvre of a painter", Title, "The {\\OE}uvre of a Painter"},
+		{"mapReduce In Go", Title, "MapReduce in Go"},
+		{"The {NASA} Way and {\\'e}t{\\'e}", Upper, "THE {NASA} WAY AND {\\'E}T{\\'E}"},
+		{"Die Stra{\\ss}e", Upper, "DIE STRA{SS}E"},
+		{"l'{\\oe}uvre {\\aa}", Upper, "L'{\\OE}UVRE {\\AA}"},
+		{"{\\c{c}}a va", Upper, "{\\c{C}}A VA"},
+		{"The {NASA} Way and {\\'E}T{\\'E}", Lower, "the {NASA} way and {\\'e}t{\\'e}"},
+		{"{\\OE}UVRE {\\L}ODZ \\'E", Lower, "{\\oe}uvre {\\l}odz \\'e"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode.String()+"/"+tt.text, func(t *testing.T) {
+			x, err := parser.ParseExpr("{" + tt.text + "}")
+			if err != nil {
+				t.Fatal(err)
+			}
+			txt := x.(*ast.ParsedText)
+			before := render(t, txt)
+
+			got := render(t, Convert(txt, tt.mode))
+			if diff := cmp.Diff("{"+tt.want+"}", got); diff != "" {
+				t.Errorf("Convert() mismatch (-want +got):\n%s", diff)
+			}
+			if after := render(t, txt); after != before {
+				t.Errorf("Convert() modified the input: got %s; want %s", after, before)
+			}
+		})
+	}
+}
+
+func TestTransformer_Transform(t *testing.T) {
+	src := "@article{key, title = {the {NASA} way}, journal = {the journal}}"
+	f, err := parser.ParseFile(gotok.NewFileSet(), "", src, parser.ParseStrings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := (Transformer{Mode: Title, Tags: []string{"title"}}).Transform(f); err != nil {
+		t.Fatal(err)
+	}
+	sb := &strings.Builder{}
+	if err := printer.Fprint(sb, nil, f); err != nil {
+		t.Fatal(err)
+	}
+	want := "@article{key,\n  title = {The {NASA} Way},\n  journal = {the journal}\n}\n"
+	if diff := cmp.Diff(want, sb.String()); diff != "" {
+		t.Errorf("Transform() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+// render prints the bibtex source of x.
+func render(t *testing.T, x ast.Expr) string {
+	t.Helper()
+	sb := &strings.Builder{}
+	if err := printer.Fprint(sb, nil, x); err != nil {
+		t.Fatal(err)
+	}
+	return sb.String()
+}
@@ -4,11 +4,37 @@ package token
 type Accent rune
 
 const (
-	AccentAcute      Accent = '\''
-	AccentCedilla    Accent = 'c'
-	AccentCircumflex Accent = '^'
-	AccentDot        Accent = '.'
-	AccentGrave      Accent = '`'
-	AccentTilde      Accent = '~'
-	AccentUmlaut     Accent = '"'
+	AccentAcute       Accent = '\''
+	AccentBarUnder    Accent = 'b'
+	AccentBreve       Accent = 'u'
+	AccentCaron       Accent = 'v'
+	AccentCedilla     Accent = 'c'
+	AccentCircumflex  Accent = '^'
+	AccentDot         Accent = '.'
+	AccentDotUnder    Accent = 'd'
+	AccentDoubleAcute Accent = 'H'
+	AccentGrave       Accent = '`'
+	AccentMacron      Accent = '='
+	AccentOgonek      Accent = 'k'
+	AccentRing        Accent = 'r'
+	AccentTilde       Accent = '~'
+	AccentUmlaut      Accent = '"'
 )
+
+// IsLetter returns true if the accent marker is a letter, like the 'v' in \v{s}.
+// A letter accent is only an accent if the macro name is a single letter.
+func (a Accent) IsLetter() bool {
+	return 'a' <= a && a <= 'z' || 'A' <= a && a <= 'Z'
+}
+
+// IsAccent returns true if ch is the marker of a LaTeX text-mode accent.
+func IsAccent(ch rune) bool {
+	switch Accent(ch) {
+	case AccentAcute, AccentBarUnder, AccentBreve, AccentCaron, AccentCedilla,
+		AccentCircumflex, AccentDot, AccentDotUnder, AccentDoubleAcute,
+		AccentGrave, AccentMacron, AccentOgonek, AccentRing, AccentTilde,
+		AccentUmlaut:
+		return true
+	}
+	return false
+}
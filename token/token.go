@@ -31,6 +31,7 @@ const (
 	String      // "abc"
 	BraceString // {abc}
 	Number      // 2005
+	CommentText // contents of an @comment, like the "foo" in @comment{foo}
 	literalEnd
 
 	// Tokens delimiting strings or contained in a bibtex string literal.
@@ -77,6 +78,7 @@ var tokens = [...]string{
 	String:      "String",
 	BraceString: "BraceString",
 	Number:      "Number",
+	CommentText: "CommentText",
 
 	// String literals
 	DoubleQuote:     "DoubleQuote",